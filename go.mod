@@ -8,4 +8,5 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.11.1
 	golang.org/x/crypto v0.47.0
+	golang.org/x/sync v0.19.0
 )
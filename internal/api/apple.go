@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -14,6 +15,9 @@ import (
 
 const itunesBaseURL = "https://itunes.apple.com"
 
+// maxAppleLookupLimit is the largest result count the iTunes lookup endpoint accepts
+const maxAppleLookupLimit = 200
+
 type AppleArtist struct {
 	ArtistID         int    `json:"artistId"`
 	ArtistName       string `json:"artistName"`
@@ -81,17 +85,17 @@ type appleLookupItem struct {
 	Currency      string `json:"currency"`
 }
 
-type appleArtworkCacheItem struct {
-	URL       string
-	ExpiresAt time.Time
-}
+const (
+	defaultAppleArtworkCacheTTL  = 30 * time.Minute
+	defaultAppleArtworkCacheSize = 2000
+)
 
-var appleArtworkCache = struct {
-	mu sync.RWMutex
-	m  map[int]appleArtworkCacheItem
-}{
-	m: make(map[int]appleArtworkCacheItem),
-}
+// getAppleArtworkCache holds normalized base artwork URLs by artist ID, sizes are applied on read
+var getAppleArtworkCache = lazyTTLCache[int, string]("APPLE_ARTWORK_CACHE", defaultAppleArtworkCacheTTL, defaultAppleArtworkCacheSize)
+
+// appleArtworkInflight coalesces concurrent misses so one iTunes lookup serves them all
+// The lookup doesn't depend on the requested size, so callers share it across sizes too
+var appleArtworkInflight inflightGroup[int, string]
 
 // appleDoJSON performs a GET request to iTunes and decodes the JSON response into out
 func appleDoJSON(u string, out any) error {
@@ -104,8 +108,7 @@ func appleDoJSON(u string, out any) error {
 	req.Header.Set("User-Agent", "GroupieTrackerSchoolProject/1.0")
 
 	// Keep a short timeout so the UI doesn't hang on external APIs
-	client := &http.Client{Timeout: 8 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := upstreamDo(req, 8*time.Second, upstreamRetryAttempts())
 	if err != nil {
 		return err
 	}
@@ -118,8 +121,44 @@ func appleDoJSON(u string, out any) error {
 	return json.NewDecoder(resp.Body).Decode(out)
 }
 
-// SearchAppleArtists searches iTunes for music artists matching query
+// defaultAppleStoreCountry is the storefront used when APPLE_STORE_COUNTRY is unset or invalid
+const defaultAppleStoreCountry = "FR"
+
+// appleStoreCountry is read once, on first use, so the value loaded from `.env` at startup is honored
+var appleStoreCountry = sync.OnceValue(configuredAppleStoreCountry)
+
+// configuredAppleStoreCountry reads APPLE_STORE_COUNTRY, falling back to defaultAppleStoreCountry
+func configuredAppleStoreCountry() string {
+	if c, ok := NormalizeAppleCountry(os.Getenv("APPLE_STORE_COUNTRY")); ok {
+		return c
+	}
+	return defaultAppleStoreCountry
+}
+
+// NormalizeAppleCountry upper-cases a two-letter ISO country code, ok is false for anything else
+func NormalizeAppleCountry(c string) (string, bool) {
+	c = strings.ToUpper(strings.TrimSpace(c))
+	if len(c) != 2 || c[0] < 'A' || c[0] > 'Z' || c[1] < 'A' || c[1] > 'Z' {
+		return "", false
+	}
+	return c, true
+}
+
+// appleCountryOr returns country when it is a valid code, else the configured storefront
+func appleCountryOr(country string) string {
+	if c, ok := NormalizeAppleCountry(country); ok {
+		return c
+	}
+	return appleStoreCountry()
+}
+
+// SearchAppleArtists searches iTunes for music artists matching query in the configured storefront
 func SearchAppleArtists(query string) ([]AppleArtist, error) {
+	return SearchAppleArtistsIn(query, "")
+}
+
+// SearchAppleArtistsIn searches one storefront, an empty or invalid country uses the configured one
+func SearchAppleArtistsIn(query, country string) ([]AppleArtist, error) {
 	term := strings.TrimSpace(query)
 	if term == "" {
 		// iTunes rejects empty terms, so use a cheap fallback
@@ -131,8 +170,8 @@ func SearchAppleArtists(query string) ([]AppleArtist, error) {
 	params.Set("media", "music")
 	params.Set("entity", "musicArtist")
 	params.Set("limit", "30")
-	// Using a fixed market keeps results stable for the project
-	params.Set("country", "FR")
+	// A fixed storefront keeps results stable unless the caller picks one
+	params.Set("country", appleCountryOr(country))
 	params.Set("lang", "en_us")
 
 	var payload appleSearchResponse
@@ -158,7 +197,8 @@ func SearchAppleArtists(query string) ([]AppleArtist, error) {
 }
 
 // SearchAppleArtistsWithArtwork returns artists plus a "best effort" artwork URL for each artist
-func SearchAppleArtistsWithArtwork(query string, limit int, artworkSize int) ([]AppleArtistWithArtwork, error) {
+// country picks the storefront searched, "" uses APPLE_STORE_COUNTRY
+func SearchAppleArtistsWithArtwork(query string, limit int, artworkSize int, country string) ([]AppleArtistWithArtwork, error) {
 	if limit <= 0 || limit > 50 {
 		limit = 30
 	}
@@ -166,7 +206,7 @@ func SearchAppleArtistsWithArtwork(query string, limit int, artworkSize int) ([]
 		artworkSize = 300
 	}
 
-	artists, err := SearchAppleArtists(query)
+	artists, err := SearchAppleArtistsIn(query, country)
 	if err != nil {
 		return nil, err
 	}
@@ -202,11 +242,21 @@ func SearchAppleArtistsWithArtwork(query string, limit int, artworkSize int) ([]
 }
 
 // GetAppleArtist fetches basic artist info by iTunes artist ID
+// The same lookup also returns the latest album, so it warms the artwork cache for free
 func GetAppleArtist(id int) (*AppleArtist, error) {
 	if id <= 0 {
 		return nil, fmt.Errorf("invalid apple artist id")
 	}
 
+	artist, _, err := lookupAppleArtistWithArtwork(id)
+	if err != nil {
+		return nil, err
+	}
+	if artist != nil {
+		return artist, nil
+	}
+
+	// The combined lookup is storefront-scoped, retry the plain artist lookup before giving up
 	params := url.Values{}
 	params.Set("id", strconv.Itoa(id))
 
@@ -233,21 +283,32 @@ func GetAppleArtist(id int) (*AppleArtist, error) {
 	return nil, fmt.Errorf("apple artist not found")
 }
 
-// GetAppleArtistAlbums returns the latest albums for an artist using iTunes lookup
-func GetAppleArtistAlbums(artistID int, limit int) ([]AppleAlbum, error) {
+// GetAppleArtistAlbums returns a page of the latest albums for an artist using iTunes lookup
+func GetAppleArtistAlbums(artistID int, offset int, limit int) ([]AppleAlbum, error) {
 	if artistID <= 0 {
 		return nil, fmt.Errorf("invalid apple artist id")
 	}
 	if limit <= 0 || limit > 50 {
 		limit = 10
 	}
+	if offset < 0 {
+		offset = 0
+	}
+	// iTunes lookup has no offset parameter, so ask for everything up to the page end
+	fetch := offset + limit
+	if fetch > maxAppleLookupLimit {
+		fetch = maxAppleLookupLimit
+	}
+	if offset >= fetch {
+		return []AppleAlbum{}, nil
+	}
 
 	params := url.Values{}
 	params.Set("id", strconv.Itoa(artistID))
 	params.Set("entity", "album")
-	params.Set("limit", strconv.Itoa(limit))
+	params.Set("limit", strconv.Itoa(fetch))
 	params.Set("sort", "recent")
-	params.Set("country", "FR")
+	params.Set("country", appleStoreCountry())
 
 	var payload appleSearchResponse
 	if err := appleDoJSON(itunesBaseURL+"/lookup?"+params.Encode(), &payload); err != nil {
@@ -304,6 +365,10 @@ func GetAppleArtistAlbums(artistID int, limit int) ([]AppleAlbum, error) {
 		return albums[i].CollectionID < albums[j].CollectionID
 	})
 
+	if offset >= len(albums) {
+		return []AppleAlbum{}, nil
+	}
+	albums = albums[offset:]
 	if len(albums) > limit {
 		albums = albums[:limit]
 	}
@@ -325,7 +390,7 @@ func GetAppleArtistSongs(artistID int, limit int) ([]AppleTrack, error) {
 	params.Set("entity", "song")
 	params.Set("limit", strconv.Itoa(limit))
 	params.Set("sort", "recent")
-	params.Set("country", "FR")
+	params.Set("country", appleStoreCountry())
 
 	var payload appleSearchResponse
 	if err := appleDoJSON(itunesBaseURL+"/lookup?"+params.Encode(), &payload); err != nil {
@@ -393,53 +458,72 @@ func GetAppleArtistArtwork(artistID int, size int) (string, error) {
 		size = 300
 	}
 
-	now := time.Now()
-	appleArtworkCache.mu.RLock()
-	if it, ok := appleArtworkCache.m[artistID]; ok && it.URL != "" && now.Before(it.ExpiresAt) {
-		appleArtworkCache.mu.RUnlock()
+	if base, ok := getAppleArtworkCache().Get(artistID); ok {
 		// Cache hits are common on list pages, keep this path cheap
-		return it.URL, nil
+		return upscaleAppleArtwork(base, size), nil
 	}
-	appleArtworkCache.mu.RUnlock()
 
+	// Home and list pages fan out over many artists, share lookups already in flight
+	base, err := appleArtworkInflight.Do(artistID, func() (string, error) {
+		return fetchAppleArtistArtwork(artistID)
+	})
+	return upscaleAppleArtwork(base, size), err
+}
+
+// fetchAppleArtistArtwork looks up the base artwork URL behind GetAppleArtistArtwork and fills the cache
+func fetchAppleArtistArtwork(artistID int) (string, error) {
+	_, art, err := lookupAppleArtistWithArtwork(artistID)
+	return art, err
+}
+
+// lookupAppleArtistWithArtwork runs a single `lookup?id=X&entity=album&limit=1` call
+// iTunes answers with the artist wrapper followed by the latest album, which covers both
+// the artist info and the artwork, and the artwork cache is filled on the way out
+func lookupAppleArtistWithArtwork(artistID int) (*AppleArtist, string, error) {
 	params := url.Values{}
 	params.Set("id", strconv.Itoa(artistID))
 	params.Set("entity", "album")
 	params.Set("limit", "1")
 	params.Set("sort", "recent")
-	params.Set("country", "FR")
+	params.Set("country", appleStoreCountry())
 
 	var payload appleSearchResponse
 	if err := appleDoJSON(itunesBaseURL+"/lookup?"+params.Encode(), &payload); err != nil {
-		return "", err
+		return nil, "", err
 	}
 
+	var artist *AppleArtist
 	art := ""
 	for _, raw := range payload.Results {
 		var it appleLookupItem
 		if err := json.Unmarshal(raw, &it); err != nil {
 			continue
 		}
-		if it.WrapperType != "collection" {
-			continue
-		}
-		if it.ArtworkURL100 == "" {
-			continue
+		switch it.WrapperType {
+		case "artist":
+			if artist == nil && it.ArtistID == artistID && it.ArtistName != "" {
+				artist = &AppleArtist{
+					ArtistID:         it.ArtistID,
+					ArtistName:       it.ArtistName,
+					PrimaryGenreName: it.PrimaryGenreName,
+					ArtistLinkURL:    it.ArtistLinkURL,
+				}
+			}
+		case "collection":
+			if art == "" && it.ArtworkURL100 != "" {
+				// Keep the size-encoded URL as is, callers rewrite it to the size they need
+				art = normalizeAppleArtworkURL(it.ArtworkURL100)
+			}
 		}
-		// iTunes images are size-encoded in the last path segment
-		art = upscaleAppleArtwork(normalizeAppleArtworkURL(it.ArtworkURL100), size)
-		break
 	}
 
-	appleArtworkCache.mu.Lock()
-	// Cache empty strings too to avoid repeated lookups on missing artwork
-	appleArtworkCache.m[artistID] = appleArtworkCacheItem{
-		URL:       art,
-		ExpiresAt: now.Add(30 * time.Minute),
+	// Artists without album artwork are looked up again next time, a new release may add one,
+	// so an empty result isn't cached where it would only take a slot
+	if art != "" {
+		getAppleArtworkCache().Set(artistID, art)
 	}
-	appleArtworkCache.mu.Unlock()
 
-	return art, nil
+	return artist, art, nil
 }
 
 // upscaleAppleArtwork rewrites iTunes artwork URLs to request a larger square image
@@ -478,6 +562,9 @@ func normalizeAppleArtworkURL(u string) string {
 	if strings.HasPrefix(u, "http://") {
 		return "https://" + strings.TrimPrefix(u, "http://")
 	}
+	if strings.HasPrefix(u, "//") {
+		return "https:" + u
+	}
 	return u
 }
 
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeAppleLookup answers iTunes lookups with one album whose artwork is art, "" for none
+func fakeAppleLookup(t *testing.T, art string) *atomic.Int32 {
+	t.Helper()
+
+	var lookups atomic.Int32
+	useFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+		album := ""
+		if art != "" {
+			album = fmt.Sprintf(`,{"wrapperType":"collection","artworkUrl100":%q}`, art)
+		}
+		fmt.Fprintf(w, `{"resultCount":2,"results":[{"wrapperType":"artist","artistId":%d,"artistName":"Test"}%s]}`, id, album)
+	})
+	return &lookups
+}
+
+func TestGetAppleArtistArtworkCaches(t *testing.T) {
+	lookups := fakeAppleLookup(t, "https://is1.mzstatic.com/image/thumb/a/100x100bb.jpg")
+
+	id := newTestID()
+	for _, size := range []int{300, 600} {
+		got, err := GetAppleArtistArtwork(id, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fmt.Sprintf("https://is1.mzstatic.com/image/thumb/a/%dx%dbb.jpg", size, size)
+		if got != want {
+			t.Fatalf("artwork = %q, want %q", got, want)
+		}
+	}
+	if n := lookups.Load(); n != 1 {
+		t.Fatalf("made %d lookups, want 1 served from cache across sizes", n)
+	}
+}
+
+func TestGetAppleArtistArtworkMissingIsNotCached(t *testing.T) {
+	lookups := fakeAppleLookup(t, "")
+
+	id := newTestID()
+	for i := 0; i < 2; i++ {
+		got, err := GetAppleArtistArtwork(id, 300)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "" {
+			t.Fatalf("artwork = %q, want none", got)
+		}
+	}
+	// An artist without artwork is looked up again, a later release may add one
+	if n := lookups.Load(); n != 2 {
+		t.Fatalf("made %d lookups, want 2", n)
+	}
+	if _, ok := getAppleArtworkCache().Get(id); ok {
+		t.Fatal("an empty artwork was cached")
+	}
+}
+
+func TestGetAppleArtistArtworkCoalesces(t *testing.T) {
+	id := newTestID()
+	var lookups atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	useFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if lookups.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		fmt.Fprintf(w, `{"resultCount":2,"results":[{"wrapperType":"artist","artistId":%d,"artistName":"Test"},`+
+			`{"wrapperType":"collection","artworkUrl100":"https://is1.mzstatic.com/image/thumb/b/100x100bb.jpg"}]}`, id)
+	})
+
+	// Callers asking for different sizes of the same artist share one lookup
+	sizes := []int{100, 300, 300, 600, 1200}
+	var wg sync.WaitGroup
+	results := make([]string, len(sizes))
+	run := func(i int) {
+		defer wg.Done()
+		art, err := GetAppleArtistArtwork(id, sizes[i])
+		if err != nil {
+			t.Error(err)
+		}
+		results[i] = art
+	}
+	wg.Add(len(sizes))
+	go run(0)
+	<-started
+	for i := 1; i < len(sizes); i++ {
+		go run(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := lookups.Load(); n != 1 {
+		t.Fatalf("made %d lookups for concurrent callers, want 1", n)
+	}
+	for i, size := range sizes {
+		want := fmt.Sprintf("https://is1.mzstatic.com/image/thumb/b/%dx%dbb.jpg", size, size)
+		if results[i] != want {
+			t.Errorf("size %d: artwork = %q, want %q", size, results[i], want)
+		}
+	}
+}
+
+func TestUpscaleAppleArtwork(t *testing.T) {
+	tests := []struct {
+		in   string
+		size int
+		want string
+	}{
+		{"https://is1.mzstatic.com/image/thumb/a/100x100bb.jpg", 600, "https://is1.mzstatic.com/image/thumb/a/600x600bb.jpg"},
+		{"https://is1.mzstatic.com/image/thumb/a/600x600bb.png", 100, "https://is1.mzstatic.com/image/thumb/a/100x100bb.png"},
+		{" https://is1.mzstatic.com/image/thumb/a/100x100bb.jpg ", 300, "https://is1.mzstatic.com/image/thumb/a/300x300bb.jpg"},
+		{"https://example.com/cover.jpg", 300, "https://example.com/cover.jpg"},
+		{"https://is1.mzstatic.com/image/thumb/a/100x100bb.jpg", 0, ""},
+		{"", 300, ""},
+	}
+	for _, tt := range tests {
+		if got := upscaleAppleArtwork(tt.in, tt.size); got != tt.want {
+			t.Errorf("upscaleAppleArtwork(%q, %d) = %q, want %q", tt.in, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestGetAppleArtistArtworkSizeAfterCache(t *testing.T) {
+	fakeAppleLookup(t, "http://is1.mzstatic.com/image/thumb/c/100x100bb.jpg")
+
+	// A large first request must not leave its size in the cache for smaller ones
+	id := newTestID()
+	for _, size := range []int{1200, 100, 0} {
+		got, err := GetAppleArtistArtwork(id, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := size
+		if want == 0 {
+			want = 300
+		}
+		if u := fmt.Sprintf("https://is1.mzstatic.com/image/thumb/c/%dx%dbb.jpg", want, want); got != u {
+			t.Fatalf("size %d: artwork = %q, want %q", size, got, u)
+		}
+	}
+}
+
+func TestConfiguredAppleStoreCountry(t *testing.T) {
+	for env, want := range map[string]string{"": "FR", "us": "US", " gb ": "GB", "usa": "FR", "1x": "FR"} {
+		t.Setenv("APPLE_STORE_COUNTRY", env)
+		if got := configuredAppleStoreCountry(); got != want {
+			t.Errorf("APPLE_STORE_COUNTRY=%q gives %q, want %q", env, got, want)
+		}
+	}
+}
+
+func TestAppleRequestsUseStoreCountry(t *testing.T) {
+	prev := appleStoreCountry
+	appleStoreCountry = func() string { return "US" }
+	t.Cleanup(func() { appleStoreCountry = prev })
+
+	var mu sync.Mutex
+	var countries []string
+	useFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		countries = append(countries, r.URL.Query().Get("country"))
+		mu.Unlock()
+		fmt.Fprint(w, `{"resultCount":0,"results":[]}`)
+	})
+	searched := func(call func()) []string {
+		mu.Lock()
+		countries = nil
+		mu.Unlock()
+		call()
+		mu.Lock()
+		defer mu.Unlock()
+		return countries
+	}
+
+	id := newTestID()
+	for name, call := range map[string]func(){
+		"search":  func() { SearchAppleArtists("queen") },
+		"albums":  func() { GetAppleArtistAlbums(id, 0, 10) },
+		"songs":   func() { GetAppleArtistSongs(id, 10) },
+		"artwork": func() { GetAppleArtistArtwork(id, 300) },
+		// An invalid storefront from the caller falls back to the configured one
+		"invalid country": func() { SearchAppleArtistsIn("queen", "nope") },
+	} {
+		if got := searched(call); !reflect.DeepEqual(got, []string{"US"}) {
+			t.Errorf("%s: countries = %v, want [US]", name, got)
+		}
+	}
+
+	// A storefront picked by the caller wins over the configured one
+	if got := searched(func() { SearchAppleArtistsIn("queen", "gb") }); !reflect.DeepEqual(got, []string{"GB"}) {
+		t.Errorf("countries = %v, want [GB]", got)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,80 +17,149 @@ const (
 
 const groupieCacheTTL = 10 * time.Minute
 
+// groupieTimeout bounds downloads from the Groupie API, callers can cancel earlier through their context
+const groupieTimeout = 10 * time.Second
+
 var (
 	artistsCacheMu      sync.Mutex
 	artistsCacheFetched time.Time
-	artistsCache        []Artist
+	artistsCache        *artistsSnapshot
 
 	relationsCacheMu      sync.Mutex
 	relationsCacheFetched time.Time
-	relationsCache        *RelationIndex
+	relationsCache        *relationsSnapshot
 )
 
+// artistsSnapshot is one download of the artist list with an index by artist ID
+type artistsSnapshot struct {
+	list []Artist
+	byID map[int]int
+}
+
+func newArtistsSnapshot(list []Artist) *artistsSnapshot {
+	s := &artistsSnapshot{list: list, byID: make(map[int]int, len(list))}
+	for i, a := range list {
+		// Keep the first entry for a repeated ID, like the old linear scan
+		if _, ok := s.byID[a.ID]; !ok {
+			s.byID[a.ID] = i
+		}
+	}
+	return s
+}
+
+// relationsSnapshot is one download of the relations with an index by artist ID
+type relationsSnapshot struct {
+	index *RelationIndex
+	byID  map[int]int
+}
+
+func newRelationsSnapshot(ri *RelationIndex) *relationsSnapshot {
+	s := &relationsSnapshot{index: ri, byID: make(map[int]int, len(ri.Index))}
+	for i, r := range ri.Index {
+		if _, ok := s.byID[r.ID]; !ok {
+			s.byID[r.ID] = i
+		}
+	}
+	return s
+}
+
 func cacheFresh(since time.Time) bool {
 	return !since.IsZero() && time.Since(since) < groupieCacheTTL
 }
 
+// ExpireGroupieCache makes the next FetchArtists and FetchRelations calls download again
+// The old data is kept as a fallback in case the API is down at that point
+func ExpireGroupieCache() {
+	artistsCacheMu.Lock()
+	artistsCacheFetched = time.Time{}
+	artistsCacheMu.Unlock()
+
+	relationsCacheMu.Lock()
+	relationsCacheFetched = time.Time{}
+	relationsCacheMu.Unlock()
+}
+
 // FetchArtists loads the full artist list from the Groupie Trackers API
 func FetchArtists() ([]Artist, error) {
+	return FetchArtistsCtx(context.Background())
+}
+
+// FetchArtistsCtx is FetchArtists, giving up when ctx is done
+func FetchArtistsCtx(ctx context.Context) ([]Artist, error) {
+	s, err := fetchArtistsSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.list, nil
+}
+
+func fetchArtistsSnapshot(ctx context.Context) (*artistsSnapshot, error) {
 	artistsCacheMu.Lock()
-	if cacheFresh(artistsCacheFetched) && len(artistsCache) > 0 {
+	if cacheFresh(artistsCacheFetched) && artistsCache != nil && len(artistsCache.list) > 0 {
 		cached := artistsCache
 		artistsCacheMu.Unlock()
 		return cached, nil
 	}
 	stale := artistsCache
 	artistsCacheMu.Unlock()
-
-	// Default client is fine here, this is a school project and the endpoint is public
-	resp, err := http.Get(artistsURL)
-	if err != nil {
-		if len(stale) > 0 {
-			// Best-effort: keep the UI functional if the API is temporarily unreachable
-			return stale, nil
-		}
-		return nil, err
-	}
-	defer resp.Body.Close()
+	hasStale := stale != nil && len(stale.list) > 0
 
 	var artists []Artist
 	// The endpoint returns a JSON array of artists
-	err = json.NewDecoder(resp.Body).Decode(&artists)
-	if err != nil {
-		if len(stale) > 0 {
+	if err := groupieGetJSON(ctx, artistsURL, &artists); err != nil {
+		if hasStale && ctx.Err() == nil {
+			// Best-effort: keep the UI functional if the API is temporarily unreachable
 			return stale, nil
 		}
 		return nil, err
 	}
 
+	s := newArtistsSnapshot(artists)
 	artistsCacheMu.Lock()
-	artistsCache = artists
+	artistsCache = s
 	artistsCacheFetched = time.Now()
 	artistsCacheMu.Unlock()
 
-	return artists, nil
+	return s, nil
 }
 
 // FetchArtistByID returns the artist whose ID matches the given id
 func FetchArtistByID(id int) (*Artist, error) {
-	// The API doesn't provide a single-artist endpoint, so we filter client-side
-	artists, err := FetchArtists()
+	return FetchArtistByIDCtx(context.Background(), id)
+}
+
+// FetchArtistByIDCtx is FetchArtistByID, giving up when ctx is done
+func FetchArtistByIDCtx(ctx context.Context, id int) (*Artist, error) {
+	// The API doesn't provide a single-artist endpoint, the cached list is indexed instead
+	s, err := fetchArtistsSnapshot(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, a := range artists {
-		if a.ID == id {
-			// Return a pointer so templates can handle "missing" with nil checks
-			return &a, nil
-		}
+	i, ok := s.byID[id]
+	if !ok {
+		return nil, errors.New("artist not found")
 	}
-
-	return nil, errors.New("artist not found")
+	// Return a copy so callers can't modify the cached list
+	a := s.list[i]
+	return &a, nil
 }
 
 // FetchRelations loads the full relations index from the Groupie Trackers API
 func FetchRelations() (*RelationIndex, error) {
+	return FetchRelationsCtx(context.Background())
+}
+
+// FetchRelationsCtx is FetchRelations, giving up when ctx is done
+func FetchRelationsCtx(ctx context.Context) (*RelationIndex, error) {
+	s, err := fetchRelationsSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.index, nil
+}
+
+func fetchRelationsSnapshot(ctx context.Context) (*relationsSnapshot, error) {
 	relationsCacheMu.Lock()
 	if cacheFresh(relationsCacheFetched) && relationsCache != nil {
 		cached := relationsCache
@@ -98,46 +168,61 @@ func FetchRelations() (*RelationIndex, error) {
 	}
 	stale := relationsCache
 	relationsCacheMu.Unlock()
-
-	resp, err := http.Get(relationURL)
-	if err != nil {
-		if stale != nil && len(stale.Index) > 0 {
-			return stale, nil
-		}
-		return nil, err
-	}
-	defer resp.Body.Close()
+	hasStale := stale != nil && len(stale.index.Index) > 0
 
 	var ri RelationIndex
 	// The endpoint returns an object with an `index` array
-	err = json.NewDecoder(resp.Body).Decode(&ri)
-	if err != nil {
-		if stale != nil && len(stale.Index) > 0 {
+	if err := groupieGetJSON(ctx, relationURL, &ri); err != nil {
+		if hasStale && ctx.Err() == nil {
 			return stale, nil
 		}
 		return nil, err
 	}
 
+	s := newRelationsSnapshot(&ri)
 	relationsCacheMu.Lock()
-	relationsCache = &ri
+	relationsCache = s
 	relationsCacheFetched = time.Now()
 	relationsCacheMu.Unlock()
 
-	return &ri, nil
+	return s, nil
 }
 
 // FetchRelationForArtist extracts the relation entry for a specific artist ID
 func FetchRelationForArtist(id int) (*Relation, error) {
-	ri, err := FetchRelations()
+	return FetchRelationForArtistCtx(context.Background(), id)
+}
+
+// FetchRelationForArtistCtx is FetchRelationForArtist, giving up when ctx is done
+func FetchRelationForArtistCtx(ctx context.Context, id int) (*Relation, error) {
+	s, err := fetchRelationsSnapshot(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, r := range ri.Index {
-		if r.ID == id {
-			return &r, nil
-		}
+	i, ok := s.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("relation not found for id %d", id)
 	}
+	r := s.index.Index[i]
+	return &r, nil
+}
 
-	return nil, fmt.Errorf("relation not found for id %d", id)
+// groupieGetJSON downloads url into out, a canceled ctx aborts the request
+func groupieGetJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := upstreamDo(req, groupieTimeout, upstreamRetryAttempts())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("groupie api request failed: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
 }
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeGroupie serves artists and relations as the Groupie API, failing with 500 while down is set
+// It starts from empty caches and puts the previous ones back when the test ends
+func fakeGroupie(t *testing.T, artists, relations string, down *atomic.Bool) (artistHits, relationHits *atomic.Int32) {
+	t.Helper()
+	t.Setenv("UPSTREAM_RETRY_ATTEMPTS", "1")
+
+	artistsCacheMu.Lock()
+	prevArtists, prevArtistsFetched := artistsCache, artistsCacheFetched
+	artistsCache, artistsCacheFetched = nil, time.Time{}
+	artistsCacheMu.Unlock()
+	relationsCacheMu.Lock()
+	prevRelations, prevRelationsFetched := relationsCache, relationsCacheFetched
+	relationsCache, relationsCacheFetched = nil, time.Time{}
+	relationsCacheMu.Unlock()
+	t.Cleanup(func() {
+		artistsCacheMu.Lock()
+		artistsCache, artistsCacheFetched = prevArtists, prevArtistsFetched
+		artistsCacheMu.Unlock()
+		relationsCacheMu.Lock()
+		relationsCache, relationsCacheFetched = prevRelations, prevRelationsFetched
+		relationsCacheMu.Unlock()
+	})
+
+	artistHits, relationHits = new(atomic.Int32), new(atomic.Int32)
+	useFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if down != nil && down.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		switch r.URL.String() {
+		case artistsURL:
+			artistHits.Add(1)
+			fmt.Fprint(w, artists)
+		case relationURL:
+			relationHits.Add(1)
+			fmt.Fprint(w, relations)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return artistHits, relationHits
+}
+
+const (
+	testArtistsJSON   = `[{"id":1,"name":"Queen"},{"id":2,"name":"SOJA"},{"id":2,"name":"Duplicate"}]`
+	testRelationsJSON = `{"index":[{"id":1,"datesLocations":{"london-uk":["01-01-2020"]}},{"id":2,"datesLocations":{}}]}`
+)
+
+func TestFetchArtistsCachesWithinTTL(t *testing.T) {
+	artistHits, _ := fakeGroupie(t, testArtistsJSON, testRelationsJSON, nil)
+
+	for i := 0; i < 3; i++ {
+		artists, err := FetchArtists()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(artists) != 3 {
+			t.Fatalf("got %d artists, want 3", len(artists))
+		}
+	}
+	a, err := FetchArtistByID(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The index keeps the first artist of a repeated ID
+	if a.Name != "SOJA" {
+		t.Fatalf("artist 2 = %q, want SOJA", a.Name)
+	}
+	if _, err := FetchArtistByID(99); err == nil {
+		t.Fatal("unknown artist found")
+	}
+	if n := artistHits.Load(); n != 1 {
+		t.Fatalf("made %d downloads, want 1", n)
+	}
+
+	// Callers get copies, the cache stays as downloaded
+	a.Name = "changed"
+	if again, _ := FetchArtistByID(2); again.Name != "SOJA" {
+		t.Fatalf("cached artist changed to %q", again.Name)
+	}
+
+	ExpireGroupieCache()
+	if _, err := FetchArtists(); err != nil {
+		t.Fatal(err)
+	}
+	if n := artistHits.Load(); n != 2 {
+		t.Fatalf("made %d downloads after a forced refresh, want 2", n)
+	}
+}
+
+func TestFetchRelationsCachesWithinTTL(t *testing.T) {
+	_, relationHits := fakeGroupie(t, testArtistsJSON, testRelationsJSON, nil)
+
+	if _, err := FetchRelations(); err != nil {
+		t.Fatal(err)
+	}
+	rel, err := FetchRelationForArtist(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rel.DatesLocations["london-uk"]; !ok {
+		t.Fatalf("relation 1 = %+v, want the london-uk dates", rel)
+	}
+	if _, err := FetchRelationForArtist(99); err == nil {
+		t.Fatal("unknown relation found")
+	}
+	if n := relationHits.Load(); n != 1 {
+		t.Fatalf("made %d downloads, want 1", n)
+	}
+}
+
+func TestFetchArtistsServesStaleWhileDown(t *testing.T) {
+	var down atomic.Bool
+	artistHits, _ := fakeGroupie(t, testArtistsJSON, testRelationsJSON, &down)
+
+	if _, err := FetchArtists(); err != nil {
+		t.Fatal(err)
+	}
+	ExpireGroupieCache()
+	down.Store(true)
+
+	artists, err := FetchArtists()
+	if err != nil {
+		t.Fatalf("stale list not served: %v", err)
+	}
+	if len(artists) != 3 {
+		t.Fatalf("got %d artists, want the 3 cached ones", len(artists))
+	}
+
+	// Once the API is back the next call downloads again
+	down.Store(false)
+	if _, err := FetchArtists(); err != nil {
+		t.Fatal(err)
+	}
+	if n := artistHits.Load(); n != 2 {
+		t.Fatalf("made %d successful downloads, want 2", n)
+	}
+}
+
+func TestFetchArtistsFailsWithoutCache(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	fakeGroupie(t, testArtistsJSON, testRelationsJSON, &down)
+
+	if _, err := FetchArtists(); err == nil {
+		t.Fatal("no error with the API down and nothing cached")
+	}
+}
+
+// useGroupieSnapshots serves artists and relations from fresh caches until the benchmark ends
+func useGroupieSnapshots(b *testing.B, artists []Artist, relations *RelationIndex) {
+	b.Helper()
+
+	artistsCacheMu.Lock()
+	prevArtists, prevArtistsFetched := artistsCache, artistsCacheFetched
+	artistsCache, artistsCacheFetched = newArtistsSnapshot(artists), time.Now()
+	artistsCacheMu.Unlock()
+	relationsCacheMu.Lock()
+	prevRelations, prevRelationsFetched := relationsCache, relationsCacheFetched
+	relationsCache, relationsCacheFetched = newRelationsSnapshot(relations), time.Now()
+	relationsCacheMu.Unlock()
+
+	b.Cleanup(func() {
+		artistsCacheMu.Lock()
+		artistsCache, artistsCacheFetched = prevArtists, prevArtistsFetched
+		artistsCacheMu.Unlock()
+		relationsCacheMu.Lock()
+		relationsCache, relationsCacheFetched = prevRelations, prevRelationsFetched
+		relationsCacheMu.Unlock()
+	})
+}
+
+// BenchmarkFetchArtistByID looks up every artist of a large list once per iteration, like
+// the favorites page does, next to the linear scan the index replaced
+func BenchmarkFetchArtistByID(b *testing.B) {
+	const n = 2000
+	artists := make([]Artist, n)
+	relations := &RelationIndex{Index: make([]Relation, n)}
+	for i := range artists {
+		artists[i] = Artist{ID: i + 1, Name: fmt.Sprintf("Artist %d", i+1)}
+		relations.Index[i] = Relation{ID: i + 1}
+	}
+	useGroupieSnapshots(b, artists, relations)
+
+	b.Run("index", func(b *testing.B) {
+		for b.Loop() {
+			for id := 1; id <= n; id++ {
+				if _, err := FetchArtistByID(id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("linearscan", func(b *testing.B) {
+		for b.Loop() {
+			for id := 1; id <= n; id++ {
+				list, err := FetchArtists()
+				if err != nil {
+					b.Fatal(err)
+				}
+				found := false
+				for i := range list {
+					if list[i].ID == id {
+						found = true
+						break
+					}
+				}
+				if !found {
+					b.Fatal("artist not found")
+				}
+			}
+		}
+	})
+	b.Run("relations", func(b *testing.B) {
+		for b.Loop() {
+			for id := 1; id <= n; id++ {
+				if _, err := FetchRelationForArtist(id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+// hangUpstream makes every upstream request hang until its context is done
+func hangUpstream(t *testing.T) {
+	t.Helper()
+	prev := upstreamHTTP
+	upstreamHTTP = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})}
+	t.Cleanup(func() { upstreamHTTP = prev })
+}
+
+func TestFetchCtxCanceledMidFlight(t *testing.T) {
+	calls := map[string]func(context.Context) error{
+		"FetchArtistsCtx": func(ctx context.Context) error {
+			_, err := FetchArtistsCtx(ctx)
+			return err
+		},
+		"FetchArtistByIDCtx": func(ctx context.Context) error {
+			_, err := FetchArtistByIDCtx(ctx, 1)
+			return err
+		},
+		"FetchRelationsCtx": func(ctx context.Context) error {
+			_, err := FetchRelationsCtx(ctx)
+			return err
+		},
+		"FetchRelationForArtistCtx": func(ctx context.Context) error {
+			_, err := FetchRelationForArtistCtx(ctx, 1)
+			return err
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			fakeGroupie(t, testArtistsJSON, testRelationsJSON, nil)
+			hangUpstream(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+			start := time.Now()
+			err := call(ctx)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("err = %v, want context.Canceled", err)
+			}
+			if d := time.Since(start); d > time.Second {
+				t.Fatalf("returned after %v, want promptly after the cancel", d)
+			}
+		})
+	}
+}
+
+func TestFetchArtistsCtxCanceledSkipsStale(t *testing.T) {
+	fakeGroupie(t, testArtistsJSON, testRelationsJSON, nil)
+	if _, err := FetchArtists(); err != nil {
+		t.Fatal(err)
+	}
+	ExpireGroupieCache()
+	hangUpstream(t)
+
+	// The stale list covers API outages, not callers that gave up
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := FetchArtistsCtx(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
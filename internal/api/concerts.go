@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Concert is one artist playing one location on one day
+type Concert struct {
+	ArtistID int
+	// Location is the raw Groupie key, e.g. "paris-france"
+	Location string
+}
+
+var (
+	concertsCacheMu sync.Mutex
+	// concertsBuiltFrom is the relations snapshot the index was built from
+	concertsBuiltFrom *RelationIndex
+	concertsByDate    map[string][]Concert
+)
+
+// FetchConcertsByDate indexes every concert in the relations data by day (YYYY-MM-DD)
+// The index is rebuilt only when the cached relations are refreshed
+func FetchConcertsByDate() (map[string][]Concert, error) {
+	return FetchConcertsByDateCtx(context.Background())
+}
+
+// FetchConcertsByDateCtx is FetchConcertsByDate, giving up when ctx is done
+func FetchConcertsByDateCtx(ctx context.Context) (map[string][]Concert, error) {
+	ri, err := FetchRelationsCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	concertsCacheMu.Lock()
+	defer concertsCacheMu.Unlock()
+
+	if concertsBuiltFrom == ri && concertsByDate != nil {
+		return concertsByDate, nil
+	}
+
+	index := make(map[string][]Concert)
+	for _, rel := range ri.Index {
+		for location, dates := range rel.DatesLocations {
+			for _, d := range dates {
+				day, ok := ConcertDay(d)
+				if !ok {
+					continue
+				}
+				index[day] = append(index[day], Concert{ArtistID: rel.ID, Location: location})
+			}
+		}
+	}
+
+	concertsBuiltFrom = ri
+	concertsByDate = index
+	return index, nil
+}
+
+// ConcertDay turns a Groupie date ("23-08-2019", sometimes with a leading "*") into "2019-08-23"
+func ConcertDay(raw string) (string, bool) {
+	s := strings.TrimPrefix(strings.TrimSpace(raw), "*")
+	t, err := time.Parse("02-01-2006", s)
+	if err != nil {
+		return "", false
+	}
+	return t.Format("2006-01-02"), true
+}
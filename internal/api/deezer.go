@@ -15,6 +15,9 @@ import (
 
 const deezerBaseURL = "https://api.deezer.com"
 
+// maxDeezerAlbumCandidates bounds both the list page size and how deep album paging can go
+const maxDeezerAlbumCandidates = 100
+
 type DeezerAPIError struct {
 	Type    string `json:"type"`
 	Message string `json:"message"`
@@ -96,8 +99,7 @@ func deezerGetJSON(fullURL string, out any) error {
 	req.Header.Set("User-Agent", "GroupieTrackerSchoolProject/1.0")
 
 	// Use a small timeout so slow external calls don't stall the UI
-	client := &http.Client{Timeout: 8 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := upstreamDo(req, 8*time.Second, upstreamRetryAttempts())
 	if err != nil {
 		return err
 	}
@@ -188,8 +190,19 @@ func GetDeezerArtistTopTracks(id int, limit int) ([]DeezerTrack, error) {
 	return payload.Data, nil
 }
 
-// GetDeezerArtistAlbums returns a best-effort list of the artist's latest albums and singles
-func GetDeezerArtistAlbums(id int, limit int) ([]DeezerAlbum, error) {
+// GetDeezerArtistAlbums returns a best-effort page of the artist's latest albums and singles
+// Each album is enriched from the album endpoint (track counts, fans, full release dates)
+func GetDeezerArtistAlbums(id int, offset int, limit int) ([]DeezerAlbum, error) {
+	return fetchDeezerArtistAlbums(id, offset, limit, true)
+}
+
+// GetDeezerArtistAlbumsLite returns the same page using only the artist albums listing
+// It skips the per-album lookups, for views that don't show track counts or fans
+func GetDeezerArtistAlbumsLite(id int, offset int, limit int) ([]DeezerAlbum, error) {
+	return fetchDeezerArtistAlbums(id, offset, limit, false)
+}
+
+func fetchDeezerArtistAlbums(id int, offset int, limit int, enrich bool) ([]DeezerAlbum, error) {
 	if id <= 0 {
 		return nil, fmt.Errorf("invalid deezer artist id")
 	}
@@ -200,9 +213,15 @@ func GetDeezerArtistAlbums(id int, limit int) ([]DeezerAlbum, error) {
 	if want > 50 {
 		want = 50
 	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= maxDeezerAlbumCandidates {
+		return []DeezerAlbum{}, nil
+	}
 
 	// Request a larger pool, then enrich and sort locally
-	fetch := 50
+	fetch := maxDeezerAlbumCandidates
 	recordTypes := []string{"", "single", "ep", "album"}
 
 	ordered := make([]DeezerAlbum, 0, fetch)
@@ -248,26 +267,70 @@ func GetDeezerArtistAlbums(id int, limit int) ([]DeezerAlbum, error) {
 		return ordered, nil
 	}
 
-	// Enrichment is expensive, only do it for a reasonable "candidate" window
-	candidateCount := want * 6
-	if candidateCount < 30 {
-		candidateCount = 30
-	}
-	if candidateCount > 50 {
-		candidateCount = 50
-	}
-	if candidateCount > len(ordered) {
-		candidateCount = len(ordered)
-	}
-	if candidateCount < want {
-		candidateCount = want
+	albums := ordered
+	if enrich {
+		// Enrichment is expensive, only do it for a reasonable "candidate" window
+		candidateCount := want * 6
+		if candidateCount < 30 {
+			candidateCount = 30
+		}
+		if candidateCount > 50 {
+			candidateCount = 50
+		}
+		// Later pages need the window to reach past the albums already shown
+		candidateCount += offset
+		if candidateCount > maxDeezerAlbumCandidates {
+			candidateCount = maxDeezerAlbumCandidates
+		}
 		if candidateCount > len(ordered) {
 			candidateCount = len(ordered)
 		}
+		if candidateCount < offset+want {
+			candidateCount = offset + want
+			if candidateCount > len(ordered) {
+				candidateCount = len(ordered)
+			}
+		}
+
+		albums = ordered[:candidateCount]
+		enrichDeezerAlbums(albums)
+	}
+
+	sort.SliceStable(albums, func(i, j int) bool { // newest first, then stable tie-breakers
+		di, okI := ParseDeezerReleaseDate(albums[i].ReleaseDate)
+		dj, okJ := ParseDeezerReleaseDate(albums[j].ReleaseDate)
+
+		if okI && okJ && !di.Equal(dj) {
+			// Prefer newest releases when both dates are parseable
+			return di.After(dj)
+		}
+		if okI != okJ {
+			// Prefer albums with parseable dates
+			return okI
+		}
+
+		ti := strings.ToLower(albums[i].Title)
+		tj := strings.ToLower(albums[j].Title)
+		if ti != tj {
+			return ti < tj
+		}
+
+		return albums[i].ID < albums[j].ID
+	})
+
+	if offset >= len(albums) {
+		return []DeezerAlbum{}, nil
+	}
+	albums = albums[offset:]
+	if len(albums) > want {
+		albums = albums[:want]
 	}
 
-	albums := ordered[:candidateCount]
+	return albums, nil
+}
 
+// enrichDeezerAlbums fills release dates, track counts and covers from the album endpoint in place
+func enrichDeezerAlbums(albums []DeezerAlbum) {
 	// Fetch album details concurrently, but cap concurrency to avoid rate limits
 	sem := make(chan struct{}, 6)
 	var wg sync.WaitGroup
@@ -318,49 +381,45 @@ func GetDeezerArtistAlbums(id int, limit int) ([]DeezerAlbum, error) {
 	}
 
 	wg.Wait()
+}
 
-	sort.SliceStable(albums, func(i, j int) bool { // newest first, then stable tie-breakers
-		di, okI := ParseDeezerReleaseDate(albums[i].ReleaseDate)
-		dj, okJ := ParseDeezerReleaseDate(albums[j].ReleaseDate)
-
-		if okI && okJ && !di.Equal(dj) {
-			// Prefer newest releases when both dates are parseable
-			return di.After(dj)
-		}
-		if okI != okJ {
-			// Prefer albums with parseable dates
-			return okI
-		}
-
-		ti := strings.ToLower(albums[i].Title)
-		tj := strings.ToLower(albums[j].Title)
-		if ti != tj {
-			return ti < tj
-		}
-
-		return albums[i].ID < albums[j].ID
-	})
-
-	if len(albums) > want {
-		albums = albums[:want]
-	}
+const (
+	defaultDeezerAlbumCacheTTL  = time.Hour
+	defaultDeezerAlbumCacheSize = 5000
+)
 
-	return albums, nil
-}
+// getDeezerAlbumCache keeps enriched album details by album ID across detail renders
+var (
+	getDeezerAlbumCache = lazyTTLCache[int, DeezerAlbum]("DEEZER_ALBUM_CACHE", defaultDeezerAlbumCacheTTL, defaultDeezerAlbumCacheSize)
+	deezerAlbumInflight inflightGroup[int, DeezerAlbum]
+)
 
 // GetDeezerAlbum fetches full album details by Deezer album ID
+// Results are cached and concurrent lookups for the same album share one request
 func GetDeezerAlbum(id int) (*DeezerAlbum, error) {
 	if id <= 0 {
 		return nil, fmt.Errorf("invalid deezer album id")
 	}
 
-	var album DeezerAlbum
-	if err := deezerGetJSON(deezerBaseURL+"/album/"+strconv.Itoa(id), &album); err != nil {
-		return nil, err
+	if album, ok := getDeezerAlbumCache().Get(id); ok {
+		// Return a copy so callers can't mutate the cached entry
+		return &album, nil
 	}
 
-	if album.ID == 0 {
-		return nil, fmt.Errorf("deezer album not found")
+	album, err := deezerAlbumInflight.Do(id, func() (DeezerAlbum, error) {
+		var album DeezerAlbum
+		if err := deezerGetJSON(deezerBaseURL+"/album/"+strconv.Itoa(id), &album); err != nil {
+			return DeezerAlbum{}, err
+		}
+		if album.ID == 0 {
+			return DeezerAlbum{}, fmt.Errorf("deezer album not found")
+		}
+
+		getDeezerAlbumCache().Set(id, album)
+		return album, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &album, nil
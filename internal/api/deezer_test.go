@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeDeezerAlbums serves three albums for any artist, and counts the per-album lookups
+func fakeDeezerAlbums(t *testing.T) *atomic.Int32 {
+	t.Helper()
+
+	firstID := newTestID()
+	var albumLookups atomic.Int32
+	useFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/albums"):
+			fmt.Fprintf(w, `{"data":[
+				{"id":%d,"title":"Old","record_type":"album","release_date":"2001-01-01"},
+				{"id":%d,"title":"New","record_type":"single","release_date":"2024-05-01"},
+				{"id":%d,"title":"Middle","record_type":"ep","release_date":"2015-03-02"}
+			]}`, firstID, firstID+1, firstID+2)
+		case strings.HasPrefix(r.URL.Path, "/album/"):
+			albumLookups.Add(1)
+			id := strings.TrimPrefix(r.URL.Path, "/album/")
+			fmt.Fprintf(w, `{"id":%s,"release_date":"2024-05-01","nb_tracks":12,"fans":34}`, id)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return &albumLookups
+}
+
+func TestGetDeezerArtistAlbumsLite(t *testing.T) {
+	lookups := fakeDeezerAlbums(t)
+
+	albums, err := GetDeezerArtistAlbumsLite(newTestID(), 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := lookups.Load(); n != 0 {
+		t.Fatalf("lite made %d album lookups, want 0", n)
+	}
+
+	var titles []string
+	for _, a := range albums {
+		titles = append(titles, a.Title)
+		if a.NbTracks != 0 {
+			t.Errorf("%s has NbTracks %d from an enrichment lookup", a.Title, a.NbTracks)
+		}
+	}
+	// Still newest first, from the listing's own release dates
+	if got, want := strings.Join(titles, ","), "New,Middle,Old"; got != want {
+		t.Fatalf("titles = %s, want %s", got, want)
+	}
+}
+
+func TestGetDeezerArtistAlbumsEnriches(t *testing.T) {
+	lookups := fakeDeezerAlbums(t)
+
+	albums, err := GetDeezerArtistAlbums(newTestID(), 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := lookups.Load(); n != 3 {
+		t.Fatalf("made %d album lookups, want 3", n)
+	}
+	for _, a := range albums {
+		if a.NbTracks != 12 || a.Fans != 34 {
+			t.Errorf("%s not enriched: NbTracks %d, Fans %d", a.Title, a.NbTracks, a.Fans)
+		}
+	}
+}
+
+func TestGetDeezerArtistAlbumsReusesEnrichment(t *testing.T) {
+	lookups := fakeDeezerAlbums(t)
+
+	artistID := newTestID()
+	for i := 0; i < 2; i++ {
+		if _, err := GetDeezerArtistAlbums(artistID, 0, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := lookups.Load(); n != 3 {
+		t.Fatalf("made %d album lookups over two renders, want 3", n)
+	}
+}
+
+func TestGetDeezerAlbumCache(t *testing.T) {
+	id := newTestID()
+	missing := id + 1
+
+	var lookups atomic.Int32
+	useFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		id := strings.TrimPrefix(r.URL.Path, "/album/")
+		if id == strconv.Itoa(missing) {
+			// Deezer answers unknown albums with an error body, not a 404
+			fmt.Fprint(w, `{"error":{"type":"DataException","message":"no data","code":800}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"id":%s,"title":"Cached","nb_tracks":9}`, id)
+	})
+
+	first, err := GetDeezerAlbum(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Callers get a copy, changing it leaves the cached album alone
+	first.Title = "changed"
+	second, err := GetDeezerAlbum(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Title != "Cached" {
+		t.Fatalf("cached title = %q, want %q", second.Title, "Cached")
+	}
+	if n := lookups.Load(); n != 1 {
+		t.Fatalf("made %d lookups, want 1", n)
+	}
+
+	// Missing albums aren't cached
+	for i := 0; i < 2; i++ {
+		if _, err := GetDeezerAlbum(missing); err == nil {
+			t.Fatal("missing album returned no error")
+		}
+	}
+	if n := lookups.Load(); n != 3 {
+		t.Fatalf("made %d lookups, want 3 with the missing album looked up twice", n)
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// upstreamTransport is shared by every external API call so keep-alive connections get reused
+// A few hosts (Spotify, Deezer, Groupie) take most of the traffic, hence the larger per-host pool
+var upstreamTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   16,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   5 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+// upstreamHTTP has no client-wide timeout, each call sets its own through upstreamDo
+var upstreamHTTP = &http.Client{Transport: upstreamTransport}
+
+// upstreamDo sends req through the shared client within timeout, retries included
+// attempts is passed to doWithRetry, 1 sends the request once
+// The deadline stays active until the caller closes the response body
+func upstreamDo(req *http.Request, timeout time.Duration, attempts int) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := doWithRetry(req.WithContext(ctx), upstreamHTTP, attempts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases a request context once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
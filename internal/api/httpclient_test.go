@@ -0,0 +1,81 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// useFakeUpstream answers every upstream API request with h, in process, until the test ends
+func useFakeUpstream(t *testing.T, h http.HandlerFunc) {
+	t.Helper()
+
+	prev := upstreamHTTP
+	upstreamHTTP = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		h(w, r)
+		resp := w.Result()
+		resp.Request = r
+		return resp, nil
+	})}
+	t.Cleanup(func() { upstreamHTTP = prev })
+}
+
+// testIDSeq hands out upstream IDs, the API caches are package-wide and outlive a single run
+var testIDSeq atomic.Int32
+
+// newTestID returns an ID no other test or repeated run has used, with room for 10 after it
+func newTestID() int {
+	return 900000 + int(testIDSeq.Add(10))
+}
+
+// BenchmarkUpstreamClient sends requests to a local TLS server through the shared pooled
+// client, and through a fresh transport per request like the old per-call clients
+func BenchmarkUpstreamClient(b *testing.B) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+	tlsConfig := srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+	get := func(b *testing.B, do func(*http.Request) (*http.Response, error)) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		resp, err := do(req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	b.Run("shared", func(b *testing.B) {
+		prev := upstreamHTTP
+		transport := upstreamTransport.Clone()
+		transport.TLSClientConfig = tlsConfig
+		upstreamHTTP = &http.Client{Transport: transport}
+		defer func() { upstreamHTTP = prev }()
+
+		b.ReportAllocs()
+		for b.Loop() {
+			get(b, func(req *http.Request) (*http.Response, error) {
+				return upstreamDo(req, 5*time.Second, 1)
+			})
+		}
+	})
+	b.Run("per-request", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			transport := &http.Transport{TLSClientConfig: tlsConfig}
+			client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+			get(b, client.Do)
+			transport.CloseIdleConnections()
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package api
+
+import "sync"
+
+// inflightGroup coalesces concurrent calls for the same key into a single upstream request
+type inflightGroup[K comparable, V any] struct {
+	mu sync.Mutex
+	m  map[K]*inflightCall[V]
+}
+
+// inflightCall is a request in progress that other callers wait on
+type inflightCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// Do runs fn once per key at a time, concurrent callers for that key share its result
+func (g *inflightGroup[K, V]) Do(key K, fn func() (V, error)) (V, error) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[K]*inflightCall[V])
+	}
+	if call, ok := g.m[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+	call := &inflightCall[V]{done: make(chan struct{})}
+	g.m[key] = call
+	g.mu.Unlock()
+
+	call.value, call.err = fn()
+
+	g.mu.Lock()
+	delete(g.m, key)
+	g.mu.Unlock()
+	close(call.done)
+
+	return call.value, call.err
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startWaiting runs n calls of g.Do(key, fn) once fn is blocked, and returns their results
+// The short sleep gives the goroutines time to join the call in flight
+func startWaiting(g *inflightGroup[int, string], key, n int, fn func() (string, error)) <-chan string {
+	results := make(chan string, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			v, _ := g.Do(key, fn)
+			results <- v
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	return results
+}
+
+func TestInflightGroupCoalesces(t *testing.T) {
+	var g inflightGroup[int, string]
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	fn := func() (string, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return "value", nil
+	}
+
+	first := make(chan string, 1)
+	go func() {
+		v, _ := g.Do(1, fn)
+		first <- v
+	}()
+	<-started
+	others := startWaiting(&g, 1, 9, fn)
+	close(release)
+
+	if v := <-first; v != "value" {
+		t.Fatalf("first caller got %q", v)
+	}
+	for i := 0; i < 9; i++ {
+		if v := <-others; v != "value" {
+			t.Fatalf("waiting caller got %q", v)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("fn ran %d times, want 1", n)
+	}
+
+	// Once done, the next call for the key runs again
+	if _, err := g.Do(1, fn); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("fn ran %d times after the first call ended, want 2", n)
+	}
+}
+
+func TestInflightGroupKeysAndErrors(t *testing.T) {
+	var g inflightGroup[int, string]
+	boom := errors.New("boom")
+
+	var wg sync.WaitGroup
+	var calls atomic.Int32
+	for key := 0; key < 3; key++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := g.Do(key, func() (string, error) {
+				calls.Add(1)
+				return "", boom
+			})
+			if !errors.Is(err, boom) {
+				t.Errorf("key %d: err = %v, want boom", key, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("fn ran %d times for 3 keys, want 3", n)
+	}
+}
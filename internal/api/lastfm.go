@@ -21,7 +21,30 @@ type lastfmArtistInfo struct {
 	} `json:"artist"`
 }
 
+const (
+	defaultLastfmCacheTTL     = time.Hour
+	defaultLastfmMissCacheTTL = 10 * time.Minute
+	defaultLastfmCacheSize    = 2000
+)
+
+// errLastfmNoListeners is returned when Last.fm doesn't know the artist, it is cached like a zero count
+var errLastfmNoListeners = errors.New("no listeners in response")
+
+// getLastfmListenersCache keeps listener counts by normalized artist name
+// getLastfmMissCache remembers unknown artists and zero counts for a shorter time, so new artists show up soon
+var (
+	getLastfmListenersCache = lazyTTLCache[string, int]("LASTFM_CACHE", defaultLastfmCacheTTL, defaultLastfmCacheSize)
+	getLastfmMissCache      = lazyTTLCache[string, error]("LASTFM_MISS_CACHE", defaultLastfmMissCacheTTL, defaultLastfmCacheSize)
+	lastfmListenersInflight inflightGroup[string, int]
+)
+
+// lastfmCacheKey folds case and spacing so "Daft  Punk" and "daft punk" share an entry
+func lastfmCacheKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
 // FetchArtistMonthlyListeners fetches the Last.fm listener count for the given artist name
+// Results are cached, request failures are not so they are retried on the next call
 func FetchArtistMonthlyListeners(artistName string) (int, error) {
 	// Credentials are provided via .env for local dev
 	apiKey := os.Getenv("LASTFM_API_KEY")
@@ -34,6 +57,32 @@ func FetchArtistMonthlyListeners(artistName string) (int, error) {
 		return 0, errors.New("empty artist name")
 	}
 
+	key := lastfmCacheKey(name)
+	if value, ok := getLastfmListenersCache().Get(key); ok {
+		return value, nil
+	}
+	if err, ok := getLastfmMissCache().Get(key); ok {
+		return 0, err
+	}
+
+	return lastfmListenersInflight.Do(key, func() (int, error) {
+		value, err := fetchLastfmListeners(apiKey, name)
+		switch {
+		case errors.Is(err, errLastfmNoListeners):
+			getLastfmMissCache().Set(key, err)
+		case err != nil:
+			// Network or upstream errors may be transient, leave them uncached
+		case value == 0:
+			getLastfmMissCache().Set(key, nil)
+		default:
+			getLastfmListenersCache().Set(key, value)
+		}
+		return value, err
+	})
+}
+
+// fetchLastfmListeners asks Last.fm's artist.getInfo for the listener count, without caching
+func fetchLastfmListeners(apiKey, name string) (int, error) {
 	params := url.Values{}
 	params.Set("method", "artist.getInfo")
 	params.Set("artist", name)
@@ -50,8 +99,7 @@ func FetchArtistMonthlyListeners(artistName string) (int, error) {
 	req.Header.Set("User-Agent", "GroupieTrackerSchoolProject/1.0 (contact@example.com)")
 
 	// Use a short timeout since this is "extra" data for sorting/display
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := upstreamDo(req, 5*time.Second, upstreamRetryAttempts())
 	if err != nil {
 		return 0, err
 	}
@@ -70,7 +118,7 @@ func FetchArtistMonthlyListeners(artistName string) (int, error) {
 
 	listenersStr := strings.TrimSpace(payload.Artist.Stats.Listeners)
 	if listenersStr == "" {
-		return 0, errors.New("no listeners in response")
+		return 0, errLastfmNoListeners
 	}
 
 	value, err := strconv.Atoi(listenersStr)
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeLastfm answers artist.getInfo by the artist name's prefix and counts requests per prefix
+// "known" has 1234 listeners, "zero" none, "unknown" isn't on Last.fm and "down" fails
+func fakeLastfm(t *testing.T) map[string]*atomic.Int32 {
+	t.Helper()
+	t.Setenv("LASTFM_API_KEY", "key")
+	t.Setenv("UPSTREAM_RETRY_ATTEMPTS", "1")
+
+	hits := map[string]*atomic.Int32{}
+	for _, p := range []string{"known", "zero", "unknown", "down"} {
+		hits[p] = new(atomic.Int32)
+	}
+	useFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		prefix, _, _ := strings.Cut(strings.ToLower(r.URL.Query().Get("artist")), " ")
+		hits[prefix].Add(1)
+		switch prefix {
+		case "known":
+			fmt.Fprint(w, `{"artist":{"stats":{"listeners":"1234"}}}`)
+		case "zero":
+			fmt.Fprint(w, `{"artist":{"stats":{"listeners":"0"}}}`)
+		case "unknown":
+			fmt.Fprint(w, `{"error":6,"message":"The artist you supplied could not be found"}`)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	})
+	return hits
+}
+
+// lastfmTestName gives a name no earlier run has cached
+func lastfmTestName(prefix string) string {
+	return fmt.Sprintf("%s %d", prefix, newTestID())
+}
+
+func TestFetchArtistMonthlyListenersCaches(t *testing.T) {
+	hits := fakeLastfm(t)
+
+	name := lastfmTestName("known")
+	for _, n := range []string{name, "  " + strings.ToUpper(name) + " ", strings.ReplaceAll(name, " ", "   ")} {
+		got, err := FetchArtistMonthlyListeners(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != 1234 {
+			t.Fatalf("listeners = %d, want 1234", got)
+		}
+	}
+	if n := hits["known"].Load(); n != 1 {
+		t.Fatalf("made %d requests, want 1", n)
+	}
+}
+
+func TestFetchArtistMonthlyListenersMisses(t *testing.T) {
+	hits := fakeLastfm(t)
+
+	zero, unknown, down := lastfmTestName("zero"), lastfmTestName("unknown"), lastfmTestName("down")
+	for i := 0; i < 2; i++ {
+		if got, err := FetchArtistMonthlyListeners(zero); err != nil || got != 0 {
+			t.Fatalf("zero: %d, %v", got, err)
+		}
+		if _, err := FetchArtistMonthlyListeners(unknown); !errors.Is(err, errLastfmNoListeners) {
+			t.Fatalf("unknown: err = %v, want errLastfmNoListeners", err)
+		}
+		if _, err := FetchArtistMonthlyListeners(down); err == nil {
+			t.Fatal("down: no error")
+		}
+	}
+
+	// Misses are cached, failures are retried on the next call
+	for prefix, want := range map[string]int32{"zero": 1, "unknown": 1, "down": 2} {
+		if n := hits[prefix].Load(); n != want {
+			t.Errorf("%s: made %d requests, want %d", prefix, n, want)
+		}
+	}
+}
+
+func TestFetchArtistMonthlyListenersMissExpiresFirst(t *testing.T) {
+	hits := fakeLastfm(t)
+
+	known, unknown := lastfmTestName("known"), lastfmTestName("unknown")
+	FetchArtistMonthlyListeners(known)
+	FetchArtistMonthlyListeners(unknown)
+
+	// Past the miss TTL but within the listeners TTL
+	later := time.Now().Add(defaultLastfmMissCacheTTL + time.Minute)
+	for _, now := range []*func() time.Time{&getLastfmListenersCache().now, &getLastfmMissCache().now} {
+		prev := *now
+		*now = func() time.Time { return later }
+		t.Cleanup(func() { *now = prev })
+	}
+
+	FetchArtistMonthlyListeners(known)
+	FetchArtistMonthlyListeners(unknown)
+	if n := hits["known"].Load(); n != 1 {
+		t.Errorf("known: made %d requests, want 1", n)
+	}
+	if n := hits["unknown"].Load(); n != 2 {
+		t.Errorf("unknown: made %d requests, want 2 once the miss expired", n)
+	}
+}
+
+func TestFetchArtistMonthlyListenersNeedsKey(t *testing.T) {
+	t.Setenv("LASTFM_API_KEY", "")
+	if _, err := FetchArtistMonthlyListeners("Queen"); err == nil {
+		t.Fatal("no error without an API key")
+	}
+}
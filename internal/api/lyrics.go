@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLyricsCacheTTL  = 24 * time.Hour
+	defaultLyricsCacheSize = 1000
+)
+
+// ErrLyricsDisabled is returned when LYRICS_API_URL or LYRICS_API_KEY isn't set
+var ErrLyricsDisabled = errors.New("lyrics api not configured")
+
+// errLyricsNotFound is cached as an empty string so misses don't hit the API again
+var errLyricsNotFound = errors.New("lyrics not found")
+
+type lyricsResponse struct {
+	Lyrics string `json:"lyrics"`
+}
+
+// lyricsConfig returns the URL template and token for the lyrics provider
+// The URL carries `{artist}` and `{title}` placeholders, e.g. https://lyrics.example/v1?artist={artist}&title={title}
+func lyricsConfig() (string, string) {
+	return strings.TrimSpace(os.Getenv("LYRICS_API_URL")), strings.TrimSpace(os.Getenv("LYRICS_API_KEY"))
+}
+
+// LyricsEnabled reports whether a lyrics provider is configured
+func LyricsEnabled() bool {
+	u, key := lyricsConfig()
+	return u != "" && key != ""
+}
+
+// getLyricsCache keeps lyrics by normalized (artist, title)
+var (
+	getLyricsCache = lazyTTLCache[string, string]("LYRICS_CACHE", defaultLyricsCacheTTL, defaultLyricsCacheSize)
+	lyricsInflight inflightGroup[string, string]
+)
+
+// lyricsCacheKey normalizes case and spacing so equivalent lookups share an entry
+func lyricsCacheKey(artist, title string) string {
+	norm := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+	return norm(artist) + "\x00" + norm(title)
+}
+
+// FetchLyrics returns the lyrics of a song from the configured provider
+// Errors mention "not found" when the provider has no lyrics for the song
+func FetchLyrics(artist, title string) (string, error) {
+	if !LyricsEnabled() {
+		return "", ErrLyricsDisabled
+	}
+
+	artist = strings.TrimSpace(artist)
+	title = strings.TrimSpace(title)
+	if artist == "" || title == "" {
+		return "", errors.New("missing artist or title")
+	}
+
+	key := lyricsCacheKey(artist, title)
+	lyrics, ok := getLyricsCache().Get(key)
+	if !ok {
+		var err error
+		lyrics, err = lyricsInflight.Do(key, func() (string, error) {
+			lyrics, err := requestLyrics(artist, title)
+			if err != nil && !errors.Is(err, errLyricsNotFound) {
+				return "", err
+			}
+			getLyricsCache().Set(key, lyrics)
+			return lyrics, nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if lyrics == "" {
+		return "", errLyricsNotFound
+	}
+	return lyrics, nil
+}
+
+// requestLyrics calls the provider, sending the key as a bearer token
+func requestLyrics(artist, title string) (string, error) {
+	tmpl, key := lyricsConfig()
+	u := strings.NewReplacer(
+		"{artist}", url.QueryEscape(artist),
+		"{title}", url.QueryEscape(title),
+	).Replace(tmpl)
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("User-Agent", "GroupieTrackerSchoolProject/1.0 (contact@example.com)")
+
+	resp, err := upstreamDo(req, 8*time.Second, 1)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", errLyricsNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("lyrics request failed: %s", resp.Status)
+	}
+
+	var payload lyricsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", err
+	}
+
+	lyrics := strings.TrimSpace(strings.ReplaceAll(payload.Lyrics, "\r\n", "\n"))
+	if lyrics == "" {
+		return "", errLyricsNotFound
+	}
+	return lyrics, nil
+}
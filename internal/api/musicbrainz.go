@@ -0,0 +1,226 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	musicBrainzBaseURL = "https://musicbrainz.org/ws/2"
+	musicBrainzHost    = "musicbrainz.org"
+	// MusicBrainz asks for an app name, version and contact URL in every User-Agent
+	musicBrainzUserAgent = "GroupieTrackerSchoolProject/1.0 ( https://github.com/Palawizard/Pala-s-Groupie-Tracker )"
+	// Anonymous clients get one request per second per IP
+	musicBrainzInterval = time.Second
+
+	defaultMusicBrainzCacheTTL  = 6 * time.Hour
+	defaultMusicBrainzCacheSize = 1000
+)
+
+type MusicBrainzArtist struct {
+	ID             string              `json:"id"`
+	Name           string              `json:"name"`
+	SortName       string              `json:"sort-name"`
+	Type           string              `json:"type"`
+	Country        string              `json:"country"`
+	Disambiguation string              `json:"disambiguation"`
+	Score          int                 `json:"score"`
+	LifeSpan       MusicBrainzLifeSpan `json:"life-span"`
+	Area           *MusicBrainzArea    `json:"area"`
+	Tags           []MusicBrainzTag    `json:"tags"`
+}
+
+type MusicBrainzLifeSpan struct {
+	Begin string `json:"begin"`
+	End   string `json:"end"`
+	Ended bool   `json:"ended"`
+}
+
+type MusicBrainzArea struct {
+	Name string `json:"name"`
+}
+
+type MusicBrainzTag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type musicBrainzSearchResponse struct {
+	Count   int                 `json:"count"`
+	Artists []MusicBrainzArtist `json:"artists"`
+}
+
+// BeginYear returns the year part of the life-span start (formed, born), or ""
+func (l MusicBrainzLifeSpan) BeginYear() string {
+	return musicBrainzYear(l.Begin)
+}
+
+// EndYear returns the year part of the life-span end (dissolved, died), or ""
+func (l MusicBrainzLifeSpan) EndYear() string {
+	return musicBrainzYear(l.End)
+}
+
+// musicBrainzYear extracts YYYY from partial dates like "1994", "1994-03" or "1994-03-12"
+func musicBrainzYear(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) < 4 {
+		return ""
+	}
+	if _, err := strconv.Atoi(s[:4]); err != nil {
+		return ""
+	}
+	return s[:4]
+}
+
+// TopTags returns up to n tag names, most voted first
+func (a MusicBrainzArtist) TopTags(n int) []string {
+	tags := make([]MusicBrainzTag, 0, len(a.Tags))
+	for _, t := range a.Tags {
+		if strings.TrimSpace(t.Name) != "" {
+			tags = append(tags, t)
+		}
+	}
+	// Tags come back in arbitrary order, keep the popular ones
+	for i := 1; i < len(tags); i++ {
+		for j := i; j > 0 && tags[j].Count > tags[j-1].Count; j-- {
+			tags[j], tags[j-1] = tags[j-1], tags[j]
+		}
+	}
+	if n > 0 && len(tags) > n {
+		tags = tags[:n]
+	}
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.Name
+	}
+	return out
+}
+
+// MusicBrainz caches keep search results and artists, since every miss costs a rate-limited second
+var (
+	getMusicBrainzSearchCache = lazyTTLCache[string, []MusicBrainzArtist]("MUSICBRAINZ_CACHE", defaultMusicBrainzCacheTTL, defaultMusicBrainzCacheSize)
+	getMusicBrainzArtistCache = lazyTTLCache[string, MusicBrainzArtist]("MUSICBRAINZ_CACHE", defaultMusicBrainzCacheTTL, defaultMusicBrainzCacheSize)
+	musicBrainzSearchInflight inflightGroup[string, []MusicBrainzArtist]
+	musicBrainzArtistInflight inflightGroup[string, MusicBrainzArtist]
+)
+
+// musicBrainzGetJSON performs a rate-limited GET against the MusicBrainz web service
+func musicBrainzGetJSON(fullURL string, out any) error {
+	req, err := http.NewRequest("GET", fullURL, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", musicBrainzUserAgent)
+
+	// Requests over the limit get a 503, so queue instead of firing in parallel
+	// No doWithRetry here, its backoff would not go through the per-host queue
+	waitForHost(musicBrainzHost, musicBrainzInterval)
+
+	resp, err := upstreamDo(req, 8*time.Second, 1)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("musicbrainz artist not found")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("musicbrainz request failed: %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// SearchMusicBrainzArtists searches artists by name (Lucene syntax is passed through)
+func SearchMusicBrainzArtists(query string, limit int) ([]MusicBrainzArtist, error) {
+	q := strings.TrimSpace(query)
+	if q == "" {
+		return []MusicBrainzArtist{}, nil
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 25
+	}
+
+	key := strings.ToLower(q) + "|" + strconv.Itoa(limit)
+	if cached, ok := getMusicBrainzSearchCache().Get(key); ok {
+		return cached, nil
+	}
+
+	return musicBrainzSearchInflight.Do(key, func() ([]MusicBrainzArtist, error) {
+		params := url.Values{}
+		params.Set("query", q)
+		params.Set("limit", strconv.Itoa(limit))
+		params.Set("fmt", "json")
+
+		var payload musicBrainzSearchResponse
+		if err := musicBrainzGetJSON(musicBrainzBaseURL+"/artist?"+params.Encode(), &payload); err != nil {
+			return nil, err
+		}
+
+		artists := payload.Artists
+		if artists == nil {
+			artists = []MusicBrainzArtist{}
+		}
+		getMusicBrainzSearchCache().Set(key, artists)
+		for _, a := range artists {
+			// Search hits include tags too, so seed the artist cache and skip a lookup on click
+			if a.ID != "" {
+				if _, ok := getMusicBrainzArtistCache().Get(a.ID); !ok {
+					getMusicBrainzArtistCache().Set(a.ID, a)
+				}
+			}
+		}
+
+		return artists, nil
+	})
+}
+
+// musicBrainzIDPattern matches MusicBrainz IDs (lowercase UUIDs)
+var musicBrainzIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// IsMusicBrainzID reports whether s looks like a MusicBrainz artist ID
+func IsMusicBrainzID(s string) bool {
+	return musicBrainzIDPattern.MatchString(s)
+}
+
+// GetMusicBrainzArtist fetches an artist with tags by MusicBrainz ID
+func GetMusicBrainzArtist(id string) (*MusicBrainzArtist, error) {
+	if !IsMusicBrainzID(id) {
+		return nil, fmt.Errorf("invalid musicbrainz artist id")
+	}
+
+	if cached, ok := getMusicBrainzArtistCache().Get(id); ok {
+		// Return a copy so callers can't mutate the cached entry
+		return &cached, nil
+	}
+
+	artist, err := musicBrainzArtistInflight.Do(id, func() (MusicBrainzArtist, error) {
+		params := url.Values{}
+		params.Set("inc", "tags")
+		params.Set("fmt", "json")
+
+		var artist MusicBrainzArtist
+		if err := musicBrainzGetJSON(musicBrainzBaseURL+"/artist/"+id+"?"+params.Encode(), &artist); err != nil {
+			return MusicBrainzArtist{}, err
+		}
+		if artist.ID == "" {
+			return MusicBrainzArtist{}, fmt.Errorf("musicbrainz artist not found")
+		}
+
+		getMusicBrainzArtistCache().Set(id, artist)
+		return artist, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &artist, nil
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// newsArtistPlaceholder is replaced by the URL-escaped artist name in ARTIST_NEWS_FEED_URL
+	newsArtistPlaceholder = "{artist}"
+
+	defaultArtistNewsCacheTTL  = 30 * time.Minute
+	defaultArtistNewsCacheSize = 500
+	// Feeds can be long, we only ever show a handful of headlines
+	maxArtistNewsItems = 20
+)
+
+// ErrArtistNewsDisabled is returned when no news feed is configured
+var ErrArtistNewsDisabled = errors.New("artist news feed not configured")
+
+// ArtistNewsItem is one headline from an artist news feed
+type ArtistNewsItem struct {
+	Title     string
+	Link      string
+	Source    string
+	Published time.Time
+}
+
+// newsFeed covers both RSS 2.0 (<rss><channel><item>) and Atom (<feed><entry>)
+type newsFeed struct {
+	Channel struct {
+		Items []struct {
+			Title   string `xml:"title"`
+			Link    string `xml:"link"`
+			PubDate string `xml:"pubDate"`
+			Source  string `xml:"source"`
+		} `xml:"item"`
+	} `xml:"channel"`
+	Entries []struct {
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Updated   string `xml:"updated"`
+		Published string `xml:"published"`
+		Author    struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+	} `xml:"entry"`
+}
+
+// artistNewsFeedURL returns the configured feed template, e.g.
+// https://news.google.com/rss/search?q={artist}&hl=en-US&gl=US&ceid=US:en
+func artistNewsFeedURL() string {
+	return strings.TrimSpace(os.Getenv("ARTIST_NEWS_FEED_URL"))
+}
+
+// ArtistNewsEnabled reports whether a news feed is configured
+func ArtistNewsEnabled() bool {
+	return strings.Contains(artistNewsFeedURL(), newsArtistPlaceholder)
+}
+
+// getArtistNewsCache keeps parsed headlines per artist name
+var (
+	getArtistNewsCache = lazyTTLCache[string, []ArtistNewsItem]("ARTIST_NEWS_CACHE", defaultArtistNewsCacheTTL, defaultArtistNewsCacheSize)
+	artistNewsInflight inflightGroup[string, []ArtistNewsItem]
+)
+
+// FetchArtistNews returns recent headlines about an artist, newest first
+// It returns ErrArtistNewsDisabled when ARTIST_NEWS_FEED_URL isn't set
+func FetchArtistNews(artistName string, limit int) ([]ArtistNewsItem, error) {
+	if !ArtistNewsEnabled() {
+		return nil, ErrArtistNewsDisabled
+	}
+
+	name := strings.TrimSpace(artistName)
+	if name == "" {
+		return nil, errors.New("empty artist name")
+	}
+
+	key := strings.ToLower(name)
+	items, ok := getArtistNewsCache().Get(key)
+	if !ok {
+		var err error
+		items, err = artistNewsInflight.Do(key, func() ([]ArtistNewsItem, error) {
+			items, err := fetchArtistNewsFeed(name)
+			if err != nil {
+				return nil, err
+			}
+			// Cache empty feeds too so quiet artists don't refetch on every view
+			getArtistNewsCache().Set(key, items)
+			return items, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	// Return a copy so callers can't mutate the cached entry
+	return append([]ArtistNewsItem(nil), items...), nil
+}
+
+// fetchArtistNewsFeed downloads and parses the configured feed for one artist
+func fetchArtistNewsFeed(name string) ([]ArtistNewsItem, error) {
+	u := strings.ReplaceAll(artistNewsFeedURL(), newsArtistPlaceholder, url.QueryEscape(name))
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml, text/xml")
+	req.Header.Set("User-Agent", "GroupieTrackerSchoolProject/1.0 (contact@example.com)")
+
+	// News is a nice-to-have, don't hold the detail page for long
+	resp, err := upstreamDo(req, 5*time.Second, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("news feed status: %s", resp.Status)
+	}
+
+	var feed newsFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	items := make([]ArtistNewsItem, 0, len(feed.Channel.Items)+len(feed.Entries))
+	for _, it := range feed.Channel.Items {
+		items = append(items, ArtistNewsItem{
+			Title:     strings.TrimSpace(it.Title),
+			Link:      strings.TrimSpace(it.Link),
+			Source:    strings.TrimSpace(it.Source),
+			Published: parseNewsDate(it.PubDate),
+		})
+	}
+	for _, e := range feed.Entries {
+		link := ""
+		for _, l := range e.Links {
+			// Atom entries can carry several links, the alternate one is the article
+			if l.Rel == "" || l.Rel == "alternate" {
+				link = strings.TrimSpace(l.Href)
+				break
+			}
+		}
+		published := e.Published
+		if published == "" {
+			published = e.Updated
+		}
+		items = append(items, ArtistNewsItem{
+			Title:     strings.TrimSpace(e.Title),
+			Link:      link,
+			Source:    strings.TrimSpace(e.Author.Name),
+			Published: parseNewsDate(published),
+		})
+	}
+
+	out := items[:0]
+	for _, it := range items {
+		// Only keep headlines we can actually link to over http(s)
+		if it.Title == "" || !(strings.HasPrefix(it.Link, "https://") || strings.HasPrefix(it.Link, "http://")) {
+			continue
+		}
+		out = append(out, it)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		// Newest first, undated items last
+		return out[i].Published.After(out[j].Published)
+	})
+
+	if len(out) > maxArtistNewsItems {
+		out = out[:maxArtistNewsItems]
+	}
+	return out, nil
+}
+
+// parseNewsDate handles the RSS (RFC 1123) and Atom (RFC 3339) date formats
+func parseNewsDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
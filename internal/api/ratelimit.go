@@ -0,0 +1,31 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// hostLimiter hands out request slots per host for APIs with strict rate limits
+// Each caller reserves the next free slot, so bursts are spread out instead of rejected
+var hostLimiter = struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}{
+	next: make(map[string]time.Time),
+}
+
+// waitForHost blocks until a request to host is allowed, at most one per interval
+func waitForHost(host string, interval time.Duration) {
+	hostLimiter.mu.Lock()
+	now := time.Now()
+	slot := hostLimiter.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	hostLimiter.next[host] = slot.Add(interval)
+	hostLimiter.mu.Unlock()
+
+	if d := time.Until(slot); d > 0 {
+		time.Sleep(d)
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRetryAfter caps how long a Retry-After header may delay the next attempt
+// Longer waits are not worth holding a page render for, so the response is returned as is
+const maxRetryAfter = 5 * time.Second
+
+// maxRetryDelay caps the exponential backoff between two attempts
+const maxRetryDelay = 2 * time.Second
+
+// upstreamRetryAttempts is the total number of tries per upstream request, 1 disables retries
+func upstreamRetryAttempts() int {
+	return envInt("UPSTREAM_RETRY_ATTEMPTS", 3)
+}
+
+// upstreamRetryBaseDelay is the backoff before the first retry, doubled after each one
+func upstreamRetryBaseDelay() time.Duration {
+	return envDuration("UPSTREAM_RETRY_BASE_DELAY", 200*time.Millisecond)
+}
+
+// doWithRetry sends req with client, retrying connection errors and 5xx/429 responses
+// Waits grow exponentially with jitter, a Retry-After header takes precedence when present
+// The caller owns the returned response body, like with client.Do
+func doWithRetry(req *http.Request, client *http.Client, attempts int) (*http.Response, error) {
+	if attempts < 1 || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		// A body that can't be replayed only gets one shot
+		attempts = 1
+	}
+
+	ctx := req.Context()
+	base := upstreamRetryBaseDelay()
+
+	for attempt := 1; ; attempt++ {
+		try := req
+		if attempt > 1 {
+			try = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				try.Body = body
+			}
+		}
+
+		resp, err := client.Do(try)
+		if attempt >= attempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay := backoffDelay(base, attempt)
+		if err == nil {
+			if !retryableStatus(resp.StatusCode) {
+				return resp, nil
+			}
+			if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				if d > maxRetryAfter {
+					return resp, nil
+				}
+				delay = d
+			}
+			// Drain so the connection can be reused for the next attempt
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		}
+
+		if !sleepCtx(ctx, delay) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryableStatus reports whether an upstream status is worth another attempt
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// backoffDelay returns the wait after the given failed attempt, between half and all of base*2^(attempt-1)
+func backoffDelay(base time.Duration, attempt int) time.Duration {
+	d := base << (attempt - 1)
+	if d <= 0 || d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// parseRetryAfter reads a Retry-After value given either in seconds or as an HTTP date
+func parseRetryAfter(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// sleepCtx waits for d and returns false if ctx is canceled first
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
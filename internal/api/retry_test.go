@@ -0,0 +1,200 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer fails its first `failures` requests with status, then answers 200 with the request body
+func flakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	t.Setenv("UPSTREAM_RETRY_BASE_DELAY", "1ms")
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if hits.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		fmt.Fprintf(w, "ok %s", body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestDoWithRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int32
+		status   int
+		header   http.Header
+		attempts int
+		want     int
+		hits     int32
+	}{
+		{"fails twice then succeeds", 2, http.StatusServiceUnavailable, nil, 3, http.StatusOK, 3},
+		{"gives up after the attempts", 5, http.StatusBadGateway, nil, 3, http.StatusBadGateway, 3},
+		{"one attempt disables retries", 1, http.StatusInternalServerError, nil, 1, http.StatusInternalServerError, 1},
+		{"client errors aren't retried", 1, http.StatusNotFound, nil, 3, http.StatusNotFound, 1},
+		{"rate limit retried", 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}}, 3, http.StatusOK, 2},
+		{"long Retry-After returned as is", 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"60"}}, 3, http.StatusTooManyRequests, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, hits := flakyServer(t, tt.failures, tt.status, tt.header)
+
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+			resp, err := doWithRetry(req, srv.Client(), tt.attempts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if n := hits.Load(); n != tt.hits {
+				t.Fatalf("made %d requests, want %d", n, tt.hits)
+			}
+		})
+	}
+}
+
+func TestDoWithRetryReplaysBody(t *testing.T) {
+	srv, hits := flakyServer(t, 2, http.StatusServiceUnavailable, nil)
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+	resp, err := doWithRetry(req, srv.Client(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "ok payload" {
+		t.Fatalf("body = %q, want the payload on the last attempt", body)
+	}
+	if n := hits.Load(); n != 3 {
+		t.Fatalf("made %d requests, want 3", n)
+	}
+
+	// A body that can't be read again is only sent once
+	hits.Store(0)
+	req, _ = http.NewRequest(http.MethodPost, srv.URL, io.NopCloser(strings.NewReader("once")))
+	req.GetBody = nil
+	resp, err = doWithRetry(req, srv.Client(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("made %d requests with a one-shot body, want 1", n)
+	}
+}
+
+func TestDoWithRetryConnectionErrors(t *testing.T) {
+	t.Setenv("UPSTREAM_RETRY_BASE_DELAY", "1ms")
+	var calls atomic.Int32
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if calls.Add(1) <= 2 {
+			return nil, errors.New("connection reset")
+		}
+		w := httptest.NewRecorder()
+		w.WriteString("ok")
+		return w.Result(), nil
+	})}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://upstream.test/", nil)
+	resp, err := doWithRetry(req, client, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("made %d requests, want 3", n)
+	}
+}
+
+func TestDoWithRetryStopsOnCancel(t *testing.T) {
+	t.Setenv("UPSTREAM_RETRY_BASE_DELAY", "10s")
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://upstream.test/", nil)
+	start := time.Now()
+	if _, err := doWithRetry(req, client, 3); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("waited %v through the backoff after the cancel", d)
+	}
+}
+
+func TestUpstreamHelpersRetry(t *testing.T) {
+	t.Setenv("UPSTREAM_RETRY_BASE_DELAY", "1ms")
+	var hits atomic.Int32
+	useFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/album/")
+		fmt.Fprintf(w, `{"id":%s,"title":"Retried"}`, id)
+	})
+
+	album, err := GetDeezerAlbum(newTestID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if album.Title != "Retried" || hits.Load() != 3 {
+		t.Fatalf("album %q after %d requests, want Retried after 3", album.Title, hits.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		in   string
+		min  time.Duration
+		max  time.Duration
+		want bool
+	}{
+		{"", 0, 0, false},
+		{"5", 5 * time.Second, 5 * time.Second, true},
+		{" 0 ", 0, 0, true},
+		{"-1", 0, 0, false},
+		{"soon", 0, 0, false},
+		{future, 59 * time.Minute, time.Hour, true},
+		{past, 0, 0, true},
+	}
+	for _, tt := range tests {
+		d, ok := parseRetryAfter(tt.in)
+		if ok != tt.want || d < tt.min || d > tt.max {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v in [%v, %v]", tt.in, d, ok, tt.want, tt.min, tt.max)
+		}
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	base := 100 * time.Millisecond
+	for attempt, full := range map[int]time.Duration{1: base, 2: 2 * base, 3: 4 * base, 10: maxRetryDelay, 100: maxRetryDelay} {
+		for i := 0; i < 50; i++ {
+			if d := backoffDelay(base, attempt); d < full/2 || d > full {
+				t.Fatalf("attempt %d: delay %v outside [%v, %v]", attempt, d, full/2, full)
+			}
+		}
+	}
+}
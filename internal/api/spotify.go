@@ -2,15 +2,19 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 )
 
 type SpotifyFollowers struct {
@@ -72,9 +76,32 @@ type spotifyTopTracksResponse struct {
 
 type spotifyArtistAlbumsResponse struct {
 	Items []SpotifyAlbum `json:"items"`
+	Next  string         `json:"next"`
 }
 
-var spotifyHTTP = &http.Client{Timeout: 8 * time.Second}
+// maxSpotifyAlbumDepth bounds how far into an artist's discography paging can go
+const maxSpotifyAlbumDepth = 200
+
+// spotifyTimeout bounds each Web API and token call
+const spotifyTimeout = 8 * time.Second
+
+// Endpoints are variables so they can be pointed at a local server
+var (
+	spotifyAPIBaseURL = "https://api.spotify.com/v1"
+	spotifyTokenURL   = "https://accounts.spotify.com/api/token"
+)
+
+// spotifyStatusError is returned when Spotify answers with an unexpected status
+type spotifyStatusError struct {
+	Status     string
+	StatusCode int
+	// RetryAfter is parsed from the Retry-After header on 429 responses
+	RetryAfter time.Duration
+}
+
+func (e *spotifyStatusError) Error() string {
+	return "spotify request failed: " + e.Status
+}
 
 var spotifyTokenCache = struct {
 	mu        sync.Mutex
@@ -103,14 +130,18 @@ func spotifyNewJSONRequest(method, u string, body io.Reader, token string) (*htt
 
 // spotifyDoJSON executes req, checks the expected status code, then decodes JSON into out
 func spotifyDoJSON(req *http.Request, expectedStatus int, out any) error {
-	resp, err := spotifyHTTP.Do(req)
+	resp, err := upstreamDo(req, spotifyTimeout, upstreamRetryAttempts())
 	if err != nil {
 		return err
 	}
 	defer spotifyClose(resp.Body)
 
 	if resp.StatusCode != expectedStatus {
-		return fmt.Errorf("spotify request failed: %s", resp.Status)
+		statusErr := &spotifyStatusError{Status: resp.Status, StatusCode: resp.StatusCode}
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			statusErr.RetryAfter = d
+		}
+		return statusErr
 	}
 
 	if out == nil {
@@ -141,7 +172,7 @@ func getSpotifyToken() (string, error) {
 	data := url.Values{}
 	data.Set("grant_type", "client_credentials")
 
-	req, err := http.NewRequest("POST", "https://accounts.spotify.com/api/token", strings.NewReader(data.Encode()))
+	req, err := http.NewRequest("POST", spotifyTokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return "", err
 	}
@@ -174,14 +205,130 @@ func getSpotifyToken() (string, error) {
 	return body.AccessToken, nil
 }
 
+// invalidateSpotifyToken drops the cached token if it is still the one that was rejected
+// A concurrent request may already have stored a fresh token, which we keep
+func invalidateSpotifyToken(rejected string) {
+	spotifyTokenCache.mu.Lock()
+	defer spotifyTokenCache.mu.Unlock()
+	if spotifyTokenCache.token == rejected {
+		spotifyTokenCache.token = ""
+		spotifyTokenCache.expiresAt = time.Time{}
+	}
+}
+
+// spotifyRequest performs an authenticated Web API call and decodes the JSON response into out
+// A 401 invalidates the cached token and retries once with a fresh one
+// Rate limits and server errors are already retried by doWithRetry
+func spotifyRequest(method, u string, expectedStatus int, out any) error {
+	retried401 := false
+	for {
+		token, err := getSpotifyToken()
+		if err != nil {
+			return err
+		}
+
+		req, err := spotifyNewJSONRequest(method, u, nil, token)
+		if err != nil {
+			return err
+		}
+
+		err = spotifyDoJSON(req, expectedStatus, out)
+		var statusErr *spotifyStatusError
+		if err == nil || !errors.As(err, &statusErr) {
+			return err
+		}
+
+		if statusErr.StatusCode != http.StatusUnauthorized || retried401 {
+			return err
+		}
+		// Tokens can be revoked or expire before expires_in says they do
+		retried401 = true
+		invalidateSpotifyToken(token)
+	}
+}
+
 // SearchSpotifyArtists searches Spotify for artists matching query
 func SearchSpotifyArtists(query string) ([]SpotifyArtist, error) {
-	token, err := getSpotifyToken()
-	if err != nil {
+	return SearchSpotifyArtistsFiltered(query, SpotifySearchFilters{})
+}
+
+// SpotifySearchFilters are optional field filters added to the Spotify `q` parameter
+type SpotifySearchFilters struct {
+	// Genre matches Spotify's genre tags, e.g. "grunge" or "hip hop"
+	Genre string
+	// Year is a single year ("1994") or an inclusive range ("1990-1999")
+	Year string
+}
+
+// IsZero reports whether no filter is set
+func (f SpotifySearchFilters) IsZero() bool {
+	return f.Genre == "" && f.Year == ""
+}
+
+var (
+	spotifyYearFilterPattern = regexp.MustCompile(`^\d{4}(-\d{4})?$`)
+	// Genre tags are plain words, anything else could inject extra field filters
+	spotifyGenreFilterPattern = regexp.MustCompile(`^[\p{L}\p{N} &'\-]{1,50}$`)
+)
+
+// NormalizeSpotifySearchFilters trims the filters and drops values Spotify can't use
+func NormalizeSpotifySearchFilters(f SpotifySearchFilters) SpotifySearchFilters {
+	genre := strings.Join(strings.Fields(strings.ToLower(f.Genre)), " ")
+	if !spotifyGenreFilterPattern.MatchString(genre) {
+		genre = ""
+	}
+
+	year := strings.ReplaceAll(strings.TrimSpace(f.Year), " ", "")
+	if !spotifyYearFilterPattern.MatchString(year) {
+		year = ""
+	} else if from, to, ok := strings.Cut(year, "-"); ok && from > to {
+		// Accept reversed ranges instead of sending one that matches nothing
+		year = to + "-" + from
+	}
+
+	return SpotifySearchFilters{Genre: genre, Year: year}
+}
+
+// BuildSpotifyArtistQuery composes the Spotify `q` value from free text and field filters
+// Without filters the query is sent as typed, e.g. ("nirvana", {Genre: "grunge"}) gives `nirvana genre:grunge`
+func BuildSpotifyArtistQuery(query string, f SpotifySearchFilters) string {
+	f = NormalizeSpotifySearchFilters(f)
+	parts := make([]string, 0, 3)
+	if q := strings.TrimSpace(query); q != "" {
+		parts = append(parts, q)
+	}
+	if f.Genre != "" {
+		if strings.Contains(f.Genre, " ") {
+			// Multi-word genres must be quoted or only the first word is filtered
+			parts = append(parts, `genre:"`+f.Genre+`"`)
+		} else {
+			parts = append(parts, "genre:"+f.Genre)
+		}
+	}
+	if f.Year != "" {
+		parts = append(parts, "year:"+f.Year)
+	}
+	return strings.Join(parts, " ")
+}
+
+// SearchSpotifyArtistsFiltered searches Spotify artists with optional genre and year filters
+func SearchSpotifyArtistsFiltered(query string, f SpotifySearchFilters) ([]SpotifyArtist, error) {
+	var body spotifySearchResponse
+	if err := spotifyRequest("GET", spotifySearchURL(BuildSpotifyArtistQuery(query, f)), http.StatusOK, &body); err != nil {
 		return nil, err
 	}
 
-	baseURL := "https://api.spotify.com/v1/search"
+	return body.Artists.Items, nil
+}
+
+// spotifySearchMarket returns the optional market used to filter artist search
+// Search works without one, and setting it hides artists unavailable in that market
+func spotifySearchMarket() string {
+	return strings.ToUpper(strings.TrimSpace(os.Getenv("SPOTIFY_SEARCH_MARKET")))
+}
+
+// spotifySearchURL builds the artist search URL, only adding `market` when one is configured
+func spotifySearchURL(query string) string {
 	params := url.Values{}
 	q := strings.TrimSpace(query)
 	if q == "" {
@@ -191,80 +338,52 @@ func SearchSpotifyArtists(query string) ([]SpotifyArtist, error) {
 	params.Set("q", q)
 	params.Set("type", "artist")
 	params.Set("limit", "30")
-	// Market can affect which artists are returned
-	params.Set("market", "US")
-
-	req, err := spotifyNewJSONRequest("GET", baseURL+"?"+params.Encode(), nil, token)
-	if err != nil {
-		return nil, err
-	}
-
-	var body spotifySearchResponse
-	if err := spotifyDoJSON(req, http.StatusOK, &body); err != nil {
-		return nil, err
+	if m := spotifySearchMarket(); m != "" {
+		params.Set("market", m)
 	}
-
-	return body.Artists.Items, nil
+	return spotifyAPIBaseURL + "/search?" + params.Encode()
 }
 
 // GetSpotifyArtist fetches an artist by Spotify ID
 func GetSpotifyArtist(id string) (*SpotifyArtist, error) {
-	token, err := getSpotifyToken()
-	if err != nil {
-		return nil, err
-	}
-
-	artistURL := "https://api.spotify.com/v1/artists/" + id
-	req, err := spotifyNewJSONRequest("GET", artistURL, nil, token)
-	if err != nil {
-		return nil, err
-	}
+	artistURL := spotifyAPIBaseURL + "/artists/" + id
 
 	var artist SpotifyArtist
-	if err := spotifyDoJSON(req, http.StatusOK, &artist); err != nil {
+	if err := spotifyRequest("GET", artistURL, http.StatusOK, &artist); err != nil {
 		return nil, err
 	}
 
 	return &artist, nil
 }
 
-// GetSpotifyArtistTopTracks returns the artist's top tracks for a given market
-func GetSpotifyArtistTopTracks(id string, market string) ([]SpotifyTrack, error) {
-	token, err := getSpotifyToken()
-	if err != nil {
-		return nil, err
-	}
-
+// GetSpotifyArtistTopTracks returns up to limit top tracks for a given market (Spotify caps this at 10)
+func GetSpotifyArtistTopTracks(id string, market string, limit int) ([]SpotifyTrack, error) {
 	m := strings.TrimSpace(market)
 	if m == "" {
 		// Spotify requires a market, default to US
 		m = "US"
 	}
 
-	baseURL := "https://api.spotify.com/v1/artists/" + id + "/top-tracks"
+	baseURL := spotifyAPIBaseURL + "/artists/" + id + "/top-tracks"
 	params := url.Values{}
 	params.Set("market", m)
 
-	req, err := spotifyNewJSONRequest("GET", baseURL+"?"+params.Encode(), nil, token)
-	if err != nil {
+	var body spotifyTopTracksResponse
+	if err := spotifyRequest("GET", baseURL+"?"+params.Encode(), http.StatusOK, &body); err != nil {
 		return nil, err
 	}
 
-	var body spotifyTopTracksResponse
-	if err := spotifyDoJSON(req, http.StatusOK, &body); err != nil {
-		return nil, err
+	// The endpoint has no limit parameter, so trim locally
+	if limit > 0 && len(body.Tracks) > limit {
+		body.Tracks = body.Tracks[:limit]
 	}
 
 	return body.Tracks, nil
 }
 
-// GetSpotifyArtistAlbums returns a de-duplicated and sorted list of an artist's latest albums and singles
-func GetSpotifyArtistAlbums(id string, market string, limit int) ([]SpotifyAlbum, error) {
-	token, err := getSpotifyToken()
-	if err != nil {
-		return nil, err
-	}
-
+// GetSpotifyArtistAlbums returns a de-duplicated and sorted page of an artist's latest albums and singles
+// offset and limit apply to the sorted list, not to Spotify's raw paging
+func GetSpotifyArtistAlbums(id string, market string, offset int, limit int) ([]SpotifyAlbum, error) {
 	m := strings.TrimSpace(market)
 	if m == "" {
 		m = "US"
@@ -277,25 +396,38 @@ func GetSpotifyArtistAlbums(id string, market string, limit int) ([]SpotifyAlbum
 	if want > 50 {
 		want = 50
 	}
-	// Spotify album list API caps limit at 50; fetch a full page to reduce duplicates
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= maxSpotifyAlbumDepth {
+		return []SpotifyAlbum{}, nil
+	}
+	// Spotify album list API caps limit at 50; fetch full pages to reduce duplicates
 	// before de-duplication and truncation to `want`
 	fetch := 50
 
-	baseURL := "https://api.spotify.com/v1/artists/" + id + "/albums"
-	params := url.Values{}
-	params.Set("include_groups", "album,single")
-	params.Set("market", m)
-	params.Set("limit", fmt.Sprintf("%d", fetch))
-	params.Set("offset", "0")
-
-	req, err := spotifyNewJSONRequest("GET", baseURL+"?"+params.Encode(), nil, token)
-	if err != nil {
-		return nil, err
-	}
-
+	baseURL := spotifyAPIBaseURL + "/artists/" + id + "/albums"
 	var body spotifyArtistAlbumsResponse
-	if err := spotifyDoJSON(req, http.StatusOK, &body); err != nil {
-		return nil, err
+	for page := 0; page*fetch < offset+want+fetch && page*fetch < maxSpotifyAlbumDepth; page++ {
+		params := url.Values{}
+		params.Set("include_groups", "album,single")
+		params.Set("market", m)
+		params.Set("limit", fmt.Sprintf("%d", fetch))
+		params.Set("offset", fmt.Sprintf("%d", page*fetch))
+
+		var pageBody spotifyArtistAlbumsResponse
+		if err := spotifyRequest("GET", baseURL+"?"+params.Encode(), http.StatusOK, &pageBody); err != nil {
+			if page > 0 {
+				// Later pages are best-effort, keep what we already have
+				break
+			}
+			return nil, err
+		}
+
+		body.Items = append(body.Items, pageBody.Items...)
+		if pageBody.Next == "" || len(body.Items) >= offset+want+fetch {
+			break
+		}
 	}
 
 	// The API can return duplicates across include_groups, merge by ID
@@ -321,6 +453,9 @@ func GetSpotifyArtistAlbums(id string, market string, limit int) ([]SpotifyAlbum
 		merged = append(merged, a)
 	}
 
+	// Reissues and market variants come back under different IDs, collapse them too
+	merged = dedupeSpotifyAlbumsByTitle(merged)
+
 	sort.SliceStable(merged, func(i, j int) bool { // newest first, then stable tie-breakers
 		di, okI := ParseSpotifyReleaseDate(merged[i].ReleaseDate)
 		dj, okJ := ParseSpotifyReleaseDate(merged[j].ReleaseDate)
@@ -343,6 +478,10 @@ func GetSpotifyArtistAlbums(id string, market string, limit int) ([]SpotifyAlbum
 		return merged[i].ID < merged[j].ID
 	})
 
+	if offset >= len(merged) {
+		return []SpotifyAlbum{}, nil
+	}
+	merged = merged[offset:]
 	if len(merged) > want {
 		merged = merged[:want]
 	}
@@ -350,6 +489,95 @@ func GetSpotifyArtistAlbums(id string, market string, limit int) ([]SpotifyAlbum
 	return merged, nil
 }
 
+// dedupeSpotifyAlbumsByTitle collapses albums sharing a normalized name and release year
+// It prefers the "album" type over singles, then the earliest release, then the lowest ID
+func dedupeSpotifyAlbumsByTitle(albums []SpotifyAlbum) []SpotifyAlbum {
+	if len(albums) < 2 {
+		return albums
+	}
+
+	best := make(map[string]int, len(albums))
+	out := make([]SpotifyAlbum, 0, len(albums))
+	for _, a := range albums {
+		name := normalizeSpotifyAlbumName(a.Name)
+		if name == "" {
+			out = append(out, a)
+			continue
+		}
+
+		year := ""
+		if d, ok := ParseSpotifyReleaseDate(a.ReleaseDate); ok {
+			year = strconv.Itoa(d.Year())
+		}
+		key := name + "|" + year
+
+		idx, ok := best[key]
+		if !ok {
+			best[key] = len(out)
+			out = append(out, a)
+			continue
+		}
+		if spotifyAlbumPreferred(a, out[idx]) {
+			out[idx] = a
+		}
+	}
+
+	return out
+}
+
+// spotifyAlbumPreferred reports whether a should replace b when both are the same release
+func spotifyAlbumPreferred(a, b SpotifyAlbum) bool {
+	ra, rb := spotifyAlbumTypeRank(a.AlbumType), spotifyAlbumTypeRank(b.AlbumType)
+	if ra != rb {
+		return ra < rb
+	}
+
+	da, okA := ParseSpotifyReleaseDate(a.ReleaseDate)
+	db, okB := ParseSpotifyReleaseDate(b.ReleaseDate)
+	if okA && okB && !da.Equal(db) {
+		// The earliest date is usually the original release rather than a reissue
+		return da.Before(db)
+	}
+	if okA != okB {
+		return okA
+	}
+
+	return a.ID < b.ID
+}
+
+// spotifyAlbumTypeRank orders album types from most to least canonical
+func spotifyAlbumTypeRank(t string) int {
+	switch strings.ToLower(strings.TrimSpace(t)) {
+	case "album":
+		return 0
+	case "single":
+		return 1
+	case "compilation":
+		return 2
+	default:
+		return 3
+	}
+}
+
+// normalizeSpotifyAlbumName lowercases a title and collapses punctuation so near-identical names match
+func normalizeSpotifyAlbumName(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	space := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		if !space && b.Len() > 0 {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
 // ParseSpotifyReleaseDate parses Spotify's release_date which can be yyyy, yyyy-mm, or yyyy-mm-dd
 func ParseSpotifyReleaseDate(s string) (time.Time, bool) {
 	s = strings.TrimSpace(s)
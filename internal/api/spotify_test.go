@@ -0,0 +1,278 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDedupeSpotifyAlbumsByTitle(t *testing.T) {
+	album := func(id, name, typ, date string) SpotifyAlbum {
+		return SpotifyAlbum{ID: id, Name: name, AlbumType: typ, ReleaseDate: date}
+	}
+
+	tests := []struct {
+		name   string
+		albums []SpotifyAlbum
+		want   []string // IDs left, in order
+	}{
+		{"nil", nil, nil},
+		{"empty", []SpotifyAlbum{}, []string{}},
+		{"single album", []SpotifyAlbum{album("a", "Abbey Road", "album", "1969-09-26")}, []string{"a"}},
+		{
+			"case differs",
+			[]SpotifyAlbum{album("a", "Abbey Road", "album", "1969"), album("b", "ABBEY road", "album", "1969")},
+			[]string{"a"},
+		},
+		{
+			"whitespace and punctuation differ",
+			[]SpotifyAlbum{album("a", "Abbey Road", "album", "1969"), album("b", "  Abbey   Road! ", "album", "1969")},
+			[]string{"a"},
+		},
+		{
+			"kept in the first album's place",
+			[]SpotifyAlbum{
+				album("z", "Help", "single", "1965"),
+				album("m", "Revolver", "album", "1966"),
+				album("b", "help", "album", "1965"),
+			},
+			[]string{"b", "m"},
+		},
+		{
+			"album beats single",
+			[]SpotifyAlbum{album("a", "Help", "single", "1965-01-01"), album("b", "Help", "album", "1965-08-06")},
+			[]string{"b"},
+		},
+		{
+			"earliest release wins",
+			[]SpotifyAlbum{album("a", "Help", "album", "1965-12-01"), album("b", "Help", "album", "1965-08-06")},
+			[]string{"b"},
+		},
+		{
+			"lowest ID breaks a tie",
+			[]SpotifyAlbum{album("b", "Help", "album", "1965"), album("a", "Help", "album", "1965")},
+			[]string{"a"},
+		},
+		{
+			"different years stay apart",
+			[]SpotifyAlbum{album("a", "Live", "album", "1970"), album("b", "Live", "album", "1980")},
+			[]string{"a", "b"},
+		},
+		{
+			"names without letters are kept",
+			[]SpotifyAlbum{album("a", "...", "album", "1970"), album("b", "!!!", "album", "1970")},
+			[]string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dedupeSpotifyAlbumsByTitle(tt.albums)
+			var ids []string
+			if got != nil {
+				ids = make([]string, 0, len(got))
+			}
+			for _, a := range got {
+				ids = append(ids, a.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Fatalf("IDs = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	// Copies that tie on type, date and ID leave the first one in place
+	got := dedupeSpotifyAlbumsByTitle([]SpotifyAlbum{
+		album("a", "Abbey Road", "album", "1969"),
+		album("a", "ABBEY ROAD", "album", "1969"),
+	})
+	if len(got) != 1 || got[0].Name != "Abbey Road" {
+		t.Fatalf("exact tie = %+v, want the first Abbey Road", got)
+	}
+}
+
+// fakeSpotify hands out tokens t1, t2... and answers API calls with api, counting token grants
+func fakeSpotify(t *testing.T, api func(w http.ResponseWriter, r *http.Request, token string)) *atomic.Int32 {
+	t.Helper()
+	t.Setenv("SPOTIFY_CLIENT_ID", "id")
+	t.Setenv("SPOTIFY_CLIENT_SECRET", "secret")
+	resetSpotifyToken := func() { invalidateSpotifyToken(spotifyCachedToken()) }
+	resetSpotifyToken()
+	t.Cleanup(resetSpotifyToken)
+
+	var grants atomic.Int32
+	useFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() == spotifyTokenURL {
+			fmt.Fprintf(w, `{"access_token":"t%d","token_type":"Bearer","expires_in":3600}`, grants.Add(1))
+			return
+		}
+		api(w, r, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	})
+	return &grants
+}
+
+func spotifyCachedToken() string {
+	spotifyTokenCache.mu.Lock()
+	defer spotifyTokenCache.mu.Unlock()
+	return spotifyTokenCache.token
+}
+
+func TestSpotifyRequestRefreshesRejectedToken(t *testing.T) {
+	grants := fakeSpotify(t, func(w http.ResponseWriter, r *http.Request, token string) {
+		if token == "t1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"token":%q}`, token)
+	})
+
+	var out struct{ Token string }
+	if err := spotifyRequest(http.MethodGet, spotifyAPIBaseURL+"/me", http.StatusOK, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Token != "t2" {
+		t.Fatalf("answered with token %q, want the fresh t2", out.Token)
+	}
+	if n := grants.Load(); n != 2 {
+		t.Fatalf("got %d tokens, want 2", n)
+	}
+	if got := spotifyCachedToken(); got != "t2" {
+		t.Fatalf("cached token = %q, want t2", got)
+	}
+}
+
+func TestSpotifyRequestRetriesOnce(t *testing.T) {
+	var calls atomic.Int32
+	grants := fakeSpotify(t, func(w http.ResponseWriter, r *http.Request, token string) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	err := spotifyRequest(http.MethodGet, spotifyAPIBaseURL+"/me", http.StatusOK, nil)
+	var statusErr *spotifyStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("err = %v, want a 401 status error", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("made %d API calls, want 2", n)
+	}
+	if n := grants.Load(); n != 2 {
+		t.Fatalf("got %d tokens, want 2", n)
+	}
+}
+
+func TestInvalidateSpotifyTokenKeepsNewerToken(t *testing.T) {
+	spotifyTokenCache.mu.Lock()
+	spotifyTokenCache.token = "fresh"
+	spotifyTokenCache.expiresAt = time.Now().Add(time.Hour)
+	spotifyTokenCache.mu.Unlock()
+	t.Cleanup(func() { invalidateSpotifyToken("fresh") })
+
+	// A request that was rejected with an older token leaves the refreshed one alone
+	invalidateSpotifyToken("stale")
+	if got := spotifyCachedToken(); got != "fresh" {
+		t.Fatalf("cached token = %q, want fresh", got)
+	}
+}
+
+func TestSpotifySearchURLMarket(t *testing.T) {
+	tests := []struct {
+		name, market, query string
+		want                url.Values
+	}{
+		{"no market", "", "nirvana", url.Values{"q": {"nirvana"}, "type": {"artist"}, "limit": {"30"}}},
+		{"configured market", " fr ", "nirvana", url.Values{"q": {"nirvana"}, "type": {"artist"}, "limit": {"30"}, "market": {"FR"}}},
+		{"empty query", "", "  ", url.Values{"q": {"a"}, "type": {"artist"}, "limit": {"30"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SPOTIFY_SEARCH_MARKET", tt.market)
+			u, err := url.Parse(spotifySearchURL(tt.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := u.Query(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("params = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchSpotifyArtistsWithoutMarket(t *testing.T) {
+	t.Setenv("SPOTIFY_SEARCH_MARKET", "")
+	var gotURL atomic.Value
+	fakeSpotify(t, func(w http.ResponseWriter, r *http.Request, token string) {
+		gotURL.Store(r.URL)
+		fmt.Fprint(w, `{"artists":{"items":[{"id":"6olE6TJLqED3rqDCT0FyPh","name":"Nirvana"}]}}`)
+	})
+
+	artists, err := SearchSpotifyArtists("nirvana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artists) != 1 || artists[0].Name != "Nirvana" {
+		t.Fatalf("artists = %+v, want Nirvana", artists)
+	}
+	u, _ := gotURL.Load().(*url.URL)
+	if u == nil {
+		t.Fatal("no search request made")
+	}
+	if u.Query().Has("market") {
+		t.Fatalf("search sent a market: %s", u)
+	}
+}
+
+func TestNormalizeSpotifySearchFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		in   SpotifySearchFilters
+		want SpotifySearchFilters
+	}{
+		{"empty", SpotifySearchFilters{}, SpotifySearchFilters{}},
+		{"trimmed and lowercased", SpotifySearchFilters{Genre: "  Hip   Hop ", Year: " 1994 "}, SpotifySearchFilters{Genre: "hip hop", Year: "1994"}},
+		{"year range", SpotifySearchFilters{Year: "1990 - 1999"}, SpotifySearchFilters{Year: "1990-1999"}},
+		{"reversed range", SpotifySearchFilters{Year: "1999-1990"}, SpotifySearchFilters{Year: "1990-1999"}},
+		{"bad year", SpotifySearchFilters{Year: "199"}, SpotifySearchFilters{}},
+		{"genre with a field filter", SpotifySearchFilters{Genre: "rock year:2000"}, SpotifySearchFilters{}},
+		{"genre with a quote", SpotifySearchFilters{Genre: `rock" artist:"x`}, SpotifySearchFilters{}},
+		{"accented genre", SpotifySearchFilters{Genre: "Chanson Française"}, SpotifySearchFilters{Genre: "chanson française"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeSpotifySearchFilters(tt.in)
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			if got.IsZero() != (tt.want == SpotifySearchFilters{}) {
+				t.Fatalf("IsZero = %v for %+v", got.IsZero(), got)
+			}
+		})
+	}
+}
+
+func TestBuildSpotifyArtistQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		f     SpotifySearchFilters
+		want  string
+	}{
+		{"nirvana", SpotifySearchFilters{}, "nirvana"},
+		{"  nirvana  ", SpotifySearchFilters{}, "nirvana"},
+		{"nirvana", SpotifySearchFilters{Genre: "grunge"}, "nirvana genre:grunge"},
+		{"", SpotifySearchFilters{Genre: "Hip Hop", Year: "1994"}, `genre:"hip hop" year:1994`},
+		{"daft punk", SpotifySearchFilters{Year: "2001-1997"}, "daft punk year:1997-2001"},
+		{"x", SpotifySearchFilters{Genre: "rock genre:pop", Year: "soon"}, "x"},
+		{"", SpotifySearchFilters{}, ""},
+	}
+	for _, tt := range tests {
+		if got := BuildSpotifyArtistQuery(tt.query, tt.f); got != tt.want {
+			t.Errorf("BuildSpotifyArtistQuery(%q, %+v) = %q, want %q", tt.query, tt.f, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"container/list"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ttlCache is a small in-memory cache with per-entry expiry and a size cap
+// Once the cap is reached, the least recently used entry is evicted
+type ttlCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	max     int
+	entries map[K]*list.Element
+	// Front is the most recently used entry
+	order *list.List
+	// now is the clock, swapped in tests
+	now func() time.Time
+}
+
+type ttlCacheEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// newTTLCache builds a cache, max <= 0 disables the size cap
+func newTTLCache[K comparable, V any](ttl time.Duration, max int) *ttlCache[K, V] {
+	return &ttlCache[K, V]{
+		ttl:     ttl,
+		max:     max,
+		entries: make(map[K]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Get returns a fresh value for key and marks it as recently used
+func (c *ttlCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	entry := el.Value.(*ttlCacheEntry[K, V])
+	if c.now().After(entry.expiresAt) {
+		// Drop expired entries eagerly so they don't count against the cap
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+// Set stores value for key, evicting the least recently used entries past the cap
+func (c *ttlCache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*ttlCacheEntry[K, V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&ttlCacheEntry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	for c.max > 0 && c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*ttlCacheEntry[K, V]).key)
+	}
+}
+
+// lazyTTLCache returns a getter for a cache built on first use from `<envPrefix>_TTL` and
+// `<envPrefix>_SIZE`, so env vars loaded from `.env` at startup are honored
+func lazyTTLCache[K comparable, V any](envPrefix string, defTTL time.Duration, defSize int) func() *ttlCache[K, V] {
+	return sync.OnceValue(func() *ttlCache[K, V] {
+		return newTTLCache[K, V](envDuration(envPrefix+"_TTL", defTTL), envInt(envPrefix+"_SIZE", defSize))
+	})
+}
+
+// envDuration reads a Go duration (e.g. "30m") from the environment, falling back to def
+func envDuration(name string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(strings.TrimSpace(os.Getenv(name)))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+// envInt reads a positive integer from the environment, falling back to def
+func envInt(name string, def int) int {
+	v, err := strconv.Atoi(strings.TrimSpace(os.Getenv(name)))
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestTTLCache returns a cache on a fake clock and a function moving that clock forward
+func newTestTTLCache(ttl time.Duration, max int) (*ttlCache[string, int], func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := newTTLCache[string, int](ttl, max)
+	c.now = func() time.Time { return now }
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestTTLCacheExpiry(t *testing.T) {
+	c, advance := newTestTTLCache(time.Minute, 0)
+	c.Set("a", 1)
+
+	advance(time.Minute)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get at the TTL = %d, %v, want 1, true", v, ok)
+	}
+	advance(time.Nanosecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("entry still served past its TTL")
+	}
+
+	// Setting again starts a new TTL
+	c.Set("a", 2)
+	advance(30 * time.Second)
+	c.Set("a", 3)
+	advance(45 * time.Second)
+	if v, ok := c.Get("a"); !ok || v != 3 {
+		t.Fatalf("Get after refresh = %d, %v, want 3, true", v, ok)
+	}
+}
+
+func TestTTLCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	tests := []struct {
+		name    string
+		ops     func(c *ttlCache[string, int])
+		present []string
+		evicted []string
+	}{
+		{
+			name: "oldest set goes first",
+			ops: func(c *ttlCache[string, int]) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Set("c", 3)
+				c.Set("d", 4)
+			},
+			present: []string{"b", "c", "d"},
+			evicted: []string{"a"},
+		},
+		{
+			name: "get marks as recently used",
+			ops: func(c *ttlCache[string, int]) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Set("c", 3)
+				c.Get("a")
+				c.Set("d", 4)
+			},
+			present: []string{"a", "c", "d"},
+			evicted: []string{"b"},
+		},
+		{
+			name: "updating marks as recently used without growing",
+			ops: func(c *ttlCache[string, int]) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Set("c", 3)
+				c.Set("a", 10)
+				c.Set("d", 4)
+			},
+			present: []string{"a", "c", "d"},
+			evicted: []string{"b"},
+		},
+		{
+			name: "zero values take a slot like any other",
+			ops: func(c *ttlCache[string, int]) {
+				c.Set("zero", 0)
+				c.Set("b", 2)
+				c.Set("c", 3)
+			},
+			present: []string{"zero", "b", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestTTLCache(time.Hour, 3)
+			tt.ops(c)
+			for _, k := range tt.present {
+				if _, ok := c.Get(k); !ok {
+					t.Errorf("%s was evicted", k)
+				}
+			}
+			for _, k := range tt.evicted {
+				if _, ok := c.Get(k); ok {
+					t.Errorf("%s was kept past the cap", k)
+				}
+			}
+		})
+	}
+}
+
+func TestTTLCacheNoCap(t *testing.T) {
+	c, _ := newTestTTLCache(time.Hour, 0)
+	for i := 0; i < 1000; i++ {
+		c.Set(strconv.Itoa(i), i)
+	}
+	if got := c.order.Len(); got != 1000 {
+		t.Fatalf("held %d entries, want 1000 without a cap", got)
+	}
+}
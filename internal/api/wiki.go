@@ -2,20 +2,56 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 )
 
-const (
-	wikiSummaryEndpoint = "https://en.wikipedia.org/api/rest_v1/page/summary/"
-	wikiSearchEndpoint  = "https://en.wikipedia.org/w/api.php"
-)
+// defaultWikipediaLang is the wiki used when WIKIPEDIA_LANG is unset or invalid
+const defaultWikipediaLang = "en"
+
+// wikiBaseURL is the wiki origin, read once on first use so the value loaded from `.env` is honored
+// WIKIPEDIA_BASE_URL replaces it entirely (a mirror or a local stub), otherwise WIKIPEDIA_LANG picks the language
+var wikiBaseURL = sync.OnceValue(func() string {
+	if base := strings.TrimRight(strings.TrimSpace(os.Getenv("WIKIPEDIA_BASE_URL")), "/"); base != "" {
+		return base
+	}
+	lang := strings.ToLower(strings.TrimSpace(os.Getenv("WIKIPEDIA_LANG")))
+	if !validWikiLang(lang) {
+		lang = defaultWikipediaLang
+	}
+	return "https://" + lang + ".wikipedia.org"
+})
+
+// validWikiLang accepts wiki subdomains like "fr", "simple" or "zh-yue"
+func validWikiLang(lang string) bool {
+	if len(lang) < 2 || len(lang) > 12 || lang[0] == '-' || lang[len(lang)-1] == '-' {
+		return false
+	}
+	for _, c := range lang {
+		if (c < 'a' || c > 'z') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func wikiSummaryEndpoint() string { return wikiBaseURL() + "/api/rest_v1/page/summary/" }
+func wikiSearchEndpoint() string  { return wikiBaseURL() + "/w/api.php" }
 
 type wikiSummaryResponse struct {
-	Extract     string `json:"extract"`
+	// Type is "standard" for regular articles, "disambiguation" for name lists
+	Type      string `json:"type"`
+	Extract   string `json:"extract"`
+	Thumbnail struct {
+		Source string `json:"source"`
+	} `json:"thumbnail"`
 	ContentUrls struct {
 		Desktop struct {
 			Page string `json:"page"`
@@ -31,23 +67,14 @@ type wikiSearchResponse struct {
 	} `json:"query"`
 }
 
-// searchWikipediaTitle runs a search query and tries to pick the most suitable page title
-func searchWikipediaTitle(rawQuery string) (string, error) {
+// searchWikipediaCandidates runs a search query and returns up to limit page titles, most suitable first
+// Exact title matches come first, then common music disambiguation variants, then the search order
+func searchWikipediaCandidates(rawQuery string, limit int) ([]string, error) {
 	if rawQuery == "" {
-		return "", fmt.Errorf("empty query")
+		return nil, fmt.Errorf("empty query")
 	}
 
-	base := strings.TrimSpace(rawQuery)
-	lowerBase := strings.ToLower(base)
-	// Strip common disambiguation suffixes to increase the chance of an exact match
-	suffixes := []string{" band", " music group", " musical group", " singer", " musician", " rapper", " artist"}
-	for _, s := range suffixes {
-		if strings.HasSuffix(lowerBase, s) {
-			base = strings.TrimSpace(base[:len(base)-len(s)])
-			break
-		}
-	}
-	lowerBase = strings.ToLower(base)
+	lowerBase := strings.ToLower(wikiQueryBase(rawQuery))
 
 	params := url.Values{}
 	params.Set("action", "query")
@@ -57,138 +84,317 @@ func searchWikipediaTitle(rawQuery string) (string, error) {
 	params.Set("srlimit", "10")
 	params.Set("srsearch", rawQuery)
 
-	u := wikiSearchEndpoint + "?" + params.Encode()
+	u := wikiSearchEndpoint() + "?" + params.Encode()
 
 	req, err := http.NewRequest("GET", u, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Wikipedia recommends setting a descriptive UA
 	req.Header.Set("User-Agent", "GroupieTrackerSchoolProject/1.0 (contact@example.com)")
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := upstreamDo(req, 5*time.Second, upstreamRetryAttempts())
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("search status: %s", resp.Status)
+		return nil, fmt.Errorf("search status: %s", resp.Status)
 	}
 
 	var payload wikiSearchResponse
 	err = json.NewDecoder(resp.Body).Decode(&payload)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if len(payload.Query.Search) == 0 {
-		return "", fmt.Errorf("no search results")
+		return nil, fmt.Errorf("no search results")
 	}
 
-	// Prefer exact title matches, then common disambiguation variants, then first hit
-	exactTitle := ""
-	prefTitle := ""
-	anyTitle := ""
-
+	var exact, preferred, rest []string
 	for _, hit := range payload.Query.Search {
 		title := hit.Title
 		lower := strings.ToLower(title)
 
-		if lower == lowerBase {
-			exactTitle = title
-			break
+		switch {
+		case lower == lowerBase:
+			exact = append(exact, title)
+		case strings.HasPrefix(lower, lowerBase+" (") && hasMusicQualifier(lower):
+			preferred = append(preferred, title)
+		default:
+			rest = append(rest, title)
 		}
+	}
 
-		if strings.HasPrefix(lower, lowerBase+" (") {
-			if strings.Contains(lower, "(band)") ||
-				strings.Contains(lower, "(music group)") ||
-				strings.Contains(lower, "(musical group)") ||
-				strings.Contains(lower, "(singer)") ||
-				strings.Contains(lower, "(musician)") ||
-				strings.Contains(lower, "(rapper)") ||
-				strings.Contains(lower, "(artist)") {
-				if prefTitle == "" {
-					prefTitle = title
-				}
-			}
+	titles := append(append(exact, preferred...), rest...)
+	if len(titles) > limit {
+		titles = titles[:limit]
+	}
+	return titles, nil
+}
+
+// wikiQuerySuffixes are the hints appended to artist names, stripped again to compare titles
+var wikiQuerySuffixes = []string{" band", " music group", " musical group", " singer", " musician", " rapper", " artist"}
+
+// wikiQueryBase strips a trailing hint so "Queen band" compares against the "Queen" title
+func wikiQueryBase(q string) string {
+	base := strings.TrimSpace(q)
+	lower := strings.ToLower(base)
+	for _, s := range wikiQuerySuffixes {
+		if strings.HasSuffix(lower, s) {
+			return strings.TrimSpace(base[:len(base)-len(s)])
 		}
+	}
+	return base
+}
 
-		if anyTitle == "" {
-			anyTitle = title
+func hasMusicQualifier(lowerTitle string) bool {
+	for _, q := range []string{"(band)", "(music group)", "(musical group)", "(singer)", "(musician)", "(rapper)", "(artist)"} {
+		if strings.Contains(lowerTitle, q) {
+			return true
 		}
 	}
+	return false
+}
+
+// wikiMusicWords mark an extract as being about a musician rather than a namesake
+// They are English, other WIKIPEDIA_LANG wikis rely on the title match alone
+var wikiMusicWords = map[string]bool{
+	"band": true, "singer": true, "songwriter": true, "rapper": true, "musician": true,
+	"musical": true, "music": true, "album": true, "albums": true, "song": true, "songs": true,
+	"duo": true, "trio": true, "dj": true, "composer": true, "hip": true, "rock": true,
+	"pop": true, "jazz": true, "orchestra": true, "recording": true, "vocalist": true,
+}
 
-	if exactTitle != "" {
-		return exactTitle, nil
+func extractMentionsMusic(extract string) bool {
+	words := strings.FieldsFunc(strings.ToLower(extract), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		if wikiMusicWords[w] {
+			return true
+		}
 	}
-	if prefTitle != "" {
-		return prefTitle, nil
+	return false
+}
+
+// wikiTitleMatches is the fuzzy check for pages without music words: the title, minus any
+// "(qualifier)", and the artist name contain one another once case and punctuation are ignored
+func wikiTitleMatches(title, name string) bool {
+	if i := strings.Index(title, " ("); i > 0 {
+		title = title[:i]
 	}
-	if anyTitle != "" {
-		return anyTitle, nil
+	t, n := wikiMatchKey(title), wikiMatchKey(name)
+	if t == "" || n == "" {
+		return false
 	}
+	return strings.Contains(t, n) || strings.Contains(n, t)
+}
 
-	return "", fmt.Errorf("no suitable title")
+func wikiMatchKey(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
 }
 
-// FetchWikipediaSummary returns the page summary extract and desktop URL for the best matching title
-func FetchWikipediaSummary(title string) (string, string, error) {
+// WikiSummary is the Wikipedia page picked for an artist
+type WikiSummary struct {
+	Extract string
+	// URL is the desktop page link
+	URL string
+	// ThumbnailURL is the page's lead image, empty when it has none
+	ThumbnailURL  string
+	ResolvedTitle string
+	// Type is the summary API page type, "standard" for regular articles
+	Type string
+}
+
+// wikiMaxCandidates bounds the summaries fetched to resolve one artist
+const wikiMaxCandidates = 3
+
+const (
+	defaultWikiSummaryCacheTTL  = 6 * time.Hour
+	defaultWikiSummaryCacheSize = 1000
+)
+
+// getWikiSummaryCache keeps resolved summaries by artist name, a zero WikiSummary when no page fits
+var (
+	getWikiSummaryCache = lazyTTLCache[string, WikiSummary]("WIKI_SUMMARY_CACHE", defaultWikiSummaryCacheTTL, defaultWikiSummaryCacheSize)
+	wikiSummaryInflight inflightGroup[string, WikiSummary]
+)
+
+// FetchWikipediaSummary returns the summary of the page that best matches an artist name
+func FetchWikipediaSummary(title string) (*WikiSummary, error) {
+	title = strings.Join(strings.Fields(title), " ")
 	if title == "" {
-		return "", "", fmt.Errorf("empty title")
+		return nil, fmt.Errorf("empty title")
 	}
 
-	var resolvedTitle string
-	var err error
+	key := strings.ToLower(title)
+	if sum, ok := getWikiSummaryCache().Get(key); ok {
+		if sum.Extract == "" {
+			return nil, errWikiPageNotFound
+		}
+		return &sum, nil
+	}
 
-	// Try a few targeted queries first to avoid people/places with similar names
-	resolvedTitle, err = searchWikipediaTitle(title + " artist")
+	sum, err := wikiSummaryInflight.Do(key, func() (WikiSummary, error) {
+		sum, err := resolveWikipediaSummary(title)
+		if err != nil && !errors.Is(err, errWikiPageNotFound) {
+			// Transient failures are retried on the next page view
+			return WikiSummary{}, err
+		}
+		getWikiSummaryCache().Set(key, sum)
+		return sum, nil
+	})
 	if err != nil {
-		resolvedTitle, err = searchWikipediaTitle(title + " band")
+		return nil, err
 	}
-	if err != nil {
-		resolvedTitle, err = searchWikipediaTitle(title + " music group")
+	if sum.Extract == "" {
+		return nil, errWikiPageNotFound
 	}
-	if err != nil {
-		resolvedTitle, err = searchWikipediaTitle(title)
+	return &sum, nil
+}
+
+// resolveWikipediaSummary searches for the artist and checks up to wikiMaxCandidates pages
+// The first page whose extract sounds musical wins, otherwise the first one whose title matches
+// the name, disambiguation pages ("X may refer to...") never count
+func resolveWikipediaSummary(name string) (WikiSummary, error) {
+	// Try a few targeted queries first to avoid people/places with similar names
+	queries := []string{name + " artist", name + " band", name + " music group", name}
+	tried := make(map[string]bool)
+	var fallback *WikiSummary
+	for _, q := range queries {
+		if len(tried) >= wikiMaxCandidates {
+			break
+		}
+		titles, err := searchWikipediaCandidates(q, wikiMaxCandidates)
+		if err != nil {
+			continue
+		}
+
+		for _, t := range titles {
+			if tried[t] {
+				continue
+			}
+			if len(tried) >= wikiMaxCandidates {
+				break
+			}
+			tried[t] = true
+
+			payload, err := fetchWikiSummaryPage(t)
+			if errors.Is(err, errWikiPageNotFound) {
+				continue
+			}
+			if err != nil {
+				return WikiSummary{}, err
+			}
+			if payload.Type == "disambiguation" || payload.Extract == "" || payload.ContentUrls.Desktop.Page == "" {
+				continue
+			}
+
+			sum := WikiSummary{
+				Extract:       payload.Extract,
+				URL:           payload.ContentUrls.Desktop.Page,
+				ThumbnailURL:  wikiThumbURL(payload),
+				ResolvedTitle: t,
+				Type:          payload.Type,
+			}
+			if extractMentionsMusic(sum.Extract) {
+				return sum, nil
+			}
+			if fallback == nil && wikiTitleMatches(t, name) {
+				fallback = &sum
+			}
+		}
 	}
-	if err != nil {
-		return "", "", err
+
+	if fallback != nil {
+		return *fallback, nil
 	}
+	return WikiSummary{}, errWikiPageNotFound
+}
 
+// wikiThumbURL is the page's lead image over https, upload.wikimedia.org serves both schemes
+func wikiThumbURL(payload *wikiSummaryResponse) string {
+	return strings.Replace(payload.Thumbnail.Source, "http://", "https://", 1)
+}
+
+// fetchWikiSummaryPage loads the REST summary of an exact page title (redirects are followed)
+func fetchWikiSummaryPage(title string) (*wikiSummaryResponse, error) {
 	// Summary endpoint uses the page title as a path segment
-	escaped := url.PathEscape(resolvedTitle)
-	fullURL := wikiSummaryEndpoint + escaped
+	escaped := url.PathEscape(strings.ReplaceAll(title, " ", "_"))
+	fullURL := wikiSummaryEndpoint() + escaped
 
 	req, err := http.NewRequest("GET", fullURL, nil)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	req.Header.Set("User-Agent", "GroupieTrackerSchoolProject/1.0 (contact@example.com)")
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := upstreamDo(req, 5*time.Second, upstreamRetryAttempts())
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errWikiPageNotFound
+	}
 	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("summary status: %s", resp.Status)
+		return nil, fmt.Errorf("summary status: %s", resp.Status)
 	}
 
 	var payload wikiSummaryResponse
-	err = json.NewDecoder(resp.Body).Decode(&payload)
-	if err != nil {
-		return "", "", err
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
 	}
+	return &payload, nil
+}
 
-	if payload.Extract == "" || payload.ContentUrls.Desktop.Page == "" {
-		return "", "", fmt.Errorf("missing summary data")
+var errWikiPageNotFound = errors.New("wikipedia page not found")
+
+const (
+	defaultWikiThumbCacheTTL  = 24 * time.Hour
+	defaultWikiThumbCacheSize = 2000
+)
+
+// getWikiThumbCache keeps thumbnail URLs by page title, "" when the page has none or doesn't exist
+var (
+	getWikiThumbCache = lazyTTLCache[string, string]("WIKI_THUMB_CACHE", defaultWikiThumbCacheTTL, defaultWikiThumbCacheSize)
+	wikiThumbInflight inflightGroup[string, string]
+)
+
+// FetchWikipediaThumbnail returns the lead image of the page titled exactly title, "" when there is none
+// It skips the search step, so it is cheap enough for short lists like band members
+// Disambiguation pages count as no image since their picture, if any, isn't of the person
+func FetchWikipediaThumbnail(title string) (string, error) {
+	title = strings.Join(strings.Fields(title), " ")
+	if title == "" {
+		return "", fmt.Errorf("empty title")
 	}
 
-	return payload.Extract, payload.ContentUrls.Desktop.Page, nil
+	key := strings.ToLower(title)
+	if thumb, ok := getWikiThumbCache().Get(key); ok {
+		return thumb, nil
+	}
+
+	return wikiThumbInflight.Do(key, func() (string, error) {
+		payload, err := fetchWikiSummaryPage(title)
+		if err != nil && !errors.Is(err, errWikiPageNotFound) {
+			// Transient failures are retried on the next page view
+			return "", err
+		}
+
+		thumb := ""
+		if err == nil && payload.Type != "disambiguation" {
+			thumb = wikiThumbURL(payload)
+		}
+		getWikiThumbCache().Set(key, thumb)
+		return thumb, nil
+	})
 }
@@ -0,0 +1,361 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// wikiQueenSummary is a trimmed /page/summary/Queen_(band) response
+const wikiQueenSummary = `{
+  "type": "standard",
+  "title": "Queen (band)",
+  "displaytitle": "<span class=\"mw-page-title-main\">Queen (band)</span>",
+  "namespace": {"id": 0, "text": ""},
+  "wikibase_item": "Q15862",
+  "titles": {"canonical": "Queen_(band)", "normalized": "Queen (band)", "display": "<span class=\"mw-page-title-main\">Queen (band)</span>"},
+  "pageid": 42010,
+  "thumbnail": {
+    "source": "http://upload.wikimedia.org/wikipedia/commons/thumb/3/33/Queen_A_Night_At_The_Opera_1975.jpg/320px-Queen_A_Night_At_The_Opera_1975.jpg",
+    "width": 320,
+    "height": 213
+  },
+  "originalimage": {
+    "source": "https://upload.wikimedia.org/wikipedia/commons/3/33/Queen_A_Night_At_The_Opera_1975.jpg",
+    "width": 2100,
+    "height": 1400
+  },
+  "lang": "en",
+  "dir": "ltr",
+  "revision": "1212345678",
+  "timestamp": "2024-03-10T12:00:00Z",
+  "description": "British rock band",
+  "content_urls": {
+    "desktop": {"page": "https://en.wikipedia.org/wiki/Queen_(band)", "revisions": "https://en.wikipedia.org/wiki/Queen_(band)?action=history"},
+    "mobile": {"page": "https://en.m.wikipedia.org/wiki/Queen_(band)", "revisions": "https://en.m.wikipedia.org/wiki/Special:History/Queen_(band)"}
+  },
+  "extract": "Queen are a British rock band formed in London in 1970 by Freddie Mercury, Brian May and Roger Taylor, later joined by John Deacon."
+}`
+
+// wikiDisambiguationSummary is a trimmed /page/summary/Queen response, a name list
+const wikiDisambiguationSummary = `{
+  "type": "disambiguation",
+  "title": "Queen",
+  "thumbnail": {"source": "https://upload.wikimedia.org/wikipedia/commons/thumb/a/a1/Crown.svg/320px-Crown.svg.png", "width": 320, "height": 240},
+  "content_urls": {"desktop": {"page": "https://en.wikipedia.org/wiki/Queen"}},
+  "extract": "Queen most often refers to: Queen regnant, Queen consort, Queen (band)."
+}`
+
+// wikiImagelessSummary is a regular article without a lead image
+const wikiImagelessSummary = `{
+  "type": "standard",
+  "title": "Obscure (band)",
+  "content_urls": {"desktop": {"page": "https://en.wikipedia.org/wiki/Obscure_(band)"}},
+  "extract": "Obscure were a garage rock band from Ohio."
+}`
+
+// fakeWikipedia serves search hits and summaries, pages maps a title to its recorded summary
+// It returns the number of summaries requested
+func fakeWikipedia(t *testing.T, hits []string, pages map[string]string) *atomic.Int32 {
+	t.Helper()
+	t.Setenv("UPSTREAM_RETRY_ATTEMPTS", "1")
+
+	var summaries atomic.Int32
+	useFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/w/api.php" {
+			var b strings.Builder
+			for i, h := range hits {
+				if i > 0 {
+					b.WriteString(",")
+				}
+				fmt.Fprintf(&b, `{"ns":0,"title":%q}`, h)
+			}
+			fmt.Fprintf(w, `{"batchcomplete":"","query":{"search":[%s]}}`, b.String())
+			return
+		}
+		summaries.Add(1)
+		title := strings.ReplaceAll(strings.TrimPrefix(r.URL.Path, "/api/rest_v1/page/summary/"), "_", " ")
+		body, ok := pages[title]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, body)
+	})
+	return &summaries
+}
+
+func TestFetchWikipediaSummaryThumbnail(t *testing.T) {
+	name := fmt.Sprintf("Queen %d", newTestID())
+	page := name + " (band)"
+	fakeWikipedia(t, []string{name, page}, map[string]string{
+		name: wikiDisambiguationSummary,
+		page: wikiQueenSummary,
+	})
+
+	sum, err := FetchWikipediaSummary(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.ResolvedTitle != page {
+		t.Fatalf("resolved %q, want %q over the disambiguation page", sum.ResolvedTitle, page)
+	}
+	const want = "https://upload.wikimedia.org/wikipedia/commons/thumb/3/33/Queen_A_Night_At_The_Opera_1975.jpg/320px-Queen_A_Night_At_The_Opera_1975.jpg"
+	if sum.ThumbnailURL != want {
+		t.Fatalf("ThumbnailURL = %q, want %q", sum.ThumbnailURL, want)
+	}
+	if sum.URL != "https://en.wikipedia.org/wiki/Queen_(band)" || sum.Type != "standard" {
+		t.Fatalf("summary = %+v", sum)
+	}
+
+	// The cached summary keeps the thumbnail
+	useFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	cached, err := FetchWikipediaSummary(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.ThumbnailURL != want {
+		t.Fatalf("cached ThumbnailURL = %q, want %q", cached.ThumbnailURL, want)
+	}
+}
+
+func TestFetchWikipediaSummaryWithoutThumbnail(t *testing.T) {
+	name := fmt.Sprintf("Obscure %d", newTestID())
+	fakeWikipedia(t, []string{name}, map[string]string{name: wikiImagelessSummary})
+
+	sum, err := FetchWikipediaSummary(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.ThumbnailURL != "" {
+		t.Fatalf("ThumbnailURL = %q, want none", sum.ThumbnailURL)
+	}
+	if sum.Extract == "" {
+		t.Fatal("summary has no extract")
+	}
+}
+
+func TestFetchWikipediaThumbnail(t *testing.T) {
+	id := newTestID()
+	band := fmt.Sprintf("Queen %d (band)", id)
+	list := fmt.Sprintf("Queen %d", id)
+	bare := fmt.Sprintf("Obscure %d", id)
+	fakeWikipedia(t, nil, map[string]string{
+		band: wikiQueenSummary,
+		list: wikiDisambiguationSummary,
+		bare: wikiImagelessSummary,
+	})
+
+	tests := []struct {
+		title, want string
+	}{
+		{band, "https://upload.wikimedia.org/wikipedia/commons/thumb/3/33/Queen_A_Night_At_The_Opera_1975.jpg/320px-Queen_A_Night_At_The_Opera_1975.jpg"},
+		{list, ""},
+		{bare, ""},
+		{fmt.Sprintf("Missing %d", id), ""},
+	}
+	for _, tt := range tests {
+		got, err := FetchWikipediaThumbnail(tt.title)
+		if err != nil {
+			t.Fatalf("FetchWikipediaThumbnail(%q): %v", tt.title, err)
+		}
+		if got != tt.want {
+			t.Errorf("FetchWikipediaThumbnail(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestFetchWikipediaSummarySkipsDisambiguation(t *testing.T) {
+	name := fmt.Sprintf("Queen %d", newTestID())
+	fakeWikipedia(t, []string{name}, map[string]string{name: wikiDisambiguationSummary})
+
+	sum, err := FetchWikipediaSummary(name)
+	if !errors.Is(err, errWikiPageNotFound) {
+		t.Fatalf("got %+v, %v, want not found for a disambiguation page", sum, err)
+	}
+
+	// The miss is cached like any other page not found
+	useFakeUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	if _, err := FetchWikipediaSummary(name); !errors.Is(err, errWikiPageNotFound) {
+		t.Fatalf("cached err = %v, want not found", err)
+	}
+}
+
+func TestValidWikiLang(t *testing.T) {
+	tests := []struct {
+		lang string
+		want bool
+	}{
+		{"en", true},
+		{"fr", true},
+		{"simple", true},
+		{"zh-yue", true},
+		{"", false},
+		{"e", false},
+		{"-fr", false},
+		{"fr-", false},
+		{"FR", false},
+		{"fr.evil.com", false},
+		{"fr/wiki", false},
+		{"abcdefghijklm", false},
+	}
+	for _, tt := range tests {
+		if got := validWikiLang(tt.lang); got != tt.want {
+			t.Errorf("validWikiLang(%q) = %v, want %v", tt.lang, got, tt.want)
+		}
+	}
+}
+
+// wikiSummary builds a minimal standard summary for title
+func wikiSummary(title, extract string) string {
+	return fmt.Sprintf(`{"type":"standard","title":%q,"content_urls":{"desktop":{"page":"https://en.wikipedia.org/wiki/%s"}},"extract":%q}`,
+		title, strings.ReplaceAll(title, " ", "_"), extract)
+}
+
+func TestResolveWikipediaSummaryCandidates(t *testing.T) {
+	id := newTestID()
+	name := fmt.Sprintf("Mercury %d", id)
+	planet := wikiSummary(name, "Mercury is the first planet from the Sun and the smallest in the Solar System.")
+	element := wikiSummary(name+" (element)", "Mercury is a chemical element with the symbol Hg.")
+	band := wikiSummary(name+" (band)", "Mercury were an American indie rock band formed in 1997.")
+	project := wikiSummary("The "+name+" Project", "The Mercury Project is an Italian progressive rock band.")
+	quiet := wikiSummary(name+" Rev", "Mercury Rev formed in Buffalo, New York, in 1989.")
+
+	tests := []struct {
+		name      string
+		hits      []string
+		pages     map[string]string
+		want      string // resolved title, "" for not found
+		summaries int32
+	}{
+		{
+			"music extract beats an earlier namesake",
+			[]string{name + " (element)", "The " + name + " Project"},
+			map[string]string{name + " (element)": element, "The " + name + " Project": project},
+			"The " + name + " Project", 2,
+		},
+		{
+			"exact title and music qualifiers are tried first",
+			[]string{name + " (element)", name + " (band)", name},
+			map[string]string{name: planet, name + " (element)": element, name + " (band)": band},
+			name + " (band)", 2,
+		},
+		{
+			"title match without music words",
+			[]string{name + " (element)", name + " Rev"},
+			map[string]string{name + " (element)": element, name + " Rev": quiet},
+			name + " (element)", 2,
+		},
+		{
+			"at most three candidates",
+			[]string{"A " + name + " x", "B " + name + " x", "C " + name + " x", "The " + name + " Project"},
+			map[string]string{"The " + name + " Project": project},
+			"", wikiMaxCandidates,
+		},
+		{
+			"missing and disambiguation pages are skipped",
+			[]string{name, name + " (musician)", name + " (band)"},
+			map[string]string{name: wikiDisambiguationSummary, name + " (band)": band},
+			name + " (band)", 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summaries := fakeWikipedia(t, tt.hits, tt.pages)
+			sum, err := resolveWikipediaSummary(name)
+			if tt.want == "" {
+				if !errors.Is(err, errWikiPageNotFound) {
+					t.Fatalf("got %+v, %v, want not found", sum, err)
+				}
+			} else if err != nil || sum.ResolvedTitle != tt.want {
+				t.Fatalf("resolved %q, %v, want %q", sum.ResolvedTitle, err, tt.want)
+			}
+			if n := summaries.Load(); n != tt.summaries {
+				t.Fatalf("fetched %d summaries, want %d", n, tt.summaries)
+			}
+		})
+	}
+}
+
+func TestFetchWikipediaSummaryCachesResolution(t *testing.T) {
+	name := fmt.Sprintf("Mercury %d", newTestID())
+	page := "The " + name + " Project"
+	summaries := fakeWikipedia(t, []string{name + " (element)", page}, map[string]string{
+		name + " (element)": wikiSummary(name+" (element)", "Mercury is a chemical element."),
+		page:                wikiSummary(page, "The Mercury Project is a progressive rock band."),
+	})
+
+	for range 3 {
+		sum, err := FetchWikipediaSummary(name)
+		if err != nil || sum.ResolvedTitle != page {
+			t.Fatalf("resolved %+v, %v, want %q", sum, err, page)
+		}
+	}
+	if n := summaries.Load(); n != 2 {
+		t.Fatalf("fetched %d summaries over 3 lookups, want 2", n)
+	}
+}
+
+func TestExtractMentionsMusic(t *testing.T) {
+	tests := []struct {
+		extract string
+		want    bool
+	}{
+		{"Queen are a British rock band formed in London.", true},
+		{"Eminem is an American rapper.", true},
+		{"A hip-hop duo from Paris.", true},
+		{"Mercury is the first planet from the Sun.", false},
+		{"Bandits is a 2001 film.", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := extractMentionsMusic(tt.extract); got != tt.want {
+			t.Errorf("extractMentionsMusic(%q) = %v, want %v", tt.extract, got, tt.want)
+		}
+	}
+}
+
+func TestWikiTitleMatches(t *testing.T) {
+	tests := []struct {
+		title, name string
+		want        bool
+	}{
+		{"Queen (band)", "Queen", true},
+		{"AC/DC", "ACDC", false},
+		{"AC/DC", "ac dc", true},
+		{"The Rolling Stones", "Rolling Stones", true},
+		{"Mercury Rev", "Mercury", true},
+		{"Mercury (planet)", "Freddie Mercury", true},
+		{"Sun", "Moon", false},
+		{"(band)", "Queen", false},
+		{"Queen", "!!!", false},
+	}
+	for _, tt := range tests {
+		if got := wikiTitleMatches(tt.title, tt.name); got != tt.want {
+			t.Errorf("wikiTitleMatches(%q, %q) = %v, want %v", tt.title, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSearchWikipediaCandidatesOrder(t *testing.T) {
+	fakeWikipedia(t, []string{"Queen regnant", "Queen (film)", "Queen (band)", "Queen", "Queen Latifah"}, nil)
+
+	got, err := searchWikipediaCandidates("Queen band", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Exact title first, then music qualifiers, then the search order
+	want := []string{"Queen", "Queen (band)", "Queen regnant", "Queen (film)"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("candidates = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	youtubeSearchEndpoint = "https://www.googleapis.com/youtube/v3/search"
+
+	// Each search costs 100 quota units out of 10k a day, so keep results around
+	defaultYouTubeCacheTTL  = 12 * time.Hour
+	defaultYouTubeCacheSize = 1000
+)
+
+// ErrYouTubeDisabled is returned when YOUTUBE_API_KEY isn't set
+var ErrYouTubeDisabled = errors.New("missing YOUTUBE_API_KEY")
+
+// YouTubeVideo is the top video found for an artist
+type YouTubeVideo struct {
+	ID           string
+	Title        string
+	ChannelTitle string
+}
+
+// WatchURL is the youtube.com page for the video
+func (v YouTubeVideo) WatchURL() string {
+	return "https://www.youtube.com/watch?v=" + url.QueryEscape(v.ID)
+}
+
+// EmbedURL is the privacy-enhanced player URL for iframes
+func (v YouTubeVideo) EmbedURL() string {
+	return "https://www.youtube-nocookie.com/embed/" + url.PathEscape(v.ID)
+}
+
+type youtubeSearchResponse struct {
+	Items []struct {
+		ID struct {
+			VideoID string `json:"videoId"`
+		} `json:"id"`
+		Snippet struct {
+			Title        string `json:"title"`
+			ChannelTitle string `json:"channelTitle"`
+		} `json:"snippet"`
+	} `json:"items"`
+}
+
+// YouTubeSearchURL builds a deep link to YouTube search results for an artist
+// It works without any API key, so detail pages can always offer it
+func YouTubeSearchURL(artistName string) string {
+	name := strings.TrimSpace(artistName)
+	if name == "" {
+		return ""
+	}
+	return "https://www.youtube.com/results?search_query=" + url.QueryEscape(name)
+}
+
+// getYouTubeVideoCache keeps the top video per artist name, nil when the search found nothing
+var (
+	getYouTubeVideoCache = lazyTTLCache[string, *YouTubeVideo]("YOUTUBE_CACHE", defaultYouTubeCacheTTL, defaultYouTubeCacheSize)
+	youtubeVideoInflight inflightGroup[string, *YouTubeVideo]
+)
+
+// FetchTopYouTubeVideo searches YouTube for the artist's most relevant embeddable video
+// It returns ErrYouTubeDisabled without a key, and nil without error when nothing matched
+func FetchTopYouTubeVideo(artistName string) (*YouTubeVideo, error) {
+	apiKey := strings.TrimSpace(os.Getenv("YOUTUBE_API_KEY"))
+	if apiKey == "" {
+		return nil, ErrYouTubeDisabled
+	}
+
+	name := strings.TrimSpace(artistName)
+	if name == "" {
+		return nil, errors.New("empty artist name")
+	}
+
+	key := strings.ToLower(name)
+	video, ok := getYouTubeVideoCache().Get(key)
+	if !ok {
+		var err error
+		video, err = youtubeVideoInflight.Do(key, func() (*YouTubeVideo, error) {
+			video, err := searchTopYouTubeVideo(apiKey, name)
+			if err != nil {
+				return nil, err
+			}
+			getYouTubeVideoCache().Set(key, video)
+			return video, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if video == nil {
+		return nil, nil
+	}
+	// Return a copy so callers can't mutate the cached entry
+	out := *video
+	return &out, nil
+}
+
+// searchTopYouTubeVideo runs one Data API search and returns the first video, if any
+func searchTopYouTubeVideo(apiKey, name string) (*YouTubeVideo, error) {
+	params := url.Values{}
+	params.Set("part", "snippet")
+	params.Set("type", "video")
+	params.Set("videoEmbeddable", "true")
+	params.Set("maxResults", "1")
+	params.Set("order", "relevance")
+	params.Set("q", name)
+	params.Set("key", apiKey)
+
+	req, err := http.NewRequest("GET", youtubeSearchEndpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := upstreamDo(req, 5*time.Second, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("youtube search failed: %s", resp.Status)
+	}
+
+	var payload youtubeSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	for _, it := range payload.Items {
+		if it.ID.VideoID == "" {
+			continue
+		}
+		// Snippets come back HTML-escaped (e.g. &#39;), templates escape again on output
+		return &YouTubeVideo{
+			ID:           it.ID.VideoID,
+			Title:        html.UnescapeString(it.Snippet.Title),
+			ChannelTitle: html.UnescapeString(it.Snippet.ChannelTitle),
+		}, nil
+	}
+	return nil, nil
+}
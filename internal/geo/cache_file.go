@@ -0,0 +1,167 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// cacheFlushInterval is how often new results are written to the cache file
+const cacheFlushInterval = time.Minute
+
+// fileCacheEntry is one resolved location in the cache file
+type fileCacheEntry struct {
+	Lat     float64   `json:"lat"`
+	Lng     float64   `json:"lng"`
+	Display string    `json:"display"`
+	At      time.Time `json:"at"`
+}
+
+// cacheFile persists positive geocoding results between restarts
+type cacheFile struct {
+	path string
+	// dirty is signaled after a new result, without blocking the geocode call
+	dirty chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewGeocoderWithCache is NewGeocoder backed by a JSON file of resolved locations
+// Entries are loaded on startup and new ones are flushed in the background and on Close
+// An empty path gives a memory-only geocoder
+func NewGeocoderWithCache(path string) *Geocoder {
+	g := NewGeocoder()
+	if path == "" {
+		return g
+	}
+
+	if err := g.loadCacheFile(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		// A corrupt or unreadable file only costs a cold cache
+		log.Println("geocoder: could not load cache file:", err)
+	}
+
+	g.file = &cacheFile{
+		path:  path,
+		dirty: make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go g.flushLoop()
+	return g
+}
+
+// Close writes pending results to the cache file and stops the background flusher
+func (g *Geocoder) Close() error {
+	if g.file == nil {
+		return nil
+	}
+
+	var err error
+	g.file.once.Do(func() {
+		close(g.file.stop)
+		<-g.file.done
+		err = g.writeCacheFile()
+	})
+	return err
+}
+
+// markDirty tells the flusher there is something new to save
+func (g *Geocoder) markDirty() {
+	if g.file == nil {
+		return
+	}
+	select {
+	case g.file.dirty <- struct{}{}:
+	default:
+		// A flush is already pending
+	}
+}
+
+func (g *Geocoder) flushLoop() {
+	defer close(g.file.done)
+
+	ticker := time.NewTicker(cacheFlushInterval)
+	defer ticker.Stop()
+
+	pending := false
+	for {
+		select {
+		case <-g.file.dirty:
+			pending = true
+		case <-ticker.C:
+			if !pending {
+				continue
+			}
+			pending = false
+			if err := g.writeCacheFile(); err != nil {
+				log.Println("geocoder: could not write cache file:", err)
+			}
+		case <-g.file.stop:
+			return
+		}
+	}
+}
+
+func (g *Geocoder) loadCacheFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var entries map[string]fileCacheEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for key, e := range entries {
+		g.cache[key] = cachedResult{
+			result: Result{Lat: e.Lat, Lng: e.Lng, Display: e.Display},
+			ok:     true,
+			at:     e.At,
+		}
+	}
+	return nil
+}
+
+// writeCacheFile snapshots the unexpired positive results and replaces the file atomically
+// Negative results stay in memory only, so a provider outage isn't remembered forever
+func (g *Geocoder) writeCacheFile() error {
+	g.mu.Lock()
+	now := time.Now()
+	entries := make(map[string]fileCacheEntry, len(g.cache))
+	for key, c := range g.cache {
+		if !c.ok || !g.fresh(c, now) {
+			continue
+		}
+		entries[key] = fileCacheEntry{Lat: c.result.Lat, Lng: c.result.Lng, Display: c.result.Display, At: c.at}
+	}
+	g.mu.Unlock()
+
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	// Write next to the target so the rename stays on the same filesystem
+	tmp, err := os.CreateTemp(filepath.Dir(g.file.path), filepath.Base(g.file.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), g.file.path)
+}
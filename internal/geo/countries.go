@@ -0,0 +1,308 @@
+package geo
+
+// countryCodes maps lowercase English country names to ISO 3166-1 alpha-2 codes
+// Keys use spaces, CountryCodeFromKey turns the underscores of location keys into spaces first
+var countryCodes = map[string]string{
+	"afghanistan":                       "AF",
+	"aland islands":                     "AX",
+	"albania":                           "AL",
+	"algeria":                           "DZ",
+	"american samoa":                    "AS",
+	"andorra":                           "AD",
+	"angola":                            "AO",
+	"anguilla":                          "AI",
+	"antarctica":                        "AQ",
+	"antigua and barbuda":               "AG",
+	"argentina":                         "AR",
+	"armenia":                           "AM",
+	"aruba":                             "AW",
+	"australia":                         "AU",
+	"austria":                           "AT",
+	"azerbaijan":                        "AZ",
+	"bahamas":                           "BS",
+	"bahrain":                           "BH",
+	"bangladesh":                        "BD",
+	"barbados":                          "BB",
+	"belarus":                           "BY",
+	"belgium":                           "BE",
+	"belize":                            "BZ",
+	"benin":                             "BJ",
+	"bermuda":                           "BM",
+	"bhutan":                            "BT",
+	"bolivia":                           "BO",
+	"bonaire sint eustatius and saba":   "BQ",
+	"bosnia and herzegovina":            "BA",
+	"botswana":                          "BW",
+	"bouvet island":                     "BV",
+	"brazil":                            "BR",
+	"british indian ocean territory":    "IO",
+	"brunei":                            "BN",
+	"bulgaria":                          "BG",
+	"burkina faso":                      "BF",
+	"burundi":                           "BI",
+	"cabo verde":                        "CV",
+	"cambodia":                          "KH",
+	"cameroon":                          "CM",
+	"canada":                            "CA",
+	"cayman islands":                    "KY",
+	"central african republic":          "CF",
+	"chad":                              "TD",
+	"chile":                             "CL",
+	"china":                             "CN",
+	"christmas island":                  "CX",
+	"cocos islands":                     "CC",
+	"colombia":                          "CO",
+	"comoros":                           "KM",
+	"congo":                             "CG",
+	"democratic republic of the congo":  "CD",
+	"cook islands":                      "CK",
+	"costa rica":                        "CR",
+	"cote d'ivoire":                     "CI",
+	"croatia":                           "HR",
+	"cuba":                              "CU",
+	"curacao":                           "CW",
+	"cyprus":                            "CY",
+	"czechia":                           "CZ",
+	"denmark":                           "DK",
+	"djibouti":                          "DJ",
+	"dominica":                          "DM",
+	"dominican republic":                "DO",
+	"ecuador":                           "EC",
+	"egypt":                             "EG",
+	"el salvador":                       "SV",
+	"equatorial guinea":                 "GQ",
+	"eritrea":                           "ER",
+	"estonia":                           "EE",
+	"eswatini":                          "SZ",
+	"ethiopia":                          "ET",
+	"falkland islands":                  "FK",
+	"faroe islands":                     "FO",
+	"fiji":                              "FJ",
+	"finland":                           "FI",
+	"france":                            "FR",
+	"french guiana":                     "GF",
+	"french polynesia":                  "PF",
+	"french southern territories":       "TF",
+	"gabon":                             "GA",
+	"gambia":                            "GM",
+	"georgia":                           "GE",
+	"germany":                           "DE",
+	"ghana":                             "GH",
+	"gibraltar":                         "GI",
+	"greece":                            "GR",
+	"greenland":                         "GL",
+	"grenada":                           "GD",
+	"guadeloupe":                        "GP",
+	"guam":                              "GU",
+	"guatemala":                         "GT",
+	"guernsey":                          "GG",
+	"guinea":                            "GN",
+	"guinea-bissau":                     "GW",
+	"guyana":                            "GY",
+	"haiti":                             "HT",
+	"heard island and mcdonald islands": "HM",
+	"holy see":                          "VA",
+	"honduras":                          "HN",
+	"hong kong":                         "HK",
+	"hungary":                           "HU",
+	"iceland":                           "IS",
+	"india":                             "IN",
+	"indonesia":                         "ID",
+	"iran":                              "IR",
+	"iraq":                              "IQ",
+	"ireland":                           "IE",
+	"isle of man":                       "IM",
+	"israel":                            "IL",
+	"italy":                             "IT",
+	"jamaica":                           "JM",
+	"japan":                             "JP",
+	"jersey":                            "JE",
+	"jordan":                            "JO",
+	"kazakhstan":                        "KZ",
+	"kenya":                             "KE",
+	"kiribati":                          "KI",
+	"north korea":                       "KP",
+	"south korea":                       "KR",
+	"kuwait":                            "KW",
+	"kyrgyzstan":                        "KG",
+	"laos":                              "LA",
+	"latvia":                            "LV",
+	"lebanon":                           "LB",
+	"lesotho":                           "LS",
+	"liberia":                           "LR",
+	"libya":                             "LY",
+	"liechtenstein":                     "LI",
+	"lithuania":                         "LT",
+	"luxembourg":                        "LU",
+	"macao":                             "MO",
+	"madagascar":                        "MG",
+	"malawi":                            "MW",
+	"malaysia":                          "MY",
+	"maldives":                          "MV",
+	"mali":                              "ML",
+	"malta":                             "MT",
+	"marshall islands":                  "MH",
+	"martinique":                        "MQ",
+	"mauritania":                        "MR",
+	"mauritius":                         "MU",
+	"mayotte":                           "YT",
+	"mexico":                            "MX",
+	"micronesia":                        "FM",
+	"moldova":                           "MD",
+	"monaco":                            "MC",
+	"mongolia":                          "MN",
+	"montenegro":                        "ME",
+	"montserrat":                        "MS",
+	"morocco":                           "MA",
+	"mozambique":                        "MZ",
+	"myanmar":                           "MM",
+	"namibia":                           "NA",
+	"nauru":                             "NR",
+	"nepal":                             "NP",
+	"netherlands":                       "NL",
+	"new caledonia":                     "NC",
+	"new zealand":                       "NZ",
+	"nicaragua":                         "NI",
+	"niger":                             "NE",
+	"nigeria":                           "NG",
+	"niue":                              "NU",
+	"norfolk island":                    "NF",
+	"north macedonia":                   "MK",
+	"northern mariana islands":          "MP",
+	"norway":                            "NO",
+	"oman":                              "OM",
+	"pakistan":                          "PK",
+	"palau":                             "PW",
+	"palestine":                         "PS",
+	"panama":                            "PA",
+	"papua new guinea":                  "PG",
+	"paraguay":                          "PY",
+	"peru":                              "PE",
+	"philippines":                       "PH",
+	"pitcairn":                          "PN",
+	"poland":                            "PL",
+	"portugal":                          "PT",
+	"puerto rico":                       "PR",
+	"qatar":                             "QA",
+	"reunion":                           "RE",
+	"romania":                           "RO",
+	"russia":                            "RU",
+	"rwanda":                            "RW",
+	"saint barthelemy":                  "BL",
+	"saint helena":                      "SH",
+	"saint kitts and nevis":             "KN",
+	"saint lucia":                       "LC",
+	"saint martin":                      "MF",
+	"saint pierre and miquelon":         "PM",
+	"saint vincent and the grenadines":  "VC",
+	"samoa":                             "WS",
+	"san marino":                        "SM",
+	"sao tome and principe":             "ST",
+	"saudi arabia":                      "SA",
+	"senegal":                           "SN",
+	"serbia":                            "RS",
+	"seychelles":                        "SC",
+	"sierra leone":                      "SL",
+	"singapore":                         "SG",
+	"sint maarten":                      "SX",
+	"slovakia":                          "SK",
+	"slovenia":                          "SI",
+	"solomon islands":                   "SB",
+	"somalia":                           "SO",
+	"south africa":                      "ZA",
+	"south georgia and the south sandwich islands": "GS",
+	"south sudan":                          "SS",
+	"spain":                                "ES",
+	"sri lanka":                            "LK",
+	"sudan":                                "SD",
+	"suriname":                             "SR",
+	"svalbard and jan mayen":               "SJ",
+	"sweden":                               "SE",
+	"switzerland":                          "CH",
+	"syria":                                "SY",
+	"taiwan":                               "TW",
+	"tajikistan":                           "TJ",
+	"tanzania":                             "TZ",
+	"thailand":                             "TH",
+	"timor-leste":                          "TL",
+	"togo":                                 "TG",
+	"tokelau":                              "TK",
+	"tonga":                                "TO",
+	"trinidad and tobago":                  "TT",
+	"tunisia":                              "TN",
+	"turkey":                               "TR",
+	"turkmenistan":                         "TM",
+	"turks and caicos islands":             "TC",
+	"tuvalu":                               "TV",
+	"uganda":                               "UG",
+	"ukraine":                              "UA",
+	"united arab emirates":                 "AE",
+	"united kingdom":                       "GB",
+	"united states":                        "US",
+	"united states minor outlying islands": "UM",
+	"uruguay":                              "UY",
+	"uzbekistan":                           "UZ",
+	"vanuatu":                              "VU",
+	"venezuela":                            "VE",
+	"vietnam":                              "VN",
+	"british virgin islands":               "VG",
+	"us virgin islands":                    "VI",
+	"wallis and futuna":                    "WF",
+	"western sahara":                       "EH",
+	"yemen":                                "YE",
+	"zambia":                               "ZM",
+	"zimbabwe":                             "ZW",
+
+	// Aliases: short forms, former names and spellings seen in location keys
+	"usa":                            "US",
+	"us":                             "US",
+	"united states of america":       "US",
+	"uk":                             "GB",
+	"great britain":                  "GB",
+	"england":                        "GB",
+	"scotland":                       "GB",
+	"wales":                          "GB",
+	"northern ireland":               "GB",
+	"ca":                             "CA",
+	"holland":                        "NL",
+	"the netherlands":                "NL",
+	"netherlands antilles":           "CW",
+	"korea":                          "KR",
+	"republic of korea":              "KR",
+	"czech republic":                 "CZ",
+	"ivory coast":                    "CI",
+	"cape verde":                     "CV",
+	"swaziland":                      "SZ",
+	"macedonia":                      "MK",
+	"burma":                          "MM",
+	"east timor":                     "TL",
+	"vatican":                        "VA",
+	"vatican city":                   "VA",
+	"russian federation":             "RU",
+	"turkiye":                        "TR",
+	"viet nam":                       "VN",
+	"uae":                            "AE",
+	"dr congo":                       "CD",
+	"drc":                            "CD",
+	"republic of the congo":          "CG",
+	"macau":                          "MO",
+	"brunei darussalam":              "BN",
+	"lao":                            "LA",
+	"st barthelemy":                  "BL",
+	"st lucia":                       "LC",
+	"st kitts and nevis":             "KN",
+	"st vincent and the grenadines":  "VC",
+	"bosnia":                         "BA",
+	"trinidad":                       "TT",
+	"the bahamas":                    "BS",
+	"the gambia":                     "GM",
+	"federated states of micronesia": "FM",
+	"falklands":                      "FK",
+	"faroes":                         "FO",
+	"reunion island":                 "RE",
+	"curaçao":                        "CW",
+	"réunion":                        "RE",
+	"côte d'ivoire":                  "CI",
+	"são tomé and príncipe":          "ST",
+	"åland islands":                  "AX",
+}
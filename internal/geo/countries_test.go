@@ -0,0 +1,73 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountryCodeFromKey(t *testing.T) {
+	tests := map[string]string{
+		// Everything the old hand-written switch knew
+		"new_york-usa":                   "US",
+		"boston-united_states":           "US",
+		"miami-united_states_of_america": "US",
+		"london-uk":                      "GB",
+		"leeds-united_kingdom":           "GB",
+		"paris-france":                   "FR",
+		"zurich-switzerland":             "CH",
+		"sydney-australia":               "AU",
+		"auckland-new_zealand":           "NZ",
+		"osaka-japan":                    "JP",
+		"jakarta-indonesia":              "ID",
+		"budapest-hungary":               "HU",
+		"minsk-belarus":                  "BY",
+		"bratislava-slovakia":            "SK",
+		"mexico_city-mexico":             "MX",
+		"toronto-canada":                 "CA",
+		"quebec-ca":                      "CA",
+		"papeete-french_polynesia":       "PF",
+		"noumea-new_caledonia":           "NC",
+
+		// Countries the switch missed
+		"rio_de_janeiro-brazil": "BR",
+		"berlin-germany":        "DE",
+		"lagos-nigeria":         "NG",
+		"seoul-south_korea":     "KR",
+		"abidjan-ivory_coast":   "CI",
+
+		// Aliases and input normalization
+		"amsterdam-holland":      "NL",
+		"glasgow-scotland":       "GB",
+		"busan-korea":            "KR",
+		"dubai-uae":              "AE",
+		"prague-Czech_Republic":  "CZ",
+		"lisbon- Portugal ":      "PT",
+		"rome-italy__":           "IT",
+		"oslo-NORWAY":            "NO",
+		"somewhere-atlantis":     "",
+		"no_country_in_this_key": "",
+		"":                       "",
+	}
+	for key, want := range tests {
+		if got := CountryCodeFromKey(key); got != want {
+			t.Errorf("CountryCodeFromKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestCountryCodesTable(t *testing.T) {
+	codes := make(map[string]bool)
+	for name, code := range countryCodes {
+		// Lookups are lowercased and single-spaced, other keys could never match
+		if name != strings.ToLower(name) || name != strings.Join(strings.Fields(name), " ") {
+			t.Errorf("key %q isn't normalized", name)
+		}
+		if len(code) != 2 || code != strings.ToUpper(code) {
+			t.Errorf("%q maps to %q, want an alpha-2 code", name, code)
+		}
+		codes[code] = true
+	}
+	if len(codes) < 245 {
+		t.Errorf("table covers %d codes, want the full ISO 3166-1 list", len(codes))
+	}
+}
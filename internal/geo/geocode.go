@@ -3,6 +3,7 @@ package geo
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -10,13 +11,30 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 type Geocoder struct {
 	client *http.Client
 
+	// PositiveTTL and NegativeTTL bound how long found and not-found results are reused
+	// Zero keeps entries for the process lifetime, set them before the first Geocode call
+	PositiveTTL time.Duration
+	NegativeTTL time.Duration
+
+	// NominatimInterval spaces out Nominatim requests across all geocoders, zero disables it
+	// Open-Meteo has no such limit and isn't throttled
+	NominatimInterval time.Duration
+
 	mu    sync.Mutex
 	cache map[string]cachedResult
+
+	// inflight shares one provider lookup between concurrent calls for the same cache key
+	inflight singleflight.Group
+
+	// file is set by NewGeocoderWithCache, nil keeps the cache in memory only
+	file *cacheFile
 }
 
 type cachedResult struct {
@@ -31,12 +49,31 @@ type Result struct {
 	Display string
 }
 
+const (
+	// Places don't move, but a month lets corrected provider data through eventually
+	defaultPositiveTTL = 30 * 24 * time.Hour
+	// Misses are often rate limits or outages, retry them soon
+	defaultNegativeTTL = 10 * time.Minute
+)
+
 // NewGeocoder creates a Geocoder with a small in-memory cache and a short timeout
 func NewGeocoder() *Geocoder {
 	return &Geocoder{
-		client: &http.Client{Timeout: 6 * time.Second},
-		cache:  make(map[string]cachedResult),
+		client:            &http.Client{Timeout: 6 * time.Second},
+		PositiveTTL:       defaultPositiveTTL,
+		NegativeTTL:       defaultNegativeTTL,
+		NominatimInterval: defaultNominatimInterval,
+		cache:             make(map[string]cachedResult),
+	}
+}
+
+// fresh reports whether a cached result can still be served, call it with g.mu held
+func (g *Geocoder) fresh(c cachedResult, now time.Time) bool {
+	ttl := g.NegativeTTL
+	if c.ok {
+		ttl = g.PositiveTTL
 	}
+	return ttl <= 0 || now.Sub(c.at) < ttl
 }
 
 // Geocode resolves a place name to coordinates and a display label
@@ -51,53 +88,158 @@ func (g *Geocoder) Geocode(ctx context.Context, name string, countryCode string)
 	// Cache key is normalized to avoid duplicates from case and spacing differences
 	key := strings.ToLower(n) + "|" + cc
 
-	g.mu.Lock()
-	if hit, ok := g.cache[key]; ok {
-		g.mu.Unlock()
+	if hit, ok := g.cached(key); ok {
 		// Cached results include negative hits to avoid repeated provider calls
 		return hit.result, hit.ok, nil
 	}
-	g.mu.Unlock()
 
-	res, ok, err := g.tryGeocode(ctx, n, cc)
+	ch := g.inflight.DoChan(key, func() (any, error) {
+		// A flight for this key may have finished between our cache check and Do
+		if hit, ok := g.cached(key); ok {
+			return hit, nil
+		}
+
+		// Other callers wait on this lookup, so one of them canceling must not abort it
+		// The client timeout still bounds it
+		res, ok, err := g.tryGeocode(context.WithoutCancel(ctx), n, cc)
+		entry := cachedResult{result: res, ok: ok, at: time.Now()}
+
+		// A lookup skipped because the Nominatim queue was full says nothing about the place,
+		// so it isn't kept as a miss and the next caller tries again
+		if errors.Is(err, errNominatimBusy) {
+			return entry, err
+		}
+
+		g.mu.Lock()
+		// Cache everything else, even failures, since upstream APIs can be rate-limited
+		// Expired entries are simply overwritten here
+		g.cache[key] = entry
+		g.mu.Unlock()
+
+		if ok {
+			g.markDirty()
+		}
+		return entry, err
+	})
+
+	select {
+	case res := <-ch:
+		entry := res.Val.(cachedResult)
+		return entry.result, entry.ok, res.Err
+	case <-ctx.Done():
+		// The lookup keeps going and still fills the cache for the next caller
+		return Result{}, false, ctx.Err()
+	}
+}
+
+// GeocodeBatch resolves many Groupie location keys with a few workers, e.g. to warm the cache
+// Repeated keys are looked up once and only found locations are returned
+// It stops handing out keys once ctx is done and returns what was resolved so far
+func (g *Geocoder) GeocodeBatch(ctx context.Context, keys []string) map[string]Result {
+	const workers = 4
+
+	jobs := make(chan string)
+	out := make(map[string]Result, len(keys))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range jobs {
+				place, countryCode, _ := QueryFromLocationKey(key)
+				res, ok, err := g.Geocode(ctx, place, countryCode)
+				if err != nil || !ok {
+					continue
+				}
+				mu.Lock()
+				out[key] = res
+				mu.Unlock()
+			}
+		}()
+	}
+
+	seen := make(map[string]bool, len(keys))
+feed:
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		select {
+		case jobs <- key:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return out
+}
 
+// cached returns the entry for key if it hasn't expired
+func (g *Geocoder) cached(key string) (cachedResult, bool) {
 	g.mu.Lock()
-	// Cache everything, even failures, since upstream APIs can be rate-limited
-	g.cache[key] = cachedResult{result: res, ok: ok, at: time.Now()}
-	g.mu.Unlock()
+	defer g.mu.Unlock()
 
-	return res, ok, err
+	hit, ok := g.cache[key]
+	if !ok || !g.fresh(hit, time.Now()) {
+		return cachedResult{}, false
+	}
+	return hit, true
 }
 
 // tryGeocode applies project-specific normalization and provider fallbacks
 func (g *Geocoder) tryGeocode(ctx context.Context, name string, countryCode string) (Result, bool, error) {
 
-	if countryCode == "US" {
-		// US state names are frequently misspelled in the dataset, normalize first
-		if norm, ok := normalizeUSStateName(name); ok {
-			if res, ok2, err := g.geocodeNominatim(ctx, norm, countryCode); err == nil && ok2 {
-				return res, true, nil
-			}
+	// busy records a Nominatim lookup skipped by the rate limiter, the miss may not be real
+	var busy bool
+
+	// US states and Canadian provinces are frequently misspelled in the dataset, normalize first
+	norm, isRegion := normalizeRegionName(name, countryCode)
+	if isRegion {
+		res, ok2, err := g.geocodeNominatim(ctx, norm, countryCode)
+		if err == nil && ok2 {
+			return res, true, nil
 		}
+		busy = busy || errors.Is(err, errNominatimBusy)
 	}
 
 	// Try providers with the raw name first
-	if res, ok, err := g.tryProviders(ctx, name, countryCode); err == nil && ok {
+	res, ok, err := g.tryProviders(ctx, name, countryCode)
+	if err == nil && ok {
 		return res, ok, nil
 	}
+	busy = busy || errors.Is(err, errNominatimBusy)
 
-	if countryCode == "US" {
-		// Retry with a normalized state name if the raw query failed
-		if norm, ok := normalizeUSStateName(name); ok && !strings.EqualFold(norm, name) {
-			if res, ok, err := g.tryProviders(ctx, norm, countryCode); err == nil && ok {
-				return res, ok, nil
-			}
+	// Retry with the normalized region name if the raw query failed
+	if isRegion && !strings.EqualFold(norm, name) {
+		res, ok, err := g.tryProviders(ctx, norm, countryCode)
+		if err == nil && ok {
+			return res, ok, nil
 		}
+		busy = busy || errors.Is(err, errNominatimBusy)
 	}
 
+	if busy {
+		return Result{}, false, errNominatimBusy
+	}
 	return Result{}, false, nil
 }
 
+// normalizeRegionName picks the state or province normalizer for the country, ok is false elsewhere
+func normalizeRegionName(name, countryCode string) (string, bool) {
+	switch countryCode {
+	case "US":
+		return normalizeUSStateName(name)
+	case "CA":
+		return normalizeCanadianProvinceName(name)
+	default:
+		return "", false
+	}
+}
+
 // tryProviders calls multiple geocoding providers in a fixed order
 func (g *Geocoder) tryProviders(ctx context.Context, name string, countryCode string) (Result, bool, error) {
 	// Open-Meteo is fast and doesn't require any keys
@@ -112,11 +254,8 @@ func (g *Geocoder) tryProviders(ctx context.Context, name string, countryCode st
 		return res2, true, nil
 	}
 
-	// Prefer returning the first provider error we got to help debugging
-	if err != nil {
-		return Result{}, false, err
-	}
-	return Result{}, false, nil
+	// Return both provider errors, so a rate-limited Nominatim can be told apart from a miss
+	return Result{}, false, errors.Join(err, err2)
 }
 
 // geocodeOpenMeteo queries Open-Meteo's geocoding API and picks the best candidate
@@ -232,6 +371,10 @@ func (g *Geocoder) geocodeNominatim(ctx context.Context, name string, countryCod
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", "GroupieTrackerSchoolProject/1.0")
 
+	if err := g.waitNominatim(ctx); err != nil {
+		return Result{}, false, err
+	}
+
 	resp, err := g.client.Do(req)
 	if err != nil {
 		return Result{}, false, err
@@ -340,43 +483,13 @@ func QueryFromLocationKey(key string) (string, string, string) {
 	return titleWords(place), CountryCodeFromKey(key), HumanizeLocationKey(key)
 }
 
-// CountryCodeFromKey maps the country portion of a Groupie location key to an ISO-like code
+// CountryCodeFromKey maps the country portion of a Groupie location key to an ISO 3166-1 alpha-2 code
+// Unknown countries give "", which leaves geocoding unfiltered
 func CountryCodeFromKey(key string) string {
 	_, country := splitLocationKey(key)
 	c := strings.ToLower(strings.TrimSpace(country))
-	c = strings.ReplaceAll(c, "_", " ")
-	switch c {
-	case "usa", "united states", "united states of america":
-		return "US"
-	case "uk", "united kingdom":
-		return "GB"
-	case "france":
-		return "FR"
-	case "switzerland":
-		return "CH"
-	case "australia":
-		return "AU"
-	case "new zealand":
-		return "NZ"
-	case "japan":
-		return "JP"
-	case "indonesia":
-		return "ID"
-	case "hungary":
-		return "HU"
-	case "belarus":
-		return "BY"
-	case "slovakia":
-		return "SK"
-	case "mexico":
-		return "MX"
-	case "french polynesia":
-		return "PF"
-	case "new caledonia":
-		return "NC"
-	default:
-		return ""
-	}
+	c = strings.Join(strings.Fields(strings.ReplaceAll(c, "_", " ")), " ")
+	return countryCodes[c]
 }
 
 // splitLocationKey splits `place-country` keys into (place, country) and normalizes separators
@@ -436,7 +549,7 @@ func scoreCandidate(query, name, admin1 string) int {
 
 	if q != "" && n != "" {
 		// Levenshtein helps when the data has small typos like "califronia"
-		d := levenshtein(q, n)
+		d := Levenshtein(q, n)
 		if d == 0 {
 			score += 30
 		} else if d == 1 {
@@ -451,54 +564,42 @@ func scoreCandidate(query, name, admin1 string) int {
 	return score
 }
 
-// levenshtein computes the edit distance between two strings
-func levenshtein(a, b string) int {
+// Levenshtein is the edit distance between two strings in runes, so "björk" is one edit from "bjork"
+// Location matching and artist search relevance both use it
+func Levenshtein(a, b string) int {
 	if a == b {
 		return 0
 	}
-	if len(a) == 0 {
-		return len(b)
+	ar, br := []rune(a), []rune(b)
+	if len(ar) == 0 {
+		return len(br)
 	}
-	if len(b) == 0 {
-		return len(a)
+	if len(br) == 0 {
+		return len(ar)
 	}
 
-	prev := make([]int, len(b)+1)
-	cur := make([]int, len(b)+1)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
 
-	for j := 0; j <= len(b); j++ {
+	for j := range prev {
 		prev[j] = j
 	}
 
-	for i := 1; i <= len(a); i++ {
+	for i := 1; i <= len(ar); i++ {
 		cur[0] = i
-		ai := a[i-1]
-		for j := 1; j <= len(b); j++ {
+		ai := ar[i-1]
+		for j := 1; j <= len(br); j++ {
 			cost := 0
-			if ai != b[j-1] {
+			if ai != br[j-1] {
 				cost = 1
 			}
-			del := prev[j] + 1
-			ins := cur[j-1] + 1
-			sub := prev[j-1] + cost
-			cur[j] = min3(del, ins, sub)
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
 		}
 		// Reuse buffers to keep allocations low
 		prev, cur = cur, prev
 	}
 
-	return prev[len(b)]
-}
-
-// min3 returns the smallest of the three ints
-func min3(a, b, c int) int {
-	if a <= b && a <= c {
-		return a
-	}
-	if b <= a && b <= c {
-		return b
-	}
-	return c
+	return prev[len(br)]
 }
 
 // normalizeUSStateName tries to map a noisy state string to a canonical state name
@@ -525,7 +626,7 @@ func normalizeUSStateName(s string) (string, bool) {
 	best := ""
 	bestD := 999
 	for _, st := range states {
-		d := levenshtein(q, st)
+		d := Levenshtein(q, st)
 		if d < bestD {
 			bestD = d
 			best = st
@@ -538,3 +639,43 @@ func normalizeUSStateName(s string) (string, bool) {
 	}
 	return "", false
 }
+
+// canadianProvinces lists the 10 provinces and 3 territories under the names Nominatim knows
+var canadianProvinces = []string{
+	"Alberta", "British Columbia", "Manitoba", "New Brunswick", "Newfoundland and Labrador",
+	"Nova Scotia", "Ontario", "Prince Edward Island", "Quebec", "Saskatchewan",
+	"Northwest Territories", "Nunavut", "Yukon",
+}
+
+// accentFolder strips the accents found in Canadian place names ("Québec", "Montréal")
+var accentFolder = strings.NewReplacer(
+	"à", "a", "â", "a", "ç", "c", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "ô", "o", "ù", "u", "û", "u", "ü", "u",
+)
+
+// normalizeCanadianProvinceName is the Canadian counterpart of normalizeUSStateName
+func normalizeCanadianProvinceName(s string) (string, bool) {
+	q := accentFolder.Replace(strings.ToLower(strings.TrimSpace(s)))
+	q = strings.ReplaceAll(q, ",", " ")
+	q = strings.Join(strings.Fields(q), " ")
+	if q == "" {
+		return "", false
+	}
+
+	// Find the closest province name by edit distance
+	best := ""
+	bestD := 999
+	for _, p := range canadianProvinces {
+		d := Levenshtein(q, strings.ToLower(p))
+		if d < bestD {
+			bestD = d
+			best = p
+		}
+	}
+
+	if best != "" && bestD <= 2 {
+		// Same tight threshold as the US states so cities keep their own name
+		return best, true
+	}
+	return "", false
+}
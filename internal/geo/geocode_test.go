@@ -0,0 +1,311 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// stubGeocoder answers every provider request with Paris once release is closed, counting hits
+func stubGeocoder(release <-chan struct{}) (*Geocoder, *atomic.Int32) {
+	var hits atomic.Int32
+	g := NewGeocoder()
+	g.NominatimInterval = 0
+	g.client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits.Add(1)
+		<-release
+		w := httptest.NewRecorder()
+		w.WriteString(`{"results":[{"name":"Paris","latitude":48.85,"longitude":2.35,"country":"France","country_code":"FR"}]}`)
+		return w.Result(), nil
+	})}
+	return g, &hits
+}
+
+func TestGeocodeSharesConcurrentLookups(t *testing.T) {
+	release := make(chan struct{})
+	g, hits := stubGeocoder(release)
+
+	// Spellings that normalize to the same cache key share the lookup too
+	names := []string{"Paris", "paris", " PARIS "}
+	const callers = 50
+	results := make([]Result, callers)
+	var started, done sync.WaitGroup
+	started.Add(callers)
+	done.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+			res, ok, err := g.Geocode(context.Background(), names[i%len(names)], "fr")
+			if err != nil || !ok {
+				t.Errorf("caller %d: ok = %v, err = %v", i, ok, err)
+			}
+			results[i] = res
+		}()
+	}
+	started.Wait()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	done.Wait()
+
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("transport hit %d times, want 1", n)
+	}
+	for i, res := range results {
+		if res != results[0] {
+			t.Fatalf("caller %d got %+v, want %+v", i, res, results[0])
+		}
+	}
+	if results[0].Display != "Paris, France" {
+		t.Fatalf("Display = %q, want %q", results[0].Display, "Paris, France")
+	}
+
+	// Later calls come from the cache
+	if _, _, err := g.Geocode(context.Background(), "Paris", "FR"); err != nil {
+		t.Fatal(err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("transport hit %d times after the cache was filled, want 1", n)
+	}
+}
+
+func TestGeocodeCanceledCallerLeavesLookupRunning(t *testing.T) {
+	release := make(chan struct{})
+	g, hits := stubGeocoder(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, _, err := g.Geocode(ctx, "Paris", "FR")
+		errc <- err
+	}()
+	for hits.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("canceled caller: err = %v, want context.Canceled", err)
+	}
+
+	// The lookup still finishes and fills the cache for the next caller
+	close(release)
+	res, ok, err := g.Geocode(context.Background(), "Paris", "FR")
+	if err != nil || !ok || res.Display == "" {
+		t.Fatalf("after cancel: %+v, ok = %v, err = %v", res, ok, err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("transport hit %d times, want 1", n)
+	}
+}
+
+func TestGeocodeNegativeEntriesExpire(t *testing.T) {
+	var hits atomic.Int32
+	g := NewGeocoder()
+	g.NominatimInterval = 0
+	g.NegativeTTL = 20 * time.Millisecond
+	g.client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits.Add(1)
+		w := httptest.NewRecorder()
+		switch q := r.URL.Query(); {
+		case q.Get("name") == "Paris" || q.Get("q") == "Paris":
+			w.WriteString(`{"results":[{"name":"Paris","latitude":48.85,"longitude":2.35,"country":"France","country_code":"FR"}]}`)
+		case strings.Contains(r.URL.Host, "nominatim"):
+			w.WriteString(`[]`)
+		default:
+			w.WriteString(`{"results":[]}`)
+		}
+		return w.Result(), nil
+	})}
+	ctx := context.Background()
+
+	if _, ok, _ := g.Geocode(ctx, "Atlantis", "GR"); ok {
+		t.Fatal("Atlantis was found")
+	}
+	if _, ok, err := g.Geocode(ctx, "Paris", "FR"); !ok || err != nil {
+		t.Fatalf("Paris: ok = %v, err = %v", ok, err)
+	}
+	afterFirst := hits.Load()
+
+	// Both are served from the cache while fresh
+	g.Geocode(ctx, "Atlantis", "GR")
+	g.Geocode(ctx, "Paris", "FR")
+	if n := hits.Load(); n != afterFirst {
+		t.Fatalf("fresh entries made %d more provider calls", n-afterFirst)
+	}
+
+	time.Sleep(2 * g.NegativeTTL)
+
+	// The miss has expired and is looked up again, the found place hasn't
+	g.Geocode(ctx, "Paris", "FR")
+	if n := hits.Load(); n != afterFirst {
+		t.Fatalf("positive entry made %d provider calls after the negative TTL", n-afterFirst)
+	}
+	if _, ok, _ := g.Geocode(ctx, "Atlantis", "GR"); ok {
+		t.Fatal("Atlantis was found on retry")
+	}
+	if n := hits.Load(); n == afterFirst {
+		t.Fatal("expired negative entry was served from the cache")
+	}
+}
+
+func TestGeocodeDoesNotCacheBusyNominatim(t *testing.T) {
+	var hits atomic.Int32
+	g := NewGeocoder()
+	g.NominatimInterval = time.Second
+	g.client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hits.Add(1)
+		w := httptest.NewRecorder()
+		// Only Nominatim knows the place, so a skipped Nominatim lookup looks like a miss
+		if strings.Contains(r.URL.Host, "nominatim") {
+			w.WriteString(`[{"lat":"64.15","lon":"-21.94","name":"Reykjavik","display_name":"Reykjavik, Iceland","address":{"country_code":"is"}}]`)
+		} else {
+			w.WriteString(`{"results":[]}`)
+		}
+		return w.Result(), nil
+	})}
+
+	// Fill the shared queue so the next Nominatim request is refused
+	nominatimSlots.mu.Lock()
+	saved := nominatimSlots.next
+	nominatimSlots.next = time.Now().Add(time.Hour)
+	nominatimSlots.mu.Unlock()
+	t.Cleanup(func() {
+		nominatimSlots.mu.Lock()
+		nominatimSlots.next = saved
+		nominatimSlots.mu.Unlock()
+	})
+
+	ctx := context.Background()
+	if _, ok, err := g.Geocode(ctx, "Reykjavik", "IS"); ok || !errors.Is(err, errNominatimBusy) {
+		t.Fatalf("busy lookup: ok = %v, err = %v, want errNominatimBusy", ok, err)
+	}
+
+	// Once the queue drains the place is looked up again instead of served as a cached miss
+	g.NominatimInterval = 0
+	before := hits.Load()
+	res, ok, err := g.Geocode(ctx, "Reykjavik", "IS")
+	if err != nil || !ok || res.Display != "Reykjavik, Iceland" {
+		t.Fatalf("retry: %+v, ok = %v, err = %v", res, ok, err)
+	}
+	if hits.Load() == before {
+		t.Fatal("busy result was served from the cache")
+	}
+}
+
+func TestNormalizeCanadianProvinceName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"Québec", "Quebec", true},
+		{"quebec", "Quebec", true},
+		{"Ontari", "Ontario", true},
+		{"  ontario  ", "Ontario", true},
+		{"british columbia", "British Columbia", true},
+		{"Prince Edward Island", "Prince Edward Island", true},
+		{"Yukonn", "Yukon", true},
+		{"Nunavut", "Nunavut", true},
+		{"Toronto", "", false},
+		{"Montréal", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := normalizeCanadianProvinceName(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("normalizeCanadianProvinceName(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeRegionName(t *testing.T) {
+	tests := []struct {
+		name, cc string
+		want     string
+		ok       bool
+	}{
+		{"Ontari", "CA", "Ontario", true},
+		{"californa", "US", "California", true},
+		// Provinces are only looked for in Canada and states only in the US
+		{"Ontario", "US", "", false},
+		{"California", "CA", "", false},
+		{"Quebec", "FR", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := normalizeRegionName(tt.name, tt.cc)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("normalizeRegionName(%q, %q) = %q, %v; want %q, %v", tt.name, tt.cc, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestQueryFromCanadianLocationKey(t *testing.T) {
+	place, cc, display := QueryFromLocationKey("quebec-canada")
+	if place != "Quebec" || cc != "CA" || display != "Quebec, Canada" {
+		t.Fatalf("QueryFromLocationKey = %q, %q, %q", place, cc, display)
+	}
+}
+
+func TestGeocodeProvinceTriesNominatimFirst(t *testing.T) {
+	var requests []*url.URL
+	g := NewGeocoder()
+	g.NominatimInterval = 0
+	g.client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests = append(requests, r.URL)
+		w := httptest.NewRecorder()
+		w.WriteString(`[{"lat":"51.25","lon":"-85.32","name":"Ontario","display_name":"Ontario, Canada","address":{"country_code":"ca"}}]`)
+		return w.Result(), nil
+	})}
+
+	res, ok, err := g.Geocode(context.Background(), "Ontari", "CA")
+	if err != nil || !ok {
+		t.Fatalf("ok = %v, err = %v", ok, err)
+	}
+	if res.Display != "Ontario, Canada" {
+		t.Fatalf("Display = %q, want %q", res.Display, "Ontario, Canada")
+	}
+	if len(requests) != 1 {
+		t.Fatalf("made %d requests, want 1", len(requests))
+	}
+	if u := requests[0]; u.Host != "nominatim.openstreetmap.org" || u.Query().Get("q") != "Ontario" {
+		t.Fatalf("first request = %s, want Nominatim with the corrected province", u)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"metalica", "metallica", 1},
+		{"califronia", "california", 2},
+		// Accented letters count as one edit, not one per byte
+		{"björk", "bjork", 1},
+		{"beyoncé", "beyonce", 1},
+		{"zürich", "zurich", 1},
+		{"ö", "", 1},
+	}
+	for _, tt := range tests {
+		if got := Levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := Levenshtein(tt.b, tt.a); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
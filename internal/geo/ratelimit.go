@@ -0,0 +1,60 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// Nominatim's usage policy allows at most one request per second per application
+	defaultNominatimInterval = time.Second
+	// maxNominatimWait skips Nominatim instead of queueing lookups further out than this
+	maxNominatimWait = 10 * time.Second
+)
+
+// errNominatimBusy is returned when the request queue is too long to wait for
+var errNominatimBusy = errors.New("nominatim rate limit: too many queued requests")
+
+// nominatimSlots is shared by every Geocoder, the policy applies to the whole process
+// Each caller reserves the next free slot, so concurrent lookups are spread out instead of rejected
+var nominatimSlots = struct {
+	mu   sync.Mutex
+	next time.Time
+}{}
+
+// waitNominatim blocks until a Nominatim request is allowed or ctx is done
+// A zero NominatimInterval disables the limit
+func (g *Geocoder) waitNominatim(ctx context.Context) error {
+	interval := g.NominatimInterval
+	if interval <= 0 {
+		return nil
+	}
+
+	nominatimSlots.mu.Lock()
+	now := time.Now()
+	slot := nominatimSlots.next
+	if slot.Before(now) {
+		slot = now
+	}
+	if slot.Sub(now) > maxNominatimWait {
+		nominatimSlots.mu.Unlock()
+		return errNominatimBusy
+	}
+	nominatimSlots.next = slot.Add(interval)
+	nominatimSlots.mu.Unlock()
+
+	d := time.Until(slot)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -3,24 +3,18 @@ package handlers
 import (
 	"html/template"
 	"net/http"
-
-	"palasgroupietracker/internal/store"
 )
 
 type NotFoundPageData struct {
-	Title      string
-	Source     string
-	ActiveNav  string
-	BasePath   string
-	CurrentURL string
-	User       *store.User
-	IsAuthed   bool
+	LayoutData
 }
 
 // NotFound renders the custom 404 page using the shared layout
 func NotFound(w http.ResponseWriter, r *http.Request) {
-	// Set status before writing any body content
-	w.WriteHeader(http.StatusNotFound)
+	if wantsJSON(r) {
+		renderJSONError(w, r, http.StatusNotFound, "not found")
+		return
+	}
 
 	tmpl, err := template.ParseFiles(
 		"web/templates/layout.gohtml",
@@ -35,15 +29,11 @@ func NotFound(w http.ResponseWriter, r *http.Request) {
 	user, authed := getCurrentUser(w, r)
 
 	data := NotFoundPageData{
-		Title:      "Page not found",
-		Source:     getSource(r),
-		ActiveNav:  "",
-		BasePath:   getBasePath(r),
-		CurrentURL: buildCurrentURL(r),
-		User:       user,
-		IsAuthed:   authed,
+		LayoutData: newLayoutData(w, r, "Page not found", user, authed),
 	}
 
+	// Status goes out after the cookies above and before any body content
+	w.WriteHeader(http.StatusNotFound)
 	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
 		http.Error(w, "404 not found", http.StatusNotFound)
 		return
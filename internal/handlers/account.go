@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"palasgroupietracker/internal/store"
+)
+
+// AccountPageData powers the account page, with its change password and delete account forms
+type AccountPageData struct {
+	LayoutData
+
+	Error  string
+	Notice string
+	// DeleteError is shown next to the delete account form
+	DeleteError string
+}
+
+// AccountPasswordHandler lets a logged-in user change their password
+// The current password is required, and every other session of the account is ended on success
+func AccountPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	sess, user, authed := currentSession(w, r)
+	if !authed {
+		http.Redirect(w, r, withBasePath(r, "/login")+"?next="+url.QueryEscape(buildCurrentURL(r)), http.StatusSeeOther)
+		return
+	}
+
+	data := AccountPageData{
+		LayoutData: newLayoutData(w, r, "Account", user, authed),
+	}
+	if r.Method != http.MethodPost {
+		if r.URL.Query().Get("changed") == "1" {
+			data.Notice = "Password changed. Other devices have been logged out."
+		}
+		renderAccountTemplate(w, r, http.StatusOK, data)
+		return
+	}
+	if !requireCSRF(w, r) {
+		return
+	}
+
+	// Wrong current passwords count as failed logins, so a stolen session can't guess it either
+	emailKey := "email:" + strings.ToLower(user.Email)
+	now := time.Now()
+	if wait := loginEmailLimiter.locked(emailKey, now); wait > 0 {
+		data.Error = "Too many wrong passwords, try again in " + lockoutMinutes(wait) + "."
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Round(time.Second)/time.Second)))
+		renderAccountTemplate(w, r, http.StatusTooManyRequests, data)
+		return
+	}
+
+	current := r.FormValue("current_password")
+	password := r.FormValue("password")
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(current)); err != nil {
+		loginEmailLimiter.fail(emailKey, now)
+		data.Error = "Current password is incorrect."
+		renderAccountTemplate(w, r, http.StatusOK, data)
+		return
+	}
+	// Same password rules as register
+	if len(password) < 8 {
+		data.Error = "Password must be at least 8 characters."
+	} else if r.FormValue("confirm_password") != password {
+		data.Error = "Passwords do not match."
+	}
+	if data.Error != "" {
+		renderAccountTemplate(w, r, http.StatusOK, data)
+		return
+	}
+
+	hashBytes, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, "could not change the password")
+		return
+	}
+	if err := appStore.UpdateUserPassword(r.Context(), user.ID, string(hashBytes)); err != nil {
+		renderError(w, r, http.StatusInternalServerError, "could not change the password")
+		return
+	}
+	// Sessions stolen before the change stop working, this one stays logged in
+	if err := appStore.DeleteSessionsByUserIDExcept(r.Context(), user.ID, sess.TokenHash); err != nil {
+		renderError(w, r, http.StatusInternalServerError, "could not log out other devices")
+		return
+	}
+	loginEmailLimiter.reset(emailKey)
+
+	http.Redirect(w, r, withBasePath(r, "/account/password")+"?changed=1", http.StatusSeeOther)
+}
+
+// AccountDeleteHandler deletes the logged-in account after the password is entered again
+// and the confirmation box is ticked, then logs out and goes home
+func AccountDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		renderError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !requireCSRF(w, r) {
+		return
+	}
+	user, authed := getCurrentUser(w, r)
+	if !authed {
+		http.Redirect(w, r, withBasePath(r, "/login")+"?next="+url.QueryEscape(withBasePath(r, "/account/password")), http.StatusSeeOther)
+		return
+	}
+
+	data := AccountPageData{
+		LayoutData: newLayoutData(w, r, "Account", user, authed),
+	}
+	// The form posts here, links on the re-rendered page should point back at the account page
+	data.CurrentURL = withBasePath(r, "/account/password")
+
+	emailKey := "email:" + strings.ToLower(user.Email)
+	now := time.Now()
+	if wait := loginEmailLimiter.locked(emailKey, now); wait > 0 {
+		data.DeleteError = "Too many wrong passwords, try again in " + lockoutMinutes(wait) + "."
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Round(time.Second)/time.Second)))
+		renderAccountTemplate(w, r, http.StatusTooManyRequests, data)
+		return
+	}
+	if r.FormValue("confirm_delete") != "yes" {
+		data.DeleteError = "Tick the box to confirm you want to delete your account."
+		renderAccountTemplate(w, r, http.StatusOK, data)
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(r.FormValue("password"))); err != nil {
+		loginEmailLimiter.fail(emailKey, now)
+		data.DeleteError = "Password is incorrect."
+		renderAccountTemplate(w, r, http.StatusOK, data)
+		return
+	}
+
+	// Sessions, favorites and reset links are removed by the database along with the user
+	if err := appStore.DeleteUser(r.Context(), user.ID); err != nil {
+		renderError(w, r, http.StatusInternalServerError, "could not delete the account")
+		return
+	}
+	loginEmailLimiter.reset(emailKey)
+
+	clearSessionCookie(w, r)
+	http.Redirect(w, r, withBasePath(r, "/"), http.StatusSeeOther)
+}
+
+// AccountSessionsPageData powers the sessions page
+type AccountSessionsPageData struct {
+	LayoutData
+
+	Sessions []AccountSessionRow
+	Notice   string
+}
+
+// AccountSessionRow is one login of the account, Current marks the one viewing the page
+type AccountSessionRow struct {
+	store.Session
+	Current bool
+}
+
+// AccountSessionsHandler lists the logged-in user's sessions, a POST logs out every other one
+func AccountSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	sess, user, authed := currentSession(w, r)
+	if !authed {
+		http.Redirect(w, r, withBasePath(r, "/login")+"?next="+url.QueryEscape(buildCurrentURL(r)), http.StatusSeeOther)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if !requireCSRF(w, r) {
+			return
+		}
+		if err := appStore.DeleteSessionsByUserIDExcept(r.Context(), user.ID, sess.TokenHash); err != nil {
+			renderError(w, r, http.StatusInternalServerError, "could not log out other devices")
+			return
+		}
+		http.Redirect(w, r, withBasePath(r, "/account/sessions")+"?revoked=1", http.StatusSeeOther)
+		return
+	}
+
+	sessions, err := appStore.ListSessionsByUserID(r.Context(), user.ID)
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, "failed to load sessions")
+		return
+	}
+
+	data := AccountSessionsPageData{
+		LayoutData: newLayoutData(w, r, "Sessions", user, authed),
+	}
+	for _, s := range sessions {
+		// Token hashes are compared, the raw token is never stored
+		data.Sessions = append(data.Sessions, AccountSessionRow{Session: s, Current: s.TokenHash == sess.TokenHash})
+	}
+	if r.URL.Query().Get("revoked") == "1" {
+		data.Notice = "Logged out of every other device."
+	}
+
+	tmpl, err := templateWithLayout("web/templates/account_sessions.gohtml")
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, "template error")
+		return
+	}
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		renderError(w, r, http.StatusInternalServerError, "render error")
+		return
+	}
+}
+
+func renderAccountTemplate(w http.ResponseWriter, r *http.Request, status int, data AccountPageData) {
+	tmpl, err := templateWithLayout("web/templates/account.gohtml")
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, "template error")
+		return
+	}
+
+	w.WriteHeader(status)
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		renderError(w, r, http.StatusInternalServerError, "render error")
+		return
+	}
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAccountPasswordHandlerAnonymous(t *testing.T) {
+	w := httptest.NewRecorder()
+	AccountPasswordHandler(w, httptest.NewRequest(http.MethodGet, "/account/password", nil))
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if got, want := w.Header().Get("Location"), "/login?next="+url.QueryEscape("/account/password"); got != want {
+		t.Fatalf("Location = %q, want %q", got, want)
+	}
+}
+
+func TestAccountPasswordHandlerValidation(t *testing.T) {
+	s := useTestStore(t)
+	user, cookie := newTestAccount(t, s, "validate@example.com", "old-password")
+
+	tests := []struct {
+		name    string
+		current string
+		next    string
+		confirm string
+		want    string
+	}{
+		{"wrong current", "nope", "new-password", "new-password", "Current password is incorrect."},
+		{"too short", "old-password", "short", "short", "Password must be at least 8 characters."},
+		{"mismatch", "old-password", "new-password", "other-password", "Passwords do not match."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			AccountPasswordHandler(w, newFormRequest("/account/password", url.Values{
+				"current_password": {tt.current},
+				"password":         {tt.next},
+				"confirm_password": {tt.confirm},
+			}, cookie))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Fatalf("body does not contain %q", tt.want)
+			}
+		})
+	}
+	loginEmailLimiter.reset("email:" + user.Email)
+
+	got, err := s.GetUserByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(got.PasswordHash), []byte("old-password")) != nil {
+		t.Fatal("a rejected change replaced the password")
+	}
+}
+
+func TestAccountPasswordHandlerChanges(t *testing.T) {
+	s := useTestStore(t)
+	user, cookie := newTestAccount(t, s, "change@example.com", "old-password")
+	other := newTestSession(t, s, user.ID)
+
+	w := httptest.NewRecorder()
+	AccountPasswordHandler(w, newFormRequest("/account/password", url.Values{
+		"current_password": {"old-password"},
+		"password":         {"new-password"},
+		"confirm_password": {"new-password"},
+	}, cookie))
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if got, want := w.Header().Get("Location"), "/account/password?changed=1"; got != want {
+		t.Fatalf("Location = %q, want %q", got, want)
+	}
+
+	got, err := s.GetUserByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(got.PasswordHash), []byte("new-password")) != nil {
+		t.Fatal("the new password does not match the stored hash")
+	}
+	if cost, _ := bcrypt.Cost([]byte(got.PasswordHash)); cost != passwordHashCost {
+		t.Fatalf("bcrypt cost = %d, want %d", cost, passwordHashCost)
+	}
+	if !sessionAlive(t, s, cookie) {
+		t.Fatal("the session that changed the password was logged out")
+	}
+	if sessionAlive(t, s, other) {
+		t.Fatal("another session survived the password change")
+	}
+}
+
+func TestAccountDeleteHandlerAnonymous(t *testing.T) {
+	w := httptest.NewRecorder()
+	AccountDeleteHandler(w, newFormRequest("/account/delete", url.Values{"confirm_delete": {"yes"}}))
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if got, want := w.Header().Get("Location"), "/login?next="+url.QueryEscape("/account/password"); got != want {
+		t.Fatalf("Location = %q, want %q", got, want)
+	}
+}
+
+func TestAccountDeleteHandlerMethod(t *testing.T) {
+	w := httptest.NewRecorder()
+	AccountDeleteHandler(w, httptest.NewRequest(http.MethodGet, "/account/delete", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestAccountDeleteHandlerGuards(t *testing.T) {
+	s := useTestStore(t)
+	user, cookie := newTestAccount(t, s, "keep@example.com", "the-password")
+	t.Cleanup(func() { loginEmailLimiter.reset("email:" + user.Email) })
+
+	tests := []struct {
+		name     string
+		password string
+		confirm  string
+		want     string
+	}{
+		{"unconfirmed", "the-password", "", "Tick the box to confirm you want to delete your account."},
+		{"wrong password", "nope", "yes", "Password is incorrect."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			AccountDeleteHandler(w, newFormRequest("/account/delete", url.Values{
+				"password":       {tt.password},
+				"confirm_delete": {tt.confirm},
+			}, cookie))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Fatalf("body does not contain %q", tt.want)
+			}
+		})
+	}
+
+	if _, err := s.GetUserByID(context.Background(), user.ID); err != nil {
+		t.Fatalf("a rejected delete removed the account: %v", err)
+	}
+	if !sessionAlive(t, s, cookie) {
+		t.Fatal("a rejected delete logged the user out")
+	}
+}
+
+func TestAccountDeleteHandlerDeletes(t *testing.T) {
+	s := useTestStore(t)
+	user, cookie := newTestAccount(t, s, "delete@example.com", "the-password")
+	other := newTestSession(t, s, user.ID)
+	if _, err := s.AddFavorites(context.Background(), user.ID, "groupie", []string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	AccountDeleteHandler(w, newFormRequest("/account/delete", url.Values{
+		"password":       {"the-password"},
+		"confirm_delete": {"yes"},
+	}, cookie))
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if got := w.Header().Get("Location"); got != "/" {
+		t.Fatalf("Location = %q, want /", got)
+	}
+	if _, err := s.GetUserByID(context.Background(), user.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetUserByID err = %v, want sql.ErrNoRows", err)
+	}
+	if sessionAlive(t, s, cookie) || sessionAlive(t, s, other) {
+		t.Fatal("a session survived the account deletion")
+	}
+	if hasSessionCookie(w) {
+		t.Fatal("the session cookie was not cleared")
+	}
+}
+
+func TestAccountSessionsHandlerAnonymous(t *testing.T) {
+	w := httptest.NewRecorder()
+	AccountSessionsHandler(w, httptest.NewRequest(http.MethodGet, "/account/sessions", nil))
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if got, want := w.Header().Get("Location"), "/login?next="+url.QueryEscape("/account/sessions"); got != want {
+		t.Fatalf("Location = %q, want %q", got, want)
+	}
+}
+
+func TestAccountSessionsHandlerLists(t *testing.T) {
+	s := useTestStore(t)
+	user, cookie := newTestAccount(t, s, "devices@example.com", "the-password")
+	other := newTestSession(t, s, user.ID)
+
+	r := httptest.NewRequest(http.MethodGet, "/account/sessions", nil)
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	AccountSessionsHandler(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	// Only the session viewing the page is marked, and revoking is offered with two sessions
+	if n := strings.Count(body, "This device"); n != 1 {
+		t.Fatalf("%d sessions marked as this device, want 1", n)
+	}
+	if !strings.Contains(body, "Log out everywhere else") {
+		t.Fatal("the log out everywhere else form is missing")
+	}
+	if !sessionAlive(t, s, other) {
+		t.Fatal("listing sessions revoked one")
+	}
+}
+
+func TestAccountSessionsHandlerRevokesOthers(t *testing.T) {
+	s := useTestStore(t)
+	user, cookie := newTestAccount(t, s, "revoke@example.com", "the-password")
+	others := []*http.Cookie{newTestSession(t, s, user.ID), newTestSession(t, s, user.ID)}
+	_, strangerCookie := newTestAccount(t, s, "stranger@example.com", "the-password")
+
+	// Without the form token nothing is revoked
+	r := httptest.NewRequest(http.MethodPost, "/account/sessions", nil)
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	AccountSessionsHandler(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("without a token: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if !sessionAlive(t, s, others[0]) {
+		t.Fatal("a cross-site post revoked a session")
+	}
+
+	w = httptest.NewRecorder()
+	AccountSessionsHandler(w, newFormRequest("/account/sessions", nil, cookie))
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if got, want := w.Header().Get("Location"), "/account/sessions?revoked=1"; got != want {
+		t.Fatalf("Location = %q, want %q", got, want)
+	}
+	if !sessionAlive(t, s, cookie) {
+		t.Fatal("the current session was revoked")
+	}
+	for i, c := range others {
+		if sessionAlive(t, s, c) {
+			t.Errorf("other session %d survived", i)
+		}
+	}
+	if !sessionAlive(t, s, strangerCookie) {
+		t.Fatal("another account's session was revoked")
+	}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"palasgroupietracker/internal/store"
+)
+
+// adminReportsPageSize is how many reports one admin page lists
+const adminReportsPageSize = 50
+
+// maxAdminReportsPage bounds `page` far beyond any real report count, so the offset can't overflow
+const maxAdminReportsPage = 100_000
+
+type AdminReportsPageData struct {
+	LayoutData
+
+	Reports    []AdminReportRow
+	IssueTypes []ReportIssueType
+	Issue      string
+	Status     string
+	Total      int
+	Page       int
+	PrevURL    string
+	NextURL    string
+}
+
+// AdminReportRow is a report with the links and labels the review table shows
+type AdminReportRow struct {
+	store.Report
+	IssueLabel string
+	ArtistURL  string
+}
+
+// adminToken is the shared secret for `/admin/*`, the admin pages are disabled when it is empty
+func adminToken() string {
+	return strings.TrimSpace(os.Getenv("ADMIN_TOKEN"))
+}
+
+// requireAdmin checks ADMIN_TOKEN, sent as a Basic auth password (any user name) or a Bearer token
+// It writes the error response and returns false when the request isn't allowed
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	want := adminToken()
+	if want == "" {
+		// Without a token there is no admin area at all
+		NotFound(w, r)
+		return false
+	}
+
+	got := ""
+	if _, pass, ok := r.BasicAuth(); ok {
+		got = pass
+	} else if v, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		got = strings.TrimSpace(v)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+		renderError(w, r, http.StatusUnauthorized, "admin token required")
+		return false
+	}
+
+	// Keep review pages out of shared caches and search engines
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	return true
+}
+
+// AdminReportsHandler lists submitted reports, filtered by `issue` and `status` and paged by `page`
+func AdminReportsHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	if appStore == nil {
+		renderError(w, r, http.StatusServiceUnavailable, "database not configured")
+		return
+	}
+
+	q := r.URL.Query()
+	issue := strings.TrimSpace(q.Get("issue"))
+	if !isReportIssueType(issue) {
+		issue = ""
+	}
+	status := normalizeReportStatus(q.Get("status"))
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil {
+		page = 1
+	}
+	page = clampInt(page, 1, maxAdminReportsPage)
+
+	filter := store.ReportFilter{
+		IssueType: issue,
+		Status:    status,
+		Limit:     adminReportsPageSize,
+		Offset:    (page - 1) * adminReportsPageSize,
+	}
+	if filter.Status == "all" {
+		filter.Status = ""
+	}
+	reports, total, err := appStore.ListReports(r.Context(), filter)
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, "failed to load reports")
+		return
+	}
+
+	basePath := getBasePath(r)
+	rows := make([]AdminReportRow, 0, len(reports))
+	for _, rep := range reports {
+		rows = append(rows, AdminReportRow{
+			Report:     rep,
+			IssueLabel: reportIssueLabel(rep.IssueType),
+			ArtistURL:  basePath + "/artists/" + url.PathEscape(rep.ArtistID) + "?source=" + url.QueryEscape(rep.Source),
+		})
+	}
+
+	user, authed := getCurrentUser(w, r)
+	data := AdminReportsPageData{
+		LayoutData: newLayoutData(w, r, "Reports", user, authed),
+		Reports:    rows,
+		IssueTypes: reportIssueTypes,
+		Issue:      issue,
+		Status:     status,
+		Total:      total,
+		Page:       page,
+	}
+	if page > 1 {
+		data.PrevURL = adminReportsURL(r, issue, status, page-1)
+	}
+	if page*adminReportsPageSize < total {
+		data.NextURL = adminReportsURL(r, issue, status, page+1)
+	}
+
+	tmpl, err := templateWithLayout("web/templates/admin_reports.gohtml")
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, "template error")
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		renderError(w, r, http.StatusInternalServerError, "render error")
+		return
+	}
+}
+
+// AdminResolveReportHandler marks a report resolved (`action=reopen` undoes it) and goes back to the list
+func AdminResolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		renderError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	// Browsers resend Basic auth on cross-site posts too, so the token is still needed
+	if !requireCSRF(w, r) {
+		return
+	}
+	if appStore == nil {
+		renderError(w, r, http.StatusServiceUnavailable, "database not configured")
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimSpace(r.FormValue("id")), 10, 64)
+	if err != nil || id <= 0 {
+		renderError(w, r, http.StatusBadRequest, "invalid report id")
+		return
+	}
+
+	resolved := r.FormValue("action") != "reopen"
+	if err := appStore.ResolveReport(r.Context(), id, resolved); err != nil {
+		if errors.Is(err, store.ErrReportNotFound) {
+			renderError(w, r, http.StatusNotFound, "report not found")
+			return
+		}
+		renderError(w, r, http.StatusInternalServerError, "failed to update report")
+		return
+	}
+
+	redirectTo := resolveNextURL(r.FormValue("redirect"), r)
+	if !strings.HasPrefix(redirectTo, withBasePath(r, "/admin/reports")) {
+		redirectTo = withBasePath(r, "/admin/reports")
+	}
+	http.Redirect(w, r, redirectTo, http.StatusSeeOther)
+}
+
+// normalizeReportStatus defaults to open reports, "all" lists both
+func normalizeReportStatus(v string) string {
+	switch v = strings.ToLower(strings.TrimSpace(v)); v {
+	case store.ReportStatusResolved, "all":
+		return v
+	default:
+		return store.ReportStatusOpen
+	}
+}
+
+func reportIssueLabel(v string) string {
+	for _, t := range reportIssueTypes {
+		if t.Value == v {
+			return t.Label
+		}
+	}
+	return v
+}
+
+// adminReportsURL links to another page of the list with the same filters
+func adminReportsURL(r *http.Request, issue, status string, page int) string {
+	q := url.Values{}
+	if issue != "" {
+		q.Set("issue", issue)
+	}
+	q.Set("status", status)
+	q.Set("page", strconv.Itoa(page))
+	return withBasePath(r, "/admin/reports") + "?" + q.Encode()
+}
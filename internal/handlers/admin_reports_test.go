@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name, token string
+		// user and pass are sent as Basic auth when basic is set
+		basic      bool
+		user, pass string
+		auth       string
+		want       int
+	}{
+		// Without ADMIN_TOKEN the admin area doesn't exist, whatever is sent
+		{name: "no token, no credentials", want: http.StatusNotFound},
+		{name: "no token, empty bearer", auth: "Bearer ", want: http.StatusNotFound},
+		{name: "no token, empty basic", basic: true, want: http.StatusNotFound},
+		{name: "blank token", token: "   ", auth: "Bearer    ", want: http.StatusNotFound},
+
+		{name: "missing credentials", token: "s3cret", want: http.StatusUnauthorized},
+		{name: "basic", token: "s3cret", basic: true, user: "admin", pass: "s3cret", want: http.StatusOK},
+		{name: "basic, any user name", token: "s3cret", basic: true, user: "reviewer", pass: "s3cret", want: http.StatusOK},
+		{name: "basic, empty user name", token: "s3cret", basic: true, pass: "s3cret", want: http.StatusOK},
+		{name: "basic, wrong password", token: "s3cret", basic: true, user: "admin", pass: "nope", want: http.StatusUnauthorized},
+		{name: "basic, token as user name", token: "s3cret", basic: true, user: "s3cret", want: http.StatusUnauthorized},
+		{name: "basic, prefix of the token", token: "s3cret", basic: true, pass: "s3c", want: http.StatusUnauthorized},
+		{name: "bearer", token: "s3cret", auth: "Bearer s3cret", want: http.StatusOK},
+		{name: "bearer with spaces", token: "  s3cret  ", auth: "Bearer  s3cret ", want: http.StatusOK},
+		{name: "bearer, wrong token", token: "s3cret", auth: "Bearer nope", want: http.StatusUnauthorized},
+		{name: "bearer, empty", token: "s3cret", auth: "Bearer ", want: http.StatusUnauthorized},
+		{name: "bare token", token: "s3cret", auth: "s3cret", want: http.StatusUnauthorized},
+		{name: "other scheme", token: "s3cret", auth: "Token s3cret", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ADMIN_TOKEN", tt.token)
+			r := httptest.NewRequest(http.MethodGet, "/admin/reports", nil)
+			if tt.basic {
+				r.SetBasicAuth(tt.user, tt.pass)
+			} else if tt.auth != "" {
+				r.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			ok := requireAdmin(w, r)
+
+			if ok != (tt.want == http.StatusOK) {
+				t.Fatalf("requireAdmin = %v, want status %d", ok, tt.want)
+			}
+			if ok {
+				if got := w.Header().Get("Cache-Control"); got != "no-store" {
+					t.Fatalf("Cache-Control = %q, want no-store", got)
+				}
+				return
+			}
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			// Only a real challenge asks for credentials, a disabled admin area stays hidden
+			if got := w.Header().Get("WWW-Authenticate"); (got != "") != (tt.want == http.StatusUnauthorized) {
+				t.Fatalf("WWW-Authenticate = %q with status %d", got, w.Code)
+			}
+		})
+	}
+}
+
+func TestAdminReportsHandlerClampsPage(t *testing.T) {
+	useTestStore(t)
+	t.Setenv("ADMIN_TOKEN", "s3cret")
+
+	// Pages past the end, negative or big enough to overflow the offset still render
+	for _, page := range []string{"0", "-3", "99", "9223372036854775807", "x"} {
+		r := httptest.NewRequest(http.MethodGet, "/admin/reports?page="+page, nil)
+		r.Header.Set("Authorization", "Bearer s3cret")
+		w := httptest.NewRecorder()
+		AdminReportsHandler(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("page=%s: status = %d, want %d", page, w.Code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// handleArtistAlbums renders the next page of album cards for the detail page "show more" button
+// Served under `/artists/{id}/albums?source=&offset=&limit=` and returns an HTML fragment
+func handleArtistAlbums(w http.ResponseWriter, r *http.Request, idSegment string) {
+	if r.Method != http.MethodGet {
+		renderError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	source := getSource(r)
+	pager, ok := sourceFor(source).(albumPager)
+	if !ok {
+		// Groupie artists have no discography to page through
+		renderError(w, r, http.StatusBadRequest, "albums are not available for this source")
+		return
+	}
+	_, maxAlbums := detailMaxima(source)
+
+	offset := 0
+	if raw := strings.TrimSpace(r.URL.Query().Get("offset")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			renderError(w, r, http.StatusBadRequest, "invalid offset")
+			return
+		}
+		offset = v
+	}
+
+	limit := envPositiveInt("DETAIL_ALBUMS_LIMIT", defaultDetailAlbums)
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			renderError(w, r, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = v
+	}
+	limit = clampInt(limit, 1, maxAlbums)
+
+	cardTemplate, cards, err := pager.AlbumCards(idSegment, offset, limit)
+	if err != nil {
+		if errors.Is(err, errInvalidArtistID) {
+			renderError(w, r, http.StatusBadRequest, "invalid artist id")
+			return
+		}
+		renderError(w, r, http.StatusBadGateway, "failed to load albums")
+		return
+	}
+
+	tmpl, err := template.ParseFiles("web/templates/artist_detail.gohtml")
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, "template error")
+		return
+	}
+
+	// Render into a buffer so a template failure doesn't leave a half-written fragment
+	var buf bytes.Buffer
+	for _, c := range cards {
+		if err := tmpl.ExecuteTemplate(&buf, cardTemplate, c); err != nil {
+			renderError(w, r, http.StatusInternalServerError, "render error")
+			return
+		}
+	}
+
+	// A full page means there may be more, the client stops once a short page comes back
+	hasMore := "0"
+	if len(cards) == limit {
+		hasMore = "1"
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Has-More", hasMore)
+	w.Header().Set("X-Next-Offset", strconv.Itoa(offset+len(cards)))
+	_, _ = w.Write(buf.Bytes())
+}
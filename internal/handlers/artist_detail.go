@@ -2,14 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"html/template"
 	"net/http"
-	"path"
-	"sort"
 	"strconv"
 	"strings"
-	"sync"
-	"unicode"
 
 	"palasgroupietracker/internal/api"
 	"palasgroupietracker/internal/geo"
@@ -17,26 +14,47 @@ import (
 )
 
 type MapLocation struct {
-	Name  string   `json:"name"`
-	Lat   float64  `json:"lat"`
-	Lng   float64  `json:"lng"`
-	Dates []string `json:"dates"`
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lng  float64 `json:"lng"`
+	// Dates are YYYY-MM-DD (raw when unparseable), sorted, Labels are their display forms
+	Dates  []string `json:"dates"`
+	Labels []string `json:"labels"`
+	// HasUpcoming marks locations with a concert today or later, the map highlights them
+	HasUpcoming bool `json:"has_upcoming"`
+	// Upcoming (soonest first) and Past (most recent first) are display labels split around today
+	Upcoming []string `json:"upcoming"`
+	Past     []string `json:"past"`
+}
+
+// ConcertStop is one Groupie tour location with all its dates, listed on the printable sheet
+// Dates are display labels, in chronological order
+type ConcertStop struct {
+	Location string
+	Dates    []string
 }
 
 // Shared geocoder instance keeps a warm cache across requests
+// SetGeocoder swaps in the disk-backed one at startup
 var groupieGeocoder = geo.NewGeocoder()
 
-type ArtistDetailPageData struct {
-	Title      string
-	Source     string
-	ActiveNav  string
-	BasePath   string
-	CurrentURL string
-	User       *store.User
-	IsAuthed   bool
-	IsFavorite bool
+// AlbumGroup is a labeled discography section (Albums, Singles & EPs, Compilations)
+type AlbumGroup[T any] struct {
+	Label  string
+	Albums []T
+}
+
+// ArtistDetail is what a source loads for the detail page, only its own provider's fields are set
+type ArtistDetail struct {
+	// name is searched on Wikipedia, news and YouTube and titles the page
+	name string
+	// musicGroup is the JSON-LD for the artist, the handler adds the Wikipedia link
+	musicGroup musicGroupLD
+
 	FavoriteID string
 	Artist     *api.Artist
+	// Members mirrors Artist.Members with optional photos (Groupie only)
+	Members []GroupieMember
 
 	SpotifyArtist           *api.SpotifyArtist
 	SpotifyGenre            string
@@ -44,6 +62,7 @@ type ArtistDetailPageData struct {
 	SpotifyMonthlyListeners int
 	SpotifyTopTracks        []api.SpotifyTrack
 	SpotifyLatestAlbums     []api.SpotifyAlbum
+	SpotifyAlbumGroups      []AlbumGroup[api.SpotifyAlbum]
 
 	DeezerArtist           *api.DeezerArtist
 	DeezerFans             int
@@ -52,6 +71,7 @@ type ArtistDetailPageData struct {
 	DeezerMonthlyListeners int
 	DeezerTopTracks        []api.DeezerTrack
 	DeezerLatestAlbums     []api.DeezerAlbum
+	DeezerAlbumGroups      []AlbumGroup[api.DeezerAlbum]
 
 	AppleArtist           *api.AppleArtist
 	AppleGenre            string
@@ -60,552 +80,340 @@ type ArtistDetailPageData struct {
 	AppleTopTracks        []api.AppleTrack
 	AppleLatestAlbums     []api.AppleAlbum
 
-	LocationsJSON template.JS
-	WikiSummary   string
-	WikiURL       string
-	HasWiki       bool
+	MusicBrainzArtist   *api.MusicBrainzArtist
+	MusicBrainzTags     []string
+	MusicBrainzLifeSpan string
+
+	// Counts requested from the providers, used to build "show more" links
+	TracksLimit int
+	AlbumsLimit int
+
+	// locations are the geocoded tour stops for the map (Groupie only)
+	locations []MapLocation
+	// Concerts lists every tour stop, including ones missing from the map (Groupie only)
+	Concerts []ConcertStop
+	// UpcomingConcerts and PastConcerts split the tour stops around today (Groupie only)
+	UpcomingConcerts []ConcertStop
+	PastConcerts     []ConcertStop
+	// sharedConcerts maps concert days to other artists playing them (Groupie only)
+	sharedConcerts map[string][]SharedConcert
+
+	// LyricsEnabled shows the per-track lyrics buttons, backed by `/lyrics`
+	LyricsEnabled bool
 }
 
-// ArtistDetailHandler routes to the correct detail handler based on the `source` query parameter
-func ArtistDetailHandler(w http.ResponseWriter, r *http.Request) {
-	source := getSource(r)
-	// The router is registered as `/artists/`, so the last segment is the ID
-	idSegment := path.Base(r.URL.Path)
-	user, authed := getCurrentUser(w, r)
+type ArtistDetailPageData struct {
+	LayoutData
+	ArtistDetail
 
-	if idSegment == "" || idSegment == "artists" {
-		// `/artists/` without an ID should go back to the list page
-		http.Redirect(w, r, withBasePath(r, "/artists")+"?source="+source, http.StatusSeeOther)
-		return
-	}
+	IsFavorite bool
 
-	if source == "spotify" {
-		handleSpotifyArtistDetail(w, r, idSegment, user, authed)
-		return
-	}
-	if source == "deezer" {
-		handleDeezerArtistDetail(w, r, idSegment, user, authed)
-		return
-	}
-	if source == "apple" {
-		handleAppleArtistDetail(w, r, idSegment, user, authed)
-		return
-	}
+	// MusicBrainzHeroImage is the Wikipedia image or the placeholder, MusicBrainz has no artwork
+	MusicBrainzHeroImage string
 
-	handleGroupieArtistDetail(w, r, idSegment, user, authed)
-}
+	// LocationsJSON is embedded into a script tag for the Leaflet map
+	LocationsJSON template.JS
+	// SharedConcertsJSON feeds the "also playing" lists of the concert days
+	SharedConcertsJSON template.JS
 
-// handleGroupieArtistDetail renders the detail page for artists from the Groupie Tracker dataset
-func handleGroupieArtistDetail(w http.ResponseWriter, r *http.Request, idSegment string, user *store.User, authed bool) {
-	id, err := strconv.Atoi(idSegment)
-	if err != nil || id <= 0 {
-		// IDs are numeric in Groupie mode
-		http.Redirect(w, r, withBasePath(r, "/artists")+"?source=groupie", http.StatusSeeOther)
-		return
-	}
+	WikiSummary string
+	WikiURL     string
+	HasWiki     bool
+	// WikiImageURL is the Wikipedia lead image, shown when the provider has no artwork
+	WikiImageURL string
 
-	artist, err := api.FetchArtistByID(id)
-	if err != nil {
-		NotFound(w, r)
-		return
-	}
+	// News holds recent headlines when ARTIST_NEWS_FEED_URL is configured
+	News []api.ArtistNewsItem
 
-	relation, err := api.FetchRelationForArtist(id)
-	if err != nil {
-		http.Error(w, "failed to load concerts", http.StatusInternalServerError)
-		return
-	}
+	// YouTubeVideo is embedded when YOUTUBE_API_KEY is set, otherwise only the search link is shown
+	YouTubeVideo     *api.YouTubeVideo
+	YouTubeSearchURL string
 
-	var locations []MapLocation
-	// Sort keys for stable output and predictable map ordering
-	keys := make([]string, 0, len(relation.DatesLocations))
-	for k := range relation.DatesLocations {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	const maxLocations = 25
-	if len(keys) > maxLocations {
-		// Avoid geocoding too many points in one request
-		keys = keys[:maxLocations]
-	}
-
-	locations = make([]MapLocation, 0, len(keys))
-	var mu sync.Mutex
-	// Cap concurrency since geocoding calls external providers
-	sem := make(chan struct{}, 4)
-	var wg sync.WaitGroup
-
-	for _, name := range keys {
-		dates := relation.DatesLocations[name]
-		// Convert Groupie location keys into a geocoding-friendly query
-		place, countryCode, display := geo.QueryFromLocationKey(name)
-
-		wg.Add(1)
-		go func(place, countryCode, display string, dates []string) { // geocode locations concurrently
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }() // release concurrency slot
-
-			res, ok, geoErr := groupieGeocoder.Geocode(r.Context(), place, countryCode)
-			if geoErr != nil || !ok {
-				// Missing geocodes are expected for noisy location strings
-				return
-			}
-
-			if strings.TrimSpace(res.Display) == "" {
-				// Fall back to our own label if the provider didn't return one
-				res.Display = display
-			}
-
-			mu.Lock()
-			locations = append(locations, MapLocation{
-				Name:  res.Display,
-				Lat:   res.Lat,
-				Lng:   res.Lng,
-				Dates: dates,
-			})
-			mu.Unlock()
-		}(place, countryCode, display, dates)
-	}
-
-	wg.Wait()
-
-	// Sort final locations alphabetically for consistent popups
-	sort.SliceStable(locations, func(i, j int) bool { // case-insensitive by display name
-		return strings.ToLower(locations[i].Name) < strings.ToLower(locations[j].Name)
-	})
+	// StructuredData is the schema.org MusicGroup JSON-LD for search engines
+	StructuredData template.JS
 
-	locBytes, err := json.Marshal(locations)
-	if err != nil {
-		http.Error(w, "failed to encode concerts", http.StatusInternalServerError)
-		return
-	}
+	// The "Report a problem" form posts to `/report`, it needs the database
+	ReportsEnabled   bool
+	ReportIssueTypes []ReportIssueType
+	ReportNoteMaxLen int
+	Reported         bool
 
-	// Wikipedia is best-effort, the page should still render without it
-	wikiSummary, wikiURL, wikiErr := api.FetchWikipediaSummary(artist.Name)
-	hasWiki := wikiErr == nil && wikiSummary != "" && wikiURL != ""
+	// Locale formats counts and dates for the visitor, from Accept-Language
+	Locale *Locale
+}
 
-	tmpl, err := template.ParseFiles(
-		"web/templates/layout.gohtml",
-		"web/templates/artist_detail.gohtml",
-	)
-	if err != nil {
-		http.Error(w, "template error", http.StatusInternalServerError)
-		return
+// fetchArtistWiki looks up the artist's Wikipedia summary, Wikipedia is best-effort so failures
+// return an empty summary and the page renders without it
+func fetchArtistWiki(name string) api.WikiSummary {
+	wiki, err := api.FetchWikipediaSummary(name)
+	if err != nil || wiki == nil {
+		return api.WikiSummary{}
 	}
+	return *wiki
+}
 
-	data := ArtistDetailPageData{
-		Title:      artist.Name,
-		Source:     "groupie",
-		ActiveNav:  "artists",
-		BasePath:   getBasePath(r),
-		CurrentURL: buildCurrentURL(r),
-		User:       user,
-		IsAuthed:   authed,
-		IsFavorite: isFavorite(r, user, "groupie", idSegment),
-		FavoriteID: idSegment,
-		Artist:     artist,
-
-		SpotifyArtist:           nil,
-		SpotifyGenre:            "",
-		SpotifyFollowers:        0,
-		SpotifyMonthlyListeners: 0,
-		SpotifyTopTracks:        nil,
-		SpotifyLatestAlbums:     nil,
-
-		DeezerArtist:           nil,
-		DeezerFans:             0,
-		DeezerAlbumsCount:      0,
-		DeezerHasRadio:         false,
-		DeezerMonthlyListeners: 0,
-		DeezerTopTracks:        nil,
-		DeezerLatestAlbums:     nil,
-
-		AppleArtist:           nil,
-		AppleGenre:            "",
-		AppleMonthlyListeners: 0,
-		AppleHeroImage:        "",
-		AppleTopTracks:        nil,
-		AppleLatestAlbums:     nil,
-
-		// LocationsJSON is embedded into a script tag for the Leaflet map
-		LocationsJSON: template.JS(locBytes),
-		WikiSummary:   wikiSummary,
-		WikiURL:       wikiURL,
-		HasWiki:       hasWiki,
-	}
-
-	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
-		http.Error(w, "render error", http.StatusInternalServerError)
-		return
+// fetchArtistVideo looks up the artist's top YouTube video, nil when disabled or unavailable
+func fetchArtistVideo(name string) *api.YouTubeVideo {
+	video, err := api.FetchTopYouTubeVideo(name)
+	if err != nil {
+		return nil
 	}
+	return video
 }
 
-// handleSpotifyArtistDetail renders the detail page for a Spotify artist ID
-func handleSpotifyArtistDetail(w http.ResponseWriter, r *http.Request, idSegment string, user *store.User, authed bool) {
-	if !isLikelySpotifyID(idSegment) {
-		// Protect the API from random strings and keep URLs predictable
-		http.Redirect(w, r, withBasePath(r, "/artists")+"?source=spotify", http.StatusSeeOther)
-		return
-	}
+// artistNewsLimit is how many headlines the "Latest news" section shows
+const artistNewsLimit = 5
 
-	artist, err := api.GetSpotifyArtist(idSegment)
+// fetchArtistNews loads headlines for the detail page, news is optional so failures render nothing
+func fetchArtistNews(name string) []api.ArtistNewsItem {
+	news, err := api.FetchArtistNews(name, artistNewsLimit)
 	if err != nil {
-		if isNotFoundError(err) {
+		return nil
+	}
+	return news
+}
+
+// ArtistDetailHandler routes to the correct detail handler based on the `source` query parameter
+func ArtistDetailHandler(w http.ResponseWriter, r *http.Request) {
+	source := getSource(r)
+
+	// `/artists/{id}/albums` serves extra album cards for the "show more" button
+	if rest := strings.TrimPrefix(r.URL.Path, "/artists/"); strings.HasSuffix(rest, "/albums") {
+		idSegment := strings.TrimSuffix(rest, "/albums")
+		if !isArtistIDSegment(idSegment) {
 			NotFound(w, r)
 			return
 		}
-		http.Error(w, "failed to load spotify artist", http.StatusInternalServerError)
+		handleArtistAlbums(w, r, idSegment)
 		return
 	}
 
-	// Non-Groupie sources don't have concert locations
-	emptyLocations, err := json.Marshal([]MapLocation{})
-	if err != nil {
-		http.Error(w, "failed to encode concerts", http.StatusInternalServerError)
+	// `/artists/{id}/concerts.ics` exports the Groupie tour as a calendar
+	if rest := strings.TrimPrefix(r.URL.Path, "/artists/"); strings.HasSuffix(rest, "/concerts.ics") {
+		idSegment := strings.TrimSuffix(rest, "/concerts.ics")
+		if !isArtistIDSegment(idSegment) {
+			NotFound(w, r)
+			return
+		}
+		handleArtistConcertsICS(w, r, idSegment)
 		return
 	}
 
-	wikiSummary, wikiURL, wikiErr := api.FetchWikipediaSummary(artist.Name)
-	hasWiki := wikiErr == nil && wikiSummary != "" && wikiURL != ""
-
-	genre := ""
-	if len(artist.Genres) > 0 {
-		// Capitalize the first genre for nicer display
-		runes := []rune(artist.Genres[0])
-		if len(runes) > 0 {
-			runes[0] = unicode.ToUpper(runes[0])
+	// `/artists/{id}/locations.geojson` exports the geocoded tour stops for other map tools
+	if rest := strings.TrimPrefix(r.URL.Path, "/artists/"); strings.HasSuffix(rest, "/locations.geojson") {
+		idSegment := strings.TrimSuffix(rest, "/locations.geojson")
+		if !isArtistIDSegment(idSegment) {
+			renderJSONError(w, r, http.StatusNotFound, "artist not found")
+			return
 		}
-		genre = string(runes)
-	}
-
-	listeners, err := api.FetchArtistMonthlyListeners(artist.Name)
-	if err != nil {
-		// Last.fm can fail independently, keep the rest of the page
-		listeners = 0
-	}
-
-	followers := 0
-	if artist.Followers != nil {
-		followers = artist.Followers.Total
+		// Other map tools fetch it from their own origin, like the JSON API
+		CORS(func(w http.ResponseWriter, r *http.Request) {
+			handleArtistLocationsGeoJSON(w, r, idSegment)
+		})(w, r)
+		return
 	}
 
-	topTracks, err := api.GetSpotifyArtistTopTracks(artist.ID, "FR")
-	if err != nil {
-		// Tracks are optional for the page to work
-		topTracks = nil
+	// The router is registered as `/artists/`, so what follows it is the ID
+	// `/artists/{id}/sheet` renders the same data as a printable page
+	idSegment := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/artists/"), "/sheet")
+	if idSegment == "" {
+		// `/artists/` without an ID should go back to the list page, TrimTrailingSlash
+		// already does that for GET so this is for other methods
+		http.Redirect(w, r, withBasePath(r, "/artists")+"?source="+source, http.StatusSeeOther)
+		return
 	}
-
-	latestAlbums, err := api.GetSpotifyArtistAlbums(artist.ID, "FR", 8)
-	if err != nil {
-		latestAlbums = nil
+	if !isArtistIDSegment(idSegment) {
+		NotFound(w, r)
+		return
 	}
 
-	if len(latestAlbums) > 1 {
-		// Keep album ordering deterministic even if the API ordering changes
-		sort.SliceStable(latestAlbums, func(i, j int) bool { // newest first, then stable tie-breakers
-			di, okI := api.ParseSpotifyReleaseDate(latestAlbums[i].ReleaseDate)
-			dj, okJ := api.ParseSpotifyReleaseDate(latestAlbums[j].ReleaseDate)
-
-			if okI && okJ && !di.Equal(dj) {
-				return di.After(dj)
-			}
-			if okI != okJ {
-				return okI
-			}
+	user, authed := getCurrentUser(w, r)
+	handleArtistDetail(w, r, sourceFor(source), idSegment, user, authed)
+}
 
-			ni := strings.ToLower(latestAlbums[i].Name)
-			nj := strings.ToLower(latestAlbums[j].Name)
-			if ni != nj {
-				return ni < nj
-			}
+// reservedArtistSegments are `/artists/...` names that are routes of their own, never IDs
+// `/artists/ajax/` or `/artists/suggest/x` fall through to the prefix handler and must not
+// be looked up as artists
+var reservedArtistSegments = map[string]bool{
+	"ajax":    true,
+	"suggest": true,
+	"artists": true,
+}
 
-			return latestAlbums[i].ID < latestAlbums[j].ID
-		})
-	}
+// isArtistIDSegment reports whether s, the part of a `/artists/` path naming the artist,
+// can be an ID: one non-empty path segment that isn't a reserved route name
+func isArtistIDSegment(s string) bool {
+	return s != "" && !strings.Contains(s, "/") && !reservedArtistSegments[strings.ToLower(s)]
+}
 
-	tmpl, err := template.ParseFiles(
-		"web/templates/layout.gohtml",
-		"web/templates/artist_detail.gohtml",
-	)
-	if err != nil {
-		http.Error(w, "template error", http.StatusInternalServerError)
+// handleArtistDetail loads the artist from src, adds the lookups every source shares and renders the page
+func handleArtistDetail(w http.ResponseWriter, r *http.Request, src Source, idSegment string, user *store.User, authed bool) {
+	timing := newServerTiming()
+	ctx := withServerTiming(r.Context(), timing)
+
+	tracks, albums := detailCounts(r, src.Name())
+	detail, err := src.GetDetail(ctx, idSegment, DetailOptions{
+		Tracks: tracks,
+		Albums: albums,
+		Sheet:  isArtistSheet(r),
+		Locale: resolveLocale(r),
+	})
+	switch {
+	case errors.Is(err, errInvalidArtistID):
+		// Protect the APIs from random strings and keep URLs predictable
+		http.Redirect(w, r, withBasePath(r, "/artists")+"?source="+src.Name(), http.StatusSeeOther)
 		return
-	}
-
-	data := ArtistDetailPageData{
-		Title:      artist.Name,
-		Source:     "spotify",
-		ActiveNav:  "artists",
-		BasePath:   getBasePath(r),
-		CurrentURL: buildCurrentURL(r),
-		User:       user,
-		IsAuthed:   authed,
-		IsFavorite: isFavorite(r, user, "spotify", idSegment),
-		FavoriteID: idSegment,
-		Artist:     nil,
-
-		SpotifyArtist:           artist,
-		SpotifyGenre:            genre,
-		SpotifyFollowers:        followers,
-		SpotifyMonthlyListeners: listeners,
-		SpotifyTopTracks:        topTracks,
-		SpotifyLatestAlbums:     latestAlbums,
-
-		DeezerArtist:           nil,
-		DeezerFans:             0,
-		DeezerAlbumsCount:      0,
-		DeezerHasRadio:         false,
-		DeezerMonthlyListeners: 0,
-		DeezerTopTracks:        nil,
-		DeezerLatestAlbums:     nil,
-
-		AppleArtist:           nil,
-		AppleGenre:            "",
-		AppleMonthlyListeners: 0,
-		AppleHeroImage:        "",
-		AppleTopTracks:        nil,
-		AppleLatestAlbums:     nil,
-
-		LocationsJSON: template.JS(emptyLocations),
-		WikiSummary:   wikiSummary,
-		WikiURL:       wikiURL,
-		HasWiki:       hasWiki,
-	}
-
-	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
-		http.Error(w, "render error", http.StatusInternalServerError)
+	case errors.Is(err, errArtistNotFound):
+		NotFound(w, r)
 		return
-	}
-}
-
-// handleDeezerArtistDetail renders the detail page for a Deezer artist ID
-func handleDeezerArtistDetail(w http.ResponseWriter, r *http.Request, idSegment string, user *store.User, authed bool) {
-	id, err := strconv.Atoi(idSegment)
-	if err != nil || id <= 0 {
-		http.Redirect(w, r, withBasePath(r, "/artists")+"?source=deezer", http.StatusSeeOther)
+	case err != nil:
+		renderError(w, r, http.StatusInternalServerError, "failed to load "+src.Name()+" artist")
 		return
 	}
 
-	artist, err := api.GetDeezerArtist(id)
-	if err != nil {
-		if isNotFoundError(err) {
-			NotFound(w, r)
-			return
-		}
-		http.Error(w, "failed to load deezer artist", http.StatusInternalServerError)
-		return
-	}
+	// Wikipedia is best-effort, the page should still render without it
+	stop := timing.start("wiki")
+	wiki := fetchArtistWiki(detail.name)
+	stop()
+	hasWiki := wiki.Extract != "" && wiki.URL != ""
 
-	emptyLocations, err := json.Marshal([]MapLocation{})
-	if err != nil {
-		http.Error(w, "failed to encode concerts", http.StatusInternalServerError)
-		return
-	}
+	stop = timing.start("news")
+	news := fetchArtistNews(detail.name)
+	stop()
 
-	wikiSummary, wikiURL, wikiErr := api.FetchWikipediaSummary(artist.Name)
-	hasWiki := wikiErr == nil && wikiSummary != "" && wikiURL != ""
+	stop = timing.start("youtube")
+	video := fetchArtistVideo(detail.name)
+	stop()
 
-	monthly, err := api.FetchArtistMonthlyListeners(artist.Name)
-	if err != nil {
-		monthly = 0
+	basePath := getBasePath(r)
+	data := ArtistDetailPageData{
+		LayoutData:   newLayoutData(w, r, detail.name, user, authed),
+		ArtistDetail: *detail,
+		IsFavorite:   isFavorite(r, user, src.Name(), detail.FavoriteID),
+
+		WikiSummary:  wiki.Extract,
+		WikiURL:      wiki.URL,
+		WikiImageURL: wiki.ThumbnailURL,
+		HasWiki:      hasWiki,
+
+		News:             news,
+		YouTubeVideo:     video,
+		YouTubeSearchURL: api.YouTubeSearchURL(detail.name),
+
+		StructuredData: detail.musicGroup.withWiki(wiki.URL, hasWiki).JS(),
+	}
+	if data.MusicBrainzArtist != nil {
+		// MusicBrainz has no artwork, the Wikipedia image beats the placeholder
+		data.MusicBrainzHeroImage = placeholderImage(basePath, "musicbrainz")
+		if wiki.ThumbnailURL != "" {
+			data.MusicBrainzHeroImage = wiki.ThumbnailURL
+		}
 	}
 
-	topTracks, err := api.GetDeezerArtistTopTracks(artist.ID, 10)
-	if err != nil {
-		// Track lists are optional for the rest of the page
-		topTracks = nil
+	// Only Groupie has concerts, the map scripts still expect an empty list and object
+	locations := detail.locations
+	if locations == nil {
+		locations = []MapLocation{}
 	}
-
-	latestAlbums, err := api.GetDeezerArtistAlbums(artist.ID, 8)
-	if err != nil {
-		latestAlbums = nil
+	shared := detail.sharedConcerts
+	if shared == nil {
+		shared = map[string][]SharedConcert{}
 	}
-
-	if len(latestAlbums) > 1 {
-		// Sort on the backend so template rendering stays simple
-		sort.SliceStable(latestAlbums, func(i, j int) bool { // newest first, then stable tie-breakers
-			di, okI := api.ParseDeezerReleaseDate(latestAlbums[i].ReleaseDate)
-			dj, okJ := api.ParseDeezerReleaseDate(latestAlbums[j].ReleaseDate)
-
-			if okI && okJ && !di.Equal(dj) {
-				return di.After(dj)
-			}
-			if okI != okJ {
-				return okI
-			}
-
-			ni := strings.ToLower(latestAlbums[i].Title)
-			nj := strings.ToLower(latestAlbums[j].Title)
-			if ni != nj {
-				return ni < nj
-			}
-
-			return latestAlbums[i].ID < latestAlbums[j].ID
-		})
+	for _, list := range shared {
+		for i := range list {
+			list[i].URL = basePath + list[i].URL
+		}
 	}
-
-	tmpl, err := template.ParseFiles(
-		"web/templates/layout.gohtml",
-		"web/templates/artist_detail.gohtml",
-	)
+	locBytes, err := json.Marshal(locations)
 	if err != nil {
-		http.Error(w, "template error", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "failed to encode concerts")
 		return
 	}
-
-	data := ArtistDetailPageData{
-		Title:      artist.Name,
-		Source:     "deezer",
-		ActiveNav:  "artists",
-		BasePath:   getBasePath(r),
-		CurrentURL: buildCurrentURL(r),
-		User:       user,
-		IsAuthed:   authed,
-		IsFavorite: isFavorite(r, user, "deezer", idSegment),
-		FavoriteID: idSegment,
-		Artist:     nil,
-
-		SpotifyArtist:           nil,
-		SpotifyGenre:            "",
-		SpotifyFollowers:        0,
-		SpotifyMonthlyListeners: 0,
-		SpotifyTopTracks:        nil,
-		SpotifyLatestAlbums:     nil,
-
-		DeezerArtist:           artist,
-		DeezerFans:             artist.NbFan,
-		DeezerAlbumsCount:      artist.NbAlbum,
-		DeezerHasRadio:         artist.Radio,
-		DeezerMonthlyListeners: monthly,
-		DeezerTopTracks:        topTracks,
-		DeezerLatestAlbums:     latestAlbums,
-
-		AppleArtist:           nil,
-		AppleGenre:            "",
-		AppleMonthlyListeners: 0,
-		AppleHeroImage:        "",
-		AppleTopTracks:        nil,
-		AppleLatestAlbums:     nil,
-
-		LocationsJSON: template.JS(emptyLocations),
-		WikiSummary:   wikiSummary,
-		WikiURL:       wikiURL,
-		HasWiki:       hasWiki,
-	}
-
-	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
-		http.Error(w, "render error", http.StatusInternalServerError)
+	sharedBytes, err := json.Marshal(shared)
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, "failed to encode concerts")
 		return
 	}
+	data.LocationsJSON = template.JS(locBytes)
+	data.SharedConcertsJSON = template.JS(sharedBytes)
+
+	renderArtistDetail(w, r, timing, data)
 }
 
-// handleAppleArtistDetail renders the detail page for an iTunes artist ID
-func handleAppleArtistDetail(w http.ResponseWriter, r *http.Request, idSegment string, user *store.User, authed bool) {
-	id, err := strconv.Atoi(idSegment)
-	if err != nil || id <= 0 {
-		http.Redirect(w, r, withBasePath(r, "/artists")+"?source=apple", http.StatusSeeOther)
-		return
+// renderArtistDetail writes the detail page, or the printable sheet for `/artists/{id}/sheet`
+func renderArtistDetail(w http.ResponseWriter, r *http.Request, timing *serverTiming, data ArtistDetailPageData) {
+	data.ReportsEnabled = appStore != nil
+	data.ReportIssueTypes = reportIssueTypes
+	data.ReportNoteMaxLen = maxReportNoteRunes
+	data.Reported = r.URL.Query().Get("reported") == "1"
+	data.Locale = resolveLocale(r)
+
+	files := []string{"web/templates/layout.gohtml", "web/templates/artist_detail.gohtml"}
+	name := "layout"
+	if isArtistSheet(r) {
+		// The sheet is a standalone page, without the site header and scripts
+		files = []string{"web/templates/artist_sheet.gohtml"}
+		name = "sheet"
 	}
 
-	artist, err := api.GetAppleArtist(id)
+	tmpl, err := template.ParseFiles(files...)
 	if err != nil {
-		if isNotFoundError(err) {
-			NotFound(w, r)
-			return
-		}
-		http.Error(w, "failed to load apple artist", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "template error")
 		return
 	}
 
-	emptyLocations, err := json.Marshal([]MapLocation{})
-	if err != nil {
-		http.Error(w, "failed to encode concerts", http.StatusInternalServerError)
+	timing.write(w)
+	if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+		renderError(w, r, http.StatusInternalServerError, "render error")
 		return
 	}
+}
 
-	wikiSummary, wikiURL, wikiErr := api.FetchWikipediaSummary(artist.ArtistName)
-	hasWiki := wikiErr == nil && wikiSummary != "" && wikiURL != ""
-
-	monthly, err := api.FetchArtistMonthlyListeners(artist.ArtistName)
-	if err != nil {
-		monthly = 0
-	}
+func isArtistSheet(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, "/sheet")
+}
 
-	latestAlbums, err := api.GetAppleArtistAlbums(artist.ArtistID, 8)
-	if err != nil {
-		latestAlbums = nil
+// albumGroupLabel maps Spotify album types and Deezer record types to a section label
+func albumGroupLabel(t string) string {
+	switch strings.ToLower(strings.TrimSpace(t)) {
+	case "album":
+		return "Albums"
+	case "single", "ep":
+		return "Singles & EPs"
+	case "compilation", "compile":
+		return "Compilations"
+	default:
+		return ""
 	}
+}
 
-	topTracks, err := api.GetAppleArtistSongs(artist.ArtistID, 10)
-	if err != nil {
-		topTracks = nil
+// groupAlbumsByType splits an already sorted discography into labeled sections
+// It returns nil when any album has an unknown type so the template can render a flat list
+func groupAlbumsByType[T any](albums []T, typeOf func(T) string) []AlbumGroup[T] {
+	if len(albums) == 0 {
+		return nil
 	}
 
-	hero := ""
-	if len(latestAlbums) > 0 {
-		// Use the newest album cover as the hero image when possible
-		hero = upscaleAppleArtwork(latestAlbums[0].ArtworkURL100, 600)
-	}
-	if hero == "" && len(topTracks) > 0 {
-		// Fall back to a track artwork if we didn't get an album cover
-		hero = upscaleAppleArtwork(topTracks[0].ArtworkURL100, 600)
+	order := []string{"Albums", "Singles & EPs", "Compilations"}
+	byLabel := make(map[string][]T, len(order))
+	for _, a := range albums {
+		label := albumGroupLabel(typeOf(a))
+		if label == "" {
+			return nil
+		}
+		// Appending in input order keeps the recency sort within each group
+		byLabel[label] = append(byLabel[label], a)
 	}
 
-	tmpl, err := template.ParseFiles(
-		"web/templates/layout.gohtml",
-		"web/templates/artist_detail.gohtml",
-	)
-	if err != nil {
-		http.Error(w, "template error", http.StatusInternalServerError)
-		return
+	groups := make([]AlbumGroup[T], 0, len(order))
+	for _, label := range order {
+		if len(byLabel[label]) == 0 {
+			continue
+		}
+		groups = append(groups, AlbumGroup[T]{Label: label, Albums: byLabel[label]})
 	}
 
-	data := ArtistDetailPageData{
-		Title:      artist.ArtistName,
-		Source:     "apple",
-		ActiveNav:  "artists",
-		BasePath:   getBasePath(r),
-		CurrentURL: buildCurrentURL(r),
-		User:       user,
-		IsAuthed:   authed,
-		IsFavorite: isFavorite(r, user, "apple", idSegment),
-		FavoriteID: idSegment,
-		Artist:     nil,
-
-		SpotifyArtist:           nil,
-		SpotifyGenre:            "",
-		SpotifyFollowers:        0,
-		SpotifyMonthlyListeners: 0,
-		SpotifyTopTracks:        nil,
-		SpotifyLatestAlbums:     nil,
-
-		DeezerArtist:           nil,
-		DeezerFans:             0,
-		DeezerAlbumsCount:      0,
-		DeezerHasRadio:         false,
-		DeezerMonthlyListeners: 0,
-		DeezerTopTracks:        nil,
-		DeezerLatestAlbums:     nil,
-
-		AppleArtist:           artist,
-		AppleGenre:            artist.PrimaryGenreName,
-		AppleMonthlyListeners: monthly,
-		AppleHeroImage:        hero,
-		AppleTopTracks:        topTracks,
-		AppleLatestAlbums:     latestAlbums,
-
-		LocationsJSON: template.JS(emptyLocations),
-		WikiSummary:   wikiSummary,
-		WikiURL:       wikiURL,
-		HasWiki:       hasWiki,
-	}
-
-	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
-		http.Error(w, "render error", http.StatusInternalServerError)
-		return
-	}
+	return groups
 }
 
 // upscaleAppleArtwork rewrites `100x100bb.jpg` style artwork URLs to a larger size
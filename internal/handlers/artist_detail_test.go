@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestArtistDetailHandlerEmptyID(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		status   int
+		location string
+	}{
+		// GETs are redirected by TrimTrailingSlash first, other methods reach the handler
+		{"empty id", http.MethodPost, "/artists/", http.StatusSeeOther, "/artists?source=groupie"},
+		{"empty id keeps source", http.MethodPost, "/artists/?source=deezer", http.StatusSeeOther, "/artists?source=deezer"},
+		{"artists segment", http.MethodGet, "/artists/artists", http.StatusNotFound, ""},
+		{"empty sheet id", http.MethodGet, "/artists//sheet", http.StatusSeeOther, "/artists?source=groupie"},
+		{"empty albums id", http.MethodGet, "/artists//albums", http.StatusNotFound, ""},
+		{"empty calendar id", http.MethodGet, "/artists//concerts.ics", http.StatusNotFound, ""},
+		{"empty geojson id", http.MethodGet, "/artists//locations.geojson", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ArtistDetailHandler(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Location"); got != tt.location {
+				t.Fatalf("Location = %q, want %q", got, tt.location)
+			}
+		})
+	}
+}
+
+func TestArtistDetailHandlerReservedSegments(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+	}{
+		{"ajax with a trailing slash", http.MethodGet, "/artists/ajax/"},
+		{"ajax posted with a trailing slash", http.MethodPost, "/artists/ajax/"},
+		{"suggest sub-path", http.MethodGet, "/artists/suggest/x"},
+		{"suggest with a trailing slash", http.MethodGet, "/artists/suggest/?q=queen"},
+		{"reserved name in another case", http.MethodGet, "/artists/Suggest"},
+		{"ajax as an id", http.MethodGet, "/artists/ajax?source=deezer"},
+		{"ajax sheet", http.MethodGet, "/artists/ajax/sheet"},
+		{"suggest albums", http.MethodGet, "/artists/suggest/albums"},
+		{"ajax calendar", http.MethodGet, "/artists/ajax/concerts.ics?source=groupie"},
+		{"nested id", http.MethodGet, "/artists/1/2"},
+		{"nested sheet", http.MethodGet, "/artists/1/2/sheet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ArtistDetailHandler(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+			}
+		})
+	}
+}
+
+func TestIsArtistIDSegment(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"1", true},
+		{"6olE6TJLqED3rqDCT0FyPh", true},
+		{"mbid-5b11f4ce-a62d-471e-81fc-a69a8278c7da", true},
+		{"", false},
+		{"ajax", false},
+		{"AJAX", false},
+		{"suggest", false},
+		{"artists", false},
+		{"ajax/", false},
+		{"1/2", false},
+	}
+	for _, tt := range tests {
+		if got := isArtistIDSegment(tt.s); got != tt.want {
+			t.Errorf("isArtistIDSegment(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
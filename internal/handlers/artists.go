@@ -3,55 +3,29 @@ package handlers
 import (
 	"html/template"
 	"net/http"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"palasgroupietracker/internal/api"
 	"palasgroupietracker/internal/geo"
-	"palasgroupietracker/internal/store"
 )
 
-type SpotifyArtistView struct {
-	Artist           api.SpotifyArtist
-	Followers        int
-	MonthlyListeners int
-}
-
-type DeezerArtistView struct {
-	Artist   api.DeezerArtist
-	Fans     int
-	Albums   int
-	HasRadio bool
-}
-
-type AppleArtistView struct {
-	Artist   api.AppleArtist
-	ImageURL string
-	Genre    string
-}
-
 type ArtistsPageData struct {
-	Title           string
-	Source          string
-	BasePath        string
-	CurrentURL      string
-	User            *store.User
-	IsAuthed        bool
+	LayoutData
+
 	FavoriteIDs     map[string]bool
-	Artists         []api.Artist
-	Spotify         []SpotifyArtistView
-	Deezer          []DeezerArtistView
-	Apple           []AppleArtistView
+	Cards           []ArtistCard
 	Query           string
 	YearMin         string
 	YearMax         string
 	MembersMin      string
 	MembersMax      string
 	Sort            string
-	ActiveNav       string
 	YearMinBound    int
 	YearMaxBound    int
 	MembersMinBound int
@@ -66,51 +40,66 @@ type ArtistsPageData struct {
 	AlbumFrom     string
 	AlbumTo       string
 	Location      string
+
+	// Spotify field filters (`genre:` and `year:` modifiers)
+	SpotifyGenre string
+	SpotifyYear  string
+
+	// View is the list layout, "grid" or "list"
+	View string
+
+	// Country is the iTunes storefront picked with `?country=` (Apple only), empty for the default
+	Country string
+
+	// Locale formats counts and dates for the visitor, from Accept-Language
+	Locale *Locale
+
+	// ShowingFeatured is set when there was nothing to search for and Cards holds the featured artists
+	ShowingFeatured bool
+	// QueryTooShort is set when a query was typed but is under MinQueryLength, so nothing was searched
+	QueryTooShort  bool
+	MinQueryLength int
+
+	// Pagination windows the Groupie list, nil for the other sources
+	Pagination *Pagination
 }
 
+// featuredArtistsListSize is how many featured artists fill an empty search
+const featuredArtistsListSize = 24
+
 // ArtistsHandler renders the full artists page using the shared layout
 func ArtistsHandler(w http.ResponseWriter, r *http.Request) {
 	source := getSource(r)
-	basePath := getBasePath(r)
 	user, authed := getCurrentUser(w, r)
 
 	var data ArtistsPageData
 	var err error
 
-	if source == "spotify" {
-		// Spotify mode uses the Spotify search API and adds Last.fm listeners
-		data, err = buildSpotifyData(r)
-	} else if source == "deezer" {
-		data, err = buildDeezerData(r)
-	} else if source == "apple" {
-		data, err = buildAppleData(r)
-	} else {
-		// Default mode uses the original Groupie API and supports year/member filters
-		data, err = buildGroupieData(r)
-	}
+	// Each source builds its own list data, only Groupie supports year/member filters
+	data, err = buildArtistsData(r, source)
 
 	if err != nil {
-		http.Error(w, "failed to load artists", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "failed to load artists")
 		return
 	}
 
-	data.BasePath = basePath
-	data.CurrentURL = buildCurrentURL(r)
-	data.User = user
-	data.IsAuthed = authed
+	data.LayoutData = newLayoutData(w, r, "Artists", user, authed)
 	data.FavoriteIDs = favoriteIDMap(r, user, source)
+	data.View = resolveArtistsView(w, r, user)
+	data.Locale = resolveLocale(r)
+	localizeCards(data.Cards, data.Locale)
 
 	tmpl, err := template.ParseFiles(
 		"web/templates/layout.gohtml",
 		"web/templates/artists.gohtml",
 	)
 	if err != nil {
-		http.Error(w, "template error", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "template error")
 		return
 	}
 
 	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
-		http.Error(w, "render error", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "render error")
 		return
 	}
 }
@@ -118,49 +107,60 @@ func ArtistsHandler(w http.ResponseWriter, r *http.Request) {
 // ArtistsAjaxHandler renders only the artists list section for live filtering
 func ArtistsAjaxHandler(w http.ResponseWriter, r *http.Request) {
 	source := getSource(r)
-	basePath := getBasePath(r)
 	user, authed := getCurrentUser(w, r)
 
 	var data ArtistsPageData
 	var err error
 
-	if source == "spotify" {
-		data, err = buildSpotifyData(r)
-	} else if source == "deezer" {
-		data, err = buildDeezerData(r)
-	} else if source == "apple" {
-		data, err = buildAppleData(r)
-	} else {
-		data, err = buildGroupieData(r)
-	}
+	data, err = buildArtistsData(r, source)
 
 	if err != nil {
-		http.Error(w, "failed to load artists", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "failed to load artists")
 		return
 	}
 
-	data.BasePath = basePath
+	data.LayoutData = newLayoutData(w, r, "Artists", user, authed)
+	// The fragment's forms return to the list page, not to the ajax URL
 	data.CurrentURL = buildArtistsListURL(r)
-	data.User = user
-	data.IsAuthed = authed
 	data.FavoriteIDs = favoriteIDMap(r, user, source)
+	data.View = resolveArtistsView(w, r, user)
+	data.Locale = resolveLocale(r)
+	localizeCards(data.Cards, data.Locale)
 
 	tmpl, err := template.ParseFiles("web/templates/artists.gohtml")
 	if err != nil {
-		http.Error(w, "template error", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "template error")
 		return
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := tmpl.ExecuteTemplate(w, "artist_list", data); err != nil {
-		http.Error(w, "render error", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "render error")
 		return
 	}
 }
 
+// artistsDataBuilders build the artists list and filter state of each source from the request query
+var artistsDataBuilders = map[string]func(*http.Request) (ArtistsPageData, error){
+	"groupie":     buildGroupieData,
+	"spotify":     buildSpotifyData,
+	"deezer":      buildDeezerData,
+	"apple":       buildAppleData,
+	"musicbrainz": buildMusicBrainzData,
+}
+
+// buildArtistsData builds the list data for a normalized source name
+func buildArtistsData(r *http.Request, source string) (ArtistsPageData, error) {
+	build, ok := artistsDataBuilders[source]
+	if !ok {
+		build = artistsDataBuilders[defaultSource]
+	}
+	return build(r)
+}
+
 // buildGroupieData builds the artists list and filter state for the original Groupie dataset
 func buildGroupieData(r *http.Request) (ArtistsPageData, error) {
-	artists, err := api.FetchArtists()
+	artists, err := fetchGroupieArtists(r.Context())
 	if err != nil {
 		return ArtistsPageData{}, err
 	}
@@ -247,13 +247,15 @@ func buildGroupieData(r *http.Request) (ArtistsPageData, error) {
 		membersMinValue = membersMaxValue
 	}
 
-	filtered := make([]api.Artist, 0, len(artists))
-	lowerQuery := strings.ToLower(query)
+	basePath := getBasePath(r)
+	filtered := make([]ArtistCard, 0, len(artists))
+	// Same normalization as /artists/suggest, so picking a suggestion always narrows the list
+	queryNorm := normalizeForMatch(query)
 
 	locationNorm := normalizeForMatch(locationQuery)
 	var locationsByArtistID map[int][]string
 	if locationNorm != "" {
-		relations, relErr := api.FetchRelations()
+		relations, relErr := fetchGroupieRelations(r.Context())
 		if relErr != nil {
 			return ArtistsPageData{}, relErr
 		}
@@ -270,12 +272,12 @@ func buildGroupieData(r *http.Request) (ArtistsPageData, error) {
 	albumFilterActive := albumFromValue.After(albumMinBoundDate) || albumToValue.Before(albumMaxBoundDate)
 
 	for _, a := range artists {
-		if lowerQuery != "" {
+		if queryNorm != "" {
 			// Match on artist name or member names
-			matched := strings.Contains(strings.ToLower(a.Name), lowerQuery)
+			matched := strings.Contains(normalizeForMatch(a.Name), queryNorm)
 			if !matched {
 				for _, m := range a.Members {
-					if strings.Contains(strings.ToLower(m), lowerQuery) {
+					if strings.Contains(normalizeForMatch(m), queryNorm) {
 						matched = true
 						break
 					}
@@ -336,20 +338,21 @@ func buildGroupieData(r *http.Request) (ArtistsPageData, error) {
 			}
 		}
 
-		filtered = append(filtered, a)
+		filtered = append(filtered, groupieArtistCard(basePath, a))
 	}
 
+	// Windowing runs last so page counts reflect the filters
+	cards, pagination := paginateCards(r, filtered)
+
 	data := ArtistsPageData{
-		Title:           "Artists",
-		Source:          "groupie",
-		Artists:         filtered,
+		Cards:           cards,
+		Pagination:      pagination,
 		Query:           query,
 		YearMin:         strconv.Itoa(yearMinValue),
 		YearMax:         strconv.Itoa(yearMaxValue),
 		MembersMin:      strconv.Itoa(membersMinValue),
 		MembersMax:      strconv.Itoa(membersMaxValue),
 		Sort:            "",
-		ActiveNav:       "artists",
 		YearMinBound:    yearMinBound,
 		YearMaxBound:    yearMaxBound,
 		MembersMinBound: membersMinBound,
@@ -373,23 +376,27 @@ func buildGroupieData(r *http.Request) (ArtistsPageData, error) {
 func buildSpotifyData(r *http.Request) (ArtistsPageData, error) {
 	query := strings.TrimSpace(r.URL.Query().Get("q"))
 	sortParam := strings.TrimSpace(r.URL.Query().Get("sort"))
+	// Optional field filters, invalid values are dropped rather than rejected
+	filters := api.NormalizeSpotifySearchFilters(api.SpotifySearchFilters{
+		Genre: r.URL.Query().Get("genre"),
+		Year:  r.URL.Query().Get("year"),
+	})
 
-	if query == "" {
-		// Spotify search rejects empty queries
-		query = "a"
+	if (query == "" || queryTooShort(query)) && filters.IsZero() {
+		// Nothing to search for yet, show the featured set instead of guessing a query
+		// Field filters alone are specific enough, so a short query only counts without them
+		return buildFeaturedArtistsData(r, "spotify")
 	}
 
-	results, err := api.SearchSpotifyArtists(query)
+	results, err := api.SearchSpotifyArtistsFiltered(query, filters)
 	if err != nil {
 		return ArtistsPageData{}, err
 	}
 
-	views := make([]SpotifyArtistView, len(results))
+	basePath := getBasePath(r)
+	views := make([]ArtistCard, len(results))
 	for i, a := range results {
-		views[i].Artist = a
-		if a.Followers != nil {
-			views[i].Followers = a.Followers.Total
-		}
+		views[i] = spotifyArtistCard(basePath, a, artworkSizeFor("list"))
 	}
 
 	// Fetch Last.fm listeners in parallel but cap concurrency
@@ -398,10 +405,10 @@ func buildSpotifyData(r *http.Request) (ArtistsPageData, error) {
 
 	for i := range views {
 		wg.Add(1)
-		go func(v *SpotifyArtistView) { // fetch Last.fm listeners concurrently
+		go func(v *ArtistCard) { // fetch Last.fm listeners concurrently
 			defer wg.Done()
 			sem <- struct{}{}
-			listeners, err := api.FetchArtistMonthlyListeners(v.Artist.Name)
+			listeners, err := api.FetchArtistMonthlyListeners(v.Name)
 			if err != nil {
 				// Listener counts are best-effort, keep the artist even on failure
 				listeners = 0
@@ -414,7 +421,6 @@ func buildSpotifyData(r *http.Request) (ArtistsPageData, error) {
 	wg.Wait()
 
 	if sortParam == "" {
-		// Default matches Spotify's natural "relevance" ordering
 		sortParam = "relevance"
 	}
 
@@ -435,31 +441,67 @@ func buildSpotifyData(r *http.Request) (ArtistsPageData, error) {
 		sort.Slice(views, func(i, j int) bool { // largest listener count first
 			return views[i].MonthlyListeners > views[j].MonthlyListeners
 		})
-	case "relevance":
 	default:
 		sortParam = "relevance"
+		sortByRelevance(views, query, spotifyPopularity, currentRelevanceWeights())
 	}
 
 	data := ArtistsPageData{
-		Title:   "Artists",
-		Source:  "spotify",
-		Spotify: views,
-		// Preserve the user's original query instead of the fallback "a"
-		Query:     strings.TrimSpace(r.URL.Query().Get("q")),
-		Sort:      sortParam,
-		ActiveNav: "artists",
+		Cards:        views,
+		Query:        query,
+		Sort:         sortParam,
+		SpotifyGenre: filters.Genre,
+		SpotifyYear:  filters.Year,
 	}
 
 	return data, nil
 }
 
+// buildFeaturedArtistsData fills the list with the source's featured artists when there's no query yet
+// The page then prompts for a search instead of showing results for a made-up one
+func buildFeaturedArtistsData(r *http.Request, source string) (ArtistsPageData, error) {
+	cards, err := searchSourceCards(r.Context(), sourceFor(source), getBasePath(r), "", featuredArtistsListSize)
+	if err != nil {
+		return ArtistsPageData{}, err
+	}
+
+	// Keep what was typed so far, a too-short query lands here too
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	return ArtistsPageData{
+		Cards:           cards,
+		Query:           query,
+		Sort:            "relevance",
+		ShowingFeatured: true,
+		QueryTooShort:   queryTooShort(query),
+		MinQueryLength:  SearchMinQueryLength(),
+	}, nil
+}
+
+// defaultSearchMinQueryLength matches the `/artists/suggest` guard
+const defaultSearchMinQueryLength = 2
+
+// SearchMinQueryLength reads `SEARCH_MIN_QUERY_LENGTH`, the shortest query sent to the streaming providers
+func SearchMinQueryLength() int {
+	v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("SEARCH_MIN_QUERY_LENGTH")))
+	if err != nil || v < 1 {
+		return defaultSearchMinQueryLength
+	}
+	return v
+}
+
+// queryTooShort reports a non-empty query under the minimum length, counted in characters
+func queryTooShort(query string) bool {
+	n := utf8.RuneCountInString(query)
+	return n > 0 && n < SearchMinQueryLength()
+}
+
 // buildDeezerData searches Deezer and applies simple sorting options
 func buildDeezerData(r *http.Request) (ArtistsPageData, error) {
 	query := strings.TrimSpace(r.URL.Query().Get("q"))
 	sortParam := strings.TrimSpace(r.URL.Query().Get("sort"))
 
-	if query == "" {
-		query = "a"
+	if query == "" || queryTooShort(query) {
+		return buildFeaturedArtistsData(r, "deezer")
 	}
 
 	results, err := api.SearchDeezerArtists(query)
@@ -467,12 +509,10 @@ func buildDeezerData(r *http.Request) (ArtistsPageData, error) {
 		return ArtistsPageData{}, err
 	}
 
-	views := make([]DeezerArtistView, len(results))
+	basePath := getBasePath(r)
+	views := make([]ArtistCard, len(results))
 	for i, a := range results {
-		views[i].Artist = a
-		views[i].Fans = a.NbFan
-		views[i].Albums = a.NbAlbum
-		views[i].HasRadio = a.Radio
+		views[i] = deezerArtistCard(basePath, a, artworkSizeFor("list"))
 	}
 
 	if sortParam == "" {
@@ -496,18 +536,15 @@ func buildDeezerData(r *http.Request) (ArtistsPageData, error) {
 		sort.Slice(views, func(i, j int) bool { // largest album count first
 			return views[i].Albums > views[j].Albums
 		})
-	case "relevance":
 	default:
 		sortParam = "relevance"
+		sortByRelevance(views, query, deezerPopularity, currentRelevanceWeights())
 	}
 
 	data := ArtistsPageData{
-		Title:     "Artists",
-		Source:    "deezer",
-		Deezer:    views,
-		Query:     strings.TrimSpace(r.URL.Query().Get("q")),
-		Sort:      sortParam,
-		ActiveNav: "artists",
+		Cards: views,
+		Query: query,
+		Sort:  sortParam,
 	}
 
 	return data, nil
@@ -518,20 +555,73 @@ func buildAppleData(r *http.Request) (ArtistsPageData, error) {
 	query := strings.TrimSpace(r.URL.Query().Get("q"))
 	sortParam := strings.TrimSpace(r.URL.Query().Get("sort"))
 
+	if query == "" || queryTooShort(query) {
+		return buildFeaturedArtistsData(r, "apple")
+	}
+
+	country := appleCountryParam(r)
+	results, err := api.SearchAppleArtistsWithArtwork(query, 30, artworkSizeFor("list"), country)
+	if err != nil {
+		return ArtistsPageData{}, err
+	}
+
+	basePath := getBasePath(r)
+	views := make([]ArtistCard, len(results))
+	for i, a := range results {
+		views[i] = appleArtistCard(basePath, a.Artist, a.ArtworkURL)
+	}
+
+	if sortParam == "" {
+		sortParam = "relevance"
+	}
+
+	switch sortParam {
+	case "name_asc":
+		sort.Slice(views, func(i, j int) bool { // A to Z by artist name
+			return strings.ToLower(views[i].Name) < strings.ToLower(views[j].Name)
+		})
+	case "name_desc":
+		sort.Slice(views, func(i, j int) bool { // Z to A by artist name
+			return strings.ToLower(views[i].Name) > strings.ToLower(views[j].Name)
+		})
+	default:
+		sortParam = "relevance"
+		// iTunes search has no popularity numbers, so this ranks by name match and iTunes' order
+		sortByRelevance(views, query, noPopularity, currentRelevanceWeights())
+	}
+
+	data := ArtistsPageData{
+		Cards:   views,
+		Query:   query,
+		Sort:    sortParam,
+		Country: country,
+	}
+
+	return data, nil
+}
+
+// buildMusicBrainzData searches MusicBrainz artists, keeping their relevance order by default
+func buildMusicBrainzData(r *http.Request) (ArtistsPageData, error) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	sortParam := strings.TrimSpace(r.URL.Query().Get("sort"))
+
+	if queryTooShort(query) {
+		return buildFeaturedArtistsData(r, "musicbrainz")
+	}
 	if query == "" {
-		query = "a"
+		// MusicBrainz has no "popular" listing, a broad tag search stands in for it
+		query = musicBrainzDefaultQuery
 	}
 
-	results, err := api.SearchAppleArtistsWithArtwork(query, 30, 300)
+	results, err := api.SearchMusicBrainzArtists(query, 30)
 	if err != nil {
 		return ArtistsPageData{}, err
 	}
 
-	views := make([]AppleArtistView, len(results))
+	basePath := getBasePath(r)
+	views := make([]ArtistCard, len(results))
 	for i, a := range results {
-		views[i].Artist = a.Artist
-		views[i].Genre = a.Artist.PrimaryGenreName
-		views[i].ImageURL = a.ArtworkURL
+		views[i] = musicBrainzArtistCard(basePath, a)
 	}
 
 	if sortParam == "" {
@@ -541,11 +631,11 @@ func buildAppleData(r *http.Request) (ArtistsPageData, error) {
 	switch sortParam {
 	case "name_asc":
 		sort.Slice(views, func(i, j int) bool { // A to Z by artist name
-			return strings.ToLower(views[i].Artist.ArtistName) < strings.ToLower(views[j].Artist.ArtistName)
+			return strings.ToLower(views[i].Name) < strings.ToLower(views[j].Name)
 		})
 	case "name_desc":
 		sort.Slice(views, func(i, j int) bool { // Z to A by artist name
-			return strings.ToLower(views[i].Artist.ArtistName) > strings.ToLower(views[j].Artist.ArtistName)
+			return strings.ToLower(views[i].Name) > strings.ToLower(views[j].Name)
 		})
 	case "relevance":
 	default:
@@ -553,12 +643,9 @@ func buildAppleData(r *http.Request) (ArtistsPageData, error) {
 	}
 
 	data := ArtistsPageData{
-		Title:     "Artists",
-		Source:    "apple",
-		Apple:     views,
-		Query:     strings.TrimSpace(r.URL.Query().Get("q")),
-		Sort:      sortParam,
-		ActiveNav: "artists",
+		Cards: views,
+		Query: strings.TrimSpace(r.URL.Query().Get("q")),
+		Sort:  sortParam,
 	}
 
 	return data, nil
@@ -663,20 +750,42 @@ func computeFirstAlbumBounds(artists []api.Artist) (time.Time, time.Time) {
 	return minDate, maxDate
 }
 
+// matchSeparators turns punctuation used between words into spaces
+var matchSeparators = strings.NewReplacer(
+	"_", " ",
+	"-", " ",
+	",", " ",
+	".", " ",
+	"/", " ",
+	"\\", " ",
+)
+
+// matchFolder strips diacritics so "Beyonce" finds "Beyoncé" (input is already lowercased)
+var matchFolder = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "ā", "a", "ą", "a", "æ", "ae",
+	"ç", "c", "ć", "c", "č", "c", "ď", "d", "đ", "d", "ð", "d",
+	"è", "e", "é", "e", "ê", "e", "ë", "e", "ē", "e", "ė", "e", "ę", "e", "ě", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ī", "i", "ı", "i", "ł", "l",
+	"ñ", "n", "ń", "n", "ň", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "ō", "o", "ő", "o", "œ", "oe",
+	"ŕ", "r", "ř", "r", "ś", "s", "š", "s", "ş", "s", "ß", "ss", "ť", "t", "ţ", "t", "þ", "th",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ū", "u", "ů", "u", "ű", "u",
+	"ý", "y", "ÿ", "y", "ź", "z", "ż", "z", "ž", "z",
+)
+
+// normalizeForMatch is the shared form for every text match (suggestions, list filter, locations)
+// It lowercases, folds diacritics, treats punctuation as spaces and collapses whitespace
 func normalizeForMatch(s string) string {
 	s = strings.ToLower(strings.TrimSpace(s))
 	if s == "" {
 		return ""
 	}
-	r := strings.NewReplacer(
-		"_", " ",
-		"-", " ",
-		",", " ",
-		".", " ",
-		"/", " ",
-		"\\", " ",
-	)
-	s = r.Replace(s)
-	parts := strings.Fields(s)
-	return strings.Join(parts, " ")
+	s = matchFolder.Replace(matchSeparators.Replace(s))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// appleCountryParam reads the optional `country` storefront override, "" when absent or invalid
+func appleCountryParam(r *http.Request) string {
+	c, _ := api.NormalizeAppleCountry(r.URL.Query().Get("country"))
+	return c
 }
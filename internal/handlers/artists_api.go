@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// ArtistsAPIResponse is the JSON form of the artists list, with the same filtering as `/artists`
+type ArtistsAPIResponse struct {
+	Source string `json:"source"`
+	Query  string `json:"query"`
+	Sort   string `json:"sort"`
+	Count  int    `json:"count"`
+	// Total, Page and PageCount are set for the paged Groupie list, Count is then the page size
+	Total     int             `json:"total,omitempty"`
+	Page      int             `json:"page,omitempty"`
+	PageCount int             `json:"page_count,omitempty"`
+	Artists   []ArtistAPIItem `json:"artists"`
+	// Filters is only set for Groupie, the other sources have no range filters
+	Filters *ArtistsAPIFilters `json:"filters,omitempty"`
+	// Featured is set when there was nothing to search for and Artists holds the featured list
+	Featured bool `json:"featured,omitempty"`
+	// QueryTooShort is set when the query is under the source minimum, so nothing was searched
+	QueryTooShort bool `json:"query_too_short,omitempty"`
+}
+
+// ArtistAPIItem is one artist of the list, zero per-source fields are left out
+type ArtistAPIItem struct {
+	Source           string   `json:"source"`
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	ImageURL         string   `json:"image_url"`
+	URL              string   `json:"url"`
+	Meta             string   `json:"meta,omitempty"`
+	Badge            string   `json:"badge,omitempty"`
+	Followers        int      `json:"followers,omitempty"`
+	MonthlyListeners int      `json:"monthly_listeners,omitempty"`
+	Fans             int      `json:"fans,omitempty"`
+	Albums           int      `json:"albums,omitempty"`
+	HasRadio         bool     `json:"has_radio,omitempty"`
+	Genre            string   `json:"genre,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+	CreationDate     int      `json:"creation_date,omitempty"`
+	Members          int      `json:"members,omitempty"`
+}
+
+// ArtistsAPIFilters holds the slider ranges of the whole dataset and the values applied to this list
+type ArtistsAPIFilters struct {
+	Year    ArtistsAPIRange `json:"year"`
+	Members ArtistsAPIRange `json:"members"`
+	// FirstAlbum dates are YYYY-MM-DD
+	FirstAlbum ArtistsAPIDateRange `json:"first_album"`
+	Location   string              `json:"location,omitempty"`
+}
+
+type ArtistsAPIRange struct {
+	Min      int `json:"min"`
+	Max      int `json:"max"`
+	ValueMin int `json:"value_min"`
+	ValueMax int `json:"value_max"`
+}
+
+type ArtistsAPIDateRange struct {
+	Min  string `json:"min"`
+	Max  string `json:"max"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ArtistsAPIHandler returns the artists list of a source as JSON
+// It takes the same `q`, `year_min`, `year_max`, `members_min`, `members_max`, `sort`, `page` and `limit` params as `/artists`
+func ArtistsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		renderJSONError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	source := getSource(r)
+	data, err := buildArtistsData(r, source)
+	if err != nil {
+		renderJSONError(w, r, http.StatusBadGateway, "failed to load artists")
+		return
+	}
+
+	// Meta lines are display text, so they follow Accept-Language like the page
+	localizeCards(data.Cards, resolveLocale(r))
+	writeJSON(w, http.StatusOK, artistsAPIResponse(source, data))
+}
+
+func artistsAPIResponse(source string, data ArtistsPageData) ArtistsAPIResponse {
+	resp := ArtistsAPIResponse{
+		Source:        source,
+		Query:         data.Query,
+		Sort:          data.Sort,
+		Count:         len(data.Cards),
+		Artists:       make([]ArtistAPIItem, 0, len(data.Cards)),
+		Featured:      data.ShowingFeatured,
+		QueryTooShort: data.QueryTooShort,
+	}
+	if p := data.Pagination; p != nil {
+		resp.Total, resp.Page, resp.PageCount = p.Total, p.Page, p.PageCount
+	}
+	for _, c := range data.Cards {
+		resp.Artists = append(resp.Artists, ArtistAPIItem{
+			Source:           c.Source,
+			ID:               c.ArtistID,
+			Name:             c.Name,
+			ImageURL:         c.ImageURL,
+			URL:              c.LinkURL,
+			Meta:             c.Meta,
+			Badge:            c.Badge,
+			Followers:        c.Followers,
+			MonthlyListeners: c.MonthlyListeners,
+			Fans:             c.Fans,
+			Albums:           c.Albums,
+			HasRadio:         c.HasRadio,
+			Genre:            c.Genre,
+			Tags:             c.Tags,
+			CreationDate:     c.CreationDate,
+			Members:          c.Members,
+		})
+	}
+
+	// Only Groupie computes bounds, a zero max means there are no range filters
+	if data.YearMaxBound > 0 {
+		resp.Filters = &ArtistsAPIFilters{
+			Year: ArtistsAPIRange{
+				Min:      data.YearMinBound,
+				Max:      data.YearMaxBound,
+				ValueMin: data.YearMinValue,
+				ValueMax: data.YearMaxValue,
+			},
+			Members: ArtistsAPIRange{
+				Min:      data.MembersMinBound,
+				Max:      data.MembersMaxBound,
+				ValueMin: data.MembersMinValue,
+				ValueMax: data.MembersMaxValue,
+			},
+			FirstAlbum: ArtistsAPIDateRange{
+				Min:  data.AlbumMinBound,
+				Max:  data.AlbumMaxBound,
+				From: data.AlbumFrom,
+				To:   data.AlbumTo,
+			},
+			Location: data.Location,
+		}
+	}
+
+	return resp
+}
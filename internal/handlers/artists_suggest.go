@@ -1,14 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
-	"palasgroupietracker/internal/api"
 	"palasgroupietracker/internal/geo"
 )
 
@@ -21,17 +26,53 @@ type Suggestion struct {
 
 type suggestItem struct {
 	Suggestion
-	norm string
+	norm       string
+	lowerLabel string
+}
+
+type scoredSuggestItem struct {
+	item  *suggestItem
+	score int
+}
+
+// suggestIndex holds the corpus plus rune buckets so prefix queries skip most of the scan
+type suggestIndex struct {
+	items      []suggestItem
+	byPrefix   map[rune][]int
+	byWordHead map[rune][]int
 }
 
 var (
-	suggestCacheMu      sync.Mutex
-	suggestCacheFetched time.Time
-	suggestCacheItems   []suggestItem
+	suggestCacheMu         sync.Mutex
+	suggestCacheFetched    time.Time
+	suggestCacheIndex      *suggestIndex
+	suggestCacheRefreshing bool
+	// suggestCacheRetryAt holds off the next refresh after a failed one
+	suggestCacheRetryAt time.Time
 )
 
 const suggestCacheTTL = 10 * time.Minute
 
+// suggestRefreshRetry spaces out refresh attempts while the Groupie API is failing
+const suggestRefreshRetry = time.Minute
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 25
+)
+
+// suggestLimit reads `?limit=`, falling back to SUGGEST_LIMIT then 10, clamped to 1..25
+func suggestLimit(r *http.Request) int {
+	n, err := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("limit")))
+	if err != nil {
+		n, err = strconv.Atoi(strings.TrimSpace(os.Getenv("SUGGEST_LIMIT")))
+		if err != nil {
+			n = defaultSuggestLimit
+		}
+	}
+	return min(max(n, 1), maxSuggestLimit)
+}
+
 // ArtistsSuggestHandler returns search suggestions for the artists page
 // It is intentionally limited to Groupie mode to keep it deterministic and fast
 func ArtistsSuggestHandler(w http.ResponseWriter, r *http.Request) {
@@ -51,89 +92,215 @@ func ArtistsSuggestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	items, err := getGroupieSuggestItems()
+	idx, err := getGroupieSuggestIndex()
 	if err != nil {
-		http.Error(w, "failed to build suggestions", http.StatusInternalServerError)
+		// The corpus needs the Groupie API, an outage shouldn't break typing in the search box
+		// The header tells API clients the empty list is a failure, not a lack of matches
+		w.Header().Set("X-Suggest-Status", "unavailable")
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, []Suggestion{})
 		return
 	}
 
-	type scored struct {
-		item  suggestItem
-		score int
+	// Ask for one extra match to know whether the list was cut
+	limit := suggestLimit(r)
+	out := idx.match(q, limit+1)
+	if len(out) > limit {
+		out = out[:limit]
+		w.Header().Set("X-Suggest-Truncated", "true")
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// newSuggestIndex buckets corpus items by the first rune of their name and of each inner word
+func newSuggestIndex(items []suggestItem) *suggestIndex {
+	idx := &suggestIndex{
+		items:      items,
+		byPrefix:   make(map[rune][]int, 64),
+		byWordHead: make(map[rune][]int, 64),
 	}
 
-	// Lower score is better
-	matches := make([]scored, 0, 16)
-	for _, it := range items {
+	for i := range items {
+		it := &items[i]
+		it.lowerLabel = strings.ToLower(it.Label)
 		if it.norm == "" {
 			continue
 		}
-		if !strings.Contains(it.norm, q) {
-			continue
+
+		first, _ := utf8.DecodeRuneInString(it.norm)
+		idx.byPrefix[first] = append(idx.byPrefix[first], i)
+
+		// Only index each rune once per item so buckets don't hold duplicates
+		heads := make(map[rune]struct{}, 4)
+		for pos := strings.IndexByte(it.norm, ' '); pos >= 0; {
+			rest := it.norm[pos+1:]
+			if r, size := utf8.DecodeRuneInString(rest); size > 0 {
+				if _, ok := heads[r]; !ok {
+					heads[r] = struct{}{}
+					idx.byWordHead[r] = append(idx.byWordHead[r], i)
+				}
+			}
+			next := strings.IndexByte(rest, ' ')
+			if next < 0 {
+				break
+			}
+			pos += next + 1
 		}
-		score := 2
+	}
+
+	return idx
+}
+
+// match returns up to limit suggestions for an already-normalized query
+// Prefix and word-start hits come from the rune buckets, the full scan only runs when
+// those tiers can't fill the result on their own
+func (idx *suggestIndex) match(q string, limit int) []Suggestion {
+	out := make([]Suggestion, 0, limit)
+	if q == "" || limit <= 0 {
+		return out
+	}
+
+	first, _ := utf8.DecodeRuneInString(q)
+
+	// Lower score is better
+	matches := make([]scoredSuggestItem, 0, 16)
+	for _, i := range idx.byPrefix[first] {
+		it := &idx.items[i]
 		if strings.HasPrefix(it.norm, q) {
-			score = 0
-		} else if strings.Contains(it.norm, " "+q) {
-			score = 1
+			matches = append(matches, scoredSuggestItem{item: it, score: 0})
+		}
+	}
+	for _, i := range idx.byWordHead[first] {
+		it := &idx.items[i]
+		if !strings.HasPrefix(it.norm, q) && strings.Contains(it.norm, " "+q) {
+			matches = append(matches, scoredSuggestItem{item: it, score: 1})
+		}
+	}
+
+	seen := make(map[string]struct{}, 16)
+	out = appendRankedSuggestions(out, matches, seen, limit)
+	if len(out) >= limit {
+		return out
+	}
+
+	// Substring fallback: everything that matched neither tier above
+	matches = matches[:0]
+	for i := range idx.items {
+		it := &idx.items[i]
+		if it.norm == "" || !strings.Contains(it.norm, q) {
+			continue
+		}
+		if strings.HasPrefix(it.norm, q) || strings.Contains(it.norm, " "+q) {
+			continue
 		}
-		matches = append(matches, scored{item: it, score: score})
+		matches = append(matches, scoredSuggestItem{item: it, score: 2})
 	}
 
+	return appendRankedSuggestions(out, matches, seen, limit)
+}
+
+// appendRankedSuggestions sorts matches and appends unseen ones to out until limit is reached
+func appendRankedSuggestions(out []Suggestion, matches []scoredSuggestItem, seen map[string]struct{}, limit int) []Suggestion {
 	sort.SliceStable(matches, func(i, j int) bool {
 		if matches[i].score != matches[j].score {
 			return matches[i].score < matches[j].score
 		}
 		if matches[i].item.Type != matches[j].item.Type {
 			// Keep consistent ordering: group -> member -> location
-			order := func(t string) int {
-				switch t {
-				case "group":
-					return 0
-				case "member":
-					return 1
-				case "location":
-					return 2
-				default:
-					return 3
-				}
-			}
-			return order(matches[i].item.Type) < order(matches[j].item.Type)
+			return suggestTypeOrder(matches[i].item.Type) < suggestTypeOrder(matches[j].item.Type)
 		}
-		return strings.ToLower(matches[i].item.Label) < strings.ToLower(matches[j].item.Label)
+		return matches[i].item.lowerLabel < matches[j].item.lowerLabel
 	})
 
-	out := make([]Suggestion, 0, 10)
-	seen := make(map[string]struct{}, 16)
 	for _, m := range matches {
-		k := m.item.Type + "\x00" + strings.ToLower(m.item.Label)
+		if len(out) >= limit {
+			break
+		}
+		k := m.item.Type + "\x00" + m.item.lowerLabel
 		if _, ok := seen[k]; ok {
 			continue
 		}
 		seen[k] = struct{}{}
 		out = append(out, m.item.Suggestion)
-		if len(out) >= 10 {
-			break
-		}
 	}
 
-	writeJSON(w, http.StatusOK, out)
+	return out
+}
+
+// suggestTypeOrder ranks suggestion types: group -> member -> location
+func suggestTypeOrder(t string) int {
+	switch t {
+	case "group":
+		return 0
+	case "member":
+		return 1
+	case "location":
+		return 2
+	default:
+		return 3
+	}
 }
 
-func getGroupieSuggestItems() ([]suggestItem, error) {
+func getGroupieSuggestIndex() (*suggestIndex, error) {
 	suggestCacheMu.Lock()
-	if !suggestCacheFetched.IsZero() && time.Since(suggestCacheFetched) < suggestCacheTTL && len(suggestCacheItems) > 0 {
-		cached := suggestCacheItems
+	if suggestCacheIndex != nil && len(suggestCacheIndex.items) > 0 {
+		cached := suggestCacheIndex
+		if time.Since(suggestCacheFetched) >= suggestCacheTTL && !suggestCacheRefreshing && time.Now().After(suggestCacheRetryAt) {
+			// Serve the stale corpus right away and rebuild it in the background
+			// A failed rebuild keeps the stale corpus, it only errors below when nothing was ever built
+			suggestCacheRefreshing = true
+			go refreshGroupieSuggestIndex()
+		}
 		suggestCacheMu.Unlock()
 		return cached, nil
 	}
 	suggestCacheMu.Unlock()
 
-	artists, err := api.FetchArtists()
+	// Cold cache: the first request has to wait for the corpus
+	items, err := buildGroupieSuggestItems()
+	if err != nil {
+		return nil, err
+	}
+	idx := newSuggestIndex(items)
+
+	suggestCacheMu.Lock()
+	suggestCacheIndex = idx
+	suggestCacheFetched = time.Now()
+	suggestCacheMu.Unlock()
+
+	return idx, nil
+}
+
+// refreshGroupieSuggestIndex rebuilds the corpus and swaps it in, keeping the stale one on failure
+func refreshGroupieSuggestIndex() {
+	items, err := buildGroupieSuggestItems()
+	if err == nil && len(items) == 0 {
+		err = errors.New("empty corpus")
+	}
+	var idx *suggestIndex
+	if err == nil {
+		idx = newSuggestIndex(items)
+	}
+
+	suggestCacheMu.Lock()
+	defer suggestCacheMu.Unlock()
+	suggestCacheRefreshing = false
+	if idx == nil {
+		log.Println("suggest: refresh failed, serving the previous corpus:", err)
+		suggestCacheRetryAt = time.Now().Add(suggestRefreshRetry)
+		return
+	}
+	suggestCacheIndex = idx
+	suggestCacheFetched = time.Now()
+}
+
+// buildGroupieSuggestItems fetches Groupie artists and relations and builds the suggestion corpus
+func buildGroupieSuggestItems() ([]suggestItem, error) {
+	artists, err := fetchGroupieArtists(context.Background())
 	if err != nil {
 		return nil, err
 	}
-	relations, err := api.FetchRelations()
+	relations, err := fetchGroupieRelations(context.Background())
 	if err != nil {
 		return nil, err
 	}
@@ -206,11 +373,6 @@ func getGroupieSuggestItems() ([]suggestItem, error) {
 		return strings.ToLower(items[i].Label) < strings.ToLower(items[j].Label)
 	})
 
-	suggestCacheMu.Lock()
-	suggestCacheItems = items
-	suggestCacheFetched = time.Now()
-	suggestCacheMu.Unlock()
-
 	return items, nil
 }
 
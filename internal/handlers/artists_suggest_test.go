@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"palasgroupietracker/internal/api"
+)
+
+var suggestWords = []string{
+	"black", "queen", "stone", "river", "electric", "night", "velvet", "echo",
+	"silver", "wolf", "paris", "new york", "lagos", "osaka", "saint", "john",
+}
+
+// syntheticSuggestItems builds a deterministic corpus of n groups, each with members and a location
+func syntheticSuggestItems(n int) []suggestItem {
+	items := make([]suggestItem, 0, n*4)
+	add := func(t, label, target string) {
+		items = append(items, suggestItem{
+			Suggestion: Suggestion{Type: t, Label: label, Value: label, Target: target},
+			norm:       normalizeForMatch(label),
+		})
+	}
+	w := func(i int) string { return suggestWords[i%len(suggestWords)] }
+	for i := 0; i < n; i++ {
+		add("group", fmt.Sprintf("%s %s %d", w(i), w(i/7+3), i), "q")
+		add("member", fmt.Sprintf("%s %s%d", w(i+5), w(i/3), i), "q")
+		add("member", fmt.Sprintf("%s-%s %d", w(i+9), w(i+2), i), "q")
+		add("location", fmt.Sprintf("%s, %s %d", w(i+11), w(i/5), i), "location")
+	}
+	return items
+}
+
+// scanSuggest is the plain full-scan matcher the index has to agree with
+func scanSuggest(items []suggestItem, q string, limit int) []Suggestion {
+	matches := make([]scoredSuggestItem, 0, 16)
+	for i := range items {
+		it := &items[i]
+		switch {
+		case it.norm == "" || !strings.Contains(it.norm, q):
+			continue
+		case strings.HasPrefix(it.norm, q):
+			matches = append(matches, scoredSuggestItem{item: it, score: 0})
+		case strings.Contains(it.norm, " "+q):
+			matches = append(matches, scoredSuggestItem{item: it, score: 1})
+		default:
+			matches = append(matches, scoredSuggestItem{item: it, score: 2})
+		}
+	}
+	return appendRankedSuggestions(make([]Suggestion, 0, limit), matches, map[string]struct{}{}, limit)
+}
+
+func TestSuggestIndexMatchesFullScan(t *testing.T) {
+	idx := newSuggestIndex(syntheticSuggestItems(500))
+	queries := []string{"bl", "black", "queen", "york", "ne", "ck", "er", "12", "lagos 3", "zz", "e"}
+	for _, q := range queries {
+		for _, limit := range []int{1, 10, 26, 5000} {
+			got := idx.match(q, limit)
+			want := scanSuggest(idx.items, q, limit)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("match(%q, %d) differs from the full scan\n got %v\nwant %v", q, limit, got, want)
+			}
+		}
+	}
+}
+
+func TestSuggestIndexRanking(t *testing.T) {
+	items := []suggestItem{
+		{Suggestion: Suggestion{Type: "location", Label: "Queens, USA"}, norm: "queens usa"},
+		{Suggestion: Suggestion{Type: "member", Label: "Freddie Queen"}, norm: "freddie queen"},
+		{Suggestion: Suggestion{Type: "group", Label: "Queen"}, norm: "queen"},
+		{Suggestion: Suggestion{Type: "group", Label: "Bequeened"}, norm: "bequeened"},
+	}
+	got := newSuggestIndex(items).match("queen", 10)
+
+	var labels []string
+	for _, s := range got {
+		labels = append(labels, s.Label)
+	}
+	// Prefix before word start before substring, then group, member, location
+	want := []string{"Queen", "Queens, USA", "Freddie Queen", "Bequeened"}
+	if !reflect.DeepEqual(labels, want) {
+		t.Fatalf("labels = %v, want %v", labels, want)
+	}
+}
+
+// useSuggestIndex serves the suggest handler from items instead of the Groupie API
+func useSuggestIndex(tb testing.TB, items []suggestItem) {
+	tb.Helper()
+	suggestCacheMu.Lock()
+	prevIndex, prevFetched := suggestCacheIndex, suggestCacheFetched
+	suggestCacheIndex = newSuggestIndex(items)
+	suggestCacheFetched = time.Now()
+	suggestCacheMu.Unlock()
+
+	tb.Cleanup(func() {
+		suggestCacheMu.Lock()
+		suggestCacheIndex, suggestCacheFetched = prevIndex, prevFetched
+		suggestCacheMu.Unlock()
+	})
+}
+
+// useGroupieCorpus serves the Groupie dataset from artists and relations instead of the API
+// The suggestion index is dropped so the next lookup builds it from the same corpus
+func useGroupieCorpus(t *testing.T, artists []api.Artist, relations []api.Relation) {
+	t.Helper()
+	prevArtists, prevRelations := fetchGroupieArtists, fetchGroupieRelations
+	fetchGroupieArtists = func(context.Context) ([]api.Artist, error) { return artists, nil }
+	fetchGroupieRelations = func(context.Context) (*api.RelationIndex, error) {
+		return &api.RelationIndex{Index: relations}, nil
+	}
+	useSuggestIndex(t, nil)
+	suggestCacheMu.Lock()
+	suggestCacheIndex = nil
+	suggestCacheMu.Unlock()
+
+	t.Cleanup(func() { fetchGroupieArtists, fetchGroupieRelations = prevArtists, prevRelations })
+}
+
+func TestGroupieSearchFoldsAccents(t *testing.T) {
+	useGroupieCorpus(t, []api.Artist{
+		{ID: 1, Name: "Beyoncé", Members: []string{"Beyoncé Knowles"}, CreationDate: 1997, FirstAlbum: "24-06-2003"},
+		{ID: 2, Name: "Queen", Members: []string{"Freddie Mercury", "Brian May"}, CreationDate: 1970, FirstAlbum: "13-07-1973"},
+		{ID: 3, Name: "Björk", Members: []string{"Björk Guðmundsdóttir"}, CreationDate: 1977, FirstAlbum: "01-12-1977"},
+	}, []api.Relation{
+		{ID: 1, DatesLocations: map[string][]string{"houston-usa": {"04-09-2023"}}},
+		{ID: 2, DatesLocations: map[string][]string{"london-uk": {"12-07-1986"}}},
+		{ID: 3, DatesLocations: map[string][]string{"reykjavik-iceland": {"01-01-2020"}}},
+	})
+
+	for _, q := range []string{"beyonce", "BEYONCE", "Beyoncé", "beyo"} {
+		t.Run(q, func(t *testing.T) {
+			// The suggestion corpus finds it...
+			w := httptest.NewRecorder()
+			ArtistsSuggestHandler(w, httptest.NewRequest(http.MethodGet, "/artists/suggest?source=groupie&q="+url.QueryEscape(q), nil))
+			var got []Suggestion
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("bad JSON %q: %v", w.Body.String(), err)
+			}
+			if len(got) == 0 || got[0].Label != "Beyoncé" || got[0].Type != "group" {
+				t.Fatalf("suggestions = %+v, want the group Beyoncé first", got)
+			}
+
+			// ...and picking it keeps it in the filtered list
+			r := httptest.NewRequest(http.MethodGet, "/artists?source=groupie&q="+url.QueryEscape(q), nil)
+			data, err := buildGroupieData(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(data.Cards) != 1 || data.Cards[0].Name != "Beyoncé" {
+				t.Fatalf("cards = %v, want only Beyoncé", relevanceNames(data.Cards))
+			}
+
+			cards, err := groupieSource{}.Search(context.Background(), q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cards) == 0 || cards[0].Name != "Beyoncé" {
+				t.Fatalf("Search = %v, want Beyoncé first", relevanceNames(cards))
+			}
+		})
+	}
+
+	// Folding works both ways, an unaccented name matches an accented query
+	r := httptest.NewRequest(http.MethodGet, "/artists?source=groupie&q="+url.QueryEscape("QUÉEN"), nil)
+	data, err := buildGroupieData(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := relevanceNames(data.Cards); !reflect.DeepEqual(names, []string{"Queen"}) {
+		t.Fatalf("cards = %v, want [Queen]", names)
+	}
+}
+
+func BenchmarkSuggest(b *testing.B) {
+	items := syntheticSuggestItems(5000)
+	useSuggestIndex(b, items)
+
+	for _, bc := range []struct{ name, q string }{
+		{"prefix", "black"},
+		{"word", "york"},
+		{"substring", "ck"},
+		{"none", "zzz"},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			r := httptest.NewRequest(http.MethodGet, "/artists/suggest?q="+bc.q, nil)
+			b.ReportAllocs()
+			for b.Loop() {
+				ArtistsSuggestHandler(httptest.NewRecorder(), r)
+			}
+		})
+		// The same query without the index, to keep the improvement measurable
+		b.Run(bc.name+"/fullscan", func(b *testing.B) {
+			q := normalizeForMatch(bc.q)
+			b.ReportAllocs()
+			for b.Loop() {
+				scanSuggest(items, q, defaultSuggestLimit+1)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"palasgroupietracker/internal/store"
+)
+
+const (
+	// artistsViewCookie remembers the artists page layout for visitors and across reloads
+	artistsViewCookie  = "artists_view"
+	defaultArtistsView = "grid"
+)
+
+// normalizeArtistsView accepts the known layouts, ok is false for anything else
+func normalizeArtistsView(v string) (string, bool) {
+	switch v = strings.ToLower(strings.TrimSpace(v)); v {
+	case "grid", "list":
+		return v, true
+	default:
+		return "", false
+	}
+}
+
+// resolveArtistsView picks the layout for the artists page
+// An explicit `?view=` wins and is remembered (cookie, plus the account when logged in),
+// then the cookie, then the saved account preference, then the grid
+func resolveArtistsView(w http.ResponseWriter, r *http.Request, user *store.User) string {
+	if v, ok := normalizeArtistsView(r.URL.Query().Get("view")); ok {
+		// Live filtering sends the view with every request, only save actual changes
+		if c, err := r.Cookie(artistsViewCookie); err == nil && c.Value == v {
+			return v
+		}
+		setArtistsViewCookie(w, r, v)
+		if user != nil && appStore != nil {
+			// Best effort, the cookie already covers this browser
+			_ = appStore.SetArtistsView(r.Context(), user.ID, v)
+		}
+		return v
+	}
+
+	if c, err := r.Cookie(artistsViewCookie); err == nil {
+		if v, ok := normalizeArtistsView(c.Value); ok {
+			return v
+		}
+	}
+
+	if user != nil && appStore != nil {
+		if saved, err := appStore.GetArtistsView(r.Context(), user.ID); err == nil {
+			if v, ok := normalizeArtistsView(saved); ok {
+				return v
+			}
+		}
+	}
+
+	return defaultArtistsView
+}
+
+func setArtistsViewCookie(w http.ResponseWriter, r *http.Request, view string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     artistsViewCookie,
+		Value:    view,
+		Path:     sessionCookiePath,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().AddDate(1, 0, 0),
+	})
+}
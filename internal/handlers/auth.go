@@ -3,12 +3,16 @@ package handlers
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"html/template"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,26 +24,40 @@ import (
 const sessionCookieName = "gt_session"
 const sessionDuration = 14 * 24 * time.Hour
 
+// passwordHashCost is the bcrypt cost of every stored password, whether set at register,
+// on the account page or from a reset link
+const passwordHashCost = 12
+
+// shortSessionDuration is the server-side lifetime of a login without "remember me",
+// its cookie has no expiry and ends with the browser session
+const shortSessionDuration = 24 * time.Hour
+
+// registerResubmitWindow is how recent an account must be for a repeated register post
+// with the same credentials to count as a double submit instead of a duplicate email
+const registerResubmitWindow = 2 * time.Minute
+
+// Failed logins lock out the email after 5 tries and the client IP after 20 in 15 minutes,
+// every further failure doubles the lockout up to a day
+// The IP limit is looser since one address may be a whole office behind NAT, and it only
+// applies when TRUSTED_PROXY_HOPS is set (see loginIPKey)
+var (
+	loginEmailLimiter = newFailureLimiter(5, 15*time.Minute, 24*time.Hour)
+	loginIPLimiter    = newFailureLimiter(20, 15*time.Minute, 24*time.Hour)
+)
+
 // AuthPageData powers the login and register pages
 type AuthPageData struct {
-	Title      string
-	Source     string
-	ActiveNav  string
-	BasePath   string
-	CurrentURL string
-	User       *store.User
-	IsAuthed   bool
+	LayoutData
 
 	Email   string
 	Error   string
 	NextURL string
+	// Remember is the state of the login form's "remember me" box
+	Remember bool
 }
 
 // LoginHandler renders and processes the login form
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	source := getSource(r)
-	basePath := getBasePath(r)
-
 	if r.Method == http.MethodPost {
 		handleLoginPost(w, r)
 		return
@@ -52,26 +70,18 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := AuthPageData{
-		Title:      "Login",
-		Source:     source,
-		ActiveNav:  "",
-		BasePath:   basePath,
-		CurrentURL: buildCurrentURL(r),
-		User:       user,
-		IsAuthed:   authed,
+		LayoutData: newLayoutData(w, r, "Login", user, authed),
 		Email:      "",
 		Error:      "",
 		NextURL:    resolveNextURL(r.URL.Query().Get("next"), r),
+		Remember:   true,
 	}
 
-	renderAuthTemplate(w, data, "web/templates/login.gohtml")
+	renderAuthTemplate(w, r, data, "web/templates/login.gohtml")
 }
 
 // RegisterHandler renders and processes the registration form
 func RegisterHandler(w http.ResponseWriter, r *http.Request) {
-	source := getSource(r)
-	basePath := getBasePath(r)
-
 	if r.Method == http.MethodPost {
 		handleRegisterPost(w, r)
 		return
@@ -84,19 +94,13 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := AuthPageData{
-		Title:      "Create account",
-		Source:     source,
-		ActiveNav:  "",
-		BasePath:   basePath,
-		CurrentURL: buildCurrentURL(r),
-		User:       user,
-		IsAuthed:   authed,
+		LayoutData: newLayoutData(w, r, "Create account", user, authed),
 		Email:      "",
 		Error:      "",
 		NextURL:    resolveNextURL(r.URL.Query().Get("next"), r),
 	}
 
-	renderAuthTemplate(w, data, "web/templates/register.gohtml")
+	renderAuthTemplate(w, r, data, "web/templates/register.gohtml")
 }
 
 // LogoutHandler clears the session cookie and deletes the server session
@@ -105,11 +109,15 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, withBasePath(r, "/"), http.StatusSeeOther)
 		return
 	}
+	if !requireCSRF(w, r) {
+		return
+	}
 
 	if appStore != nil {
-		if cookie, err := r.Cookie(sessionCookieName); err == nil {
-			tokenHash := hashToken(cookie.Value)
-			_ = appStore.DeleteSessionByTokenHash(r.Context(), tokenHash)
+		for _, cookie := range r.Cookies() {
+			if cookie.Name == sessionCookieName && cookie.Value != "" {
+				_ = appStore.DeleteSessionByTokenHash(r.Context(), hashToken(cookie.Value))
+			}
 		}
 	}
 
@@ -118,111 +126,141 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleLoginPost(w http.ResponseWriter, r *http.Request) {
-	source := getSource(r)
-	basePath := getBasePath(r)
-	currentURL := buildCurrentURL(r)
-
+	if !requireCSRF(w, r) {
+		return
+	}
 	if appStore == nil {
 		data := AuthPageData{
-			Title:      "Login",
-			Source:     source,
-			ActiveNav:  "",
-			BasePath:   basePath,
-			CurrentURL: currentURL,
-			User:       nil,
-			IsAuthed:   false,
+			LayoutData: newLayoutData(w, r, "Login", nil, false),
 			Email:      "",
 			Error:      "Database is not configured.",
 			NextURL:    resolveNextURL(r.FormValue("next"), r),
+			Remember:   rememberLogin(r),
 		}
-		renderAuthTemplate(w, data, "web/templates/login.gohtml")
+		renderAuthTemplate(w, r, data, "web/templates/login.gohtml")
 		return
 	}
 
+	// A second tab can post the form while already logged in, the header should say so
+	current, authed := getCurrentUser(w, r)
+
 	email := strings.TrimSpace(r.FormValue("email"))
 	password := r.FormValue("password")
 	next := resolveNextURL(r.FormValue("next"), r)
+	remember := rememberLogin(r)
 
 	if email == "" || password == "" {
 		data := AuthPageData{
-			Title:      "Login",
-			Source:     source,
-			ActiveNav:  "",
-			BasePath:   basePath,
-			CurrentURL: currentURL,
+			LayoutData: newLayoutData(w, r, "Login", current, authed),
 			Email:      email,
 			Error:      "Email and password are required.",
 			NextURL:    next,
+			Remember:   remember,
+		}
+		renderAuthTemplate(w, r, data, "web/templates/login.gohtml")
+		return
+	}
+
+	// Checked before the database and bcrypt, so a locked out client costs nothing
+	emailKey, ipKey := "email:"+strings.ToLower(email), loginIPKey(r)
+	now := time.Now()
+	wait := loginEmailLimiter.locked(emailKey, now)
+	if ipKey != "" {
+		wait = max(wait, loginIPLimiter.locked(ipKey, now))
+	}
+	if wait > 0 {
+		data := AuthPageData{
+			LayoutData: newLayoutData(w, r, "Login", current, authed),
+			Email:      email,
+			Error:      "Too many login attempts, try again in " + lockoutMinutes(wait) + ".",
+			NextURL:    next,
+			Remember:   remember,
 		}
-		renderAuthTemplate(w, data, "web/templates/login.gohtml")
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Round(time.Second)/time.Second)))
+		renderAuthTemplateStatus(w, r, http.StatusTooManyRequests, data, "web/templates/login.gohtml")
 		return
 	}
+	failed := func() {
+		loginEmailLimiter.fail(emailKey, now)
+		if ipKey != "" {
+			loginIPLimiter.fail(ipKey, now)
+		}
+	}
 
 	user, err := appStore.GetUserByEmail(r.Context(), email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			failed()
 			data := AuthPageData{
-				Title:      "Login",
-				Source:     source,
-				ActiveNav:  "",
-				BasePath:   basePath,
-				CurrentURL: currentURL,
+				LayoutData: newLayoutData(w, r, "Login", current, authed),
 				Email:      email,
 				Error:      "Invalid email or password.",
 				NextURL:    next,
+				Remember:   remember,
 			}
-			renderAuthTemplate(w, data, "web/templates/login.gohtml")
+			renderAuthTemplate(w, r, data, "web/templates/login.gohtml")
 			return
 		}
-		http.Error(w, "login failed", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "login failed")
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		failed()
 		data := AuthPageData{
-			Title:      "Login",
-			Source:     source,
-			ActiveNav:  "",
-			BasePath:   basePath,
-			CurrentURL: currentURL,
+			LayoutData: newLayoutData(w, r, "Login", current, authed),
 			Email:      email,
 			Error:      "Invalid email or password.",
 			NextURL:    next,
+			Remember:   remember,
 		}
-		renderAuthTemplate(w, data, "web/templates/login.gohtml")
+		renderAuthTemplate(w, r, data, "web/templates/login.gohtml")
 		return
 	}
 
-	if err := createSession(w, r, user.ID); err != nil {
-		http.Error(w, "login failed", http.StatusInternalServerError)
+	// Only the email is cleared, a valid login to one account says nothing
+	// about the other emails tried from the same IP
+	loginEmailLimiter.reset(emailKey)
+
+	if err := createSession(w, r, user.ID, remember); err != nil {
+		renderError(w, r, http.StatusInternalServerError, "login failed")
 		return
 	}
 
 	http.Redirect(w, r, next, http.StatusSeeOther)
 }
 
-func handleRegisterPost(w http.ResponseWriter, r *http.Request) {
-	source := getSource(r)
-	basePath := getBasePath(r)
-	currentURL := buildCurrentURL(r)
+// loginIPKey is the loginIPLimiter key for the client, "" when the IP lockout is off
+// Without trusted proxy hops a reverse proxy's own address is every client's address,
+// and 20 failures from anyone would lock everybody out, so only emails are limited then
+func loginIPKey(r *http.Request) string {
+	if trustedProxyHops() == 0 {
+		return ""
+	}
+	ip := ClientIP(r)
+	if ip == "" {
+		return ""
+	}
+	return "ip:" + ip
+}
 
+func handleRegisterPost(w http.ResponseWriter, r *http.Request) {
+	if !requireCSRF(w, r) {
+		return
+	}
 	if appStore == nil {
 		data := AuthPageData{
-			Title:      "Create account",
-			Source:     source,
-			ActiveNav:  "",
-			BasePath:   basePath,
-			CurrentURL: currentURL,
-			User:       nil,
-			IsAuthed:   false,
+			LayoutData: newLayoutData(w, r, "Create account", nil, false),
 			Email:      "",
 			Error:      "Database is not configured.",
 			NextURL:    resolveNextURL(r.FormValue("next"), r),
 		}
-		renderAuthTemplate(w, data, "web/templates/register.gohtml")
+		renderAuthTemplate(w, r, data, "web/templates/register.gohtml")
 		return
 	}
 
+	current, authed := getCurrentUser(w, r)
+
 	email := strings.TrimSpace(r.FormValue("email"))
 	password := r.FormValue("password")
 	confirm := r.FormValue("confirm_password")
@@ -230,96 +268,117 @@ func handleRegisterPost(w http.ResponseWriter, r *http.Request) {
 
 	if email == "" || password == "" {
 		data := AuthPageData{
-			Title:      "Create account",
-			Source:     source,
-			ActiveNav:  "",
-			BasePath:   basePath,
-			CurrentURL: currentURL,
+			LayoutData: newLayoutData(w, r, "Create account", current, authed),
 			Email:      email,
 			Error:      "Email and password are required.",
 			NextURL:    next,
 		}
-		renderAuthTemplate(w, data, "web/templates/register.gohtml")
+		renderAuthTemplate(w, r, data, "web/templates/register.gohtml")
 		return
 	}
 
 	if len(password) < 8 {
 		data := AuthPageData{
-			Title:      "Create account",
-			Source:     source,
-			ActiveNav:  "",
-			BasePath:   basePath,
-			CurrentURL: currentURL,
+			LayoutData: newLayoutData(w, r, "Create account", current, authed),
 			Email:      email,
 			Error:      "Password must be at least 8 characters.",
 			NextURL:    next,
 		}
-		renderAuthTemplate(w, data, "web/templates/register.gohtml")
+		renderAuthTemplate(w, r, data, "web/templates/register.gohtml")
 		return
 	}
 
 	if confirm != "" && confirm != password {
 		data := AuthPageData{
-			Title:      "Create account",
-			Source:     source,
-			ActiveNav:  "",
-			BasePath:   basePath,
-			CurrentURL: currentURL,
+			LayoutData: newLayoutData(w, r, "Create account", current, authed),
 			Email:      email,
 			Error:      "Passwords do not match.",
 			NextURL:    next,
 		}
-		renderAuthTemplate(w, data, "web/templates/register.gohtml")
+		renderAuthTemplate(w, r, data, "web/templates/register.gohtml")
 		return
 	}
 
-	hashBytes, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	hashBytes, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
 	if err != nil {
-		http.Error(w, "registration failed", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "registration failed")
 		return
 	}
 
 	user, err := appStore.CreateUser(r.Context(), email, string(hashBytes))
+	if errors.Is(err, store.ErrEmailExists) {
+		// A double-submitted form collides with the account its first post just created, log in instead
+		if dup, ok := resubmittedRegistration(r, email, password); ok {
+			user, err = dup, nil
+		}
+	}
 	if err != nil {
 		if errors.Is(err, store.ErrEmailExists) {
 			data := AuthPageData{
-				Title:      "Create account",
-				Source:     source,
-				ActiveNav:  "",
-				BasePath:   basePath,
-				CurrentURL: currentURL,
+				LayoutData: newLayoutData(w, r, "Create account", current, authed),
 				Email:      email,
 				Error:      "Email already exists.",
 				NextURL:    next,
 			}
-			renderAuthTemplate(w, data, "web/templates/register.gohtml")
+			renderAuthTemplate(w, r, data, "web/templates/register.gohtml")
 			return
 		}
-		http.Error(w, "registration failed", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "registration failed")
 		return
 	}
 
-	if err := createSession(w, r, user.ID); err != nil {
-		http.Error(w, "registration failed", http.StatusInternalServerError)
+	if err := createSession(w, r, user.ID, true); err != nil {
+		renderError(w, r, http.StatusInternalServerError, "registration failed")
 		return
 	}
 
 	http.Redirect(w, r, next, http.StatusSeeOther)
 }
 
-func renderAuthTemplate(w http.ResponseWriter, data AuthPageData, pageTemplate string) {
+// resubmittedRegistration returns the account a register post collided with when it was
+// created moments ago with the same password, i.e. the form was submitted twice
+func resubmittedRegistration(r *http.Request, email, password string) (*store.User, bool) {
+	user, err := appStore.GetUserByEmail(r.Context(), email)
+	if err != nil {
+		return nil, false
+	}
+	if time.Since(user.CreatedAt) > registerResubmitWindow {
+		return nil, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, false
+	}
+	return user, true
+}
+
+func renderAuthTemplate(w http.ResponseWriter, r *http.Request, data AuthPageData, pageTemplate string) {
+	renderAuthTemplateStatus(w, r, http.StatusOK, data, pageTemplate)
+}
+
+// renderAuthTemplateStatus is renderAuthTemplate with a status other than 200
+func renderAuthTemplateStatus(w http.ResponseWriter, r *http.Request, status int, data AuthPageData, pageTemplate string) {
 	tmpl, err := templateWithLayout(pageTemplate)
 	if err != nil {
-		http.Error(w, "template error", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "template error")
 		return
 	}
 
+	w.WriteHeader(status)
 	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
-		http.Error(w, "render error", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "render error")
 		return
 	}
 }
 
+// lockoutMinutes phrases a lockout for the login error, rounded up to whole minutes
+func lockoutMinutes(d time.Duration) string {
+	m := int((d + time.Minute - 1) / time.Minute)
+	if m == 1 {
+		return "1 minute"
+	}
+	return strconv.Itoa(m) + " minutes"
+}
+
 // templateWithLayout loads layout + page template
 func templateWithLayout(pageTemplate string) (*template.Template, error) {
 	return template.ParseFiles(
@@ -328,7 +387,20 @@ func templateWithLayout(pageTemplate string) (*template.Template, error) {
 	)
 }
 
-func createSession(w http.ResponseWriter, r *http.Request, userID int64) error {
+// rememberLogin reads the login form's "remember me" box
+// The form sends a hidden remember=0 before the checkbox, so the last value wins and an
+// unticked box reads as 0; posts without the field at all keep the long session
+func rememberLogin(r *http.Request) bool {
+	values := r.PostForm["remember"]
+	if len(values) == 0 {
+		return true
+	}
+	return values[len(values)-1] != "0"
+}
+
+// createSession starts a session for userID and sets its cookie
+// Remembered sessions last sessionDuration, others shortSessionDuration with a browser-session cookie
+func createSession(w http.ResponseWriter, r *http.Request, userID int64, remember bool) error {
 	if appStore == nil {
 		return errors.New("store not configured")
 	}
@@ -338,12 +410,21 @@ func createSession(w http.ResponseWriter, r *http.Request, userID int64) error {
 		return err
 	}
 
-	expiresAt := time.Now().Add(sessionDuration)
+	duration := sessionDuration
+	if !remember {
+		duration = shortSessionDuration
+	}
+	expiresAt := time.Now().Add(duration)
 	if _, err := appStore.CreateSession(r.Context(), userID, tokenHash, expiresAt); err != nil {
 		return err
 	}
 
-	setSessionCookie(w, r, token, expiresAt)
+	cookieExpires := expiresAt
+	if !remember {
+		// No Expires makes it a session cookie, the server still ends the session after a day
+		cookieExpires = time.Time{}
+	}
+	setSessionCookie(w, r, token, cookieExpires)
 	return nil
 }
 
@@ -362,11 +443,13 @@ func hashToken(token string) string {
 	return hex.EncodeToString(sum[:])
 }
 
+// setSessionCookie sets the session cookie, a zero expiresAt leaves it a browser-session cookie
 func setSessionCookie(w http.ResponseWriter, r *http.Request, token string, expiresAt time.Time) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookieName,
 		Value:    token,
-		Path:     sessionCookiePath(r),
+		Path:     sessionCookiePath,
+		Domain:   sessionCookieDomain(),
 		HttpOnly: true,
 		Secure:   isSecureRequest(r),
 		SameSite: http.SameSiteLaxMode,
@@ -375,23 +458,142 @@ func setSessionCookie(w http.ResponseWriter, r *http.Request, token string, expi
 }
 
 func clearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	domains := []string{""}
+	if d := sessionCookieDomain(); d != "" {
+		// Also drop a host-only cookie set before the domain was configured
+		domains = []string{d, ""}
+	}
+	paths := []string{sessionCookiePath}
+	if base := getBasePath(r); base != "" {
+		// Cookies used to be scoped to the base path, expire those too
+		paths = append(paths, base+"/")
+	}
+
+	for _, domain := range domains {
+		for _, p := range paths {
+			http.SetCookie(w, &http.Cookie{
+				Name:     sessionCookieName,
+				Value:    "",
+				Path:     p,
+				Domain:   domain,
+				HttpOnly: true,
+				Secure:   isSecureRequest(r),
+				SameSite: http.SameSiteLaxMode,
+				MaxAge:   -1,
+			})
+		}
+	}
+}
+
+// sessionCookiePath is the root rather than the base path: a proxy that only sometimes sends
+// X-Forwarded-Prefix would otherwise set and look for the cookie on different paths and log users out
+const sessionCookiePath = "/"
+
+// sessionCookieDomain reads `SESSION_COOKIE_DOMAIN` (e.g. example.com) so the session is shared
+// with subdomains, "" keeps the cookie host-only, which is also the fallback for invalid values
+func sessionCookieDomain() string {
+	d := strings.ToLower(strings.TrimSpace(os.Getenv("SESSION_COOKIE_DOMAIN")))
+	// Browsers ignore a leading dot, accept it since many docs still write domains that way
+	d = strings.TrimPrefix(d, ".")
+	if !isValidCookieDomain(d) {
+		return ""
+	}
+	return d
+}
+
+// isValidCookieDomain accepts dotted hostnames only, IPs and single labels can't carry a domain cookie
+func isValidCookieDomain(d string) bool {
+	if d == "" || len(d) > 253 || !strings.Contains(d, ".") {
+		return false
+	}
+	if net.ParseIP(d) != nil {
+		return false
+	}
+	for _, label := range strings.Split(d, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CSRF uses a double-submit cookie: every POST form carries the token from the `gt_csrf` cookie,
+// a cross-site page can make the browser send the cookie but can't read it to fill in the field
+const (
+	csrfCookieName = "gt_csrf"
+	csrfFieldName  = "csrf_token"
+	// csrfHeaderName lets scripts send the token without a form field
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// csrfToken returns the visitor's CSRF token for the page's forms, issuing the cookie on first use
+// Calling it twice in one response reuses the token already being set
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	for _, cookie := range r.Cookies() {
+		if cookie.Name == csrfCookieName && isCSRFToken(cookie.Value) {
+			return cookie.Value
+		}
+	}
+	for _, line := range w.Header().Values("Set-Cookie") {
+		if c, err := http.ParseSetCookie(line); err == nil && c.Name == csrfCookieName && isCSRFToken(c.Value) {
+			return c.Value
+		}
+	}
+
+	token, _, err := newSessionToken()
+	if err != nil {
+		return ""
+	}
 	http.SetCookie(w, &http.Cookie{
-		Name:     sessionCookieName,
-		Value:    "",
-		Path:     sessionCookiePath(r),
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     sessionCookiePath,
+		Domain:   sessionCookieDomain(),
 		HttpOnly: true,
 		Secure:   isSecureRequest(r),
 		SameSite: http.SameSiteLaxMode,
-		MaxAge:   -1,
+		Expires:  time.Now().Add(sessionDuration),
 	})
+	return token
 }
 
-func sessionCookiePath(r *http.Request) string {
-	base := getBasePath(r)
-	if base == "" {
-		return "/"
+// isCSRFToken accepts values shaped like newSessionToken output, 32 random bytes in base64url
+func isCSRFToken(v string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	return err == nil && len(b) == 32
+}
+
+// validCSRF reports whether the posted `csrf_token` field (or X-CSRF-Token header) matches the cookie
+func validCSRF(r *http.Request) bool {
+	sent := r.Header.Get(csrfHeaderName)
+	if sent == "" {
+		sent = r.PostFormValue(csrfFieldName)
+	}
+	if !isCSRFToken(sent) {
+		return false
+	}
+	// A cookie from an older path or domain can be sent too, any match is enough
+	for _, cookie := range r.Cookies() {
+		if cookie.Name == csrfCookieName && subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(sent)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// requireCSRF rejects POSTs without a matching token with 403
+// It writes the error response and returns false when the request isn't allowed
+func requireCSRF(w http.ResponseWriter, r *http.Request) bool {
+	if validCSRF(r) {
+		return true
 	}
-	return base + "/"
+	renderError(w, r, http.StatusForbidden, "invalid or missing form token, reload the page and try again")
+	return false
 }
 
 func isSecureRequest(r *http.Request) bool {
@@ -404,36 +606,49 @@ func isSecureRequest(r *http.Request) bool {
 
 // getCurrentUser resolves the logged-in user from the session cookie
 func getCurrentUser(w http.ResponseWriter, r *http.Request) (*store.User, bool) {
+	_, user, ok := currentSession(w, r)
+	return user, ok
+}
+
+// currentSession is getCurrentUser plus the session the request was authenticated with
+func currentSession(w http.ResponseWriter, r *http.Request) (*store.Session, *store.User, bool) {
 	if appStore == nil {
-		return nil, false
+		return nil, nil, false
 	}
 
-	cookie, err := r.Cookie(sessionCookieName)
-	if err != nil || strings.TrimSpace(cookie.Value) == "" {
-		return nil, false
-	}
+	// An old base-path cookie and a root one can both be sent, the first live session wins
+	stale := false
+	for _, cookie := range r.Cookies() {
+		if cookie.Name != sessionCookieName || strings.TrimSpace(cookie.Value) == "" {
+			continue
+		}
 
-	tokenHash := hashToken(cookie.Value)
-	sess, err := appStore.GetSessionByTokenHash(r.Context(), tokenHash)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			clearSessionCookie(w, r)
+		tokenHash := hashToken(cookie.Value)
+		sess, err := appStore.GetSessionByTokenHash(r.Context(), tokenHash)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				stale = true
+			}
+			continue
 		}
-		return nil, false
-	}
 
-	if time.Now().After(sess.ExpiresAt) {
-		_ = appStore.DeleteSessionByTokenHash(r.Context(), tokenHash)
-		clearSessionCookie(w, r)
-		return nil, false
-	}
+		if time.Now().After(sess.ExpiresAt) {
+			_ = appStore.DeleteSessionByTokenHash(r.Context(), tokenHash)
+			stale = true
+			continue
+		}
 
-	user, err := appStore.GetUserByID(r.Context(), sess.UserID)
-	if err != nil {
-		return nil, false
+		user, err := appStore.GetUserByID(r.Context(), sess.UserID)
+		if err != nil {
+			return nil, nil, false
+		}
+		return sess, user, true
 	}
 
-	return user, true
+	if stale {
+		clearSessionCookie(w, r)
+	}
+	return nil, nil, false
 }
 
 // buildCurrentURL builds a base-path aware URL for the current request
@@ -464,18 +679,25 @@ func buildArtistsListURL(r *http.Request) string {
 	return url
 }
 
+// isLocalPath reports whether p is a path on this site, not another host through `//`, `/\`
+// or a scheme
+func isLocalPath(p string) bool {
+	return strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") &&
+		!strings.Contains(p, `\`) && !strings.Contains(p, "://")
+}
+
 // resolveNextURL sanitizes a return path to prevent open redirects
 func resolveNextURL(next string, r *http.Request) string {
 	next = strings.TrimSpace(next)
 	if next == "" {
 		return withBasePath(r, "/")
 	}
-	if strings.Contains(next, "://") || strings.HasPrefix(next, "//") {
-		return withBasePath(r, "/")
-	}
 	if !strings.HasPrefix(next, "/") {
 		next = "/" + next
 	}
+	if !isLocalPath(next) {
+		return withBasePath(r, "/")
+	}
 
 	base := getBasePath(r)
 	if base != "" && !strings.HasPrefix(next, base+"/") && next != base {
@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginIPKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+	t.Setenv("TRUSTED_PROXY_HOPS", "")
+	if got := loginIPKey(r); got != "" {
+		t.Fatalf("without trusted hops key = %q, want none", got)
+	}
+
+	t.Setenv("TRUSTED_PROXY_HOPS", "1")
+	if got, want := loginIPKey(r), "ip:203.0.113.7"; got != want {
+		t.Fatalf("key = %q, want %q", got, want)
+	}
+}
+
+// postLogin posts the login form from the client at xff
+func postLogin(email, password, xff string) *httptest.ResponseRecorder {
+	r := newFormRequest("/login", url.Values{"email": {email}, "password": {password}})
+	r.RemoteAddr = "10.0.0.1:1234"
+	if xff != "" {
+		r.Header.Set("X-Forwarded-For", xff)
+	}
+	w := httptest.NewRecorder()
+	LoginHandler(w, r)
+	return w
+}
+
+func TestLoginLockoutAndReset(t *testing.T) {
+	s := useTestStore(t)
+	newTestAccount(t, s, "locked@example.com", "right-password")
+	t.Cleanup(func() { loginEmailLimiter.reset("email:locked@example.com") })
+
+	for i := 0; i < 4; i++ {
+		if w := postLogin("locked@example.com", "wrong", ""); w.Code != http.StatusOK {
+			t.Fatalf("failure %d: status = %d, want %d", i+1, w.Code, http.StatusOK)
+		}
+	}
+	// A success before the limit clears the count
+	if w := postLogin("locked@example.com", "right-password", ""); w.Code != http.StatusSeeOther {
+		t.Fatalf("login: status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+
+	for i := 0; i < 5; i++ {
+		if w := postLogin("LOCKED@example.com", "wrong", ""); w.Code != http.StatusOK {
+			t.Fatalf("failure %d after reset: status = %d, want %d", i+1, w.Code, http.StatusOK)
+		}
+	}
+	w := postLogin("locked@example.com", "right-password", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked login: status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("locked login has no Retry-After")
+	}
+}
+
+func TestLoginIPLockoutNeedsTrustedProxy(t *testing.T) {
+	s := useTestStore(t)
+	newTestAccount(t, s, "bystander@example.com", "right-password")
+
+	// Behind an untrusted proxy everyone shares its address, failures must not add up
+	t.Setenv("TRUSTED_PROXY_HOPS", "")
+	for i := 0; i < 25; i++ {
+		postLogin("nobody"+string(rune('a'+i))+"@example.com", "wrong", "")
+	}
+	if w := postLogin("bystander@example.com", "right-password", ""); w.Code != http.StatusSeeOther {
+		t.Fatalf("bystander: status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+
+	t.Setenv("TRUSTED_PROXY_HOPS", "1")
+	t.Cleanup(func() { loginIPLimiter.reset("ip:203.0.113.9") })
+	for i := 0; i < 20; i++ {
+		postLogin("stuffed"+string(rune('a'+i))+"@example.com", "wrong", "203.0.113.9")
+	}
+	if w := postLogin("bystander@example.com", "right-password", "203.0.113.9"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("same client: status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w := postLogin("bystander@example.com", "right-password", "198.51.100.1"); w.Code != http.StatusSeeOther {
+		t.Fatalf("other client: status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+}
+
+func TestRememberLogin(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   bool
+	}{
+		{"field missing", nil, true},
+		{"box unticked", []string{"0"}, false},
+		{"box ticked", []string{"0", "1"}, true},
+		{"checkbox only", []string{"1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/login", nil)
+			r.PostForm = url.Values{"remember": tt.values}
+			if got := rememberLogin(r); got != tt.want {
+				t.Fatalf("rememberLogin = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginSessionCookieExpiry(t *testing.T) {
+	s := useTestStore(t)
+	newTestAccount(t, s, "remember@example.com", "right-password")
+
+	tests := []struct {
+		name     string
+		remember []string
+		cookie   time.Duration // 0 for a browser-session cookie
+		session  time.Duration
+	}{
+		{"ticked", []string{"0", "1"}, sessionDuration, sessionDuration},
+		{"unticked", []string{"0"}, 0, shortSessionDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newFormRequest("/login", url.Values{
+				"email":    {"remember@example.com"},
+				"password": {"right-password"},
+				"remember": tt.remember,
+			})
+			w := httptest.NewRecorder()
+			start := time.Now()
+			LoginHandler(w, r)
+			if w.Code != http.StatusSeeOther {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusSeeOther)
+			}
+
+			var c *http.Cookie
+			for _, rc := range w.Result().Cookies() {
+				if rc.Name == sessionCookieName && rc.Value != "" {
+					c = rc
+				}
+			}
+			if c == nil {
+				t.Fatal("no session cookie set")
+			}
+			if c.MaxAge != 0 {
+				t.Errorf("MaxAge = %d, want unset", c.MaxAge)
+			}
+			header := w.Header().Values("Set-Cookie")
+			if tt.cookie == 0 {
+				for _, h := range header {
+					if strings.HasPrefix(h, sessionCookieName+"=") && strings.Contains(h, "Expires=") {
+						t.Errorf("browser-session cookie has an expiry: %s", h)
+					}
+				}
+			} else if d := c.Expires.Sub(start); d < tt.cookie-time.Minute || d > tt.cookie+time.Minute {
+				t.Errorf("cookie expires in %v, want about %v", d, tt.cookie)
+			}
+
+			sess, err := s.GetSessionByTokenHash(context.Background(), hashToken(c.Value))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := sess.ExpiresAt.Sub(start); d < tt.session-time.Minute || d > tt.session+time.Minute {
+				t.Errorf("session expires in %v, want about %v", d, tt.session)
+			}
+		})
+	}
+}
+
+func TestSetSessionCookieExpiry(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+
+	w := httptest.NewRecorder()
+	setSessionCookie(w, r, "token", time.Time{})
+	if h := w.Header().Get("Set-Cookie"); strings.Contains(h, "Expires=") || strings.Contains(h, "Max-Age=") {
+		t.Fatalf("browser-session cookie has an expiry: %s", h)
+	}
+
+	expiresAt := time.Now().Add(sessionDuration).UTC().Truncate(time.Second)
+	w = httptest.NewRecorder()
+	setSessionCookie(w, r, "token", expiresAt)
+	c := w.Result().Cookies()[0]
+	if !c.Expires.Equal(expiresAt) {
+		t.Fatalf("Expires = %v, want %v", c.Expires, expiresAt)
+	}
+}
+
+// postRegister posts the register form and returns the response
+func postRegister(email, password string) *httptest.ResponseRecorder {
+	r := newFormRequest("/register", url.Values{
+		"email":            {email},
+		"password":         {password},
+		"confirm_password": {password},
+	})
+	w := httptest.NewRecorder()
+	RegisterHandler(w, r)
+	return w
+}
+
+func hasSessionCookie(w *httptest.ResponseRecorder) bool {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookieName && c.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRegisterDoubleSubmit(t *testing.T) {
+	useTestStore(t)
+
+	// Both posts of a double-clicked button end up logged in
+	for i := 0; i < 2; i++ {
+		w := postRegister("double@example.com", "password123")
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("post %d: status = %d, want %d", i+1, w.Code, http.StatusSeeOther)
+		}
+		if !hasSessionCookie(w) {
+			t.Fatalf("post %d: no session cookie", i+1)
+		}
+	}
+
+	// Someone else trying the same email still gets the error
+	w := postRegister("double@example.com", "other-password")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Email already exists.") {
+		t.Fatalf("other password: status = %d, want the email exists error", w.Code)
+	}
+	if hasSessionCookie(w) {
+		t.Fatal("other password was logged in")
+	}
+}
+
+func TestRegisterResubmitWindow(t *testing.T) {
+	s := useTestStore(t)
+	u, _ := newTestAccount(t, s, "old@example.com", "password123")
+	if _, err := s.DB.ExecContext(context.Background(),
+		`UPDATE users SET created_at = NOW() - $2 * INTERVAL '1 second' WHERE id = $1`,
+		u.ID, int((registerResubmitWindow + time.Minute).Seconds())); err != nil {
+		t.Fatal(err)
+	}
+
+	// An older account isn't a double submit, even with the right password
+	w := postRegister("old@example.com", "password123")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Email already exists.") {
+		t.Fatalf("status = %d, want the email exists error", w.Code)
+	}
+	if hasSessionCookie(w) {
+		t.Fatal("register on an old account logged in")
+	}
+}
+
+func TestCSRFToken(t *testing.T) {
+	// A first visit gets a fresh token in a cookie
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/login", nil)
+	token := csrfToken(w, r)
+	if !isCSRFToken(token) {
+		t.Fatalf("token %q is malformed", token)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != csrfCookieName || cookies[0].Value != token || !cookies[0].HttpOnly {
+		t.Fatalf("cookies = %+v, want one HttpOnly %s with the token", cookies, csrfCookieName)
+	}
+
+	// A second call in the same response reuses it
+	if again := csrfToken(w, r); again != token || len(w.Result().Cookies()) != 1 {
+		t.Fatalf("second call gave %q and %d cookies", again, len(w.Result().Cookies()))
+	}
+
+	// A returning visitor keeps theirs
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/login", nil)
+	r.AddCookie(&http.Cookie{Name: csrfCookieName, Value: testCSRFToken})
+	if got := csrfToken(w, r); got != testCSRFToken || len(w.Result().Cookies()) != 0 {
+		t.Fatalf("got %q and cookies %v, want the existing token", got, w.Result().Cookies())
+	}
+
+	// A tampered cookie is replaced
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/login", nil)
+	r.AddCookie(&http.Cookie{Name: csrfCookieName, Value: "short"})
+	if got := csrfToken(w, r); got == "short" || !isCSRFToken(got) {
+		t.Fatalf("got %q, want a fresh token", got)
+	}
+}
+
+func TestValidCSRF(t *testing.T) {
+	other := strings.Repeat("B", 43)
+	tests := []struct {
+		name    string
+		cookies []string
+		field   string
+		header  string
+		want    bool
+	}{
+		{"field matches", []string{testCSRFToken}, testCSRFToken, "", true},
+		{"header matches", []string{testCSRFToken}, "", testCSRFToken, true},
+		{"any cookie matches", []string{other, testCSRFToken}, testCSRFToken, "", true},
+		{"header wins over field", []string{testCSRFToken}, other, testCSRFToken, true},
+		{"mismatch", []string{testCSRFToken}, other, "", false},
+		{"no cookie", nil, testCSRFToken, "", false},
+		{"no token", []string{testCSRFToken}, "", "", false},
+		{"malformed pair", []string{"abc"}, "abc", "", false},
+		{"empty pair", []string{""}, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			if tt.field != "" {
+				form.Set(csrfFieldName, tt.field)
+			}
+			r := httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.header != "" {
+				r.Header.Set(csrfHeaderName, tt.header)
+			}
+			for _, c := range tt.cookies {
+				r.AddCookie(&http.Cookie{Name: csrfCookieName, Value: c})
+			}
+			if got := validCSRF(r); got != tt.want {
+				t.Fatalf("validCSRF = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStateChangingPostsRequireCSRF(t *testing.T) {
+	handlers := []struct {
+		target string
+		h      http.HandlerFunc
+	}{
+		{"/login", LoginHandler},
+		{"/register", RegisterHandler},
+		{"/logout", LogoutHandler},
+		{"/favorites/toggle", ToggleFavoriteHandler},
+	}
+	form := url.Values{"email": {"a@example.com"}, "password": {"secret123"}, "artist_id": {"1"}, "source": {"groupie"}}
+
+	for _, tt := range handlers {
+		t.Run(tt.target, func(t *testing.T) {
+			// A cross-site post carries the cookie but can't know the token
+			r := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.AddCookie(&http.Cookie{Name: csrfCookieName, Value: testCSRFToken})
+			w := httptest.NewRecorder()
+			tt.h(w, r)
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("without a token: status = %d, want 403", w.Code)
+			}
+
+			w = httptest.NewRecorder()
+			tt.h(w, newFormRequest(tt.target, maps.Clone(form)))
+			if w.Code == http.StatusForbidden {
+				t.Fatalf("with the token: status = 403")
+			}
+		})
+	}
+}
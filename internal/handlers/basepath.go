@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"strings"
 )
 
@@ -10,23 +12,37 @@ import (
 // is hosted under a subpath behind a reverse proxy (e.g. /groupie-tracker)
 func getBasePath(r *http.Request) string {
 	// Preferred: gateway sets this explicitly
-	bp := strings.TrimSpace(r.Header.Get("X-Forwarded-Prefix"))
-	if bp == "" {
+	bp, ok := cleanBasePath(r.Header.Get("X-Forwarded-Prefix"))
+	if !ok {
 		// Fallback: allow configuring locally or on platforms without that header
-		bp = strings.TrimSpace(os.Getenv("BASE_PATH"))
+		bp, _ = cleanBasePath(os.Getenv("BASE_PATH"))
 	}
+	return bp
+}
 
-	if bp == "" || bp == "/" {
-		return ""
+// cleanBasePath normalizes a prefix to "" or "/a/b": leading slash, duplicate slashes collapsed,
+// dot segments resolved and no trailing slash, so every link gets the same prefix
+// ok is false for empty values and for ones that can't be a URL path prefix (query, fragment,
+// cookie separators, spaces or control characters)
+func cleanBasePath(raw string) (string, bool) {
+	bp := strings.TrimSpace(raw)
+	if bp == "" {
+		return "", false
+	}
+	if strings.ContainsAny(bp, "?#;,\\") {
+		return "", false
 	}
-	if !strings.HasPrefix(bp, "/") {
-		bp = "/" + bp
+	for _, c := range bp {
+		if c <= ' ' || c == 0x7f {
+			return "", false
+		}
 	}
-	bp = strings.TrimRight(bp, "/")
+
+	bp = path.Clean("/" + bp)
 	if bp == "/" {
-		return ""
+		return "", true
 	}
-	return bp
+	return bp, true
 }
 
 func withBasePath(r *http.Request, p string) string {
@@ -42,3 +58,24 @@ func withBasePath(r *http.Request, p string) string {
 	}
 	return base + p
 }
+
+// publicBaseURL is the site's public address from `PUBLIC_URL`, e.g. `https://groupie.example` or
+// `https://example.com/groupie-tracker`, without a trailing slash. It's for links used outside a
+// request to this site, like password reset links
+// ok is false when it's unset or isn't an absolute http(s) URL without query or fragment
+func publicBaseURL() (string, bool) {
+	raw := strings.TrimSpace(os.Getenv("PUBLIC_URL"))
+	if raw == "" {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil ||
+		u.RawQuery != "" || u.Fragment != "" {
+		return "", false
+	}
+	p, ok := cleanBasePath(u.Path)
+	if !ok && u.Path != "" {
+		return "", false
+	}
+	return u.Scheme + "://" + u.Host + p, true
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetBasePath(t *testing.T) {
+	tests := []struct {
+		prefix, env string
+		want        string
+	}{
+		{"", "", ""},
+		{"/", "", ""},
+		{"/app", "", "/app"},
+		{"/app/", "", "/app"},
+		{"app", "", "/app"},
+		{"//app//", "", "/app"},
+		{"/team/app/", "", "/team/app"},
+		{"/team//app///", "", "/team/app"},
+		{"/team/./app/../app2", "", "/team/app2"},
+		{"/../app", "", "/app"},
+		{" /app ", "", "/app"},
+		{"/app?x=1", "/env", "/env"},
+		{"/app;path=/", "/env", "/env"},
+		{"/a b", "", ""},
+		{"", "/env/", "/env"},
+		{"/header", "/env", "/header"},
+	}
+	for _, tt := range tests {
+		t.Setenv("BASE_PATH", tt.env)
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.prefix != "" {
+			r.Header.Set("X-Forwarded-Prefix", tt.prefix)
+		}
+		if got := getBasePath(r); got != tt.want {
+			t.Errorf("prefix %q, BASE_PATH %q: base = %q, want %q", tt.prefix, tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestSessionCookiePaths(t *testing.T) {
+	t.Setenv("SESSION_COOKIE_DOMAIN", "")
+	t.Setenv("BASE_PATH", "")
+	tests := []struct {
+		prefix string
+		clear  []string
+	}{
+		{"", []string{"/"}},
+		{"/app", []string{"/", "/app/"}},
+		{"//team//app/", []string{"/", "/team/app/"}},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.prefix != "" {
+			r.Header.Set("X-Forwarded-Prefix", tt.prefix)
+		}
+
+		// The session lives at the root whatever the prefix
+		w := httptest.NewRecorder()
+		setSessionCookie(w, r, "token", time.Time{})
+		if got := w.Result().Cookies()[0].Path; got != "/" {
+			t.Errorf("prefix %q: cookie path = %q, want /", tt.prefix, got)
+		}
+
+		// Logging out also clears cookies left on the base path by older versions
+		w = httptest.NewRecorder()
+		clearSessionCookie(w, r)
+		var paths []string
+		for _, c := range w.Result().Cookies() {
+			paths = append(paths, c.Path)
+		}
+		if !reflect.DeepEqual(paths, tt.clear) {
+			t.Errorf("prefix %q: cleared paths = %v, want %v", tt.prefix, paths, tt.clear)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxFormBytes is plenty for login, register and favorite forms
+const defaultMaxFormBytes int64 = 1 << 20
+
+// MaxFormBytes reads `MAX_FORM_BYTES`, the body cap for regular form posts
+func MaxFormBytes() int64 {
+	v, err := strconv.ParseInt(strings.TrimSpace(os.Getenv("MAX_FORM_BYTES")), 10, 64)
+	if err != nil || v <= 0 {
+		return defaultMaxFormBytes
+	}
+	return v
+}
+
+// LimitFormBody caps the request body at maxBytes and parses the form up front
+// Oversized bodies get a 413 before the handler runs, and r.FormValue keeps working afterwards
+// Upload routes should wrap with their own, larger limit
+func LimitFormBody(maxBytes int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+		if err := r.ParseForm(); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				renderError(w, r, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			renderError(w, r, http.StatusBadRequest, "invalid form data")
+			return
+		}
+
+		next(w, r)
+	}
+}
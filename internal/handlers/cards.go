@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"palasgroupietracker/internal/api"
+)
+
+// ArtistCard is the artist tile shared by the home marquee, the favorites page and the artists list
+// Meta is a one-line subtitle, sources fill a short one that views may relabel, the per-source fields feed list details and sorting
+type ArtistCard struct {
+	Source   string
+	ArtistID string
+	Name     string
+	ImageURL string
+	LinkURL  string
+	Meta     string
+	Badge    string
+
+	// Per-source metadata, left at zero when the provider doesn't have it
+	Followers        int
+	MonthlyListeners int
+	Fans             int
+	Albums           int
+	HasRadio         bool
+	Genre            string
+	Tags             []string
+	CreationDate     int
+	Members          int
+
+	// Locale formats the counts in the card templates, set by localizeCards
+	Locale *Locale
+	// Count-based Meta parts, see setCountMeta
+	metaFormat  string
+	metaCount   int
+	metaCompact bool
+}
+
+// newArtistCard fills the fields every source shares, images fall back to the source placeholder
+func newArtistCard(basePath, source, id, name, imageURL, badge string) ArtistCard {
+	return ArtistCard{
+		Source:   source,
+		ArtistID: id,
+		Name:     name,
+		ImageURL: cardImage(basePath, source, imageURL),
+		LinkURL:  basePath + "/artists/" + id + "?source=" + source,
+		Badge:    badge,
+	}
+}
+
+// rebaseCards prefixes the site-relative links of cards built without a base path, see Source
+func rebaseCards(basePath string, cards []ArtistCard) {
+	if basePath == "" {
+		return
+	}
+	for i := range cards {
+		cards[i].LinkURL = basePath + cards[i].LinkURL
+		// Provider images are absolute, only the placeholders live on this site
+		if u := cards[i].ImageURL; strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//") {
+			cards[i].ImageURL = basePath + u
+		}
+	}
+}
+
+// searchSourceCards runs a source search for a view, keeping at most limit cards
+// An empty query gives the featured artists, see Source
+func searchSourceCards(ctx context.Context, src Source, basePath, query string, limit int) ([]ArtistCard, error) {
+	cards, err := src.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) > limit {
+		cards = cards[:limit]
+	}
+	rebaseCards(basePath, cards)
+	return cards, nil
+}
+
+// groupieArtistCard converts a Groupie artist
+func groupieArtistCard(basePath string, a api.Artist) ArtistCard {
+	card := newArtistCard(basePath, "groupie", strconv.Itoa(a.ID), a.Name, a.Image, "Groupie")
+	card.CreationDate = a.CreationDate
+	card.Members = len(a.Members)
+	return card
+}
+
+// spotifyArtistCard converts a Spotify artist, size picks the image for the view
+func spotifyArtistCard(basePath string, a api.SpotifyArtist, size int) ArtistCard {
+	card := newArtistCard(basePath, "spotify", a.ID, a.Name, spotifyImageFor(a.Images, size), "Spotify")
+	if a.Followers != nil {
+		card.Followers = a.Followers.Total
+	}
+	if len(a.Genres) > 0 {
+		card.Genre = a.Genres[0]
+	}
+	return card
+}
+
+// deezerArtistCard converts a Deezer artist, size picks the picture for the view
+func deezerArtistCard(basePath string, a api.DeezerArtist, size int) ArtistCard {
+	imageURL := deezerPictureFor(a.PictureSmall, a.PictureMedium, a.PictureBig, a.PictureXL, size)
+	if imageURL == "" {
+		imageURL = a.Picture
+	}
+	card := newArtistCard(basePath, "deezer", strconv.Itoa(a.ID), a.Name, imageURL, "Deezer")
+	card.Fans = a.NbFan
+	card.Albums = a.NbAlbum
+	card.HasRadio = a.Radio
+	return card
+}
+
+// appleArtistCard converts an iTunes artist, Apple has no artist images so artwork comes from an album
+func appleArtistCard(basePath string, a api.AppleArtist, artworkURL string) ArtistCard {
+	card := newArtistCard(basePath, "apple", strconv.Itoa(a.ArtistID), a.ArtistName, artworkURL, "Apple")
+	card.Genre = a.PrimaryGenreName
+	return card
+}
+
+// musicBrainzArtistCard converts a MusicBrainz artist, which always uses the placeholder image
+func musicBrainzArtistCard(basePath string, a api.MusicBrainzArtist) ArtistCard {
+	card := newArtistCard(basePath, "musicbrainz", a.ID, a.Name, "", "MusicBrainz")
+	card.Meta = musicBrainzMeta(a)
+	card.Tags = a.TopTags(3)
+	return card
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// stubSource answers searches from a fixed card list, the other methods are unused
+type stubSource struct {
+	Source
+	cards []ArtistCard
+	query string
+}
+
+func (s *stubSource) Search(_ context.Context, query string) ([]ArtistCard, error) {
+	s.query = query
+	return append([]ArtistCard(nil), s.cards...), nil
+}
+
+func TestRebaseCards(t *testing.T) {
+	cards := []ArtistCard{
+		newArtistCard("", "deezer", "27", "Daft Punk", "http://e-cdns-images.dzcdn.net/x.jpg", "Deezer"),
+		newArtistCard("", "musicbrainz", "abc", "Muse", "", "MusicBrainz"),
+		{LinkURL: "/artists/1?source=groupie", ImageURL: "//cdn.example/y.jpg"},
+	}
+
+	rebaseCards("", cards)
+	if cards[1].LinkURL != "/artists/abc?source=musicbrainz" || cards[1].ImageURL != "/static/img/placeholder-musicbrainz.svg" {
+		t.Fatalf("empty base path changed the card: %+v", cards[1])
+	}
+
+	rebaseCards("/app", cards)
+	want := [][2]string{
+		{"/app/artists/27?source=deezer", "https://e-cdns-images.dzcdn.net/x.jpg"},
+		{"/app/artists/abc?source=musicbrainz", "/app/static/img/placeholder-musicbrainz.svg"},
+		// Protocol-relative images are another host
+		{"/app/artists/1?source=groupie", "//cdn.example/y.jpg"},
+	}
+	for i, c := range cards {
+		if got := [2]string{c.LinkURL, c.ImageURL}; got != want[i] {
+			t.Errorf("card %d = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestSearchSourceCards(t *testing.T) {
+	src := &stubSource{cards: []ArtistCard{
+		newArtistCard("", "deezer", "1", "A", "", "Deezer"),
+		newArtistCard("", "deezer", "2", "B", "", "Deezer"),
+		newArtistCard("", "deezer", "3", "C", "", "Deezer"),
+	}}
+
+	cards, err := searchSourceCards(context.Background(), src, "/app", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.query != "" {
+		t.Fatalf("featured search sent query %q", src.query)
+	}
+	var links []string
+	for _, c := range cards {
+		links = append(links, c.LinkURL)
+	}
+	if want := []string{"/app/artists/1?source=deezer", "/app/artists/2?source=deezer"}; !reflect.DeepEqual(links, want) {
+		t.Fatalf("links = %v, want %v", links, want)
+	}
+	// The source's own cards keep their site-relative links
+	if src.cards[0].LinkURL != "/artists/1?source=deezer" {
+		t.Fatalf("source card changed to %q", src.cards[0].LinkURL)
+	}
+}
+
+func TestSetFavoriteCardMeta(t *testing.T) {
+	tests := []struct {
+		card ArtistCard
+		want string
+	}{
+		{ArtistCard{Source: "groupie", CreationDate: 1970, Meta: "Created 1970 • 4 members"}, "Created 1970"},
+		{ArtistCard{Source: "spotify", Followers: 1234, Meta: "Spotify artist"}, "Followers: 1,234"},
+		{ArtistCard{Source: "spotify", Genre: "rock", Meta: "Spotify artist"}, "Genre: rock"},
+		{ArtistCard{Source: "spotify", Meta: "Spotify artist"}, "Spotify artist"},
+		{ArtistCard{Source: "deezer", Fans: 5, Albums: 9, Meta: "Deezer artist"}, "Fans: 5"},
+		{ArtistCard{Source: "deezer", Albums: 9, Meta: "Deezer artist"}, "Albums: 9"},
+		{ArtistCard{Source: "apple", Genre: "Pop", Meta: "Pop"}, "Genre: Pop"},
+		{ArtistCard{Source: "musicbrainz", Meta: "Group • GB"}, "Group • GB"},
+	}
+	for _, tt := range tests {
+		setFavoriteCardMeta(&tt.card)
+		if tt.card.Meta != tt.want {
+			t.Errorf("%s card Meta = %q, want %q", tt.card.Source, tt.card.Meta, tt.want)
+		}
+	}
+}
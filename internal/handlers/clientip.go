@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// trustedProxyHops is how many reverse proxies in front of the app append to `X-Forwarded-For`
+// 0 (the default) ignores the header entirely, since any client can send it
+func trustedProxyHops() int {
+	v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("TRUSTED_PROXY_HOPS")))
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
+}
+
+// ClientIP returns the address of the client that made the request
+// Each trusted proxy appends the address it saw, so with N hops the client is the Nth entry
+// from the right, anything further left was sent by the client and can be spoofed
+// Falls back to RemoteAddr when the header is missing, too short or holds an invalid address
+// Returns "" only when RemoteAddr itself can't be parsed
+func ClientIP(r *http.Request) string {
+	if hops := trustedProxyHops(); hops > 0 {
+		entries := forwardedForEntries(r.Header)
+		if len(entries) >= hops {
+			if ip, ok := parseClientAddr(entries[len(entries)-hops]); ok {
+				return ip.String()
+			}
+		}
+	}
+
+	if ip, ok := parseClientAddr(r.RemoteAddr); ok {
+		return ip.String()
+	}
+	return ""
+}
+
+// forwardedForEntries flattens every `X-Forwarded-For` header line, keeping their order
+func forwardedForEntries(h http.Header) []string {
+	var out []string
+	for _, line := range h.Values("X-Forwarded-For") {
+		for _, part := range strings.Split(line, ",") {
+			out = append(out, strings.TrimSpace(part))
+		}
+	}
+	return out
+}
+
+// parseClientAddr accepts a bare IP or an ip:port pair, IPv4-mapped IPv6 addresses are unmapped
+func parseClientAddr(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return netip.Addr{}, false
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	// Some proxies bracket IPv6 addresses even without a port
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	// Zones are local to the proxy's host and would split one client into several keys
+	return ip.Unmap().WithZone(""), true
+}
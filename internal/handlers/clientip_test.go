@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		hops       string
+		remoteAddr string
+		xff        []string
+		want       string
+	}{
+		{"no hops ignores the header", "", "10.0.0.1:1234", []string{"203.0.113.7"}, "10.0.0.1"},
+		{"invalid hops ignores the header", "-1", "10.0.0.1:1234", []string{"203.0.113.7"}, "10.0.0.1"},
+		{"single hop", "1", "10.0.0.1:1234", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"single hop takes the last entry", "1", "10.0.0.1:1234", []string{"198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+		{"multi hop", "2", "10.0.0.2:1234", []string{"203.0.113.7, 10.0.0.1"}, "203.0.113.7"},
+		{"spoofed prefix", "2", "10.0.0.2:1234", []string{"1.2.3.4, 203.0.113.7, 10.0.0.1"}, "203.0.113.7"},
+		{"split header lines", "2", "10.0.0.2:1234", []string{"1.2.3.4", "203.0.113.7, 10.0.0.1"}, "203.0.113.7"},
+		{"chain shorter than the hops", "3", "10.0.0.2:1234", []string{"203.0.113.7, 10.0.0.1"}, "10.0.0.2"},
+		{"invalid entry", "1", "10.0.0.1:1234", []string{"not-an-ip"}, "10.0.0.1"},
+		{"header missing", "1", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"entry with a port", "1", "10.0.0.1:1234", []string{"203.0.113.7:5555"}, "203.0.113.7"},
+		{"bracketed IPv6", "1", "10.0.0.1:1234", []string{"[2001:db8::1]"}, "2001:db8::1"},
+		{"IPv6 with a port", "1", "10.0.0.1:1234", []string{"[2001:db8::1]:443"}, "2001:db8::1"},
+		{"IPv4-mapped remote address", "", "[::ffff:192.0.2.1]:80", nil, "192.0.2.1"},
+		{"zone dropped", "", "[fe80::1%eth0]:80", nil, "fe80::1"},
+		{"unparseable remote address", "", "pipe", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXY_HOPS", tt.hops)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := ClientIP(r); got != tt.want {
+				t.Fatalf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
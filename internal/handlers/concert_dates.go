@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"palasgroupietracker/internal/api"
+	"palasgroupietracker/internal/geo"
+)
+
+// ConcertDate is one Groupie concert date
+type ConcertDate struct {
+	// Value is YYYY-MM-DD for the map scripts, or the raw string when it doesn't parse
+	Value string
+	// Label is the display form in the visitor's locale, e.g. "Jan 2, 2020"
+	Label string
+	valid bool
+}
+
+// parseConcertDates turns Groupie's DD-MM-YYYY strings (some prefixed with "*") into display dates
+// Valid dates come first in chronological order, the ones that don't parse follow unchanged in their
+// original order rather than being dropped, blank ones are skipped
+func parseConcertDates(raw []string, l *Locale) []ConcertDate {
+	dates := make([]ConcertDate, 0, len(raw))
+	for _, r := range raw {
+		day, ok := api.ConcertDay(r)
+		if !ok {
+			// Blank entries carry nothing worth keeping
+			if s := strings.TrimPrefix(strings.TrimSpace(r), "*"); s != "" {
+				dates = append(dates, ConcertDate{Value: s, Label: s})
+			}
+			continue
+		}
+		t, _ := time.Parse("2006-01-02", day)
+		dates = append(dates, ConcertDate{Value: day, Label: l.Date(t), valid: true})
+	}
+
+	// ISO days sort chronologically as strings
+	sort.SliceStable(dates, func(i, j int) bool {
+		if dates[i].valid != dates[j].valid {
+			return dates[i].valid
+		}
+		return dates[i].valid && dates[i].Value < dates[j].Value
+	})
+	return dates
+}
+
+// concertDateParts splits dates into the machine values and display labels of the map JSON
+func concertDateParts(dates []ConcertDate) (values, labels []string) {
+	values = make([]string, len(dates))
+	labels = make([]string, len(dates))
+	for i, d := range dates {
+		values[i], labels[i] = d.Value, d.Label
+	}
+	return values, labels
+}
+
+// splitConcertDates separates the valid dates of a location around today, which counts as upcoming
+// Upcoming dates are soonest first and past dates most recent first, unparseable ones fit neither
+func splitConcertDates(dates []ConcertDate, now time.Time) (upcoming, past []ConcertDate) {
+	today := now.Format("2006-01-02")
+	for _, d := range dates {
+		switch {
+		case !d.valid:
+		case d.Value >= today:
+			upcoming = append(upcoming, d)
+		default:
+			past = append(past, d)
+		}
+	}
+	// dates are already ascending, so only the past ones need flipping
+	slices.Reverse(past)
+	return upcoming, past
+}
+
+// concertLabels returns the display labels of dates
+func concertLabels(dates []ConcertDate) []string {
+	_, labels := concertDateParts(dates)
+	return labels
+}
+
+// buildConcertSchedule groups tour stops into upcoming and past sections, keyed like dates
+// Upcoming stops are ordered by their next date, past stops by their most recent one
+func buildConcertSchedule(dates map[string][]ConcertDate, now time.Time) (upcoming, past []ConcertStop) {
+	type stop struct {
+		ConcertStop
+		first string
+	}
+	var up, gone []stop
+	for k, ds := range dates {
+		u, p := splitConcertDates(ds, now)
+		loc := geo.HumanizeLocationKey(k)
+		if len(u) > 0 {
+			up = append(up, stop{ConcertStop{Location: loc, Dates: concertLabels(u)}, u[0].Value})
+		}
+		if len(p) > 0 {
+			gone = append(gone, stop{ConcertStop{Location: loc, Dates: concertLabels(p)}, p[0].Value})
+		}
+	}
+	// Ties (same day) fall back to the location name so output is stable
+	sort.Slice(up, func(i, j int) bool {
+		if up[i].first != up[j].first {
+			return up[i].first < up[j].first
+		}
+		return up[i].Location < up[j].Location
+	})
+	sort.Slice(gone, func(i, j int) bool {
+		if gone[i].first != gone[j].first {
+			return gone[i].first > gone[j].first
+		}
+		return gone[i].Location < gone[j].Location
+	})
+
+	for _, s := range up {
+		upcoming = append(upcoming, s.ConcertStop)
+	}
+	for _, s := range gone {
+		past = append(past, s.ConcertStop)
+	}
+	return upcoming, past
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseConcertDates(t *testing.T) {
+	raw := []string{"05-08-2019", "*01-02-2020", "TBA", "  ", "31-02-2020", "23-01-2019", "*", "01-02-2020"}
+	got := parseConcertDates(raw, locales["en-gb"])
+
+	// Valid dates sorted chronologically, then the unparseable ones as given, blanks dropped
+	want := []ConcertDate{
+		{Value: "2019-01-23", Label: "23 Jan 2019", valid: true},
+		{Value: "2019-08-05", Label: "5 Aug 2019", valid: true},
+		{Value: "2020-02-01", Label: "1 Feb 2020", valid: true},
+		{Value: "2020-02-01", Label: "1 Feb 2020", valid: true},
+		{Value: "TBA", Label: "TBA"},
+		{Value: "31-02-2020", Label: "31-02-2020"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %+v\nwant %+v", got, want)
+	}
+
+	values, labels := concertDateParts(got)
+	if values[0] != "2019-01-23" || labels[0] != "23 Jan 2019" || values[5] != "31-02-2020" {
+		t.Fatalf("values %v, labels %v", values, labels)
+	}
+}
+
+func TestParseConcertDatesLocale(t *testing.T) {
+	got := concertLabels(parseConcertDates([]string{"01-02-2020"}, nil))
+	if want := []string{"Feb 1, 2020"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("nil locale labels = %v, want %v", got, want)
+	}
+	got = concertLabels(parseConcertDates([]string{"01-02-2020"}, locales["fr-fr"]))
+	if want := []string{"1 févr. 2020"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("fr-FR labels = %v, want %v", got, want)
+	}
+}
+
+func TestBuildConcertSchedule(t *testing.T) {
+	now := time.Date(2020, time.June, 15, 20, 0, 0, 0, time.UTC)
+	l := locales["en-gb"]
+	dates := map[string][]ConcertDate{
+		"paris-france":    parseConcertDates([]string{"10-01-2020", "15-06-2020", "01-09-2020"}, l),
+		"london-uk":       parseConcertDates([]string{"01-03-2020", "TBA"}, l),
+		"berlin-germany":  parseConcertDates([]string{"15-06-2020"}, l),
+		"nowhere-unknown": parseConcertDates([]string{"TBA"}, l),
+	}
+
+	upcoming, past := buildConcertSchedule(dates, now)
+	// Today counts as upcoming, same-day ties go by location name
+	wantUp := []ConcertStop{
+		{Location: "Berlin, Germany", Dates: []string{"15 Jun 2020"}},
+		{Location: "Paris, France", Dates: []string{"15 Jun 2020", "1 Sep 2020"}},
+	}
+	wantPast := []ConcertStop{
+		{Location: "London, UK", Dates: []string{"1 Mar 2020"}},
+		{Location: "Paris, France", Dates: []string{"10 Jan 2020"}},
+	}
+	if !reflect.DeepEqual(upcoming, wantUp) {
+		t.Errorf("upcoming = %+v, want %+v", upcoming, wantUp)
+	}
+	if !reflect.DeepEqual(past, wantPast) {
+		t.Errorf("past = %+v, want %+v", past, wantPast)
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"palasgroupietracker/internal/api"
+	"palasgroupietracker/internal/geo"
+)
+
+// maxGeocodedLocations caps how many tour stops one request geocodes
+const maxGeocodedLocations = 25
+
+// geocodeLocationKeys resolves Groupie location keys through the shared geocoder, so every
+// page and export reuses the same cached coordinates. Keys that can't be found are left out
+func geocodeLocationKeys(ctx context.Context, keys []string) map[string]geo.Result {
+	points := make(map[string]geo.Result, len(keys))
+	var mu sync.Mutex
+	// Cap concurrency since geocoding calls external providers
+	sem := make(chan struct{}, 4)
+	var wg sync.WaitGroup
+	for _, k := range keys {
+		// Convert Groupie location keys into a geocoding-friendly query
+		place, countryCode, display := geo.QueryFromLocationKey(k)
+		wg.Add(1)
+		go func(k, place, countryCode, display string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }() // release concurrency slot
+
+			res, ok, err := groupieGeocoder.Geocode(ctx, place, countryCode)
+			if err != nil || !ok {
+				return
+			}
+			if strings.TrimSpace(res.Display) == "" {
+				// Fall back to our own label if the provider didn't return one
+				res.Display = display
+			}
+			mu.Lock()
+			points[k] = res
+			mu.Unlock()
+		}(k, place, countryCode, display)
+	}
+	wg.Wait()
+	return points
+}
+
+// GeoJSONFeatureCollection is the `/artists/{id}/locations.geojson` payload (RFC 7946)
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+type GeoJSONFeature struct {
+	Type     string               `json:"type"`
+	Geometry GeoJSONPoint         `json:"geometry"`
+	Props    ConcertLocationProps `json:"properties"`
+}
+
+// GeoJSONPoint coordinates are [longitude, latitude], the GeoJSON order
+type GeoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type ConcertLocationProps struct {
+	Name string `json:"name"`
+	// Key is the Groupie location key, e.g. "seattle-usa"
+	Key string `json:"key"`
+	// Dates are YYYY-MM-DD in chronological order, unparseable ones follow unchanged
+	Dates []string `json:"dates"`
+}
+
+// handleArtistLocationsGeoJSON serves `/artists/{id}/locations.geojson?source=groupie`, the
+// geocoded tour stops as Point features. Stops that can't be geocoded are skipped
+func handleArtistLocationsGeoJSON(w http.ResponseWriter, r *http.Request, idSegment string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		renderJSONError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	// Only the Groupie dataset has concert locations
+	if getSource(r) != "groupie" {
+		renderJSONError(w, r, http.StatusNotFound, "locations are only available for groupie artists")
+		return
+	}
+
+	id, err := strconv.Atoi(idSegment)
+	if err != nil || id <= 0 {
+		renderJSONError(w, r, http.StatusNotFound, "artist not found")
+		return
+	}
+	relation, err := api.FetchRelationForArtistCtx(r.Context(), id)
+	if err != nil {
+		renderJSONError(w, r, http.StatusNotFound, "artist not found")
+		return
+	}
+
+	keys := make([]string, 0, len(relation.DatesLocations))
+	for k := range relation.DatesLocations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	keys = keys[:min(len(keys), maxGeocodedLocations)]
+
+	body, err := json.Marshal(buildLocationsGeoJSON(keys, relation.DatesLocations, geocodeLocationKeys(r.Context(), keys)))
+	if err != nil {
+		renderJSONError(w, r, http.StatusInternalServerError, "failed to encode locations")
+		return
+	}
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(body)
+	}
+}
+
+// buildLocationsGeoJSON turns the geocoded keys into features, in keys order
+func buildLocationsGeoJSON(keys []string, datesLocations map[string][]string, points map[string]geo.Result) GeoJSONFeatureCollection {
+	fc := GeoJSONFeatureCollection{Type: "FeatureCollection", Features: make([]GeoJSONFeature, 0, len(points))}
+	for _, k := range keys {
+		p, ok := points[k]
+		if !ok {
+			continue
+		}
+		dates, _ := concertDateParts(parseConcertDates(datesLocations[k], nil))
+		fc.Features = append(fc.Features, GeoJSONFeature{
+			Type:     "Feature",
+			Geometry: GeoJSONPoint{Type: "Point", Coordinates: [2]float64{p.Lng, p.Lat}},
+			Props:    ConcertLocationProps{Name: p.Display, Key: k, Dates: dates},
+		})
+	}
+	return fc
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"palasgroupietracker/internal/geo"
+)
+
+func TestBuildLocationsGeoJSON(t *testing.T) {
+	keys := []string{"london-uk", "nowhere-unknown", "seattle-usa"}
+	dates := map[string][]string{
+		"london-uk":       {"*05-08-2019", "TBA", "23-01-2019"},
+		"nowhere-unknown": {"01-01-2020"},
+		"seattle-usa":     {"01-02-2020"},
+	}
+	points := map[string]geo.Result{
+		"london-uk":   {Lat: 51.5074, Lng: -0.1278, Display: "London, UK"},
+		"seattle-usa": {Lat: 47.6062, Lng: -122.3321, Display: "Seattle, USA"},
+	}
+
+	body, err := json.Marshal(buildLocationsGeoJSON(keys, dates, points))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode generically so the check is on the wire format, not the Go types
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Type     string `json:"type"`
+			Geometry struct {
+				Type        string    `json:"type"`
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(body, &fc); err != nil {
+		t.Fatal(err)
+	}
+	if fc.Type != "FeatureCollection" {
+		t.Fatalf("type = %q", fc.Type)
+	}
+	// The stop that couldn't be geocoded is skipped
+	if len(fc.Features) != 2 {
+		t.Fatalf("got %d features, want 2: %s", len(fc.Features), body)
+	}
+
+	london := fc.Features[0]
+	if london.Type != "Feature" || london.Geometry.Type != "Point" {
+		t.Fatalf("feature = %+v", london)
+	}
+	// GeoJSON puts longitude first
+	if want := []float64{-0.1278, 51.5074}; !reflect.DeepEqual(london.Geometry.Coordinates, want) {
+		t.Fatalf("coordinates = %v, want [lng, lat] %v", london.Geometry.Coordinates, want)
+	}
+	wantProps := map[string]any{
+		"name":  "London, UK",
+		"key":   "london-uk",
+		"dates": []any{"2019-01-23", "2019-08-05", "TBA"},
+	}
+	if !reflect.DeepEqual(london.Properties, wantProps) {
+		t.Fatalf("properties = %v, want %v", london.Properties, wantProps)
+	}
+	if got := fc.Features[1].Properties["key"]; got != "seattle-usa" {
+		t.Fatalf("second feature = %v, want seattle-usa", got)
+	}
+}
+
+func TestBuildLocationsGeoJSONEmpty(t *testing.T) {
+	body, err := json.Marshal(buildLocationsGeoJSON(nil, nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An empty collection still has a features array, which the spec requires
+	if want := `{"type":"FeatureCollection","features":[]}`; string(body) != want {
+		t.Fatalf("body = %s, want %s", body, want)
+	}
+}
+
+func TestHandleArtistLocationsGeoJSONErrors(t *testing.T) {
+	tests := []struct {
+		name, method, target string
+		status               int
+	}{
+		{"other source", http.MethodGet, "/artists/1/locations.geojson?source=spotify", http.StatusNotFound},
+		{"bad id", http.MethodGet, "/artists/x/locations.geojson?source=groupie", http.StatusNotFound},
+		{"zero id", http.MethodGet, "/artists/0/locations.geojson?source=groupie", http.StatusNotFound},
+		{"delete", http.MethodDelete, "/artists/1/locations.geojson?source=groupie", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ArtistDetailHandler(w, httptest.NewRequest(tt.method, tt.target, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			// Errors stay JSON for map tools rather than an HTML page
+			if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, "json") {
+				t.Fatalf("Content-Type = %q, want JSON", ct)
+			}
+		})
+	}
+}
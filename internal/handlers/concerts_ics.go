@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"palasgroupietracker/internal/api"
+	"palasgroupietracker/internal/geo"
+)
+
+// handleArtistConcertsICS serves `/artists/{id}/concerts.ics?source=groupie`, the artist's concerts
+// as an iCalendar file with one all-day event per date and location
+func handleArtistConcertsICS(w http.ResponseWriter, r *http.Request, idSegment string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		renderError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	// Only the Groupie dataset has concert dates
+	if getSource(r) != "groupie" {
+		NotFound(w, r)
+		return
+	}
+
+	id, err := strconv.Atoi(idSegment)
+	if err != nil || id <= 0 {
+		NotFound(w, r)
+		return
+	}
+	artist, err := api.FetchArtistByIDCtx(r.Context(), id)
+	if err != nil {
+		NotFound(w, r)
+		return
+	}
+	relation, err := api.FetchRelationForArtistCtx(r.Context(), id)
+	if err != nil {
+		NotFound(w, r)
+		return
+	}
+
+	keys := make([]string, 0, len(relation.DatesLocations))
+	for k := range relation.DatesLocations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Events past the geocoding cap just have no GEO
+	points := geocodeLocationKeys(r.Context(), keys[:min(len(keys), maxGeocodedLocations)])
+	body := buildConcertsICS(artist.ID, artist.Name, relation.DatesLocations, points, time.Now())
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="artist-%d-concerts.ics"`, artist.ID))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+// buildConcertsICS renders the calendar, dates that don't parse are skipped
+// UIDs are derived from the artist, location and day so re-imports update events instead of duplicating them
+func buildConcertsICS(artistID int, artistName string, datesLocations map[string][]string, points map[string]geo.Result, now time.Time) string {
+	keys := make([]string, 0, len(datesLocations))
+	for k := range datesLocations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldICSLine(s))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Pala's Groupie Tracker//Concerts//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeICSText(artistName+" concerts"))
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, k := range keys {
+		location := geo.HumanizeLocationKey(k)
+		point, hasPoint := points[k]
+		seen := make(map[string]bool)
+		for _, raw := range datesLocations[k] {
+			day, ok := api.ConcertDay(raw)
+			if !ok || seen[day] {
+				continue
+			}
+			// The same day listed twice for one place is one concert
+			seen[day] = true
+			start, _ := time.Parse("2006-01-02", day)
+
+			line("BEGIN:VEVENT")
+			line(fmt.Sprintf("UID:groupie-%d-%s-%s@palasgroupietracker", artistID, icsUIDPart(k), start.Format("20060102")))
+			line("DTSTAMP:" + stamp)
+			line("DTSTART;VALUE=DATE:" + start.Format("20060102"))
+			line("DTEND;VALUE=DATE:" + start.AddDate(0, 0, 1).Format("20060102"))
+			line("SUMMARY:" + escapeICSText(artistName+" in "+location))
+			line("LOCATION:" + escapeICSText(location))
+			if hasPoint {
+				line(fmt.Sprintf("GEO:%s;%s", strconv.FormatFloat(point.Lat, 'f', 6, 64), strconv.FormatFloat(point.Lng, 'f', 6, 64)))
+			}
+			line("TRANSP:TRANSPARENT")
+			line("END:VEVENT")
+		}
+	}
+
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICSText escapes a TEXT value (RFC 5545 3.3.11)
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(s)
+}
+
+// icsUIDPart keeps a location key to letters, digits and dashes
+func icsUIDPart(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '-'
+	}, s)
+}
+
+// foldICSLine splits content lines longer than 75 octets, continuation lines start with a space
+// Cuts never land inside a UTF-8 sequence
+func foldICSLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	width := limit
+	for len(s) > width {
+		cut := width
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space counts toward the next line's length
+		width = limit - 1
+	}
+	b.WriteString(s)
+	return b.String()
+}
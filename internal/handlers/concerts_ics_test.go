@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"palasgroupietracker/internal/geo"
+)
+
+// icsEvent is one parsed VEVENT, properties by name with their parameters dropped
+type icsEvent map[string]string
+
+// parseICS unfolds the content lines of a calendar and returns its events
+// It fails the test on lines over 75 octets, bare LF line endings or unbalanced blocks
+func parseICS(t *testing.T, body string) []icsEvent {
+	t.Helper()
+	if !strings.HasSuffix(body, "\r\n") || strings.Contains(strings.ReplaceAll(body, "\r\n", ""), "\n") {
+		t.Fatal("calendar lines must end with CRLF")
+	}
+
+	var lines []string
+	for _, l := range strings.Split(strings.TrimSuffix(body, "\r\n"), "\r\n") {
+		if len(l) > 75 {
+			t.Fatalf("line longer than 75 octets: %q", l)
+		}
+		if strings.HasPrefix(l, " ") && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	if lines[0] != "BEGIN:VCALENDAR" || lines[len(lines)-1] != "END:VCALENDAR" {
+		t.Fatalf("calendar starts with %q and ends with %q", lines[0], lines[len(lines)-1])
+	}
+
+	var events []icsEvent
+	var cur icsEvent
+	for _, l := range lines {
+		switch l {
+		case "BEGIN:VEVENT":
+			if cur != nil {
+				t.Fatal("nested VEVENT")
+			}
+			cur = icsEvent{}
+		case "END:VEVENT":
+			if cur == nil {
+				t.Fatal("END:VEVENT without BEGIN")
+			}
+			events = append(events, cur)
+			cur = nil
+		default:
+			if cur != nil {
+				name, value, _ := strings.Cut(l, ":")
+				name, _, _ = strings.Cut(name, ";")
+				cur[name] = value
+			}
+		}
+	}
+	if cur != nil {
+		t.Fatal("unterminated VEVENT")
+	}
+	return events
+}
+
+func TestBuildConcertsICS(t *testing.T) {
+	dates := map[string][]string{
+		"paris-france":         {"01-02-2020", "*03-02-2020", "01-02-2020"},
+		"los_angeles-usa":      {"15-06-2019", "not a date"},
+		"saint_petersburg-usa": {"15-06-2019"},
+		"nowhere-unknown":      {"TBA"},
+	}
+	points := map[string]geo.Result{
+		"paris-france": {Lat: 48.8566, Lng: 2.3522},
+	}
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	events := parseICS(t, buildConcertsICS(7, "Queen; Live, Again", dates, points, now))
+
+	// A repeated day and unparseable dates produce no event
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4", len(events))
+	}
+	uids := make(map[string]bool)
+	for _, e := range events {
+		if uids[e["UID"]] {
+			t.Fatalf("duplicate UID %q", e["UID"])
+		}
+		uids[e["UID"]] = true
+		if e["DTSTAMP"] != "20240501T120000Z" {
+			t.Errorf("DTSTAMP = %q", e["DTSTAMP"])
+		}
+	}
+
+	var paris icsEvent
+	for _, e := range events {
+		if e["UID"] == "groupie-7-paris-france-20200201@palasgroupietracker" {
+			paris = e
+		}
+	}
+	if paris == nil {
+		t.Fatalf("no event for Paris on 1 Feb 2020 in %v", uids)
+	}
+	want := icsEvent{
+		"UID":      "groupie-7-paris-france-20200201@palasgroupietracker",
+		"DTSTAMP":  "20240501T120000Z",
+		"DTSTART":  "20200201",
+		"DTEND":    "20200202",
+		"SUMMARY":  `Queen\; Live\, Again in Paris\, France`,
+		"LOCATION": `Paris\, France`,
+		"GEO":      "48.856600;2.352200",
+		"TRANSP":   "TRANSPARENT",
+	}
+	for k, v := range want {
+		if paris[k] != v {
+			t.Errorf("%s = %q, want %q", k, paris[k], v)
+		}
+	}
+	for _, e := range events {
+		if strings.Contains(e["UID"], "_") {
+			t.Errorf("UID %q keeps an underscore", e["UID"])
+		}
+		if !strings.Contains(e["UID"], "paris") {
+			if _, ok := e["GEO"]; ok {
+				t.Errorf("%s has a GEO without a geocoded point", e["UID"])
+			}
+		}
+	}
+
+	// The same input gives the same UIDs, so re-imports update events
+	again := parseICS(t, buildConcertsICS(7, "Queen; Live, Again", dates, points, now.Add(time.Hour)))
+	for _, e := range again {
+		if !uids[e["UID"]] {
+			t.Fatalf("UID %q changed between exports", e["UID"])
+		}
+	}
+}
+
+func TestBuildConcertsICSEmpty(t *testing.T) {
+	body := buildConcertsICS(1, "Nobody", nil, nil, time.Now())
+	if events := parseICS(t, body); len(events) != 0 {
+		t.Fatalf("got %d events, want none", len(events))
+	}
+	if !strings.Contains(body, "X-WR-CALNAME:Nobody concerts\r\n") {
+		t.Fatalf("calendar has no name:\n%s", body)
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	long := "SUMMARY:" + strings.Repeat("Mötley Crüe ", 20)
+	folded := foldICSLine(long)
+	parts := strings.Split(folded, "\r\n")
+	if len(parts) < 2 {
+		t.Fatalf("line of %d octets wasn't folded", len(long))
+	}
+	var unfolded strings.Builder
+	for i, p := range parts {
+		if len(p) > 75 {
+			t.Errorf("part %d is %d octets", i, len(p))
+		}
+		if i > 0 {
+			if !strings.HasPrefix(p, " ") {
+				t.Fatalf("continuation %d doesn't start with a space: %q", i, p)
+			}
+			p = p[1:]
+		}
+		unfolded.WriteString(p)
+	}
+	if unfolded.String() != long {
+		t.Fatal("unfolding doesn't give the original line back")
+	}
+	// Cuts never split a UTF-8 sequence
+	for i, p := range parts {
+		if !utf8.ValidString(p) {
+			t.Errorf("part %d splits a character: %q", i, p)
+		}
+	}
+
+	if short := "SUMMARY:Queen"; foldICSLine(short) != short {
+		t.Error("short line was changed")
+	}
+}
+
+func TestEscapeICSText(t *testing.T) {
+	got := escapeICSText("a\\b;c,d\r\ne\nf\rg")
+	if want := `a\\b\;c\,d\ne\nfg`; got != want {
+		t.Fatalf("escapeICSText = %q, want %q", got, want)
+	}
+}
+
+func TestHandleArtistConcertsICSNotFound(t *testing.T) {
+	tests := []struct {
+		name, method, target string
+		status               int
+	}{
+		{"other source", http.MethodGet, "/artists/1/concerts.ics?source=deezer", http.StatusNotFound},
+		{"bad id", http.MethodGet, "/artists/abc/concerts.ics?source=groupie", http.StatusNotFound},
+		{"negative id", http.MethodGet, "/artists/-1/concerts.ics?source=groupie", http.StatusNotFound},
+		{"post", http.MethodPost, "/artists/1/concerts.ics?source=groupie", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ArtistDetailHandler(w, httptest.NewRequest(tt.method, tt.target, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"strings"
+)
+
+// corsMaxAge lets browsers cache a preflight answer for 10 minutes
+const corsMaxAge = "600"
+
+// corsOrigins reads the `CORS_ORIGINS` allow-list, e.g. "https://a.example, https://b.example"
+// An entry of "*" allows every origin, an empty list keeps the endpoints same-origin only
+func corsOrigins() []string {
+	raw := strings.TrimSpace(os.Getenv("CORS_ORIGINS"))
+	if raw == "" {
+		return nil
+	}
+
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		if o := normalizeOrigin(part); o != "" {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// normalizeOrigin lowercases an origin and drops a trailing slash so config typos still match
+func normalizeOrigin(o string) string {
+	return strings.TrimRight(strings.ToLower(strings.TrimSpace(o)), "/")
+}
+
+// allowedOrigin returns the Access-Control-Allow-Origin value for a request origin, or "" when denied
+func allowedOrigin(origin string, allowed []string) string {
+	norm := normalizeOrigin(origin)
+	if norm == "" || norm == "null" {
+		// Opaque origins (sandboxed iframes, file://) can't be told apart, never allow them
+		return ""
+	}
+	for _, a := range allowed {
+		if a == "*" {
+			return "*"
+		}
+		if a == norm {
+			return origin
+		}
+	}
+	return ""
+}
+
+// CORS wraps a read-only JSON endpoint so allow-listed origins can call it from the browser
+// Only GET is exposed and no credentials are allowed, so cookies never cross origins
+// Preflight requests from allowed origins are answered here without reaching the handler
+func CORS(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed := corsOrigins()
+		if len(allowed) == 0 {
+			next(w, r)
+			return
+		}
+
+		// Responses differ per Origin, shared caches must not mix them up
+		w.Header().Add("Vary", "Origin")
+
+		allowOrigin := allowedOrigin(r.Header.Get("Origin"), allowed)
+		if allowOrigin == "" {
+			next(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+		// Let browser clients read the suggest status headers (upstream failure, truncated list)
+		w.Header().Set("Access-Control-Expose-Headers", "X-Suggest-Status, X-Suggest-Truncated")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultDetailTracks = 10
+	defaultDetailAlbums = 8
+)
+
+// detailMaxima returns the largest track and album counts each provider accepts
+func detailMaxima(source string) (int, int) {
+	return sourceFor(source).DetailMaxima()
+}
+
+// detailCounts resolves how many top tracks and albums to show on a detail page
+// Env vars set the defaults, `?tracks=` and `?albums=` override them per request
+func detailCounts(r *http.Request, source string) (int, int) {
+	maxTracks, maxAlbums := detailMaxima(source)
+
+	tracks := envPositiveInt("DETAIL_TRACKS_LIMIT", defaultDetailTracks)
+	albums := envPositiveInt("DETAIL_ALBUMS_LIMIT", defaultDetailAlbums)
+
+	if v, err := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("tracks"))); err == nil && v > 0 {
+		tracks = v
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get("albums"))); err == nil && v > 0 {
+		albums = v
+	}
+
+	return clampInt(tracks, 1, maxTracks), clampInt(albums, 1, maxAlbums)
+}
+
+// envPositiveInt reads a positive integer env var, falling back to def when unset or invalid
+func envPositiveInt(name string, def int) int {
+	v, err := strconv.Atoi(strings.TrimSpace(os.Getenv(name)))
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
+
+// clampInt keeps v within [lo, hi]
+func clampInt(v, lo, hi int) int {
+	if hi < lo {
+		return lo
+	}
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
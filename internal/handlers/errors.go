@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type ErrorPageData struct {
+	LayoutData
+
+	Status    int
+	Message   string
+	RequestID string
+}
+
+// errorPayload is the JSON error body, shared by every JSON endpoint
+type errorPayload struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type requestIDKey struct{}
+
+// validRequestID accepts IDs set by a proxy in front of us, anything else is replaced
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// WithRequestID tags each request with an ID, reusing a sane incoming `X-Request-Id`
+// The ID is echoed in the response header and shown on error pages so reports can be matched to logs
+func WithRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(r.Header.Get("X-Request-Id"))
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set("X-Request-Id", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// requestID returns the ID set by WithRequestID, "" when the middleware isn't in use
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// wantsJSON reports whether the client asked for JSON, via `?format=json` or an Accept header
+// that lists JSON but not HTML (browsers always list text/html)
+func wantsJSON(r *http.Request) bool {
+	if strings.EqualFold(r.URL.Query().Get("format"), "json") {
+		return true
+	}
+
+	hasJSON, hasHTML := false, false
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch {
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			hasJSON = true
+		case mediaType == "text/html":
+			hasHTML = true
+		}
+	}
+	return hasJSON && !hasHTML
+}
+
+// renderJSONError writes `{"error": "...", "request_id": "..."}`, for endpoints that only speak JSON
+func renderJSONError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	writeJSON(w, status, errorPayload{Error: msg, RequestID: requestID(r)})
+}
+
+// renderError is the shared error response: JSON for API clients, the error page for browsers
+// Falls back to plain text if the error page itself can't be rendered
+func renderError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	if wantsJSON(r) {
+		renderJSONError(w, r, status, msg)
+		return
+	}
+
+	id := requestID(r)
+
+	tmpl, err := templateWithLayout("web/templates/error.gohtml")
+	if err != nil {
+		http.Error(w, plainErrorMessage(msg, id), status)
+		return
+	}
+
+	// Resolve the user before writing the status, it may clear a stale session cookie
+	user, authed := getCurrentUser(w, r)
+
+	data := ErrorPageData{
+		LayoutData: newLayoutData(w, r, strconv.Itoa(status)+" "+http.StatusText(status), user, authed),
+
+		Status:    status,
+		Message:   msg,
+		RequestID: id,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = tmpl.ExecuteTemplate(w, "layout", data)
+}
+
+func plainErrorMessage(msg, id string) string {
+	if id == "" {
+		return msg
+	}
+	return msg + " (request " + id + ")"
+}
@@ -1,39 +1,26 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 
-	"palasgroupietracker/internal/api"
 	"palasgroupietracker/internal/store"
 )
 
-type FavoriteCard struct {
-	Source   string
-	ArtistID string
-	Name     string
-	ImageURL string
-	LinkURL  string
-	Meta     string
-	Badge    string
-}
-
 type FavoritesPageData struct {
-	Title      string
-	Source     string
-	ActiveNav  string
-	BasePath   string
-	CurrentURL string
-	User       *store.User
-	IsAuthed   bool
-
-	Cards []FavoriteCard
+	LayoutData
+
+	Cards []ArtistCard
+	// Locale formats counts and dates for the visitor, from Accept-Language
+	Locale *Locale
 }
 
 // FavoritesHandler renders the favorites page for the current user
 func FavoritesHandler(w http.ResponseWriter, r *http.Request) {
+	timing := newServerTiming()
 	basePath := getBasePath(r)
 
 	user, authed := getCurrentUser(w, r)
@@ -43,41 +30,38 @@ func FavoritesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if appStore == nil {
-		http.Error(w, "database not configured", http.StatusServiceUnavailable)
+		renderError(w, r, http.StatusServiceUnavailable, "database not configured")
 		return
 	}
 
+	stop := timing.start("db")
 	favorites, err := appStore.ListFavorites(r.Context(), user.ID)
+	stop()
 	if err != nil {
-		http.Error(w, "failed to load favorites", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "failed to load favorites")
 		return
 	}
 
-	cards, err := buildFavoriteCardsFromFavorites(basePath, favorites)
-	if err != nil {
-		http.Error(w, "failed to load favorites", http.StatusInternalServerError)
-		return
-	}
+	cards := buildFavoriteCardsFromFavorites(r.Context(), basePath, favorites, timing)
+
+	locale := resolveLocale(r)
+	localizeCards(cards, locale)
 
 	data := FavoritesPageData{
-		Title:      "Favorites",
-		Source:     getSource(r),
-		ActiveNav:  "favorites",
-		BasePath:   basePath,
-		CurrentURL: buildCurrentURL(r),
-		User:       user,
-		IsAuthed:   authed,
+		LayoutData: newLayoutData(w, r, "Favorites", user, authed),
 		Cards:      cards,
+		Locale:     locale,
 	}
 
 	tmpl, err := templateWithLayout("web/templates/favorites.gohtml")
 	if err != nil {
-		http.Error(w, "template error", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "template error")
 		return
 	}
 
+	timing.write(w)
 	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
-		http.Error(w, "render error", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "render error")
 		return
 	}
 }
@@ -88,6 +72,9 @@ func ToggleFavoriteHandler(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, withBasePath(r, "/artists"), http.StatusSeeOther)
 		return
 	}
+	if !requireCSRF(w, r) {
+		return
+	}
 
 	source := normalizeSource(r.FormValue("source"))
 	artistID := strings.TrimSpace(r.FormValue("artist_id"))
@@ -105,133 +92,60 @@ func ToggleFavoriteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if appStore == nil {
-		http.Error(w, "database not configured", http.StatusServiceUnavailable)
+		renderError(w, r, http.StatusServiceUnavailable, "database not configured")
 		return
 	}
 
 	if _, err := appStore.ToggleFavorite(r.Context(), user.ID, source, artistID); err != nil {
-		http.Error(w, "failed to update favorite", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "failed to update favorite")
 		return
 	}
 
 	http.Redirect(w, r, redirectTo, http.StatusSeeOther)
 }
 
-func buildFavoriteCardsFromFavorites(basePath string, favorites []store.Favorite) ([]FavoriteCard, error) {
-	cards := make([]FavoriteCard, 0, len(favorites))
+// buildFavoriteCardsFromFavorites loads a card per favorite, timing each provider's lookups
+// Artists a provider can't load right now are left out rather than failing the page
+func buildFavoriteCardsFromFavorites(ctx context.Context, basePath string, favorites []store.Favorite, timing *serverTiming) []ArtistCard {
+	cards := make([]ArtistCard, 0, len(favorites))
 
 	for _, fav := range favorites {
-		card, ok, err := buildFavoriteCard(basePath, fav.Source, fav.ArtistID)
+		src := sourceFor(normalizeSource(fav.Source))
+		stop := timing.start(src.Name())
+		card, err := src.GetArtist(ctx, fav.ArtistID)
+		stop()
 		if err != nil {
-			return nil, err
-		}
-		if ok {
-			cards = append(cards, card)
+			continue
 		}
+		setFavoriteCardMeta(&card)
+		cards = append(cards, card)
 	}
 
-	return cards, nil
+	rebaseCards(basePath, cards)
+	return cards
 }
 
-func buildFavoriteCard(basePath, source, id string) (FavoriteCard, bool, error) {
-	switch source {
+// setFavoriteCardMeta relabels a source card for the favorites grid, which names each count
+func setFavoriteCardMeta(card *ArtistCard) {
+	switch card.Source {
+	case "groupie":
+		card.Meta = "Created " + strconv.Itoa(card.CreationDate)
 	case "spotify":
-		artist, err := api.GetSpotifyArtist(id)
-		if err != nil || artist == nil {
-			return FavoriteCard{}, false, nil
-		}
-		imageURL := ""
-		if len(artist.Images) > 0 {
-			imageURL = artist.Images[0].URL
-		}
-		meta := "Spotify artist"
-		if artist.Followers != nil && artist.Followers.Total > 0 {
-			meta = "Followers: " + strconv.Itoa(artist.Followers.Total)
-		} else if len(artist.Genres) > 0 {
-			meta = "Genre: " + artist.Genres[0]
+		if card.Followers > 0 {
+			card.setCountMeta("Followers: %s", card.Followers, false)
+		} else if card.Genre != "" {
+			card.Meta = "Genre: " + card.Genre
 		}
-		return FavoriteCard{
-			Source:   "spotify",
-			ArtistID: id,
-			Name:     artist.Name,
-			ImageURL: imageURL,
-			LinkURL:  basePath + "/artists/" + id + "?source=spotify",
-			Meta:     meta,
-			Badge:    "Spotify",
-		}, true, nil
 	case "deezer":
-		intID, err := strconv.Atoi(id)
-		if err != nil {
-			return FavoriteCard{}, false, nil
-		}
-		artist, err := api.GetDeezerArtist(intID)
-		if err != nil || artist == nil {
-			return FavoriteCard{}, false, nil
-		}
-		imageURL := artist.PictureXL
-		if imageURL == "" {
-			imageURL = artist.PictureBig
+		if card.Fans > 0 {
+			card.setCountMeta("Fans: %s", card.Fans, false)
+		} else if card.Albums > 0 {
+			card.setCountMeta("Albums: %s", card.Albums, false)
 		}
-		if imageURL == "" {
-			imageURL = artist.PictureMedium
-		}
-		meta := "Deezer artist"
-		if artist.NbFan > 0 {
-			meta = "Fans: " + strconv.Itoa(artist.NbFan)
-		} else if artist.NbAlbum > 0 {
-			meta = "Albums: " + strconv.Itoa(artist.NbAlbum)
-		}
-		return FavoriteCard{
-			Source:   "deezer",
-			ArtistID: id,
-			Name:     artist.Name,
-			ImageURL: imageURL,
-			LinkURL:  basePath + "/artists/" + id + "?source=deezer",
-			Meta:     meta,
-			Badge:    "Deezer",
-		}, true, nil
 	case "apple":
-		intID, err := strconv.Atoi(id)
-		if err != nil {
-			return FavoriteCard{}, false, nil
-		}
-		artist, err := api.GetAppleArtist(intID)
-		if err != nil || artist == nil {
-			return FavoriteCard{}, false, nil
-		}
-		artwork, _ := api.GetAppleArtistArtwork(intID, 300)
-		meta := "Apple artist"
-		if artist.PrimaryGenreName != "" {
-			meta = "Genre: " + artist.PrimaryGenreName
-		}
-		return FavoriteCard{
-			Source:   "apple",
-			ArtistID: id,
-			Name:     artist.ArtistName,
-			ImageURL: artwork,
-			LinkURL:  basePath + "/artists/" + id + "?source=apple",
-			Meta:     meta,
-			Badge:    "Apple",
-		}, true, nil
-	default:
-		intID, err := strconv.Atoi(id)
-		if err != nil {
-			return FavoriteCard{}, false, nil
-		}
-		artist, err := api.FetchArtistByID(intID)
-		if err != nil || artist == nil {
-			return FavoriteCard{}, false, nil
+		if card.Genre != "" {
+			card.Meta = "Genre: " + card.Genre
 		}
-		meta := "Created " + strconv.Itoa(artist.CreationDate)
-		return FavoriteCard{
-			Source:   "groupie",
-			ArtistID: id,
-			Name:     artist.Name,
-			ImageURL: artist.Image,
-			LinkURL:  basePath + "/artists/" + id + "?source=groupie",
-			Meta:     meta,
-			Badge:    "Groupie",
-		}, true, nil
 	}
 }
 
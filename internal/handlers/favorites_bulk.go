@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxBulkFavorites caps one bulk request, a full artists page fits well below it
+const maxBulkFavorites = 200
+
+// bulkFavoritesPayload reports what a bulk add did, Skipped counts repeats and already saved IDs
+type bulkFavoritesPayload struct {
+	Added   int `json:"added"`
+	Skipped int `json:"skipped"`
+	Invalid int `json:"invalid"`
+}
+
+// BulkFavoritesHandler saves several artists of one source at once
+// It takes repeated `artist_id` fields (or a comma-separated `artist_ids`) and answers
+// with counts as JSON, or redirects back like the single toggle for plain form posts
+func BulkFavoritesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		renderError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !requireCSRF(w, r) {
+		return
+	}
+
+	source := normalizeSource(r.FormValue("source"))
+	redirectTo := resolveNextURL(r.FormValue("redirect"), r)
+
+	raw := r.Form["artist_id"]
+	if list := r.FormValue("artist_ids"); list != "" {
+		raw = append(raw, strings.Split(list, ",")...)
+	}
+	if len(raw) > maxBulkFavorites {
+		renderError(w, r, http.StatusRequestEntityTooLarge, "too many artists in one request")
+		return
+	}
+
+	user, authed := getCurrentUser(w, r)
+	if !authed {
+		if wantsJSON(r) {
+			renderJSONError(w, r, http.StatusUnauthorized, "login required")
+			return
+		}
+		http.Redirect(w, r, withBasePath(r, "/login")+"?next="+url.QueryEscape(redirectTo), http.StatusSeeOther)
+		return
+	}
+
+	if appStore == nil {
+		renderError(w, r, http.StatusServiceUnavailable, "database not configured")
+		return
+	}
+
+	ids, repeated, invalid := partitionFavoriteIDs(sourceFor(source), raw)
+	added, err := appStore.AddFavorites(r.Context(), user.ID, source, ids)
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, "failed to update favorites")
+		return
+	}
+
+	if !wantsJSON(r) {
+		http.Redirect(w, r, redirectTo, http.StatusSeeOther)
+		return
+	}
+	writeJSON(w, http.StatusOK, bulkFavoritesPayload{
+		Added:   added,
+		Skipped: repeated + len(ids) - added,
+		Invalid: invalid,
+	})
+}
+
+// partitionFavoriteIDs normalizes raw IDs for src, dropping blanks, malformed IDs and repeats
+func partitionFavoriteIDs(src Source, raw []string) (ids []string, repeated, invalid int) {
+	seen := make(map[string]bool, len(raw))
+	for _, r := range raw {
+		if strings.TrimSpace(r) == "" {
+			continue
+		}
+		id, ok := src.NormalizeID(r)
+		if !ok {
+			invalid++
+			continue
+		}
+		if seen[id] {
+			repeated++
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, repeated, invalid
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPartitionFavoriteIDs(t *testing.T) {
+	tests := []struct {
+		name              string
+		src               Source
+		raw               []string
+		ids               []string
+		repeated, invalid int
+	}{
+		{"empty", sourceFor("groupie"), nil, nil, 0, 0},
+		{
+			"groupie mixed",
+			sourceFor("groupie"),
+			[]string{"1", " 2 ", "", "abc", "01", "0", "1", "-3"},
+			[]string{"1", "2"}, 2, 3,
+		},
+		{
+			"spotify shape",
+			sourceFor("spotify"),
+			[]string{"6olE6TJLqED3rqDCT0FyPh", "6olE6TJLqED3rqDCT0FyPh", "short", "not a spotify id!!!!!!"},
+			[]string{"6olE6TJLqED3rqDCT0FyPh"}, 1, 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, repeated, invalid := partitionFavoriteIDs(tt.src, tt.raw)
+			if !reflect.DeepEqual(ids, tt.ids) || repeated != tt.repeated || invalid != tt.invalid {
+				t.Fatalf("got %v, %d repeated, %d invalid; want %v, %d, %d",
+					ids, repeated, invalid, tt.ids, tt.repeated, tt.invalid)
+			}
+		})
+	}
+}
+
+// postBulkFavorites posts a bulk add for JSON, with the given cookies
+func postBulkFavorites(form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	r := newFormRequest("/favorites/bulk", form, cookies...)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	BulkFavoritesHandler(w, r)
+	return w
+}
+
+func TestBulkFavoritesHandlerRejects(t *testing.T) {
+	prev := appStore
+	appStore = nil
+	t.Cleanup(func() { appStore = prev })
+
+	if w := postBulkFavorites(url.Values{"source": {"groupie"}, "artist_id": {"1"}}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	ids := make([]string, maxBulkFavorites+1)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i + 1)
+	}
+	if w := postBulkFavorites(url.Values{"artist_ids": {strings.Join(ids, ",")}}); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("too many: status = %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/favorites/bulk", nil)
+	w := httptest.NewRecorder()
+	BulkFavoritesHandler(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET: status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestBulkFavoritesHandlerCounts(t *testing.T) {
+	s := useTestStore(t)
+	u, cookie := newTestAccount(t, s, "bulk@example.com", "password123")
+	if _, err := s.ToggleFavorite(context.Background(), u.ID, "groupie", "3"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := postBulkFavorites(url.Values{
+		"source":     {"groupie"},
+		"artist_id":  {"1", "2", "2", "abc"},
+		"artist_ids": {"3, 4,x"},
+	}, cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var got bulkFavoritesPayload
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	// 2 repeated in the request, 3 was already saved
+	if want := (bulkFavoritesPayload{Added: 3, Skipped: 2, Invalid: 2}); got != want {
+		t.Fatalf("payload = %+v, want %+v", got, want)
+	}
+
+	saved, err := s.ListFavoriteIDsBySource(context.Background(), u.ID, "groupie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 4 {
+		t.Fatalf("saved %v, want 4 artists", saved)
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// favoritesSyncPayload answers `/api/favorites`, Favorites is null when nothing changed
+// and the full list (possibly empty) otherwise
+type favoritesSyncPayload struct {
+	Version   int64                `json:"version"`
+	Changed   bool                 `json:"changed"`
+	Favorites []favoriteSyncRecord `json:"favorites"`
+}
+
+type favoriteSyncRecord struct {
+	Source    string    `json:"source"`
+	ArtistID  string    `json:"artist_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FavoritesSyncHandler serves the logged-in user's favorites with a version for incremental sync
+// The version goes up on every add and remove. With `?since=N` equal to the current version
+// (or a matching If-None-Match), only the version is sent back
+func FavoritesSyncHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		renderJSONError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if appStore == nil {
+		renderJSONError(w, r, http.StatusServiceUnavailable, "database not configured")
+		return
+	}
+
+	user, authed := getCurrentUser(w, r)
+	if !authed {
+		renderJSONError(w, r, http.StatusUnauthorized, "login required")
+		return
+	}
+
+	since := int64(-1)
+	if raw := strings.TrimSpace(r.URL.Query().Get("since")); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < 0 {
+			renderJSONError(w, r, http.StatusBadRequest, "since must be a non-negative version number")
+			return
+		}
+		since = v
+	}
+
+	// Read the version before the list: a toggle in between only makes the client fetch again
+	version, err := appStore.GetFavoritesVersion(r.Context(), user.ID)
+	if err != nil {
+		renderJSONError(w, r, http.StatusInternalServerError, "failed to load favorites")
+		return
+	}
+
+	etag := `"favorites-` + strconv.FormatInt(version, 10) + `"`
+	w.Header().Set("ETag", etag)
+	// Per-user data behind a cookie, keep shared caches out of it
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("Vary", "Cookie")
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if since == version {
+		writeJSON(w, http.StatusOK, favoritesSyncPayload{Version: version, Changed: false})
+		return
+	}
+
+	favorites, err := appStore.ListFavorites(r.Context(), user.ID)
+	if err != nil {
+		renderJSONError(w, r, http.StatusInternalServerError, "failed to load favorites")
+		return
+	}
+
+	records := make([]favoriteSyncRecord, 0, len(favorites))
+	for _, fav := range favorites {
+		records = append(records, favoriteSyncRecord{Source: fav.Source, ArtistID: fav.ArtistID, CreatedAt: fav.CreatedAt})
+	}
+
+	writeJSON(w, http.StatusOK, favoritesSyncPayload{Version: version, Changed: true, Favorites: records})
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// getFavoritesSync calls the sync endpoint with an optional If-None-Match
+func getFavoritesSync(target, ifNoneMatch string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	if ifNoneMatch != "" {
+		r.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	FavoritesSyncHandler(w, r)
+	return w
+}
+
+func TestFavoritesSyncMethodNotAllowed(t *testing.T) {
+	w := httptest.NewRecorder()
+	FavoritesSyncHandler(w, httptest.NewRequest(http.MethodPost, "/api/favorites", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+	if got := w.Header().Get("Allow"); got != http.MethodGet {
+		t.Fatalf("Allow = %q, want GET", got)
+	}
+}
+
+func TestFavoritesSyncHandler(t *testing.T) {
+	s := useTestStore(t)
+	user, cookie := newTestAccount(t, s, "sync@example.com", "sync-password")
+	ctx := context.Background()
+
+	if w := getFavoritesSync("/api/favorites", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := getFavoritesSync("/api/favorites?since=-1", "", cookie); w.Code != http.StatusBadRequest {
+		t.Fatalf("negative since: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	for _, id := range []string{"1", "2"} {
+		if _, err := s.ToggleFavorite(ctx, user.ID, "groupie", id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	version, err := s.GetFavoritesVersion(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := strconv.FormatInt(version, 10)
+	etag := `"favorites-` + current + `"`
+
+	decode := func(w *httptest.ResponseRecorder) favoritesSyncPayload {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+		if got := w.Header().Get("ETag"); got != etag {
+			t.Fatalf("ETag = %q, want %q", got, etag)
+		}
+		var p favoritesSyncPayload
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	// An up to date client only gets the version back
+	w := getFavoritesSync("/api/favorites?since="+current, "", cookie)
+	if p := decode(w); p.Changed || p.Version != version || p.Favorites != nil {
+		t.Fatalf("since=current: %+v, want unchanged at version %d with no list", p, version)
+	}
+
+	// A stale or missing since gets the full list
+	for _, target := range []string{"/api/favorites?since=" + strconv.FormatInt(version-1, 10), "/api/favorites"} {
+		p := decode(getFavoritesSync(target, "", cookie))
+		if !p.Changed || p.Version != version || len(p.Favorites) != 2 {
+			t.Fatalf("%s: %+v, want the 2 favorites at version %d", target, p, version)
+		}
+		for _, f := range p.Favorites {
+			if f.Source != "groupie" || (f.ArtistID != "1" && f.ArtistID != "2") || f.CreatedAt.IsZero() {
+				t.Fatalf("%s: unexpected record %+v", target, f)
+			}
+		}
+	}
+
+	w = getFavoritesSync("/api/favorites", etag, cookie)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("If-None-Match: status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+	if w.Body.Len() != 0 {
+		t.Fatalf("304 has a body: %s", w.Body)
+	}
+
+	// A removal makes the old version and ETag stale
+	if _, err := s.ToggleFavorite(ctx, user.ID, "groupie", "1"); err != nil {
+		t.Fatal(err)
+	}
+	w = getFavoritesSync("/api/favorites?since="+current, etag, cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("after a removal: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var p favoritesSyncPayload
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if !p.Changed || p.Version <= version || len(p.Favorites) != 1 || p.Favorites[0].ArtistID != "2" {
+		t.Fatalf("after a removal: %+v, want only artist 2 above version %d", p, version)
+	}
+}
@@ -1,36 +1,41 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
 	"html/template"
+	"math/rand/v2"
 	"net/http"
-	"strconv"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
 
-	"palasgroupietracker/internal/api"
 	"palasgroupietracker/internal/store"
 )
 
-type HomeArtistCard struct {
-	Name     string
-	ImageURL string
-	LinkURL  string
-	Meta     string
-	Badge    string
-}
-
 type HomePageData struct {
-	Title      string
-	Source     string
-	ActiveNav  string
-	BasePath   string
-	CurrentURL string
-	User       *store.User
-	IsAuthed   bool
-	Featured   []HomeArtistCard
+	LayoutData
+
+	Featured []ArtistCard
+	// Mixed is set when the marquee draws cards from every source
+	Mixed bool
+	// Locale formats counts and dates for the visitor, from Accept-Language
+	Locale *Locale
 }
 
+// mixedHomeSources is the order sources appear in the mixed marquee
+var mixedHomeSources = []string{"groupie", "spotify", "deezer", "apple", "musicbrainz"}
+
 // HomeHandler renders the homepage with a featured artists carousel
+// With `ROOT_REDIRECT` set, it redirects to that page instead
 func HomeHandler(w http.ResponseWriter, r *http.Request) {
+	if target, ok := rootRedirect(r); ok {
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+
 	source := getSource(r)
 	basePath := getBasePath(r)
 	user, authed := getCurrentUser(w, r)
@@ -40,212 +45,146 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 		"web/templates/home.gohtml",
 	)
 	if err != nil {
-		http.Error(w, "template error", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "template error")
 		return
 	}
 
-	// Featured cards are source-specific (Groupie vs Spotify vs Deezer vs Apple)
-	featured, err := buildHomeFeatured(basePath, source)
+	// Featured cards are source-specific (Groupie vs Spotify vs Deezer vs Apple),
+	// unless the visitor picked no source or asked for the mix
+	mixed := isMixedHome(r)
+	seed := homeShuffleSeed(user, time.Now())
+	var featured []ArtistCard
+	if mixed {
+		// Links like "View artists" still need one source, keep the default
+		source = defaultSource
+		featured, err = buildMixedHomeFeatured(r.Context(), basePath, seed)
+	} else {
+		featured, err = buildHomeFeatured(r.Context(), basePath, source)
+		shuffleCards(featured, seed)
+	}
 	if err != nil {
-		http.Error(w, "failed to load home", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "failed to load home")
 		return
 	}
 
+	locale := resolveLocale(r)
+	localizeCards(featured, locale)
+
 	data := HomePageData{
-		Title:      "Groupie Tracker",
-		Source:     source,
-		ActiveNav:  "home",
-		BasePath:   basePath,
-		CurrentURL: buildCurrentURL(r),
-		User:       user,
-		IsAuthed:   authed,
+		LayoutData: newLayoutData(w, r, "Groupie Tracker", user, authed),
 		Featured:   featured,
+		Mixed:      mixed,
+		Locale:     locale,
 	}
+	// Mixed picks above may have swapped the source for the default
+	data.Source = source
 
 	err = tmpl.ExecuteTemplate(w, "layout", data)
 	if err != nil {
-		http.Error(w, "render error", http.StatusInternalServerError)
+		renderError(w, r, http.StatusInternalServerError, "render error")
 		return
 	}
 }
 
 // buildHomeFeatured builds a small set of cards for the homepage marquee
-func buildHomeFeatured(basePath, source string) ([]HomeArtistCard, error) {
+func buildHomeFeatured(ctx context.Context, basePath, source string) ([]ArtistCard, error) {
 	// Keep the marquee lightweight so the home page renders quickly
 	desired := 24
 
-	if source == "spotify" {
-		// Use a broad query so this section always has results
-		artists, err := api.SearchSpotifyArtists("a")
-		if err != nil {
-			return nil, err
-		}
-
-		limit := desired
-		if len(artists) < limit {
-			limit = len(artists)
-		}
-
-		out := make([]HomeArtistCard, 0, limit)
-		for i := 0; i < limit; i++ {
-			a := artists[i]
-			imageURL := ""
-			if len(a.Images) > 0 {
-				// First image is typically the largest in Spotify's response
-				imageURL = a.Images[0].URL
-			}
-
-			meta := "Spotify artist"
-			if a.Followers != nil && a.Followers.Total > 0 {
-				meta = fmt.Sprintf("%s followers", formatIntCompact(a.Followers.Total))
-			} else if len(a.Genres) > 0 {
-				meta = a.Genres[0]
-			}
-
-			out = append(out, HomeArtistCard{
-				Name:     a.Name,
-				ImageURL: imageURL,
-				LinkURL:  basePath + "/artists/" + a.ID + "?source=spotify",
-				Meta:     meta,
-				Badge:    "Spotify",
-			})
-		}
+	return searchSourceCards(ctx, sourceFor(source), basePath, "", desired)
+}
 
-		return out, nil
+// isMixedHome reports whether the home page shows the mixed marquee,
+// with `?mix=1` or when no source was asked for
+func isMixedHome(r *http.Request) bool {
+	q := r.URL.Query()
+	if q.Get("mix") == "1" {
+		return true
 	}
+	return !q.Has("source")
+}
 
-	if source == "deezer" {
-		artists, err := api.SearchDeezerArtists("a")
-		if err != nil {
-			return nil, err
-		}
-
-		limit := desired
-		if len(artists) < limit {
-			limit = len(artists)
-		}
-
-		out := make([]HomeArtistCard, 0, limit)
-		for i := 0; i < limit; i++ {
-			a := artists[i]
-			// Prefer higher-res covers when available
-			imageURL := a.PictureXL
-			if imageURL == "" {
-				imageURL = a.PictureBig
-			}
-			if imageURL == "" {
-				imageURL = a.PictureMedium
-			}
-			if imageURL == "" {
-				imageURL = a.Picture
-			}
-
-			meta := "Deezer artist"
-			if a.NbFan > 0 {
-				meta = fmt.Sprintf("%s fans", formatIntCompact(a.NbFan))
-			} else if a.NbAlbum > 0 {
-				meta = fmt.Sprintf("%d albums", a.NbAlbum)
+// buildMixedHomeFeatured takes a few cards from each source and interleaves them
+// Each source's pick is drawn from a larger pool with the seed, so it changes from day to day
+// Sources are fetched concurrently, the ones that fail (no credentials, provider down) are left out
+func buildMixedHomeFeatured(ctx context.Context, basePath string, seed uint64) ([]ArtistCard, error) {
+	perSource := 6
+
+	results := make([][]ArtistCard, len(mixedHomeSources))
+	errs := make([]error, len(mixedHomeSources))
+	var wg sync.WaitGroup
+	for i, name := range mixedHomeSources {
+		wg.Add(1)
+		go func(i int, src Source) {
+			defer wg.Done()
+			cards, err := searchSourceCards(ctx, src, basePath, "", perSource*2)
+			shuffleCards(cards, seed)
+			results[i], errs[i] = cards, err
+		}(i, sourceFor(name))
+	}
+	wg.Wait()
+
+	// Round-robin so neighbouring cards come from different sources
+	var out []ArtistCard
+	for n := 0; n < perSource; n++ {
+		for i := range results {
+			if errs[i] == nil && n < len(results[i]) {
+				out = append(out, results[i][n])
 			}
-
-			out = append(out, HomeArtistCard{
-				Name:     a.Name,
-				ImageURL: imageURL,
-				LinkURL:  basePath + "/artists/" + strconv.Itoa(a.ID) + "?source=deezer",
-				Meta:     meta,
-				Badge:    "Deezer",
-			})
 		}
-
-		return out, nil
 	}
 
-	if source == "apple" {
-		// Apple doesn't expose artist images directly, so we reuse recent album artwork
-		artists, err := api.SearchAppleArtistsWithArtwork("a", desired, 300)
-		if err != nil {
-			return nil, err
-		}
-
-		limit := desired
-		if len(artists) < limit {
-			limit = len(artists)
-		}
-
-		out := make([]HomeArtistCard, 0, limit)
-		for i := 0; i < limit; i++ {
-			a := artists[i].Artist
-			meta := "Apple artist"
-			if a.PrimaryGenreName != "" {
-				meta = a.PrimaryGenreName
+	if len(out) == 0 {
+		// Only an empty marquee is worth failing the page for
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
 			}
-
-			out = append(out, HomeArtistCard{
-				Name:     a.ArtistName,
-				ImageURL: artists[i].ArtworkURL,
-				LinkURL:  basePath + "/artists/" + strconv.Itoa(a.ArtistID) + "?source=apple",
-				Meta:     meta,
-				Badge:    "Apple",
-			})
 		}
-
-		return out, nil
-	}
-
-	// Default "groupie" source uses the original dataset
-	artists, err := api.FetchArtists()
-	if err != nil {
-		return nil, err
-	}
-
-	limit := desired
-	if len(artists) < limit {
-		limit = len(artists)
 	}
+	return out, nil
+}
 
-	out := make([]HomeArtistCard, 0, limit)
-	for i := 0; i < limit; i++ {
-		a := artists[i]
-		out = append(out, HomeArtistCard{
-			Name:     a.Name,
-			ImageURL: a.Image,
-			LinkURL:  basePath + "/artists/" + strconv.Itoa(a.ID) + "?source=groupie",
-			// Keep metadata short so cards stay visually balanced
-			Meta:  fmt.Sprintf("Created %d • %d members", a.CreationDate, len(a.Members)),
-			Badge: "Groupie",
-		})
+// homeShuffleSeed changes once a day (UTC), logged-in users also get their own order
+// Reloads keep the same marquee instead of reshuffling every time
+func homeShuffleSeed(user *store.User, now time.Time) uint64 {
+	seed := uint64(now.UTC().Unix() / 86400)
+	if user != nil {
+		seed = seed<<32 ^ uint64(user.ID)
 	}
+	return seed
+}
 
-	return out, nil
+// shuffleCards reorders cards in place, the same seed always gives the same order
+func shuffleCards(cards []ArtistCard, seed uint64) {
+	rng := rand.New(rand.NewPCG(seed, 0x9e3779b97f4a7c15))
+	rng.Shuffle(len(cards), func(i, j int) {
+		cards[i], cards[j] = cards[j], cards[i]
+	})
 }
 
-// formatIntCompact formats large numbers as 1.2k, 3.4m, etc
-func formatIntCompact(n int) string {
-	if n < 1000 {
-		return strconv.Itoa(n)
+// rootRedirect is where `/` goes when `ROOT_REDIRECT` names an internal page, e.g. `/artists`
+// or `/artists?source=deezer`. Query params of the request win over the configured ones, so
+// the header's `/?source=` links keep their source. Anything that isn't a plain path on this
+// site (another host, `//`, a scheme) or that cleans to `/` itself, e.g. `/.`, is ignored and
+// the home page is shown, so it can't redirect in a loop
+func rootRedirect(r *http.Request) (string, bool) {
+	raw := strings.TrimSpace(os.Getenv("ROOT_REDIRECT"))
+	if !isLocalPath(raw) {
+		return "", false
 	}
-	if n < 1000000 {
-		v := float64(n) / 1000.0
-		s := strconv.FormatFloat(v, 'f', 1, 64)
-		// Avoid returning values like "1.0k"
-		s = trimTrailingZero(s)
-		return s + "k"
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || path.Clean(u.Path) == "/" {
+		return "", false
 	}
-	if n < 1000000000 {
-		v := float64(n) / 1000000.0
-		s := strconv.FormatFloat(v, 'f', 1, 64)
-		s = trimTrailingZero(s)
-		return s + "m"
-	}
-	v := float64(n) / 1000000000.0
-	s := strconv.FormatFloat(v, 'f', 1, 64)
-	s = trimTrailingZero(s)
-	return s + "b"
-}
 
-// trimTrailingZero removes a trailing ".0" from a decimal string
-func trimTrailingZero(s string) string {
-	if len(s) >= 2 && s[len(s)-2:] == ".0" {
-		return s[:len(s)-2]
+	q := u.Query()
+	for k, vs := range r.URL.Query() {
+		q[k] = vs
+	}
+	target := withBasePath(r, u.Path)
+	if len(q) > 0 {
+		target += "?" + q.Encode()
 	}
-	return s
+	return target, true
 }
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"palasgroupietracker/internal/store"
+)
+
+func testCards(n int) []ArtistCard {
+	cards := make([]ArtistCard, n)
+	for i := range cards {
+		cards[i] = ArtistCard{ArtistID: strconv.Itoa(i + 1)}
+	}
+	return cards
+}
+
+func cardIDs(cards []ArtistCard) []string {
+	ids := make([]string, len(cards))
+	for i, c := range cards {
+		ids[i] = c.ArtistID
+	}
+	return ids
+}
+
+func TestShuffleCards(t *testing.T) {
+	shuffled := func(seed uint64) []string {
+		cards := testCards(20)
+		shuffleCards(cards, seed)
+		return cardIDs(cards)
+	}
+
+	a := shuffled(42)
+	if b := shuffled(42); !reflect.DeepEqual(a, b) {
+		t.Fatalf("same seed gave %v then %v", a, b)
+	}
+	if c := shuffled(43); reflect.DeepEqual(a, c) {
+		t.Fatalf("seeds 42 and 43 gave the same order %v", a)
+	}
+
+	// A shuffle only reorders
+	sorted := slices.Clone(a)
+	slices.SortFunc(sorted, func(x, y string) int {
+		nx, _ := strconv.Atoi(x)
+		ny, _ := strconv.Atoi(y)
+		return nx - ny
+	})
+	if !reflect.DeepEqual(sorted, cardIDs(testCards(20))) {
+		t.Fatalf("shuffle changed the cards: %v", a)
+	}
+
+	shuffleCards(nil, 1)
+}
+
+func TestHomeShuffleSeed(t *testing.T) {
+	morning := time.Date(2026, 3, 14, 8, 0, 0, 0, time.UTC)
+	evening := time.Date(2026, 3, 14, 23, 59, 0, 0, time.UTC)
+	nextDay := time.Date(2026, 3, 15, 0, 1, 0, 0, time.UTC)
+
+	if homeShuffleSeed(nil, morning) != homeShuffleSeed(nil, evening) {
+		t.Fatal("seed changed within a UTC day")
+	}
+	if homeShuffleSeed(nil, evening) == homeShuffleSeed(nil, nextDay) {
+		t.Fatal("seed kept across days")
+	}
+	// Time zones don't move the day boundary
+	paris := evening.In(time.FixedZone("CET", 3600))
+	if homeShuffleSeed(nil, paris) != homeShuffleSeed(nil, evening) {
+		t.Fatal("seed depends on the time zone")
+	}
+
+	alice, bob := &store.User{ID: 1}, &store.User{ID: 2}
+	if homeShuffleSeed(alice, morning) != homeShuffleSeed(alice, evening) {
+		t.Fatal("user seed changed within a day")
+	}
+	if homeShuffleSeed(alice, morning) == homeShuffleSeed(bob, morning) {
+		t.Fatal("two users share a seed")
+	}
+	if homeShuffleSeed(alice, morning) == homeShuffleSeed(nil, morning) {
+		t.Fatal("a user shares the anonymous seed")
+	}
+}
+
+func TestRootRedirect(t *testing.T) {
+	tests := []struct {
+		name, env, target, prefix string
+		want                      string
+		ok                        bool
+	}{
+		{"unset", "", "/", "", "", false},
+		{"blank", "   ", "/", "", "", false},
+		{"page", "/artists", "/", "", "/artists", true},
+		{"page with query", "/artists?source=deezer", "/", "", "/artists?source=deezer", true},
+		{"request query wins", "/artists?source=deezer", "/?source=apple", "", "/artists?source=apple", true},
+		{"base path", "/artists", "/", "/app", "/app/artists", true},
+		{"base path with query", "/favorites?sort=name", "/", "/app", "/app/favorites?sort=name", true},
+
+		{"relative", "artists", "/", "", "", false},
+		{"other host", "//evil.example", "/", "", "", false},
+		{"backslash", `/\evil.example`, "/", "", "", false},
+		{"scheme", "https://evil.example/artists", "/", "", "", false},
+		{"scheme with base path", "https://evil.example", "/", "/app", "", false},
+
+		// Paths that clean to the home page would redirect to themselves
+		{"root", "/", "/", "", "", false},
+		{"root with query", "/?source=deezer", "/", "", "", false},
+		{"dot", "/.", "/", "", "", false},
+		{"dot dot", "/artists/..", "/", "", "", false},
+		{"doubled dots", "/artists/../..", "/", "", "", false},
+		{"dot with base path", "/.", "/", "/app", "", false},
+		{"dot dot with base path", "/artists/..", "/", "/app", "", false},
+	}
+	t.Setenv("BASE_PATH", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ROOT_REDIRECT", tt.env)
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.prefix != "" {
+				r.Header.Set("X-Forwarded-Prefix", tt.prefix)
+			}
+			got, ok := rootRedirect(r)
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("rootRedirect = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestResolveNextURL(t *testing.T) {
+	tests := []struct {
+		next, prefix, want string
+	}{
+		{"", "", "/"},
+		{"/favorites", "", "/favorites"},
+		{"favorites", "", "/favorites"},
+		{"/artists?source=deezer", "", "/artists?source=deezer"},
+		{"//evil.example", "", "/"},
+		{`/\evil.example`, "", "/"},
+		{`\\evil.example`, "", "/"},
+		{"https://evil.example", "", "/"},
+
+		{"/favorites", "/app", "/app/favorites"},
+		{"/app/favorites", "/app", "/app/favorites"},
+		{"//evil.example", "/app", "/app/"},
+	}
+	t.Setenv("BASE_PATH", "")
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.prefix != "" {
+			r.Header.Set("X-Forwarded-Prefix", tt.prefix)
+		}
+		if got := resolveNextURL(tt.next, r); got != tt.want {
+			t.Errorf("next %q, prefix %q: resolveNextURL = %q, want %q", tt.next, tt.prefix, got, tt.want)
+		}
+	}
+}
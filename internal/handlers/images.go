@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"strings"
+
+	"palasgroupietracker/internal/api"
+)
+
+// httpsify upgrades http:// and protocol-relative (//host/...) image URLs to https://
+// Pages are served over HTTPS in production, so plain http images would be blocked as mixed content
+func httpsify(u string) string {
+	u = strings.TrimSpace(u)
+	switch {
+	case u == "":
+		return ""
+	case strings.HasPrefix(u, "//"):
+		return "https:" + u
+	case len(u) >= 7 && strings.EqualFold(u[:7], "http://"):
+		return "https://" + u[7:]
+	default:
+		return u
+	}
+}
+
+// placeholderImage returns the source-tinted fallback artwork served from `web/static/img`
+func placeholderImage(basePath, source string) string {
+	return basePath + "/static/img/placeholder-" + normalizeSource(source) + ".svg"
+}
+
+// cardImage normalizes a card image URL and falls back to the source placeholder when it's empty
+func cardImage(basePath, source, u string) string {
+	if u = httpsify(u); u != "" {
+		return u
+	}
+	return placeholderImage(basePath, source)
+}
+
+// artworkSizes maps a view to the square artwork edge (in px) it asks providers for
+// Dense grids get small thumbnails, detail pages keep a crisp hero
+var artworkSizes = map[string]int{
+	"home":      160,
+	"list":      250,
+	"favorites": 400,
+	"detail":    600,
+}
+
+// artworkSizeFor returns the artwork size for a view, unknown views get the list size
+func artworkSizeFor(view string) int {
+	if size, ok := artworkSizes[view]; ok {
+		return size
+	}
+	return artworkSizes["list"]
+}
+
+// spotifyImageFor picks the smallest Spotify image at least `size` wide, or the largest available
+func spotifyImageFor(images []api.SpotifyImage, size int) string {
+	best := -1
+	largest := -1
+	for i, img := range images {
+		if img.URL == "" {
+			continue
+		}
+		if largest < 0 || img.Width > images[largest].Width {
+			largest = i
+		}
+		if img.Width >= size && (best < 0 || img.Width < images[best].Width) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return images[best].URL
+	}
+	if largest >= 0 {
+		return images[largest].URL
+	}
+	return ""
+}
+
+// deezerPictureFor picks the nearest Deezer picture tier (56, 250, 500, 1000 px) covering `size`
+func deezerPictureFor(small, medium, big, xl string, size int) string {
+	tiers := []struct {
+		width int
+		url   string
+	}{
+		{56, small},
+		{250, medium},
+		{500, big},
+		{1000, xl},
+	}
+
+	// Walk up from the first tier that is large enough, then fall back to smaller ones
+	start := len(tiers) - 1
+	for i, t := range tiers {
+		if t.width >= size {
+			start = i
+			break
+		}
+	}
+	for i := start; i < len(tiers); i++ {
+		if tiers[i].url != "" {
+			return tiers[i].url
+		}
+	}
+	for i := start - 1; i >= 0; i-- {
+		if tiers[i].url != "" {
+			return tiers[i].url
+		}
+	}
+	return ""
+}
+
+// The helpers below return normalized copies, API results can be shared through caches
+
+// httpsifySpotifyImages normalizes every URL in a Spotify image list
+func httpsifySpotifyImages(images []api.SpotifyImage) []api.SpotifyImage {
+	if len(images) == 0 {
+		return images
+	}
+	out := make([]api.SpotifyImage, len(images))
+	for i, img := range images {
+		img.URL = httpsify(img.URL)
+		out[i] = img
+	}
+	return out
+}
+
+// httpsifySpotifyAlbums normalizes cover images on a list of Spotify albums
+func httpsifySpotifyAlbums(albums []api.SpotifyAlbum) []api.SpotifyAlbum {
+	if len(albums) == 0 {
+		return albums
+	}
+	out := make([]api.SpotifyAlbum, len(albums))
+	for i, a := range albums {
+		a.Images = httpsifySpotifyImages(a.Images)
+		out[i] = a
+	}
+	return out
+}
+
+// httpsifySpotifyTracks normalizes album covers on a list of Spotify tracks
+func httpsifySpotifyTracks(tracks []api.SpotifyTrack) []api.SpotifyTrack {
+	if len(tracks) == 0 {
+		return tracks
+	}
+	out := make([]api.SpotifyTrack, len(tracks))
+	for i, t := range tracks {
+		t.Album.Images = httpsifySpotifyImages(t.Album.Images)
+		out[i] = t
+	}
+	return out
+}
+
+// httpsifyDeezerArtist normalizes every picture size on a Deezer artist
+func httpsifyDeezerArtist(a api.DeezerArtist) api.DeezerArtist {
+	a.Picture = httpsify(a.Picture)
+	a.PictureSmall = httpsify(a.PictureSmall)
+	a.PictureMedium = httpsify(a.PictureMedium)
+	a.PictureBig = httpsify(a.PictureBig)
+	a.PictureXL = httpsify(a.PictureXL)
+	return a
+}
+
+// httpsifyDeezerAlbums normalizes every cover size on a list of Deezer albums
+func httpsifyDeezerAlbums(albums []api.DeezerAlbum) []api.DeezerAlbum {
+	if len(albums) == 0 {
+		return albums
+	}
+	out := make([]api.DeezerAlbum, len(albums))
+	for i, a := range albums {
+		a.Cover = httpsify(a.Cover)
+		a.CoverSmall = httpsify(a.CoverSmall)
+		a.CoverMedium = httpsify(a.CoverMedium)
+		a.CoverBig = httpsify(a.CoverBig)
+		a.CoverXL = httpsify(a.CoverXL)
+		out[i] = a
+	}
+	return out
+}
+
+// httpsifyDeezerTracks normalizes album covers on a list of Deezer tracks
+func httpsifyDeezerTracks(tracks []api.DeezerTrack) []api.DeezerTrack {
+	if len(tracks) == 0 {
+		return tracks
+	}
+	out := make([]api.DeezerTrack, len(tracks))
+	for i, t := range tracks {
+		t.Album.Cover = httpsify(t.Album.Cover)
+		t.Album.CoverSmall = httpsify(t.Album.CoverSmall)
+		t.Album.CoverMedium = httpsify(t.Album.CoverMedium)
+		t.Album.CoverBig = httpsify(t.Album.CoverBig)
+		t.Album.CoverXL = httpsify(t.Album.CoverXL)
+		out[i] = t
+	}
+	return out
+}
+
+// httpsifyAppleAlbums normalizes artwork on a list of Apple albums
+func httpsifyAppleAlbums(albums []api.AppleAlbum) []api.AppleAlbum {
+	if len(albums) == 0 {
+		return albums
+	}
+	out := make([]api.AppleAlbum, len(albums))
+	for i, a := range albums {
+		a.ArtworkURL100 = httpsify(a.ArtworkURL100)
+		out[i] = a
+	}
+	return out
+}
+
+// httpsifyAppleTracks normalizes artwork on a list of Apple tracks
+func httpsifyAppleTracks(tracks []api.AppleTrack) []api.AppleTrack {
+	if len(tracks) == 0 {
+		return tracks
+	}
+	out := make([]api.AppleTrack, len(tracks))
+	for i, t := range tracks {
+		t.ArtworkURL100 = httpsify(t.ArtworkURL100)
+		out[i] = t
+	}
+	return out
+}
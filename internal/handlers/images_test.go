@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"testing"
+
+	"palasgroupietracker/internal/api"
+)
+
+func TestArtworkSizeFor(t *testing.T) {
+	tests := []struct {
+		view string
+		want int
+	}{
+		{"home", 160},
+		{"list", 250},
+		{"favorites", 400},
+		{"detail", 600},
+		// Unknown views get the list size
+		{"", 250},
+		{"sheet", 250},
+		{"Detail", 250},
+	}
+	for _, tt := range tests {
+		if got := artworkSizeFor(tt.view); got != tt.want {
+			t.Errorf("artworkSizeFor(%q) = %d, want %d", tt.view, got, tt.want)
+		}
+	}
+}
+
+func TestSpotifyImageFor(t *testing.T) {
+	// Spotify usually sends 640, 320 and 160 px images, largest first
+	full := []api.SpotifyImage{
+		{URL: "640.jpg", Width: 640, Height: 640},
+		{URL: "320.jpg", Width: 320, Height: 320},
+		{URL: "160.jpg", Width: 160, Height: 160},
+	}
+	tests := []struct {
+		name   string
+		images []api.SpotifyImage
+		size   int
+		want   string
+	}{
+		{"home", full, artworkSizeFor("home"), "160.jpg"},
+		{"list", full, artworkSizeFor("list"), "320.jpg"},
+		{"favorites", full, artworkSizeFor("favorites"), "640.jpg"},
+		{"detail", full, artworkSizeFor("detail"), "640.jpg"},
+		{"exact width", full, 320, "320.jpg"},
+		{"order doesn't matter", []api.SpotifyImage{full[2], full[0], full[1]}, 250, "320.jpg"},
+
+		// Nothing big enough falls back to the largest image
+		{"larger than any", full, 1000, "640.jpg"},
+		{"only small", []api.SpotifyImage{full[2]}, artworkSizeFor("detail"), "160.jpg"},
+		{"unknown widths", []api.SpotifyImage{{URL: "a.jpg"}}, 250, "a.jpg"},
+
+		// Images without a URL are skipped
+		{"missing size", []api.SpotifyImage{full[0], {Width: 320}, full[2]}, 250, "640.jpg"},
+		{"largest missing", []api.SpotifyImage{{Width: 640}, full[1], full[2]}, 600, "320.jpg"},
+		{"no urls", []api.SpotifyImage{{Width: 640}, {Width: 320}}, 250, ""},
+		{"no images", nil, 250, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := spotifyImageFor(tt.images, tt.size); got != tt.want {
+				t.Fatalf("spotifyImageFor(%d) = %q, want %q", tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeezerPictureFor(t *testing.T) {
+	tests := []struct {
+		name                   string
+		small, medium, big, xl string
+		size                   int
+		want                   string
+	}{
+		{"home", "s", "m", "b", "xl", artworkSizeFor("home"), "m"},
+		{"list", "s", "m", "b", "xl", artworkSizeFor("list"), "m"},
+		{"favorites", "s", "m", "b", "xl", artworkSizeFor("favorites"), "b"},
+		{"detail", "s", "m", "b", "xl", artworkSizeFor("detail"), "xl"},
+		{"tiny", "s", "m", "b", "xl", 40, "s"},
+		{"exact tier", "s", "m", "b", "xl", 500, "b"},
+		{"larger than any tier", "s", "m", "b", "xl", 2000, "xl"},
+
+		// A missing tier uses the next larger one first, then smaller ones
+		{"missing size goes up", "s", "m", "", "xl", artworkSizeFor("favorites"), "xl"},
+		{"missing larger sizes go down", "s", "m", "", "", artworkSizeFor("favorites"), "m"},
+		{"only small", "s", "", "", "", artworkSizeFor("detail"), "s"},
+		{"only xl", "", "", "", "xl", artworkSizeFor("home"), "xl"},
+		{"xl missing at the top", "s", "m", "b", "", 2000, "b"},
+		{"none", "", "", "", "", 250, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deezerPictureFor(tt.small, tt.medium, tt.big, tt.xl, tt.size); got != tt.want {
+				t.Fatalf("deezerPictureFor(%d) = %q, want %q", tt.size, got, tt.want)
+			}
+		})
+	}
+}
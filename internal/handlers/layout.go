@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"palasgroupietracker/internal/store"
+)
+
+// LayoutData is what layout.gohtml reads, every page-data struct embeds it
+// Build it with newLayoutData so the header (source switch, nav, login state, logout form)
+// is filled the same way on every page
+type LayoutData struct {
+	Title      string
+	Source     string
+	ActiveNav  string
+	BasePath   string
+	CurrentURL string
+	User       *store.User
+	IsAuthed   bool
+	// AccountsEnabled is false without a database, login, register and favorites are hidden then
+	AccountsEnabled bool
+	// CSRFToken goes in every POST form, see requireCSRF
+	CSRFToken string
+}
+
+// newLayoutData fills the shared fields for r, user and authed come from the handler's
+// getCurrentUser call so the session is only looked up once
+// It may set the CSRF cookie, so call it before writing the status
+func newLayoutData(w http.ResponseWriter, r *http.Request, title string, user *store.User, authed bool) LayoutData {
+	return LayoutData{
+		Title:           title,
+		Source:          getSource(r),
+		ActiveNav:       activeNav(r),
+		BasePath:        getBasePath(r),
+		CurrentURL:      buildCurrentURL(r),
+		User:            user,
+		IsAuthed:        authed,
+		AccountsEnabled: appStore != nil,
+		CSRFToken:       csrfToken(w, r),
+	}
+}
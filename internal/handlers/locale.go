@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale formats numbers and dates for the visitor's language
+// Templates call its methods directly (`{{ $.Locale.Int .Fans }}`), a nil Locale formats as en-US
+type Locale struct {
+	// Tag is the BCP 47 tag, e.g. "fr-FR"
+	Tag string
+
+	group   string
+	decimal string
+	// units are the compact suffixes for thousands, millions and billions
+	units [3]string
+	// unitSep goes between a compact number and its unit, a no-break space where one is used
+	unitSep string
+	months  [12]string
+	// date lays out day, abbreviated month and year
+	date func(day int, month string, year int) string
+}
+
+var localeEnUS = &Locale{
+	Tag:     "en-US",
+	group:   ",",
+	decimal: ".",
+	units:   [3]string{"k", "m", "b"},
+	months:  [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	date: func(d int, m string, y int) string {
+		return fmt.Sprintf("%s %d, %d", m, d, y)
+	},
+}
+
+// locales are the supported formats by lower-case tag, other languages get en-US
+var locales = map[string]*Locale{
+	"en-us": localeEnUS,
+	"en-gb": {
+		Tag:     "en-GB",
+		group:   ",",
+		decimal: ".",
+		units:   [3]string{"k", "m", "b"},
+		months:  localeEnUS.months,
+		date: func(d int, m string, y int) string {
+			return fmt.Sprintf("%d %s %d", d, m, y)
+		},
+	},
+	"fr-fr": {
+		Tag:     "fr-FR",
+		group:   "\u202f",
+		decimal: ",",
+		units:   [3]string{"k", "M", "Md"},
+		unitSep: "\u00a0",
+		months:  [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		date: func(d int, m string, y int) string {
+			return fmt.Sprintf("%d %s %d", d, m, y)
+		},
+	},
+	"de-de": {
+		Tag:     "de-DE",
+		group:   ".",
+		decimal: ",",
+		units:   [3]string{"Tsd.", "Mio.", "Mrd."},
+		unitSep: "\u00a0",
+		months:  [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		date: func(d int, m string, y int) string {
+			return fmt.Sprintf("%d. %s %d", d, m, y)
+		},
+	},
+	"es-es": {
+		Tag:     "es-ES",
+		group:   ".",
+		decimal: ",",
+		units:   [3]string{"mil", "M", "mil M"},
+		unitSep: "\u00a0",
+		months:  [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		date: func(d int, m string, y int) string {
+			return fmt.Sprintf("%d %s %d", d, m, y)
+		},
+	},
+}
+
+// localeByLanguage picks the format for a bare language or an unsupported region (fr-CA, de-AT)
+var localeByLanguage = map[string]string{
+	"en": "en-us",
+	"fr": "fr-fr",
+	"de": "de-de",
+	"es": "es-es",
+}
+
+// resolveLocale picks the best supported locale from Accept-Language, en-US when none fits
+func resolveLocale(r *http.Request) *Locale {
+	type langPref struct {
+		tag string
+		q   float64
+	}
+	var prefs []langPref
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f <= 0 {
+				continue
+			}
+			q = f
+		}
+		prefs = append(prefs, langPref{tag: strings.ReplaceAll(tag, "_", "-"), q: q})
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+
+	for _, p := range prefs {
+		if l, ok := locales[p.tag]; ok {
+			return l
+		}
+		lang, _, _ := strings.Cut(p.tag, "-")
+		if key, ok := localeByLanguage[lang]; ok {
+			return locales[key]
+		}
+	}
+	return localeEnUS
+}
+
+func (l *Locale) orDefault() *Locale {
+	if l == nil {
+		return localeEnUS
+	}
+	return l
+}
+
+// Int formats n with thousands separators, e.g. 1,234,567 or 1.234.567
+func (l *Locale) Int(n int) string {
+	l = l.orDefault()
+	s := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= 3 {
+		return sign + s
+	}
+
+	var b strings.Builder
+	head := len(s) % 3
+	if head > 0 {
+		b.WriteString(s[:head])
+	}
+	for i := head; i < len(s); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(l.group)
+		}
+		b.WriteString(s[i : i+3])
+	}
+	return sign + b.String()
+}
+
+// Compact shortens large counts to one decimal, e.g. 1.5k, 1,5 M or 3,4 Mio.
+func (l *Locale) Compact(n int) string {
+	l = l.orDefault()
+	if n < 1000 && n > -1000 {
+		return strconv.Itoa(n)
+	}
+
+	div, unit := 1e3, l.units[0]
+	switch abs := max(n, -n); {
+	case abs >= 1e9:
+		div, unit = 1e9, l.units[2]
+	case abs >= 1e6:
+		div, unit = 1e6, l.units[1]
+	}
+	s := strconv.FormatFloat(float64(n)/div, 'f', 1, 64)
+	// Avoid returning values like "1.0k"
+	s = trimTrailingZero(s)
+	return strings.Replace(s, ".", l.decimal, 1) + l.unitSep + unit
+}
+
+// trimTrailingZero removes a trailing ".0" from a decimal string
+func trimTrailingZero(s string) string {
+	if len(s) >= 2 && s[len(s)-2:] == ".0" {
+		return s[:len(s)-2]
+	}
+	return s
+}
+
+// Date formats t as a medium date, e.g. Jan 2, 2006 or 2 janv. 2006
+func (l *Locale) Date(t time.Time) string {
+	l = l.orDefault()
+	return l.date(t.Day(), l.months[t.Month()-1], t.Year())
+}
+
+// DateString formats the date strings providers send: YYYY-MM-DD (a time part is dropped),
+// Groupie's DD-MM-YYYY and YYYY-MM. Anything else, like a bare year, is returned unchanged
+func (l *Locale) DateString(s string) string {
+	l = l.orDefault()
+	s = strings.TrimSpace(s)
+	if len(s) > 10 && s[10] == 'T' {
+		s = s[:10]
+	}
+	for _, layout := range []string{"2006-01-02", "02-01-2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return l.Date(t)
+		}
+	}
+	if t, err := time.Parse("2006-01", s); err == nil {
+		return l.months[t.Month()-1] + " " + strconv.Itoa(t.Year())
+	}
+	return s
+}
+
+// setCountMeta sets Meta from a format with one %s verb for the count, e.g. "%s followers"
+// The parts are kept so localizeCards can redo it in the visitor's locale
+func (c *ArtistCard) setCountMeta(format string, n int, compact bool) {
+	c.metaFormat, c.metaCount, c.metaCompact = format, n, compact
+	c.Meta = c.countMeta(localeEnUS)
+}
+
+func (c *ArtistCard) countMeta(l *Locale) string {
+	if c.metaCompact {
+		return fmt.Sprintf(c.metaFormat, l.Compact(c.metaCount))
+	}
+	return fmt.Sprintf(c.metaFormat, l.Int(c.metaCount))
+}
+
+// localizeCards gives cards the visitor's locale and reformats count-based Meta lines
+func localizeCards(cards []ArtistCard, l *Locale) {
+	for i := range cards {
+		cards[i].Locale = l
+		if cards[i].metaFormat != "" {
+			cards[i].Meta = cards[i].countMeta(l)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResolveLocale(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"", "en-US"},
+		{"fr-FR,fr;q=0.9,en;q=0.8", "fr-FR"},
+		{"fr-CA", "fr-FR"},
+		{"de", "de-DE"},
+		{"en-GB", "en-GB"},
+		{"en_gb", "en-GB"},
+		{"ja-JP,es;q=0.5", "es-ES"},
+		{"en;q=0.4, de-AT;q=0.9", "de-DE"},
+		{"fr;q=0, de;q=0.1", "de-DE"},
+		{"fr;q=abc", "en-US"},
+		{"*", "en-US"},
+		{"ja, zh", "en-US"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Language", tt.header)
+		if got := resolveLocale(r).Tag; got != tt.want {
+			t.Errorf("Accept-Language %q = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleInt(t *testing.T) {
+	tests := []struct {
+		tag  string
+		n    int
+		want string
+	}{
+		{"en-us", 0, "0"},
+		{"en-us", 999, "999"},
+		{"en-us", 1000, "1,000"},
+		{"en-us", 1234567, "1,234,567"},
+		{"en-us", -1234567, "-1,234,567"},
+		{"fr-fr", 1234567, "1\u202f234\u202f567"},
+		{"de-de", 1234567, "1.234.567"},
+		{"es-es", 12345, "12.345"},
+	}
+	for _, tt := range tests {
+		if got := locales[tt.tag].Int(tt.n); got != tt.want {
+			t.Errorf("%s Int(%d) = %q, want %q", tt.tag, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleCompact(t *testing.T) {
+	tests := []struct {
+		tag  string
+		n    int
+		want string
+	}{
+		{"en-us", 999, "999"},
+		{"en-us", 1000, "1k"},
+		{"en-us", 1500, "1.5k"},
+		{"en-us", 2_400_000, "2.4m"},
+		{"en-us", 3_000_000_000, "3b"},
+		{"en-us", -1500, "-1.5k"},
+		{"fr-fr", 1500, "1,5\u00a0k"},
+		{"fr-fr", 2_400_000, "2,4\u00a0M"},
+		{"de-de", 3_400_000, "3,4\u00a0Mio."},
+		{"es-es", 7_000_000_000, "7\u00a0mil M"},
+	}
+	for _, tt := range tests {
+		if got := locales[tt.tag].Compact(tt.n); got != tt.want {
+			t.Errorf("%s Compact(%d) = %q, want %q", tt.tag, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleDate(t *testing.T) {
+	day := time.Date(2020, time.March, 2, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		tag, want string
+	}{
+		{"en-us", "Mar 2, 2020"},
+		{"en-gb", "2 Mar 2020"},
+		{"fr-fr", "2 mars 2020"},
+		{"de-de", "2. März 2020"},
+		{"es-es", "2 mar 2020"},
+	}
+	for _, tt := range tests {
+		if got := locales[tt.tag].Date(day); got != tt.want {
+			t.Errorf("%s Date = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleDateString(t *testing.T) {
+	fr := locales["fr-fr"]
+	tests := []struct {
+		in, want string
+	}{
+		{"2020-02-01", "1 févr. 2020"},
+		{"2020-02-01T10:00:00Z", "1 févr. 2020"},
+		{"01-02-2020", "1 févr. 2020"},
+		{"2020-02", "févr. 2020"},
+		{"2020", "2020"},
+		{" soon ", "soon"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := fr.DateString(tt.in); got != tt.want {
+			t.Errorf("DateString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNilLocaleIsEnUS(t *testing.T) {
+	var l *Locale
+	if got := l.Int(1234); got != "1,234" {
+		t.Errorf("Int = %q", got)
+	}
+	if got := l.Compact(1500); got != "1.5k" {
+		t.Errorf("Compact = %q", got)
+	}
+	if got := l.DateString("2020-02-01"); got != "Feb 1, 2020" {
+		t.Errorf("DateString = %q", got)
+	}
+}
+
+func TestLocalizeCards(t *testing.T) {
+	cards := make([]ArtistCard, 2)
+	cards[0].setCountMeta("%s followers", 1500, true)
+	cards[1].setCountMeta("%s fans", 1234, false)
+	cards = append(cards, ArtistCard{Meta: "Since 1970"})
+	if cards[0].Meta != "1.5k followers" || cards[1].Meta != "1,234 fans" {
+		t.Fatalf("default Meta = %q, %q", cards[0].Meta, cards[1].Meta)
+	}
+
+	de := locales["de-de"]
+	localizeCards(cards, de)
+	want := []string{"1,5\u00a0Tsd. followers", "1.234 fans", "Since 1970"}
+	for i, c := range cards {
+		if c.Meta != want[i] {
+			t.Errorf("card %d Meta = %q, want %q", i, c.Meta, want[i])
+		}
+		if c.Locale != de {
+			t.Errorf("card %d has locale %v", i, c.Locale)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"palasgroupietracker/internal/api"
+)
+
+// maxLyricsParamLen caps artist/title length so the endpoint can't be used to relay huge queries
+const maxLyricsParamLen = 200
+
+type lyricsPayload struct {
+	Artist string `json:"artist"`
+	Title  string `json:"title"`
+	Lyrics string `json:"lyrics"`
+}
+
+// LyricsHandler returns lyrics for `?artist=&title=` as JSON, loaded on demand by the detail page
+func LyricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		renderJSONError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if !api.LyricsEnabled() {
+		renderJSONError(w, r, http.StatusServiceUnavailable, "lyrics are not configured")
+		return
+	}
+
+	artist := strings.TrimSpace(r.URL.Query().Get("artist"))
+	title := strings.TrimSpace(r.URL.Query().Get("title"))
+	if artist == "" || title == "" {
+		renderJSONError(w, r, http.StatusBadRequest, "artist and title are required")
+		return
+	}
+	if utf8.RuneCountInString(artist) > maxLyricsParamLen || utf8.RuneCountInString(title) > maxLyricsParamLen {
+		renderJSONError(w, r, http.StatusBadRequest, "artist or title too long")
+		return
+	}
+
+	lyrics, err := api.FetchLyrics(artist, title)
+	if err != nil {
+		switch {
+		case errors.Is(err, api.ErrLyricsDisabled):
+			renderJSONError(w, r, http.StatusServiceUnavailable, "lyrics are not configured")
+		case isNotFoundError(err):
+			renderJSONError(w, r, http.StatusNotFound, "no lyrics found for this song")
+		default:
+			renderJSONError(w, r, http.StatusBadGateway, "failed to load lyrics")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, lyricsPayload{Artist: artist, Title: title, Lyrics: lyrics})
+}
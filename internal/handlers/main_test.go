@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Templates are loaded from web/templates relative to the working directory,
+// so tests run from the repository root like the server does
+func TestMain(m *testing.M) {
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// testCSRFToken is a well-formed token, sent both as the cookie and the form field
+var testCSRFToken = strings.Repeat("A", 43)
+
+// newFormRequest builds a form post that passes the CSRF check, with cookies attached
+func newFormRequest(target string, form url.Values, cookies ...*http.Cookie) *http.Request {
+	if form == nil {
+		form = url.Values{}
+	}
+	form.Set(csrfFieldName, testCSRFToken)
+
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(&http.Cookie{Name: csrfCookieName, Value: testCSRFToken})
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	return r
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// themeColor is the dark page background (Tailwind slate-950), the layout's theme-color meta matches it
+const themeColor = "#020617"
+
+type webManifest struct {
+	Name            string         `json:"name"`
+	ShortName       string         `json:"short_name"`
+	Description     string         `json:"description"`
+	StartURL        string         `json:"start_url"`
+	Scope           string         `json:"scope"`
+	Display         string         `json:"display"`
+	ThemeColor      string         `json:"theme_color"`
+	BackgroundColor string         `json:"background_color"`
+	Icons           []manifestIcon `json:"icons"`
+}
+
+type manifestIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+}
+
+// ManifestHandler serves `/site.webmanifest`, the web app manifest that makes the site installable
+// It is built per request so start URL, scope and icons follow the base path
+func ManifestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		renderJSONError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	m := webManifest{
+		Name:            "Pala's Groupie Tracker",
+		ShortName:       "Groupie Tracker",
+		Description:     "Browse artists, compare data sources and save favorites.",
+		StartURL:        withBasePath(r, "/"),
+		Scope:           withBasePath(r, "/"),
+		Display:         "standalone",
+		ThemeColor:      themeColor,
+		BackgroundColor: themeColor,
+		Icons: []manifestIcon{
+			{Src: withBasePath(r, "/static/img/icon-192.png"), Sizes: "192x192", Type: "image/png"},
+			{Src: withBasePath(r, "/static/img/icon-512.png"), Sizes: "512x512", Type: "image/png"},
+			{Src: withBasePath(r, "/static/img/icon.svg"), Sizes: "any", Type: "image/svg+xml"},
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/manifest+json")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		_ = json.NewEncoder(w).Encode(m)
+	}
+}
+
+// FaviconHandler serves `/favicon.ico` from the static images, browsers ask for it at the root
+// whatever the page links to
+func FaviconHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		renderError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeFile(w, r, "web/static/img/favicon.ico")
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"palasgroupietracker/internal/api"
+)
+
+// maxMemberAvatarLookups bounds the Wikipedia calls one detail page can trigger
+const maxMemberAvatarLookups = 8
+
+// GroupieMember is a band member on the detail page, ImageURL is empty when no photo was found
+type GroupieMember struct {
+	Name     string
+	ImageURL string
+	// Initial is shown in place of a missing photo
+	Initial string
+}
+
+// memberAvatarsEnabled reports whether `MEMBER_AVATARS` turns on the member photo lookups
+func memberAvatarsEnabled() bool {
+	on, err := strconv.ParseBool(strings.TrimSpace(os.Getenv("MEMBER_AVATARS")))
+	return err == nil && on
+}
+
+// buildGroupieMembers pairs each member name with a Wikipedia thumbnail when avatars are enabled
+// Lookups are cached per name and capped per page, members past the cap or without a page keep
+// only their name
+func buildGroupieMembers(names []string) []GroupieMember {
+	members := make([]GroupieMember, len(names))
+	for i, n := range names {
+		members[i].Name = n
+		if r, _ := utf8.DecodeRuneInString(strings.TrimSpace(n)); r != utf8.RuneError {
+			members[i].Initial = strings.ToUpper(string(r))
+		}
+	}
+	if !memberAvatarsEnabled() {
+		return members
+	}
+
+	sem := make(chan struct{}, 4)
+	var wg sync.WaitGroup
+	for i := range members {
+		if i >= maxMemberAvatarLookups {
+			break
+		}
+		wg.Add(1)
+		go func(m *GroupieMember) { // look up member photos concurrently
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// Missing photos are expected, the name alone is still shown
+			thumb, err := api.FetchWikipediaThumbnail(m.Name)
+			if err == nil {
+				m.ImageURL = thumb
+			}
+		}(&members[i])
+	}
+	wg.Wait()
+
+	return members
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// navSections maps the first path segment to the header link it highlights
+// New top-level pages only need a line here, paths not listed highlight nothing
+var navSections = map[string]string{
+	"":          "home",
+	"artists":   "artists",
+	"favorites": "favorites",
+}
+
+// activeNav derives the `ActiveNav` key of a page from the request path
+// The base path is dropped first when the proxy forwarded it, so "/app/artists/1" is "artists"
+func activeNav(r *http.Request) string {
+	p := r.URL.Path
+	if base := getBasePath(r); base != "" && (p == base || strings.HasPrefix(p, base+"/")) {
+		p = strings.TrimPrefix(p, base)
+	}
+	p = strings.TrimPrefix(p, "/")
+	section, rest, _ := strings.Cut(p, "/")
+	// Only the root itself is home, not every unknown path
+	if section == "" && rest != "" {
+		return ""
+	}
+	return navSections[section]
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestActiveNav(t *testing.T) {
+	tests := []struct {
+		path, prefix, want string
+	}{
+		{"/", "", "home"},
+		{"", "", "home"},
+		{"/artists", "", "artists"},
+		{"/artists/", "", "artists"},
+		{"/artists/12", "", "artists"},
+		{"/artists/12/sheet", "", "artists"},
+		{"/favorites", "", "favorites"},
+		{"/login", "", ""},
+		{"/account/sessions", "", ""},
+		{"/nope", "", ""},
+		{"/nope/artists", "", ""},
+		{"/artistsx", "", ""},
+
+		// The forwarded base path is dropped first
+		{"/app", "/app", "home"},
+		{"/app/", "/app", "home"},
+		{"/app/artists/12", "/app", "artists"},
+		{"/app/favorites", "/app/", "favorites"},
+		{"/team/app/artists", "/team/app", "artists"},
+		// Only a whole leading segment counts as the base path
+		{"/application/artists", "/app", ""},
+		// Paths the proxy didn't prefix still resolve
+		{"/artists", "/app", "artists"},
+	}
+	t.Setenv("BASE_PATH", "")
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.URL.Path = tt.path
+		if tt.prefix != "" {
+			r.Header.Set("X-Forwarded-Prefix", tt.prefix)
+		}
+		if got := activeNav(r); got != tt.want {
+			t.Errorf("path %q, prefix %q: activeNav = %q, want %q", tt.path, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestActiveNavBasePathEnv(t *testing.T) {
+	t.Setenv("BASE_PATH", "/env")
+	r := httptest.NewRequest(http.MethodGet, "/env/favorites", nil)
+	if got := activeNav(r); got != "favorites" {
+		t.Fatalf("activeNav = %q, want favorites", got)
+	}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec is the hand-maintained contract for the JSON endpoints
+// Update it together with any JSON handler or route change
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPIHandler serves the OpenAPI 3 description of the JSON endpoints
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		renderJSONError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(openAPISpec)
+	}
+}
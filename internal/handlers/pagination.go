@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultGroupiePageSize is how many Groupie artists a page shows, `GROUPIE_PAGE_SIZE` overrides it
+	defaultGroupiePageSize = 24
+	// maxGroupiePageSize caps `?limit=`, `limit=all` is the way to get everything
+	maxGroupiePageSize = 100
+)
+
+// Pagination is the page window of a list and the links around it
+type Pagination struct {
+	Page      int
+	PageCount int
+	// Total is the number of results before windowing
+	Total int
+	// ShowAll is set by `limit=all`, the whole list is on one page
+	ShowAll bool
+	// Limit is the `limit` param to carry along, empty for the default page size
+	Limit string
+
+	PrevURL string
+	NextURL string
+	// ToggleURL switches between "show all" and the first page
+	ToggleURL string
+}
+
+// paginateCards cuts cards to the page asked for by `page` and `limit`
+// Out of range pages are clamped, so a stale link still lands on a real page
+func paginateCards(r *http.Request, cards []ArtistCard) ([]ArtistCard, *Pagination) {
+	q := r.URL.Query()
+	size := envPositiveInt("GROUPIE_PAGE_SIZE", defaultGroupiePageSize)
+	p := &Pagination{Page: 1, Total: len(cards)}
+
+	switch raw := strings.ToLower(strings.TrimSpace(q.Get("limit"))); {
+	case raw == "all":
+		p.ShowAll, p.Limit = true, "all"
+	case raw != "":
+		if v, err := strconv.Atoi(raw); err == nil && v > 0 {
+			size = min(v, maxGroupiePageSize)
+			p.Limit = strconv.Itoa(size)
+		}
+	}
+	size = min(size, maxGroupiePageSize)
+
+	if p.ShowAll {
+		p.PageCount = 1
+	} else {
+		p.PageCount = max(1, (len(cards)+size-1)/size)
+		if v, err := strconv.Atoi(strings.TrimSpace(q.Get("page"))); err == nil {
+			p.Page = clampInt(v, 1, p.PageCount)
+		}
+		start := (p.Page - 1) * size
+		cards = cards[start:min(start+size, len(cards))]
+	}
+
+	if p.Page > 1 {
+		p.PrevURL = pageURL(r, p.Page-1, p.Limit)
+	}
+	if p.Page < p.PageCount {
+		p.NextURL = pageURL(r, p.Page+1, p.Limit)
+	}
+	if p.ShowAll {
+		p.ToggleURL = pageURL(r, 1, "")
+	} else {
+		p.ToggleURL = pageURL(r, 1, "all")
+	}
+	return cards, p
+}
+
+// pageURL is the artists list URL with the same filters and another page
+// It always points at `/artists`, also when built for the ajax fragment
+func pageURL(r *http.Request, page int, limit string) string {
+	q := r.URL.Query()
+	q.Del("page")
+	q.Del("limit")
+	if page > 1 {
+		q.Set("page", strconv.Itoa(page))
+	}
+	if limit != "" {
+		q.Set("limit", limit)
+	}
+	u := withBasePath(r, "/artists")
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
+}
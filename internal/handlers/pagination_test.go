@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestPaginateCards(t *testing.T) {
+	tests := []struct {
+		name   string
+		cards  int
+		target string
+		prefix string
+
+		count, first int
+		page, pages  int
+		showAll      bool
+		limit        string
+		prev, next   string
+		toggle       string
+	}{
+		{
+			name: "first page", cards: 25, target: "/artists",
+			count: 10, first: 1, page: 1, pages: 3,
+			next: "/artists?page=2", toggle: "/artists?limit=all",
+		},
+		{
+			name: "middle page", cards: 25, target: "/artists?page=2",
+			count: 10, first: 11, page: 2, pages: 3,
+			prev: "/artists", next: "/artists?page=3", toggle: "/artists?limit=all",
+		},
+		{
+			name: "last page is short", cards: 25, target: "/artists?page=3",
+			count: 5, first: 21, page: 3, pages: 3,
+			prev: "/artists?page=2", toggle: "/artists?limit=all",
+		},
+		{
+			name: "past the end clamps to the last page", cards: 25, target: "/artists?page=99",
+			count: 5, first: 21, page: 3, pages: 3,
+			prev: "/artists?page=2", toggle: "/artists?limit=all",
+		},
+		{
+			name: "zero clamps to the first page", cards: 25, target: "/artists?page=0",
+			count: 10, first: 1, page: 1, pages: 3,
+			next: "/artists?page=2", toggle: "/artists?limit=all",
+		},
+		{
+			name: "negative clamps to the first page", cards: 25, target: "/artists?page=-4",
+			count: 10, first: 1, page: 1, pages: 3,
+			next: "/artists?page=2", toggle: "/artists?limit=all",
+		},
+		{
+			name: "unparseable page", cards: 25, target: "/artists?page=two",
+			count: 10, first: 1, page: 1, pages: 3,
+			next: "/artists?page=2", toggle: "/artists?limit=all",
+		},
+		{
+			name: "empty list", cards: 0, target: "/artists?page=3",
+			count: 0, page: 1, pages: 1, toggle: "/artists?limit=all",
+		},
+		{
+			name: "show all", cards: 25, target: "/artists?limit=all",
+			count: 25, first: 1, page: 1, pages: 1, showAll: true, limit: "all",
+			toggle: "/artists",
+		},
+		{
+			name: "show all ignores page", cards: 25, target: "/artists?limit=ALL&page=3",
+			count: 25, first: 1, page: 1, pages: 1, showAll: true, limit: "all",
+			toggle: "/artists",
+		},
+		{
+			name: "custom limit is carried along", cards: 25, target: "/artists?limit=5&page=2",
+			count: 5, first: 6, page: 2, pages: 5, limit: "5",
+			prev: "/artists?limit=5", next: "/artists?limit=5&page=3", toggle: "/artists?limit=all",
+		},
+		{
+			name: "limit over the cap", cards: 250, target: "/artists?limit=500",
+			count: 100, first: 1, page: 1, pages: 3, limit: "100",
+			next: "/artists?limit=100&page=2", toggle: "/artists?limit=all",
+		},
+		{
+			name: "invalid limit falls back to the page size", cards: 25, target: "/artists?limit=0",
+			count: 10, first: 1, page: 1, pages: 3,
+			next: "/artists?page=2", toggle: "/artists?limit=all",
+		},
+		{
+			name: "filters survive", cards: 25, target: "/artists?q=queen&sort=name&source=groupie&page=2",
+			count: 10, first: 11, page: 2, pages: 3,
+			prev:   "/artists?q=queen&sort=name&source=groupie",
+			next:   "/artists?page=3&q=queen&sort=name&source=groupie",
+			toggle: "/artists?limit=all&q=queen&sort=name&source=groupie",
+		},
+		{
+			name: "filters survive show all", cards: 25, target: "/artists?q=queen&sort=name&source=groupie&limit=all",
+			count: 25, first: 1, page: 1, pages: 1, showAll: true, limit: "all",
+			toggle: "/artists?q=queen&sort=name&source=groupie",
+		},
+		{
+			name: "base path", cards: 25, target: "/artists?page=2&source=groupie", prefix: "/app",
+			count: 10, first: 11, page: 2, pages: 3,
+			prev: "/app/artists?source=groupie", next: "/app/artists?page=3&source=groupie",
+			toggle: "/app/artists?limit=all&source=groupie",
+		},
+		{
+			name: "links point at the list page", cards: 25, target: "/api/artists?page=2",
+			count: 10, first: 11, page: 2, pages: 3,
+			prev: "/artists", next: "/artists?page=3", toggle: "/artists?limit=all",
+		},
+	}
+	t.Setenv("BASE_PATH", "")
+	t.Setenv("GROUPIE_PAGE_SIZE", "10")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.prefix != "" {
+				r.Header.Set("X-Forwarded-Prefix", tt.prefix)
+			}
+			cards, p := paginateCards(r, testCards(tt.cards))
+
+			if len(cards) != tt.count {
+				t.Fatalf("got %d cards, want %d", len(cards), tt.count)
+			}
+			if tt.count > 0 && cards[0].ArtistID != strconv.Itoa(tt.first) {
+				t.Errorf("first card = %s, want %d", cards[0].ArtistID, tt.first)
+			}
+			if p.Page != tt.page || p.PageCount != tt.pages || p.Total != tt.cards {
+				t.Errorf("page %d of %d, total %d, want %d of %d, total %d", p.Page, p.PageCount, p.Total, tt.page, tt.pages, tt.cards)
+			}
+			if p.ShowAll != tt.showAll || p.Limit != tt.limit {
+				t.Errorf("ShowAll = %v, Limit = %q, want %v, %q", p.ShowAll, p.Limit, tt.showAll, tt.limit)
+			}
+			if p.PrevURL != tt.prev {
+				t.Errorf("PrevURL = %q, want %q", p.PrevURL, tt.prev)
+			}
+			if p.NextURL != tt.next {
+				t.Errorf("NextURL = %q, want %q", p.NextURL, tt.next)
+			}
+			if p.ToggleURL != tt.toggle {
+				t.Errorf("ToggleURL = %q, want %q", p.ToggleURL, tt.toggle)
+			}
+		})
+	}
+}
+
+func TestPaginateCardsPageSizeEnv(t *testing.T) {
+	t.Setenv("BASE_PATH", "")
+	r := httptest.NewRequest(http.MethodGet, "/artists", nil)
+
+	// A configured size over the cap is capped too
+	t.Setenv("GROUPIE_PAGE_SIZE", "1000")
+	if cards, p := paginateCards(r, testCards(250)); len(cards) != maxGroupiePageSize || p.PageCount != 3 {
+		t.Fatalf("got %d cards on %d pages, want %d on 3", len(cards), p.PageCount, maxGroupiePageSize)
+	}
+
+	t.Setenv("GROUPIE_PAGE_SIZE", "nope")
+	if cards, _ := paginateCards(r, testCards(250)); len(cards) != defaultGroupiePageSize {
+		t.Fatalf("got %d cards, want the default %d", len(cards), defaultGroupiePageSize)
+	}
+}
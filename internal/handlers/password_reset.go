@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"palasgroupietracker/internal/store"
+)
+
+// passwordResetTTL is how long a reset link works
+const passwordResetTTL = time.Hour
+
+// passwordResetSentNotice is shown whether or not the email has an account,
+// so the form can't be used to find out who is registered
+const passwordResetSentNotice = "If an account exists for that email, we sent a link to reset its password. It works for one hour."
+
+// Reset requests are limited per client IP, each one can write to the database and send a link
+var forgotPasswordLimiter = newWindowLimiter(5, time.Hour)
+
+// PasswordResetPageData powers the forgot and reset password pages
+type PasswordResetPageData struct {
+	LayoutData
+
+	Email  string
+	Error  string
+	Notice string
+	// Token is the reset token from the link, carried by the reset form
+	Token string
+	// TokenValid is false when the link is unknown, used or expired, the form is hidden then
+	TokenValid bool
+}
+
+// ForgotPasswordHandler asks for an email and sends a password reset link to it
+func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	user, authed := getCurrentUser(w, r)
+	data := PasswordResetPageData{
+		LayoutData: newLayoutData(w, r, "Forgot password", user, authed),
+	}
+
+	if r.Method != http.MethodPost {
+		renderPasswordResetTemplate(w, r, http.StatusOK, data, "web/templates/forgot_password.gohtml")
+		return
+	}
+	if !requireCSRF(w, r) {
+		return
+	}
+	if appStore == nil {
+		data.Error = "Database is not configured."
+		renderPasswordResetTemplate(w, r, http.StatusServiceUnavailable, data, "web/templates/forgot_password.gohtml")
+		return
+	}
+
+	data.Email = strings.TrimSpace(r.FormValue("email"))
+	if data.Email == "" {
+		data.Error = "Email is required."
+		renderPasswordResetTemplate(w, r, http.StatusOK, data, "web/templates/forgot_password.gohtml")
+		return
+	}
+	if !forgotPasswordLimiter.allow("ip:"+ClientIP(r), time.Now()) {
+		data.Error = "Too many reset requests, try again later."
+		renderPasswordResetTemplate(w, r, http.StatusTooManyRequests, data, "web/templates/forgot_password.gohtml")
+		return
+	}
+
+	if err := sendPasswordReset(r, data.Email); err != nil {
+		renderError(w, r, http.StatusInternalServerError, "could not start the password reset")
+		return
+	}
+	data.Notice = passwordResetSentNotice
+	renderPasswordResetTemplate(w, r, http.StatusOK, data, "web/templates/forgot_password.gohtml")
+}
+
+// sendPasswordReset creates a reset token for email and delivers its link
+// An unknown email is not an error, the caller answers the same either way
+func sendPasswordReset(r *http.Request, email string) error {
+	user, err := appStore.GetUserByEmail(r.Context(), email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	token, tokenHash, err := newSessionToken()
+	if err != nil {
+		return err
+	}
+	if err := appStore.CreatePasswordReset(r.Context(), user.ID, tokenHash, time.Now().Add(passwordResetTTL)); err != nil {
+		return err
+	}
+
+	// No mail transport is configured in this app, the link goes to the server log
+	log.Println("password reset link for", user.Email+":", passwordResetLink(r, token))
+	return nil
+}
+
+// passwordResetLink is the URL of the reset page for token, absolute under `PUBLIC_URL`
+// The request's Host and forwarded headers are client input, a link built from them could send
+// the token to another site. Without `PUBLIC_URL` the link is site-relative
+func passwordResetLink(r *http.Request, token string) string {
+	p := "/reset-password?token=" + url.QueryEscape(token)
+	if base, ok := publicBaseURL(); ok {
+		return base + p
+	}
+	return withBasePath(r, p)
+}
+
+// ResetPasswordHandler sets a new password from a reset link
+// A successful reset ends every session of the account and logs the visitor in
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	// The token is in the URL, keep it out of Referer headers sent by the page
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	user, authed := getCurrentUser(w, r)
+	data := PasswordResetPageData{
+		LayoutData: newLayoutData(w, r, "Reset password", user, authed),
+		Token:      strings.TrimSpace(r.FormValue("token")),
+	}
+	// The page URL carries the token, it must not come back through `next` links
+	data.CurrentURL = withBasePath(r, "/reset-password")
+
+	if appStore == nil {
+		data.Error = "Database is not configured."
+		renderPasswordResetTemplate(w, r, http.StatusServiceUnavailable, data, "web/templates/reset_password.gohtml")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		if data.Token != "" {
+			_, err := appStore.GetPasswordReset(r.Context(), hashToken(data.Token), time.Now())
+			if err != nil && !errors.Is(err, store.ErrResetTokenInvalid) {
+				renderError(w, r, http.StatusInternalServerError, "could not check the reset link")
+				return
+			}
+			data.TokenValid = err == nil
+		}
+		renderPasswordResetTemplate(w, r, http.StatusOK, data, "web/templates/reset_password.gohtml")
+		return
+	}
+	if !requireCSRF(w, r) {
+		return
+	}
+
+	// Same password rules as register, the token is only checked once they pass
+	password := r.FormValue("password")
+	data.TokenValid = data.Token != ""
+	if len(password) < 8 {
+		data.Error = "Password must be at least 8 characters."
+	} else if r.FormValue("confirm_password") != password {
+		data.Error = "Passwords do not match."
+	}
+	if !data.TokenValid || data.Error != "" {
+		renderPasswordResetTemplate(w, r, http.StatusOK, data, "web/templates/reset_password.gohtml")
+		return
+	}
+
+	hashBytes, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, "could not reset the password")
+		return
+	}
+
+	// The token, the password and the sessions change in one transaction
+	userID, err := appStore.ResetPassword(r.Context(), hashToken(data.Token), string(hashBytes), time.Now())
+	if err != nil {
+		if errors.Is(err, store.ErrResetTokenInvalid) {
+			data.TokenValid = false
+			renderPasswordResetTemplate(w, r, http.StatusOK, data, "web/templates/reset_password.gohtml")
+			return
+		}
+		renderError(w, r, http.StatusInternalServerError, "could not reset the password")
+		return
+	}
+	if resetUser, err := appStore.GetUserByID(r.Context(), userID); err == nil {
+		loginEmailLimiter.reset("email:" + strings.ToLower(resetUser.Email))
+	}
+
+	if err := createSession(w, r, userID, true); err != nil {
+		// The password is changed, the visitor can still log in with it
+		http.Redirect(w, r, withBasePath(r, "/login"), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, withBasePath(r, "/"), http.StatusSeeOther)
+}
+
+func renderPasswordResetTemplate(w http.ResponseWriter, r *http.Request, status int, data PasswordResetPageData, pageTemplate string) {
+	tmpl, err := templateWithLayout(pageTemplate)
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, "template error")
+		return
+	}
+
+	w.WriteHeader(status)
+	if err := tmpl.ExecuteTemplate(w, "layout", data); err != nil {
+		renderError(w, r, http.StatusInternalServerError, "render error")
+		return
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	quickSearchLimit = 8
+	// quickSearchTimeout bounds how long the palette waits on a provider,
+	// a slower search still finishes in the background and lands in the cache
+	quickSearchTimeout = 3 * time.Second
+	quickSearchTTL     = 5 * time.Minute
+	// quickSearchMaxEntries caps the cache, it is simply emptied when full
+	quickSearchMaxEntries = 512
+)
+
+// QuickSearchResult is one navigable target for the quick-jump palette
+type QuickSearchResult struct {
+	Source   string `json:"source"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Meta     string `json:"meta"`
+	ImageURL string `json:"image_url"`
+	URL      string `json:"url"`
+}
+
+type quickSearchEntry struct {
+	results []QuickSearchResult
+	at      time.Time
+}
+
+var (
+	quickSearchMu    sync.Mutex
+	quickSearchCache = map[string]quickSearchEntry{}
+)
+
+// QuickSearchHandler returns the top artist matches of the current source with their detail URLs
+// Unlike `/artists/suggest`, it works for every source and answers with pages to open, not input values
+func QuickSearchHandler(w http.ResponseWriter, r *http.Request) {
+	source := getSource(r)
+	raw := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len([]rune(raw)) < 2 {
+		writeJSON(w, http.StatusOK, []QuickSearchResult{})
+		return
+	}
+
+	basePath := getBasePath(r)
+	locale := resolveLocale(r)
+	// Meta counts are formatted per locale, so each one gets its own entry
+	key := source + "\x00" + basePath + "\x00" + locale.Tag + "\x00" + strings.ToLower(raw)
+	if results, ok := cachedQuickSearch(key); ok {
+		writeJSON(w, http.StatusOK, results)
+		return
+	}
+
+	type outcome struct {
+		results []QuickSearchResult
+		err     error
+	}
+	done := make(chan outcome, 1)
+	// Detached from the request so a search that outlives the timeout still fills the cache
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		cards, err := searchSourceCards(ctx, sourceFor(source), basePath, raw, quickSearchLimit)
+		if err != nil {
+			done <- outcome{err: err}
+			return
+		}
+		localizeCards(cards, locale)
+		results := quickSearchResults(cards)
+		storeQuickSearch(key, results)
+		done <- outcome{results: results}
+	}()
+
+	timer := time.NewTimer(quickSearchTimeout)
+	defer timer.Stop()
+	select {
+	case o := <-done:
+		if o.err != nil {
+			renderJSONError(w, r, http.StatusBadGateway, "search failed")
+			return
+		}
+		writeJSON(w, http.StatusOK, o.results)
+	case <-timer.C:
+		renderJSONError(w, r, http.StatusGatewayTimeout, "search timed out")
+	case <-r.Context().Done():
+	}
+}
+
+func quickSearchResults(cards []ArtistCard) []QuickSearchResult {
+	out := make([]QuickSearchResult, 0, len(cards))
+	for _, c := range cards {
+		out = append(out, QuickSearchResult{
+			Source:   c.Source,
+			ID:       c.ArtistID,
+			Name:     c.Name,
+			Meta:     c.Meta,
+			ImageURL: c.ImageURL,
+			URL:      c.LinkURL,
+		})
+	}
+	return out
+}
+
+func cachedQuickSearch(key string) ([]QuickSearchResult, bool) {
+	quickSearchMu.Lock()
+	defer quickSearchMu.Unlock()
+
+	e, ok := quickSearchCache[key]
+	if !ok || time.Since(e.at) >= quickSearchTTL {
+		return nil, false
+	}
+	return e.results, true
+}
+
+func storeQuickSearch(key string, results []QuickSearchResult) {
+	quickSearchMu.Lock()
+	defer quickSearchMu.Unlock()
+
+	if len(quickSearchCache) >= quickSearchMaxEntries {
+		clear(quickSearchCache)
+	}
+	quickSearchCache[key] = quickSearchEntry{results: results, at: time.Now()}
+}
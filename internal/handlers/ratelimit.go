@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// windowLimiter allows up to limit hits per key in each fixed time window
+// Keys are client IPs or user IDs, expired windows are dropped lazily so memory stays bounded
+type windowLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	hits      map[string]limiterWindow
+	lastPrune time.Time
+}
+
+type limiterWindow struct {
+	start time.Time
+	count int
+}
+
+func newWindowLimiter(limit int, window time.Duration) *windowLimiter {
+	return &windowLimiter{
+		limit:  limit,
+		window: window,
+		hits:   make(map[string]limiterWindow),
+	}
+}
+
+// allow records a hit for key and reports whether it is within the limit
+func (l *windowLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPrune) >= l.window {
+		for k, w := range l.hits {
+			if now.Sub(w.start) >= l.window {
+				delete(l.hits, k)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	w, ok := l.hits[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = limiterWindow{start: now}
+	}
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+	l.hits[key] = w
+	return true
+}
+
+// failureLimiter locks a key out after limit failures inside window
+// Each failure past the limit doubles the lockout, up to maxLockout, and a key is forgotten
+// once it has been quiet for a window after its last failure or lockout
+type failureLimiter struct {
+	limit      int
+	window     time.Duration
+	maxLockout time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]failureState
+	lastPrune time.Time
+}
+
+type failureState struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+func newFailureLimiter(limit int, window, maxLockout time.Duration) *failureLimiter {
+	return &failureLimiter{
+		limit:      limit,
+		window:     window,
+		maxLockout: maxLockout,
+		keys:       make(map[string]failureState),
+	}
+}
+
+// expired reports whether s no longer counts against its key at now
+// A lockout keeps the count for a window after it ends, so failing again right away doubles it
+func (l *failureLimiter) expired(s failureState, now time.Time) bool {
+	quietFrom := s.last
+	if s.lockedUntil.After(quietFrom) {
+		quietFrom = s.lockedUntil
+	}
+	return !now.Before(quietFrom.Add(l.window))
+}
+
+// locked reports how long key stays locked out at now, 0 when it may try
+func (l *failureLimiter) locked(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s, ok := l.keys[key]
+	if !ok || !now.Before(s.lockedUntil) {
+		return 0
+	}
+	return s.lockedUntil.Sub(now)
+}
+
+// fail records a failure for key at now
+func (l *failureLimiter) fail(key string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPrune) >= l.window {
+		for k, s := range l.keys {
+			if l.expired(s, now) {
+				delete(l.keys, k)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	s, ok := l.keys[key]
+	if !ok || l.expired(s, now) {
+		s = failureState{}
+	}
+	s.count++
+	s.last = now
+	if over := s.count - l.limit; over >= 0 {
+		lockout := l.maxLockout
+		// Past ~30 doublings the shift overflows, the cap applies long before that
+		if over < 30 {
+			lockout = min(l.window<<over, l.maxLockout)
+		}
+		s.lockedUntil = now.Add(lockout)
+	}
+	l.keys[key] = s
+}
+
+// reset forgets key, e.g. after a successful login
+func (l *failureLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.keys, key)
+}
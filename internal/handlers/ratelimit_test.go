@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowLimiter(t *testing.T) {
+	l := newWindowLimiter(2, time.Minute)
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, want := range []bool{true, true, false} {
+		if got := l.allow("a", t0); got != want {
+			t.Fatalf("hit %d allowed = %v, want %v", i+1, got, want)
+		}
+	}
+	if !l.allow("b", t0) {
+		t.Fatal("another key shares the limit")
+	}
+	if !l.allow("a", t0.Add(time.Minute)) {
+		t.Fatal("the limit did not reset with the next window")
+	}
+}
+
+func TestFailureLimiterLockout(t *testing.T) {
+	l := newFailureLimiter(5, 15*time.Minute, 24*time.Hour)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 4; i++ {
+		l.fail("k", now)
+	}
+	if wait := l.locked("k", now); wait != 0 {
+		t.Fatalf("locked after 4 failures for %v", wait)
+	}
+
+	// Each failure past the limit, even right after a lockout ends, doubles the next one
+	for _, want := range []time.Duration{
+		15 * time.Minute, 30 * time.Minute, time.Hour, 2 * time.Hour,
+		4 * time.Hour, 8 * time.Hour, 16 * time.Hour, 24 * time.Hour, 24 * time.Hour,
+	} {
+		l.fail("k", now)
+		if wait := l.locked("k", now); wait != want {
+			t.Fatalf("lockout = %v, want %v", wait, want)
+		}
+		now = now.Add(want)
+		if wait := l.locked("k", now); wait != 0 {
+			t.Fatalf("still locked for %v once the lockout ended", wait)
+		}
+	}
+
+	if wait := l.locked("other", now); wait != 0 {
+		t.Fatalf("another key is locked for %v", wait)
+	}
+}
+
+func TestFailureLimiterReset(t *testing.T) {
+	l := newFailureLimiter(5, 15*time.Minute, 24*time.Hour)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		l.fail("k", now)
+	}
+	if l.locked("k", now) == 0 {
+		t.Fatal("not locked after 5 failures")
+	}
+
+	l.reset("k")
+	if wait := l.locked("k", now); wait != 0 {
+		t.Fatalf("locked for %v after reset", wait)
+	}
+	// The count starts over too
+	for i := 0; i < 4; i++ {
+		l.fail("k", now)
+	}
+	if wait := l.locked("k", now); wait != 0 {
+		t.Fatalf("4 failures after reset locked for %v", wait)
+	}
+}
+
+func TestFailureLimiterForgets(t *testing.T) {
+	l := newFailureLimiter(5, 15*time.Minute, 24*time.Hour)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Failures spread wider than the window never add up
+	for i := 0; i < 10; i++ {
+		l.fail("k", now)
+		if wait := l.locked("k", now); wait != 0 {
+			t.Fatalf("failure %d locked for %v", i+1, wait)
+		}
+		now = now.Add(15 * time.Minute)
+	}
+
+	// A window of quiet after a lockout ends starts the count over
+	for i := 0; i < 5; i++ {
+		l.fail("k", now)
+	}
+	now = now.Add(15*time.Minute + 15*time.Minute)
+	l.fail("k", now)
+	if wait := l.locked("k", now); wait != 0 {
+		t.Fatalf("first failure after a quiet window locked for %v", wait)
+	}
+}
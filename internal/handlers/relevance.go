@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"palasgroupietracker/internal/geo"
+)
+
+// Default relevance weights, the query match counts most, popularity breaks near-ties and the
+// provider's own order is a small prior
+const (
+	defaultRelevanceMatchWeight      = 0.7
+	defaultRelevancePopularityWeight = 0.2
+	defaultRelevanceRankWeight       = 0.1
+)
+
+// relevanceWeights scales the three 0..1 parts of a relevance score
+type relevanceWeights struct {
+	match      float64
+	popularity float64
+	rank       float64
+}
+
+// currentRelevanceWeights reads `RELEVANCE_MATCH_WEIGHT`, `RELEVANCE_POPULARITY_WEIGHT` and
+// `RELEVANCE_RANK_WEIGHT`, invalid or negative values keep the default
+func currentRelevanceWeights() relevanceWeights {
+	return relevanceWeights{
+		match:      envWeight("RELEVANCE_MATCH_WEIGHT", defaultRelevanceMatchWeight),
+		popularity: envWeight("RELEVANCE_POPULARITY_WEIGHT", defaultRelevancePopularityWeight),
+		rank:       envWeight("RELEVANCE_RANK_WEIGHT", defaultRelevanceRankWeight),
+	}
+}
+
+func envWeight(name string, def float64) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(name)), 64)
+	if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return def
+	}
+	return v
+}
+
+// sortByRelevance orders cards by how well their name matches query, how popular they are
+// (popularity returns a follower or fan count, 0 when unknown) and where the provider ranked them
+// Equal scores keep the provider's order
+func sortByRelevance(cards []ArtistCard, query string, popularity func(ArtistCard) int, w relevanceWeights) {
+	if len(cards) < 2 {
+		return
+	}
+
+	// Popularity is log-scaled against the most popular result, so one superstar
+	// doesn't flatten everyone else to zero
+	maxPop := 0.0
+	pops := make([]float64, len(cards))
+	for i, c := range cards {
+		if p := popularity(c); p > 0 {
+			pops[i] = math.Log1p(float64(p))
+			maxPop = max(maxPop, pops[i])
+		}
+	}
+
+	q := normalizeForMatch(query)
+	scores := make([]float64, len(cards))
+	for i, c := range cards {
+		s := w.match * nameMatchScore(q, normalizeForMatch(c.Name))
+		if maxPop > 0 {
+			s += w.popularity * pops[i] / maxPop
+		}
+		s += w.rank * (1 - float64(i)/float64(len(cards)))
+		scores[i] = s
+	}
+
+	idx := make([]int, len(cards))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return scores[idx[a]] > scores[idx[b]] })
+
+	sorted := make([]ArtistCard, len(cards))
+	for i, j := range idx {
+		sorted[i] = cards[j]
+	}
+	copy(cards, sorted)
+}
+
+// nameMatchScore rates a normalized name against a normalized query from 0 to 1:
+// exact, prefix, word prefix, substring, then edit-distance similarity for typos
+func nameMatchScore(q, name string) float64 {
+	switch {
+	case q == "" || name == "":
+		return 0
+	case name == q:
+		return 1
+	case strings.HasPrefix(name, q):
+		return 0.6
+	case strings.Contains(" "+name, " "+q):
+		return 0.5
+	case strings.Contains(name, q):
+		return 0.4
+	}
+
+	d := geo.Levenshtein(q, name)
+	sim := 1 - float64(d)/float64(max(utf8.RuneCountInString(q), utf8.RuneCountInString(name)))
+	// Only close spellings count, "metalica" vs "metallica" is a typo, "muse" vs "blur" isn't
+	if sim < 0.6 {
+		return 0
+	}
+	return 0.4 * sim
+}
+
+// Popularity signals per provider, Apple search results carry none
+func spotifyPopularity(c ArtistCard) int {
+	if c.Followers > 0 {
+		return c.Followers
+	}
+	return c.MonthlyListeners
+}
+
+func deezerPopularity(c ArtistCard) int { return c.Fans }
+
+func noPopularity(ArtistCard) int { return 0 }
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+var defaultTestWeights = relevanceWeights{
+	match:      defaultRelevanceMatchWeight,
+	popularity: defaultRelevancePopularityWeight,
+	rank:       defaultRelevanceRankWeight,
+}
+
+func relevanceNames(cards []ArtistCard) []string {
+	names := make([]string, len(cards))
+	for i, c := range cards {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func TestNameMatchScore(t *testing.T) {
+	tests := []struct {
+		q, name string
+		want    float64
+	}{
+		{"muse", "muse", 1},
+		{"muse", "museum", 0.6},
+		{"doors", "the doors", 0.5},
+		{"oor", "the doors", 0.4},
+		{"", "muse", 0},
+		{"muse", "", 0},
+		{"muse", "blur", 0},
+	}
+	for _, tt := range tests {
+		if got := nameMatchScore(tt.q, tt.name); got != tt.want {
+			t.Errorf("nameMatchScore(%q, %q) = %v, want %v", tt.q, tt.name, got, tt.want)
+		}
+	}
+
+	// A typo scores below any substring match but above nothing
+	typo := nameMatchScore("metalica", "metallica")
+	if typo <= 0 || typo >= 0.4 {
+		t.Errorf("typo score = %v, want between 0 and 0.4", typo)
+	}
+}
+
+func TestSortByRelevance(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		cards []ArtistCard
+		want  []string
+	}{
+		{
+			"exact match beats the provider's first pick",
+			"muse",
+			[]ArtistCard{{Name: "Museum Pieces", Fans: 900}, {Name: "Amused", Fans: 50}, {Name: "Muse", Fans: 100}},
+			[]string{"Muse", "Museum Pieces", "Amused"},
+		},
+		{
+			"popularity breaks a tie on match quality",
+			"queen",
+			[]ArtistCard{{Name: "Queen Kwong", Fans: 10}, {Name: "Queens of the Stone Age", Fans: 5_000_000}, {Name: "Queensryche", Fans: 20_000}},
+			[]string{"Queens of the Stone Age", "Queensryche", "Queen Kwong"},
+		},
+		{
+			"typos rank above unrelated names",
+			"metalica",
+			[]ArtistCard{{Name: "Megadeth", Fans: 2_000_000}, {Name: "Metallica", Fans: 9_000_000}, {Name: "Slayer", Fans: 1_000_000}},
+			[]string{"Metallica", "Megadeth", "Slayer"},
+		},
+		{
+			"no signal keeps the provider order",
+			"",
+			[]ArtistCard{{Name: "B"}, {Name: "A"}, {Name: "C"}},
+			[]string{"B", "A", "C"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortByRelevance(tt.cards, tt.query, deezerPopularity, defaultTestWeights)
+			if got := relevanceNames(tt.cards); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortByRelevanceWeights(t *testing.T) {
+	cards := func() []ArtistCard {
+		return []ArtistCard{{Name: "Blur", Followers: 10}, {Name: "Blurry Face", Followers: 8_000_000}}
+	}
+
+	byMatch := cards()
+	sortByRelevance(byMatch, "blur", spotifyPopularity, defaultTestWeights)
+	if got := relevanceNames(byMatch); got[0] != "Blur" {
+		t.Fatalf("default weights order = %v, want the exact match first", got)
+	}
+
+	// Popularity alone puts the bigger artist first
+	byPopularity := cards()
+	sortByRelevance(byPopularity, "blur", spotifyPopularity, relevanceWeights{popularity: 1})
+	if got := relevanceNames(byPopularity); got[0] != "Blurry Face" {
+		t.Fatalf("popularity-only order = %v, want Blurry Face first", got)
+	}
+}
+
+func TestCurrentRelevanceWeights(t *testing.T) {
+	t.Setenv("RELEVANCE_MATCH_WEIGHT", "0.5")
+	t.Setenv("RELEVANCE_POPULARITY_WEIGHT", "-1")
+	t.Setenv("RELEVANCE_RANK_WEIGHT", "NaN")
+
+	want := relevanceWeights{match: 0.5, popularity: defaultRelevancePopularityWeight, rank: defaultRelevanceRankWeight}
+	if got := currentRelevanceWeights(); got != want {
+		t.Fatalf("weights = %+v, want %+v", got, want)
+	}
+}
+
+func TestSpotifyPopularity(t *testing.T) {
+	if got := spotifyPopularity(ArtistCard{Followers: 5, MonthlyListeners: 9}); got != 5 {
+		t.Errorf("with followers = %d, want 5", got)
+	}
+	if got := spotifyPopularity(ArtistCard{MonthlyListeners: 9}); got != 9 {
+		t.Errorf("listeners only = %d, want 9", got)
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"palasgroupietracker/internal/store"
+)
+
+// maxReportNoteRunes caps the free-text part of a report
+const maxReportNoteRunes = 500
+
+// reportIssueTypes are the problems the detail page form offers, in display order
+var reportIssueTypes = []ReportIssueType{
+	{Value: "missing_image", Label: "Missing or wrong image"},
+	{Value: "wrong_wiki", Label: "Wrong Wikipedia summary"},
+	{Value: "unresolved_location", Label: "Concert location missing from the map"},
+	{Value: "wrong_info", Label: "Wrong artist details"},
+	{Value: "other", Label: "Something else"},
+}
+
+// ReportIssueType is one option of the report form
+type ReportIssueType struct {
+	Value string
+	Label string
+}
+
+// Anonymous reports are limited per client IP, logged-in ones per account
+var (
+	anonReportLimiter = newWindowLimiter(5, time.Hour)
+	userReportLimiter = newWindowLimiter(20, time.Hour)
+)
+
+// reportPayload answers JSON clients with the stored report ID
+type reportPayload struct {
+	ID int64 `json:"id"`
+}
+
+// ReportHandler records a user report about broken artist data for maintainers to review
+// Plain form posts are redirected back with `reported=1`, JSON clients get the new ID
+func ReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		renderError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !requireCSRF(w, r) {
+		return
+	}
+
+	rep, msg := parseReport(r)
+	if msg != "" {
+		renderError(w, r, http.StatusBadRequest, msg)
+		return
+	}
+
+	if appStore == nil {
+		renderError(w, r, http.StatusServiceUnavailable, "database not configured")
+		return
+	}
+
+	rep.ClientIP = ClientIP(r)
+	user, authed := getCurrentUser(w, r)
+	limiter, key := anonReportLimiter, "ip:"+rep.ClientIP
+	if authed {
+		rep.UserID = user.ID
+		limiter, key = userReportLimiter, "user:"+strconv.FormatInt(user.ID, 10)
+	}
+	if !limiter.allow(key, time.Now()) {
+		renderError(w, r, http.StatusTooManyRequests, "too many reports, try again later")
+		return
+	}
+
+	id, err := appStore.CreateReport(r.Context(), rep)
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, "failed to save report")
+		return
+	}
+
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusCreated, reportPayload{ID: id})
+		return
+	}
+	http.Redirect(w, r, withQueryFlag(resolveNextURL(r.FormValue("redirect"), r), "reported"), http.StatusSeeOther)
+}
+
+// parseReport validates the form fields, msg is a user-facing reason when they are invalid
+func parseReport(r *http.Request) (rep store.Report, msg string) {
+	source := strings.ToLower(strings.TrimSpace(r.FormValue("source")))
+	if _, ok := sources[source]; !ok {
+		return rep, "unknown source"
+	}
+
+	id, ok := sourceFor(source).NormalizeID(r.FormValue("artist_id"))
+	if !ok {
+		return rep, "invalid artist id"
+	}
+
+	issue := strings.TrimSpace(r.FormValue("issue_type"))
+	if !isReportIssueType(issue) {
+		return rep, "unknown issue type"
+	}
+
+	note := strings.TrimSpace(r.FormValue("note"))
+	if !utf8.ValidString(note) {
+		return rep, "note is not valid text"
+	}
+	if utf8.RuneCountInString(note) > maxReportNoteRunes {
+		return rep, "note is too long (max " + strconv.Itoa(maxReportNoteRunes) + " characters)"
+	}
+
+	return store.Report{Source: source, ArtistID: id, IssueType: issue, Note: note}, ""
+}
+
+func isReportIssueType(v string) bool {
+	for _, t := range reportIssueTypes {
+		if t.Value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// withQueryFlag adds `name=1` to a local URL, replacing an existing value
+func withQueryFlag(target, name string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	q := u.Query()
+	q.Set(name, "1")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"palasgroupietracker/internal/api"
+	"palasgroupietracker/internal/geo"
+)
+
+// maxSharedConcertsPerDay keeps the "also playing" list short on busy festival days
+const maxSharedConcertsPerDay = 8
+
+// SharedConcert is another Groupie artist playing on one of the current artist's concert days
+type SharedConcert struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Location     string `json:"location"`
+	URL          string `json:"url"`
+	SameLocation bool   `json:"sameLocation"`
+}
+
+// buildSharedConcerts maps each of the artist's concert days (YYYY-MM-DD) to the other artists
+// playing that day, same-location concerts first
+// It is best-effort, the page renders without the list if the index can't be built
+// URLs are site-relative, the detail handler adds the base path
+func buildSharedConcerts(ctx context.Context, relation *api.Relation) map[string][]SharedConcert {
+	shared := make(map[string][]SharedConcert)
+
+	byDate, err := api.FetchConcertsByDateCtx(ctx)
+	if err != nil {
+		return shared
+	}
+	artists, err := fetchGroupieArtists(ctx)
+	if err != nil {
+		return shared
+	}
+	names := make(map[int]string, len(artists))
+	for _, a := range artists {
+		names[a.ID] = a.Name
+	}
+
+	// Own locations per day, to flag concerts at the same place
+	ownLocations := make(map[string]map[string]bool)
+	for location, dates := range relation.DatesLocations {
+		for _, d := range dates {
+			day, ok := api.ConcertDay(d)
+			if !ok {
+				continue
+			}
+			if ownLocations[day] == nil {
+				ownLocations[day] = make(map[string]bool)
+			}
+			ownLocations[day][location] = true
+		}
+	}
+
+	for day, own := range ownLocations {
+		var list []SharedConcert
+		for _, c := range byDate[day] {
+			name := names[c.ArtistID]
+			if c.ArtistID == relation.ID || name == "" {
+				continue
+			}
+			list = append(list, SharedConcert{
+				ID:           c.ArtistID,
+				Name:         name,
+				Location:     geo.HumanizeLocationKey(c.Location),
+				URL:          "/artists/" + strconv.Itoa(c.ArtistID) + "?source=groupie",
+				SameLocation: own[c.Location],
+			})
+		}
+		if len(list) == 0 {
+			continue
+		}
+
+		sort.SliceStable(list, func(i, j int) bool { // same location first, then by artist name
+			if list[i].SameLocation != list[j].SameLocation {
+				return list[i].SameLocation
+			}
+			ni := strings.ToLower(list[i].Name)
+			nj := strings.ToLower(list[j].Name)
+			if ni != nj {
+				return ni < nj
+			}
+			return list[i].Location < list[j].Location
+		})
+		if len(list) > maxSharedConcertsPerDay {
+			list = list[:maxSharedConcertsPerDay]
+		}
+		shared[day] = list
+	}
+
+	return shared
+}
@@ -1,22 +1,108 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
+// defaultSource is used when `?source=` is missing or unknown
+const defaultSource = "groupie"
+
+// Source is a music provider the UI can browse
+// Each provider lives in its own `source_<name>.go` file and registers itself from init
+// Sources only load data, the handlers pick what to show and render it
+// Cards come back with site-relative links, callers prefix them with rebaseCards
+type Source interface {
+	// Name is the `?source=` value, also stored with favorites
+	Name() string
+	// Search returns the artists matching query, best match first
+	// An empty query returns the provider's featured artists, for the home marquee and empty searches
+	Search(ctx context.Context, query string) ([]ArtistCard, error)
+	// GetArtist loads one artist as a card, with errArtistNotFound when the provider doesn't know it
+	GetArtist(ctx context.Context, id string) (ArtistCard, error)
+	// GetDetail loads what the artist detail page shows from the provider itself
+	// It fails with errInvalidArtistID or errArtistNotFound for IDs it can't load
+	GetDetail(ctx context.Context, id string, opts DetailOptions) (*ArtistDetail, error)
+	// DetailMaxima returns the largest track and album counts the provider accepts
+	DetailMaxima() (int, int)
+	// NormalizeID returns the canonical form of an artist ID, ok is false when it can't be one
+	NormalizeID(id string) (string, bool)
+}
+
+// DetailOptions are the view choices of a detail request that change what GetDetail loads
+type DetailOptions struct {
+	// Tracks and Albums are the counts to fetch, see detailCounts
+	Tracks int
+	Albums int
+	// Sheet is set for the printable sheet, which can skip enrichment it doesn't show
+	Sheet bool
+	// Locale formats the concert date labels
+	Locale *Locale
+}
+
+// albumPager is implemented by sources whose detail pages can load more albums
+type albumPager interface {
+	// AlbumCards returns the card template to render and a page of albums for it
+	AlbumCards(idSegment string, offset, limit int) (string, []any, error)
+}
+
+var (
+	// errInvalidArtistID is returned when an ID doesn't match the source's format
+	errInvalidArtistID = errors.New("invalid artist id")
+	// errArtistNotFound is returned when the provider has no artist with a valid-looking ID
+	errArtistNotFound = errors.New("artist not found")
+)
+
+// artistLookupError marks a provider's "no such artist" answers as errArtistNotFound
+func artistLookupError(err error) error {
+	if isNotFoundError(err) {
+		return fmt.Errorf("%w: %w", errArtistNotFound, err)
+	}
+	return err
+}
+
+var sources = map[string]Source{}
+
+// registerSource adds a provider to the registry, names must be unique
+func registerSource(s Source) {
+	name := s.Name()
+	if _, ok := sources[name]; ok {
+		panic("handlers: source registered twice: " + name)
+	}
+	sources[name] = s
+}
+
+// sourceFor returns the provider for a normalized source name
+func sourceFor(name string) Source {
+	if s, ok := sources[name]; ok {
+		return s
+	}
+	return sources[defaultSource]
+}
+
 // normalizeSource validates a requested source and falls back to groupie
 func normalizeSource(source string) string {
 	s := strings.TrimSpace(strings.ToLower(source))
-	switch s {
-	case "spotify", "deezer", "apple", "groupie":
+	if _, ok := sources[s]; ok {
 		return s
-	default:
-		return "groupie"
 	}
+	return defaultSource
 }
 
 // getSource reads the `source` query parameter and returns a safe known value
 func getSource(r *http.Request) string {
 	return normalizeSource(r.URL.Query().Get("source"))
 }
+
+// normalizeNumericID accepts positive integer IDs (Groupie, Deezer, Apple)
+func normalizeNumericID(id string) (string, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(id))
+	if err != nil || n <= 0 {
+		return "", false
+	}
+	return strconv.Itoa(n), true
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+
+	"palasgroupietracker/internal/api"
+)
+
+// appleSource browses artists through the iTunes Search API
+type appleSource struct{}
+
+func init() {
+	registerSource(appleSource{})
+}
+
+func (appleSource) Name() string {
+	return "apple"
+}
+
+// Search skips the album artwork lookups to stay fast, cards use the placeholder image
+// Apple doesn't expose artist images directly, so the featured set reuses recent album artwork
+func (appleSource) Search(ctx context.Context, query string) ([]ArtistCard, error) {
+	if query == "" {
+		artists, err := api.SearchAppleArtistsWithArtwork("a", featuredArtistsListSize, artworkSizeFor("home"), "")
+		if err != nil {
+			return nil, err
+		}
+		out := make([]ArtistCard, 0, len(artists))
+		for _, a := range artists {
+			out = append(out, appleSearchCard(a.Artist, a.ArtworkURL))
+		}
+		return out, nil
+	}
+
+	artists, err := api.SearchAppleArtists(query)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]ArtistCard, 0, len(artists))
+	for _, a := range artists {
+		out = append(out, appleSearchCard(a, ""))
+	}
+
+	return out, nil
+}
+
+func appleSearchCard(a api.AppleArtist, artworkURL string) ArtistCard {
+	card := appleArtistCard("", a, artworkURL)
+	card.Meta = "Apple artist"
+	if card.Genre != "" {
+		card.Meta = card.Genre
+	}
+	return card
+}
+
+// GetArtist cards fill the favorites grid, so they ask for its artwork size
+func (appleSource) GetArtist(ctx context.Context, id string) (ArtistCard, error) {
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		return ArtistCard{}, errInvalidArtistID
+	}
+	artist, err := api.GetAppleArtist(intID)
+	if err != nil {
+		return ArtistCard{}, artistLookupError(err)
+	}
+	artwork, _ := api.GetAppleArtistArtwork(intID, artworkSizeFor("favorites"))
+	return appleSearchCard(*artist, artwork), nil
+}
+
+// GetDetail loads the artist with its albums, songs and Last.fm listeners
+func (appleSource) GetDetail(ctx context.Context, id string, opts DetailOptions) (*ArtistDetail, error) {
+	timing := timingFrom(ctx)
+
+	intID, err := strconv.Atoi(id)
+	if err != nil || intID <= 0 {
+		return nil, errInvalidArtistID
+	}
+
+	stop := timing.start("apple")
+	artist, err := api.GetAppleArtist(intID)
+	stop()
+	if err != nil {
+		return nil, artistLookupError(err)
+	}
+
+	stop = timing.start("lastfm")
+	monthly, err := api.FetchArtistMonthlyListeners(artist.ArtistName)
+	stop()
+	if err != nil {
+		monthly = 0
+	}
+
+	stop = timing.start("albums")
+	latestAlbums, err := api.GetAppleArtistAlbums(artist.ArtistID, 0, opts.Albums)
+	stop()
+	if err != nil {
+		latestAlbums = nil
+	}
+
+	stop = timing.start("tracks")
+	topTracks, err := api.GetAppleArtistSongs(artist.ArtistID, opts.Tracks)
+	stop()
+	if err != nil {
+		topTracks = nil
+	}
+
+	hero := ""
+	if len(latestAlbums) > 0 {
+		// Use the newest album cover as the hero image when possible
+		hero = upscaleAppleArtwork(latestAlbums[0].ArtworkURL100, artworkSizeFor("detail"))
+	}
+	if hero == "" && len(topTracks) > 0 {
+		// Fall back to a track artwork if we didn't get an album cover
+		hero = upscaleAppleArtwork(topTracks[0].ArtworkURL100, artworkSizeFor("detail"))
+	}
+	hero = httpsify(hero)
+
+	// Upgrade image URLs so covers don't get blocked as mixed content
+	topTracks = httpsifyAppleTracks(topTracks)
+	latestAlbums = httpsifyAppleAlbums(latestAlbums)
+
+	return &ArtistDetail{
+		name:       artist.ArtistName,
+		musicGroup: appleMusicGroupLD(artist, hero),
+
+		FavoriteID: strconv.Itoa(intID),
+
+		AppleArtist:           artist,
+		AppleGenre:            artist.PrimaryGenreName,
+		AppleMonthlyListeners: monthly,
+		AppleHeroImage:        hero,
+		AppleTopTracks:        topTracks,
+		AppleLatestAlbums:     latestAlbums,
+
+		TracksLimit: opts.Tracks,
+		AlbumsLimit: opts.Albums,
+
+		LyricsEnabled: api.LyricsEnabled(),
+	}, nil
+}
+
+func (appleSource) DetailMaxima() (int, int) {
+	return 50, 50
+}
+
+func (appleSource) NormalizeID(id string) (string, bool) {
+	return normalizeNumericID(id)
+}
+
+func (appleSource) AlbumCards(idSegment string, offset, limit int) (string, []any, error) {
+	id, err := strconv.Atoi(idSegment)
+	if err != nil || id <= 0 {
+		return "", nil, errInvalidArtistID
+	}
+	albums, err := api.GetAppleArtistAlbums(id, offset, limit)
+	if err != nil {
+		return "", nil, err
+	}
+	cards := make([]any, 0, len(albums))
+	for _, a := range httpsifyAppleAlbums(albums) {
+		cards = append(cards, a)
+	}
+	return "apple_album_card", cards, nil
+}
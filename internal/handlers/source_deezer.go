@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"palasgroupietracker/internal/api"
+)
+
+// deezerSource browses artists through the public Deezer API
+type deezerSource struct{}
+
+func init() {
+	registerSource(deezerSource{})
+}
+
+func (deezerSource) Name() string {
+	return "deezer"
+}
+
+// Search takes the Deezer search hits
+func (deezerSource) Search(ctx context.Context, query string) ([]ArtistCard, error) {
+	if query == "" {
+		// Use a broad query so the featured set always has results
+		query = "a"
+	}
+	artists, err := api.SearchDeezerArtists(query)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]ArtistCard, 0, len(artists))
+	for _, a := range artists {
+		card := deezerArtistCard("", a, artworkSizeFor("home"))
+		card.Meta = "Deezer artist"
+		if card.Fans > 0 {
+			card.setCountMeta("%s fans", card.Fans, true)
+		} else if card.Albums > 0 {
+			card.setCountMeta("%s albums", card.Albums, false)
+		}
+		out = append(out, card)
+	}
+
+	return out, nil
+}
+
+// GetArtist cards fill the favorites grid, so they ask for its artwork size
+func (deezerSource) GetArtist(ctx context.Context, id string) (ArtistCard, error) {
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		return ArtistCard{}, errInvalidArtistID
+	}
+	artist, err := api.GetDeezerArtist(intID)
+	if err != nil {
+		return ArtistCard{}, artistLookupError(err)
+	}
+	card := deezerArtistCard("", *artist, artworkSizeFor("favorites"))
+	card.Meta = "Deezer artist"
+	return card, nil
+}
+
+// GetDetail loads the artist with its top tracks, albums and Last.fm listeners
+func (deezerSource) GetDetail(ctx context.Context, id string, opts DetailOptions) (*ArtistDetail, error) {
+	timing := timingFrom(ctx)
+
+	intID, err := strconv.Atoi(id)
+	if err != nil || intID <= 0 {
+		return nil, errInvalidArtistID
+	}
+
+	stop := timing.start("deezer")
+	artist, err := api.GetDeezerArtist(intID)
+	stop()
+	if err != nil {
+		return nil, artistLookupError(err)
+	}
+	deezerArtist := httpsifyDeezerArtist(*artist)
+	artist = &deezerArtist
+
+	stop = timing.start("lastfm")
+	monthly, err := api.FetchArtistMonthlyListeners(artist.Name)
+	stop()
+	if err != nil {
+		monthly = 0
+	}
+
+	stop = timing.start("tracks")
+	topTracks, err := api.GetDeezerArtistTopTracks(artist.ID, opts.Tracks)
+	stop()
+	if err != nil {
+		// Track lists are optional for the rest of the page
+		topTracks = nil
+	}
+	// Deezer's /top order drifts from its own rank score, show the most popular first
+	sort.SliceStable(topTracks, func(i, j int) bool {
+		return topTracks[i].Rank > topTracks[j].Rank
+	})
+
+	stop = timing.start("albums")
+	fetchAlbums := api.GetDeezerArtistAlbums
+	if opts.Sheet {
+		// The printable sheet lists titles, types and dates only, the listing already has them
+		fetchAlbums = api.GetDeezerArtistAlbumsLite
+	}
+	latestAlbums, err := fetchAlbums(artist.ID, 0, opts.Albums)
+	stop()
+	if err != nil {
+		latestAlbums = nil
+	}
+
+	if len(latestAlbums) > 1 {
+		// Sort on the backend so template rendering stays simple
+		sort.SliceStable(latestAlbums, func(i, j int) bool { // newest first, then stable tie-breakers
+			di, okI := api.ParseDeezerReleaseDate(latestAlbums[i].ReleaseDate)
+			dj, okJ := api.ParseDeezerReleaseDate(latestAlbums[j].ReleaseDate)
+
+			if okI && okJ && !di.Equal(dj) {
+				return di.After(dj)
+			}
+			if okI != okJ {
+				return okI
+			}
+
+			ni := strings.ToLower(latestAlbums[i].Title)
+			nj := strings.ToLower(latestAlbums[j].Title)
+			if ni != nj {
+				return ni < nj
+			}
+
+			return latestAlbums[i].ID < latestAlbums[j].ID
+		})
+	}
+
+	// Upgrade image URLs so covers don't get blocked as mixed content
+	topTracks = httpsifyDeezerTracks(topTracks)
+	latestAlbums = httpsifyDeezerAlbums(latestAlbums)
+
+	return &ArtistDetail{
+		name:       artist.Name,
+		musicGroup: deezerMusicGroupLD(artist),
+
+		FavoriteID: strconv.Itoa(intID),
+
+		DeezerArtist:           artist,
+		DeezerFans:             artist.NbFan,
+		DeezerAlbumsCount:      artist.NbAlbum,
+		DeezerHasRadio:         artist.Radio,
+		DeezerMonthlyListeners: monthly,
+		DeezerTopTracks:        topTracks,
+		DeezerLatestAlbums:     latestAlbums,
+		DeezerAlbumGroups:      groupAlbumsByType(latestAlbums, func(a api.DeezerAlbum) string { return a.RecordType }),
+
+		TracksLimit: opts.Tracks,
+		AlbumsLimit: opts.Albums,
+
+		LyricsEnabled: api.LyricsEnabled(),
+	}, nil
+}
+
+func (deezerSource) DetailMaxima() (int, int) {
+	return 50, 50
+}
+
+func (deezerSource) NormalizeID(id string) (string, bool) {
+	return normalizeNumericID(id)
+}
+
+func (deezerSource) AlbumCards(idSegment string, offset, limit int) (string, []any, error) {
+	id, err := strconv.Atoi(idSegment)
+	if err != nil || id <= 0 {
+		return "", nil, errInvalidArtistID
+	}
+	albums, err := api.GetDeezerArtistAlbums(id, offset, limit)
+	if err != nil {
+		return "", nil, err
+	}
+	cards := make([]any, 0, len(albums))
+	for _, a := range httpsifyDeezerAlbums(albums) {
+		cards = append(cards, a)
+	}
+	return "deezer_album_card", cards, nil
+}
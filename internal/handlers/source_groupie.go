@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"palasgroupietracker/internal/api"
+	"palasgroupietracker/internal/geo"
+)
+
+// groupieSearchLimit caps the suggestion-backed search results
+const groupieSearchLimit = 24
+
+// fetchGroupieArtists and fetchGroupieRelations load the Groupie dataset, tests swap in a fixed corpus
+var (
+	fetchGroupieArtists   = api.FetchArtistsCtx
+	fetchGroupieRelations = api.FetchRelationsCtx
+)
+
+// groupieSource serves the original Groupie Tracker dataset
+type groupieSource struct{}
+
+func init() {
+	registerSource(groupieSource{})
+}
+
+func (groupieSource) Name() string {
+	return "groupie"
+}
+
+// Search reuses the suggestion corpus, member hits jump to their band
+// The featured set is the whole dataset in its own order
+func (groupieSource) Search(ctx context.Context, query string) ([]ArtistCard, error) {
+	artists, err := fetchGroupieArtists(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if query == "" {
+		out := make([]ArtistCard, 0, len(artists))
+		for _, a := range artists {
+			out = append(out, groupieSearchCard(a))
+		}
+		return out, nil
+	}
+
+	idx, err := getGroupieSuggestIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]api.Artist, len(artists))
+	byMember := make(map[string]api.Artist, len(artists)*4)
+	for _, a := range artists {
+		byName[normalizeForMatch(a.Name)] = a
+		for _, m := range a.Members {
+			if _, ok := byMember[normalizeForMatch(m)]; !ok {
+				byMember[normalizeForMatch(m)] = a
+			}
+		}
+	}
+
+	// Ask for extra suggestions since locations and repeated bands are skipped
+	out := make([]ArtistCard, 0, groupieSearchLimit)
+	seen := make(map[int]bool, groupieSearchLimit)
+	for _, s := range idx.match(normalizeForMatch(query), groupieSearchLimit*3) {
+		if len(out) >= groupieSearchLimit {
+			break
+		}
+		var a api.Artist
+		var ok bool
+		switch s.Type {
+		case "group":
+			a, ok = byName[normalizeForMatch(s.Label)]
+		case "member":
+			a, ok = byMember[normalizeForMatch(s.Label)]
+		}
+		if !ok || seen[a.ID] {
+			continue
+		}
+		seen[a.ID] = true
+
+		card := groupieSearchCard(a)
+		if s.Type == "member" {
+			card.Meta = s.Label + " • member of " + a.Name
+		}
+		out = append(out, card)
+	}
+
+	return out, nil
+}
+
+// groupieSearchCard keeps the metadata short so marquee cards stay visually balanced
+func groupieSearchCard(a api.Artist) ArtistCard {
+	card := groupieArtistCard("", a)
+	card.Meta = fmt.Sprintf("Created %d • %d members", card.CreationDate, card.Members)
+	return card
+}
+
+func (groupieSource) GetArtist(ctx context.Context, id string) (ArtistCard, error) {
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		return ArtistCard{}, errInvalidArtistID
+	}
+	artist, err := api.FetchArtistByIDCtx(ctx, intID)
+	if err != nil {
+		return ArtistCard{}, fmt.Errorf("%w: %w", errArtistNotFound, err)
+	}
+	return groupieSearchCard(*artist), nil
+}
+
+// GetDetail loads the artist with its tour, the map stops are geocoded here
+func (groupieSource) GetDetail(ctx context.Context, id string, opts DetailOptions) (*ArtistDetail, error) {
+	timing := timingFrom(ctx)
+
+	intID, err := strconv.Atoi(id)
+	if err != nil || intID <= 0 {
+		// IDs are numeric in Groupie mode
+		return nil, errInvalidArtistID
+	}
+
+	stop := timing.start("groupie")
+	artist, err := api.FetchArtistByIDCtx(ctx, intID)
+	stop()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errArtistNotFound, err)
+	}
+	// Normalize on a copy so the cached dataset keeps its original URLs
+	groupieArtist := *artist
+	groupieArtist.Image = httpsify(groupieArtist.Image)
+	artist = &groupieArtist
+
+	stop = timing.start("groupie")
+	relation, err := api.FetchRelationForArtistCtx(ctx, intID)
+	stop()
+	if err != nil {
+		return nil, fmt.Errorf("load concerts: %w", err)
+	}
+
+	// Sort keys for stable output and predictable map ordering
+	keys := make([]string, 0, len(relation.DatesLocations))
+	for k := range relation.DatesLocations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	now := time.Now()
+	concertDates := make(map[string][]ConcertDate, len(keys))
+	concerts := make([]ConcertStop, 0, len(keys))
+	for _, k := range keys {
+		dates := parseConcertDates(relation.DatesLocations[k], opts.Locale)
+		concertDates[k] = dates
+		_, labels := concertDateParts(dates)
+		concerts = append(concerts, ConcertStop{Location: geo.HumanizeLocationKey(k), Dates: labels})
+	}
+	upcomingConcerts, pastConcerts := buildConcertSchedule(concertDates, now)
+
+	if len(keys) > maxGeocodedLocations {
+		// Avoid geocoding too many points in one request
+		keys = keys[:maxGeocodedLocations]
+	}
+
+	stop = timing.start("geocode")
+	points := geocodeLocationKeys(ctx, keys)
+	stop()
+
+	locations := make([]MapLocation, 0, len(points))
+	for _, name := range keys {
+		res, ok := points[name]
+		if !ok {
+			// Missing geocodes are expected for noisy location strings
+			continue
+		}
+		dates, labels := concertDateParts(concertDates[name])
+		upcoming, past := splitConcertDates(concertDates[name], now)
+		locations = append(locations, MapLocation{
+			Name:        res.Display,
+			Lat:         res.Lat,
+			Lng:         res.Lng,
+			Dates:       dates,
+			Labels:      labels,
+			HasUpcoming: len(upcoming) > 0,
+			Upcoming:    concertLabels(upcoming),
+			Past:        concertLabels(past),
+		})
+	}
+
+	// Sort final locations alphabetically for consistent popups
+	sort.SliceStable(locations, func(i, j int) bool { // case-insensitive by display name
+		return strings.ToLower(locations[i].Name) < strings.ToLower(locations[j].Name)
+	})
+
+	stop = timing.start("shared")
+	shared := buildSharedConcerts(ctx, relation)
+	stop()
+
+	stop = timing.start("members")
+	members := buildGroupieMembers(artist.Members)
+	stop()
+
+	return &ArtistDetail{
+		name:       artist.Name,
+		musicGroup: groupieMusicGroupLD(artist),
+
+		FavoriteID: strconv.Itoa(intID),
+		Artist:     artist,
+		Members:    members,
+
+		locations:        locations,
+		Concerts:         concerts,
+		UpcomingConcerts: upcomingConcerts,
+		PastConcerts:     pastConcerts,
+		sharedConcerts:   shared,
+	}, nil
+}
+
+func (groupieSource) DetailMaxima() (int, int) {
+	// Groupie artists have no tracks or albums to list
+	return 0, 0
+}
+
+func (groupieSource) NormalizeID(id string) (string, bool) {
+	return normalizeNumericID(id)
+}
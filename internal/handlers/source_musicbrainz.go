@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"palasgroupietracker/internal/api"
+)
+
+// musicBrainzDefaultQuery is searched when there is no query, since MusicBrainz has no charts
+const musicBrainzDefaultQuery = "tag:rock"
+
+// musicbrainzSource browses the open MusicBrainz catalog, metadata only (no artwork or tracks)
+type musicbrainzSource struct{}
+
+func init() {
+	registerSource(musicbrainzSource{})
+}
+
+func (musicbrainzSource) Name() string {
+	return "musicbrainz"
+}
+
+// musicBrainzSearchLimit is how many artists a search asks MusicBrainz for
+const musicBrainzSearchLimit = 24
+
+// Search asks MusicBrainz for just the artists the views show
+// The featured set is the default tag search
+func (musicbrainzSource) Search(ctx context.Context, query string) ([]ArtistCard, error) {
+	if query == "" {
+		query = musicBrainzDefaultQuery
+	}
+	artists, err := api.SearchMusicBrainzArtists(query, musicBrainzSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]ArtistCard, 0, len(artists))
+	for _, a := range artists {
+		out = append(out, musicBrainzArtistCard("", a))
+	}
+
+	return out, nil
+}
+
+func (musicbrainzSource) GetArtist(ctx context.Context, id string) (ArtistCard, error) {
+	artist, err := api.GetMusicBrainzArtist(id)
+	if err != nil {
+		return ArtistCard{}, artistLookupError(err)
+	}
+	return musicBrainzArtistCard("", *artist), nil
+}
+
+// GetDetail loads the artist metadata, MusicBrainz has no artwork, tracks or albums
+func (musicbrainzSource) GetDetail(ctx context.Context, id string, opts DetailOptions) (*ArtistDetail, error) {
+	timing := timingFrom(ctx)
+
+	id = strings.ToLower(strings.TrimSpace(id))
+	if !api.IsMusicBrainzID(id) {
+		return nil, errInvalidArtistID
+	}
+
+	stop := timing.start("musicbrainz")
+	artist, err := api.GetMusicBrainzArtist(id)
+	stop()
+	if err != nil {
+		return nil, artistLookupError(err)
+	}
+
+	return &ArtistDetail{
+		name:       artist.Name,
+		musicGroup: musicBrainzMusicGroupLD(artist),
+
+		FavoriteID: id,
+
+		MusicBrainzArtist:   artist,
+		MusicBrainzTags:     artist.TopTags(10),
+		MusicBrainzLifeSpan: musicBrainzLifeSpan(artist.LifeSpan),
+	}, nil
+}
+
+func (musicbrainzSource) DetailMaxima() (int, int) {
+	// MusicBrainz pages only show metadata
+	return 0, 0
+}
+
+// NormalizeID lowercases the MBID, the same form detail pages store favorites under
+func (musicbrainzSource) NormalizeID(id string) (string, bool) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	return id, api.IsMusicBrainzID(id)
+}
+
+// musicBrainzMeta joins type, country and life-span into a short card subtitle
+func musicBrainzMeta(a api.MusicBrainzArtist) string {
+	parts := make([]string, 0, 3)
+	if a.Type != "" {
+		parts = append(parts, a.Type)
+	}
+	if a.Country != "" {
+		parts = append(parts, a.Country)
+	}
+	if span := musicBrainzLifeSpan(a.LifeSpan); span != "" {
+		parts = append(parts, span)
+	}
+	if len(parts) == 0 {
+		return "MusicBrainz artist"
+	}
+	return strings.Join(parts, " • ")
+}
+
+// musicBrainzLifeSpan formats begin/end years, e.g. "1962 – 2012" or "1994 – present"
+func musicBrainzLifeSpan(l api.MusicBrainzLifeSpan) string {
+	begin, end := l.BeginYear(), l.EndYear()
+	switch {
+	case begin != "" && end != "":
+		return begin + " – " + end
+	case begin != "" && l.Ended:
+		// Ended without a known date
+		return "from " + begin
+	case begin != "":
+		return begin + " – present"
+	case end != "":
+		return "until " + end
+	default:
+		return ""
+	}
+}
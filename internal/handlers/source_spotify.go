@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"palasgroupietracker/internal/api"
+)
+
+// spotifySource browses artists through the Spotify Web API
+type spotifySource struct{}
+
+func init() {
+	registerSource(spotifySource{})
+}
+
+func (spotifySource) Name() string {
+	return "spotify"
+}
+
+// Search takes the Spotify search hits, without the Last.fm lookups of the list page
+func (spotifySource) Search(ctx context.Context, query string) ([]ArtistCard, error) {
+	if query == "" {
+		// Use a broad query so the featured set always has results
+		query = "a"
+	}
+	artists, err := api.SearchSpotifyArtists(query)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]ArtistCard, 0, len(artists))
+	for _, a := range artists {
+		card := spotifyArtistCard("", a, artworkSizeFor("home"))
+		card.Meta = "Spotify artist"
+		if card.Followers > 0 {
+			card.setCountMeta("%s followers", card.Followers, true)
+		} else if card.Genre != "" {
+			card.Meta = card.Genre
+		}
+		out = append(out, card)
+	}
+
+	return out, nil
+}
+
+// GetArtist cards fill the favorites grid, so they ask for its artwork size
+func (spotifySource) GetArtist(ctx context.Context, id string) (ArtistCard, error) {
+	artist, err := api.GetSpotifyArtist(id)
+	if err != nil {
+		return ArtistCard{}, artistLookupError(err)
+	}
+	card := spotifyArtistCard("", *artist, artworkSizeFor("favorites"))
+	card.Meta = "Spotify artist"
+	return card, nil
+}
+
+// GetDetail loads the artist with its top tracks, albums and Last.fm listeners
+func (spotifySource) GetDetail(ctx context.Context, id string, opts DetailOptions) (*ArtistDetail, error) {
+	timing := timingFrom(ctx)
+
+	if !isLikelySpotifyID(id) {
+		return nil, errInvalidArtistID
+	}
+
+	stop := timing.start("spotify")
+	artist, err := api.GetSpotifyArtist(id)
+	stop()
+	if err != nil {
+		return nil, artistLookupError(err)
+	}
+	spotifyArtist := *artist
+	spotifyArtist.Images = httpsifySpotifyImages(spotifyArtist.Images)
+	artist = &spotifyArtist
+
+	genre := ""
+	if len(artist.Genres) > 0 {
+		// Capitalize the first genre for nicer display
+		runes := []rune(artist.Genres[0])
+		if len(runes) > 0 {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+		genre = string(runes)
+	}
+
+	stop = timing.start("lastfm")
+	listeners, err := api.FetchArtistMonthlyListeners(artist.Name)
+	stop()
+	if err != nil {
+		// Last.fm can fail independently, keep the rest of the page
+		listeners = 0
+	}
+
+	followers := 0
+	if artist.Followers != nil {
+		followers = artist.Followers.Total
+	}
+
+	stop = timing.start("tracks")
+	topTracks, err := api.GetSpotifyArtistTopTracks(artist.ID, "FR", opts.Tracks)
+	stop()
+	if err != nil {
+		// Tracks are optional for the page to work
+		topTracks = nil
+	}
+
+	stop = timing.start("albums")
+	latestAlbums, err := api.GetSpotifyArtistAlbums(artist.ID, "FR", 0, opts.Albums)
+	stop()
+	if err != nil {
+		latestAlbums = nil
+	}
+
+	if len(latestAlbums) > 1 {
+		// Keep album ordering deterministic even if the API ordering changes
+		sort.SliceStable(latestAlbums, func(i, j int) bool { // newest first, then stable tie-breakers
+			di, okI := api.ParseSpotifyReleaseDate(latestAlbums[i].ReleaseDate)
+			dj, okJ := api.ParseSpotifyReleaseDate(latestAlbums[j].ReleaseDate)
+
+			if okI && okJ && !di.Equal(dj) {
+				return di.After(dj)
+			}
+			if okI != okJ {
+				return okI
+			}
+
+			ni := strings.ToLower(latestAlbums[i].Name)
+			nj := strings.ToLower(latestAlbums[j].Name)
+			if ni != nj {
+				return ni < nj
+			}
+
+			return latestAlbums[i].ID < latestAlbums[j].ID
+		})
+	}
+
+	// Upgrade image URLs so covers don't get blocked as mixed content
+	topTracks = httpsifySpotifyTracks(topTracks)
+	latestAlbums = httpsifySpotifyAlbums(latestAlbums)
+
+	return &ArtistDetail{
+		name:       artist.Name,
+		musicGroup: spotifyMusicGroupLD(artist),
+
+		FavoriteID: id,
+
+		SpotifyArtist:           artist,
+		SpotifyGenre:            genre,
+		SpotifyFollowers:        followers,
+		SpotifyMonthlyListeners: listeners,
+		SpotifyTopTracks:        topTracks,
+		SpotifyLatestAlbums:     latestAlbums,
+		SpotifyAlbumGroups:      groupAlbumsByType(latestAlbums, func(a api.SpotifyAlbum) string { return a.AlbumType }),
+
+		TracksLimit: opts.Tracks,
+		AlbumsLimit: opts.Albums,
+
+		LyricsEnabled: api.LyricsEnabled(),
+	}, nil
+}
+
+func (spotifySource) DetailMaxima() (int, int) {
+	// The top-tracks endpoint never returns more than 10 entries
+	return 10, 50
+}
+
+func (spotifySource) NormalizeID(id string) (string, bool) {
+	id = strings.TrimSpace(id)
+	return id, isLikelySpotifyID(id)
+}
+
+func (spotifySource) AlbumCards(idSegment string, offset, limit int) (string, []any, error) {
+	if !isLikelySpotifyID(idSegment) {
+		return "", nil, errInvalidArtistID
+	}
+	albums, err := api.GetSpotifyArtistAlbums(idSegment, "FR", offset, limit)
+	if err != nil {
+		return "", nil, err
+	}
+	cards := make([]any, 0, len(albums))
+	for _, a := range httpsifySpotifyAlbums(albums) {
+		cards = append(cards, a)
+	}
+	return "spotify_album_card", cards, nil
+}
@@ -1,6 +1,9 @@
 package handlers
 
-import "palasgroupietracker/internal/store"
+import (
+	"palasgroupietracker/internal/geo"
+	"palasgroupietracker/internal/store"
+)
 
 var appStore *store.Store
 
@@ -8,3 +11,8 @@ var appStore *store.Store
 func SetStore(s *store.Store) {
 	appStore = s
 }
+
+// SetGeocoder replaces the geocoder used for concert maps, call it before serving
+func SetGeocoder(g *geo.Geocoder) {
+	groupieGeocoder = g
+}
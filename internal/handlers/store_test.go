@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"palasgroupietracker/internal/store"
+)
+
+// testSchema keeps these tests away from the store tests sharing the same database
+const testSchema = "handlers_test"
+
+// useTestStore points the handlers at TEST_DATABASE_URL with a freshly migrated, empty schema
+// and puts the previous store back when the test ends
+// The test is skipped when the variable isn't set, the handlers need a real PostgreSQL
+func useTestStore(t *testing.T) *store.Store {
+	t.Helper()
+
+	dsn := strings.TrimSpace(os.Getenv("TEST_DATABASE_URL"))
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	for _, stmt := range []string{
+		`DROP SCHEMA IF EXISTS ` + testSchema + ` CASCADE`,
+		`CREATE SCHEMA ` + testSchema,
+	} {
+		if _, err := admin.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := sql.Open("postgres", withSearchPath(dsn, testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &store.Store{DB: db}
+	if err := s.Migrate(ctx); err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+
+	prev := appStore
+	appStore = s
+	t.Cleanup(func() {
+		appStore = prev
+		db.Close()
+	})
+	return s
+}
+
+// withSearchPath adds a search_path connection parameter to either DSN form
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
+
+// newTestAccount creates a user with password and a logged-in session, returning its cookie
+// The minimum bcrypt cost keeps the tests fast
+func newTestAccount(t *testing.T, s *store.Store, email, password string) (*store.User, *http.Cookie) {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := s.CreateUser(context.Background(), email, string(hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u, newTestSession(t, s, u.ID)
+}
+
+// newTestSession logs userID in once more, as if from another device
+func newTestSession(t *testing.T, s *store.Store, userID int64) *http.Cookie {
+	t.Helper()
+
+	token, tokenHash, err := newSessionToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateSession(context.Background(), userID, tokenHash, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	return &http.Cookie{Name: sessionCookieName, Value: token}
+}
+
+// sessionAlive reports whether the session behind cookie is still in the database
+func sessionAlive(t *testing.T, s *store.Store, cookie *http.Cookie) bool {
+	t.Helper()
+
+	_, err := s.GetSessionByTokenHash(context.Background(), hashToken(cookie.Value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"palasgroupietracker/internal/api"
+)
+
+// musicGroupLD is the schema.org MusicGroup embedded as JSON-LD on detail pages
+type musicGroupLD struct {
+	Context      string     `json:"@context"`
+	Type         string     `json:"@type"`
+	Name         string     `json:"name"`
+	Image        string     `json:"image,omitempty"`
+	Genre        []string   `json:"genre,omitempty"`
+	FoundingDate string     `json:"foundingDate,omitempty"`
+	Members      []personLD `json:"member,omitempty"`
+	SameAs       []string   `json:"sameAs,omitempty"`
+}
+
+type personLD struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+func newMusicGroupLD(name string) musicGroupLD {
+	return musicGroupLD{
+		Context: "https://schema.org",
+		Type:    "MusicGroup",
+		Name:    name,
+	}
+}
+
+// JS marshals the group for a `<script type="application/ld+json">` tag
+// json.Marshal escapes <, > and &, so the content can't close the script early
+func (g musicGroupLD) JS() template.JS {
+	g.SameAs = absoluteURLs(g.SameAs)
+	if !isAbsoluteURL(g.Image) {
+		g.Image = ""
+	}
+
+	b, err := json.Marshal(g)
+	if err != nil {
+		return ""
+	}
+	return template.JS(b)
+}
+
+// withWiki adds the Wikipedia page to sameAs when the summary lookup found one
+func (g musicGroupLD) withWiki(wikiURL string, hasWiki bool) musicGroupLD {
+	if hasWiki {
+		g.SameAs = append(g.SameAs, wikiURL)
+	}
+	return g
+}
+
+func groupieMusicGroupLD(a *api.Artist) musicGroupLD {
+	g := newMusicGroupLD(a.Name)
+	g.Image = a.Image
+	if a.CreationDate > 0 {
+		g.FoundingDate = strconv.Itoa(a.CreationDate)
+	}
+	for _, m := range a.Members {
+		if m = strings.TrimSpace(m); m != "" {
+			g.Members = append(g.Members, personLD{Type: "Person", Name: m})
+		}
+	}
+	return g
+}
+
+func spotifyMusicGroupLD(a *api.SpotifyArtist) musicGroupLD {
+	g := newMusicGroupLD(a.Name)
+	if len(a.Images) > 0 {
+		g.Image = a.Images[0].URL
+	}
+	g.Genre = a.Genres
+	g.SameAs = []string{a.ExternalURLs.Spotify}
+	return g
+}
+
+func deezerMusicGroupLD(a *api.DeezerArtist) musicGroupLD {
+	g := newMusicGroupLD(a.Name)
+	g.Image = a.PictureXL
+	g.SameAs = []string{a.Link}
+	return g
+}
+
+func appleMusicGroupLD(a *api.AppleArtist, hero string) musicGroupLD {
+	g := newMusicGroupLD(a.ArtistName)
+	g.Image = hero
+	if a.PrimaryGenreName != "" {
+		g.Genre = []string{a.PrimaryGenreName}
+	}
+	g.SameAs = []string{a.ArtistLinkURL}
+	return g
+}
+
+func musicBrainzMusicGroupLD(a *api.MusicBrainzArtist) musicGroupLD {
+	g := newMusicGroupLD(a.Name)
+	g.Genre = a.TopTags(3)
+	g.FoundingDate = a.LifeSpan.Begin
+	g.SameAs = []string{"https://musicbrainz.org/artist/" + a.ID}
+	return g
+}
+
+// absoluteURLs keeps the http(s) URLs, dropping blanks and duplicates
+func absoluteURLs(urls []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if !isAbsoluteURL(u) || seen[u] {
+			continue
+		}
+		seen[u] = true
+		out = append(out, u)
+	}
+	return out
+}
+
+func isAbsoluteURL(u string) bool {
+	return strings.HasPrefix(u, "https://") || strings.HasPrefix(u, "http://")
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serverTiming collects per-section durations for the `Server-Timing` response header
+// Browser dev tools show them in the network panel, so slow enrichment calls are easy to spot
+type serverTiming struct {
+	mu      sync.Mutex
+	started time.Time
+	names   []string
+	durs    map[string]time.Duration
+}
+
+func newServerTiming() *serverTiming {
+	return &serverTiming{
+		started: time.Now(),
+		durs:    make(map[string]time.Duration),
+	}
+}
+
+type serverTimingKey struct{}
+
+// withServerTiming attaches t to ctx so sources can time their own provider calls
+func withServerTiming(ctx context.Context, t *serverTiming) context.Context {
+	return context.WithValue(ctx, serverTimingKey{}, t)
+}
+
+// timingFrom returns the timing attached to ctx, or a fresh one nobody reads so callers
+// can always time their sections
+func timingFrom(ctx context.Context) *serverTiming {
+	if t, ok := ctx.Value(serverTimingKey{}).(*serverTiming); ok {
+		return t
+	}
+	return newServerTiming()
+}
+
+// start begins timing a section, calling the returned func records it
+// Sections with the same name add up, e.g. one lookup per favorites card
+// Safe to use from several goroutines
+func (t *serverTiming) start(name string) func() {
+	begin := time.Now()
+	return func() {
+		t.add(name, time.Since(begin))
+	}
+}
+
+func (t *serverTiming) add(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.durs[name]; !ok {
+		// Keep first-seen order so the header reads like the handler
+		t.names = append(t.names, name)
+	}
+	t.durs[name] += d
+}
+
+// header formats sections as `name;dur=ms`, ending with the total time so far
+func (t *serverTiming) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parts := make([]string, 0, len(t.names)+1)
+	for _, name := range t.names {
+		parts = append(parts, name+";dur="+formatTimingMillis(t.durs[name]))
+	}
+	parts = append(parts, "total;dur="+formatTimingMillis(time.Since(t.started)))
+
+	return strings.Join(parts, ", ")
+}
+
+// write sets the header, it must run before the response body is written
+func (t *serverTiming) write(w http.ResponseWriter) {
+	w.Header().Set("Server-Timing", t.header())
+}
+
+// formatTimingMillis renders a duration in milliseconds with one decimal
+func formatTimingMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 1, 64)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// TrimTrailingSlash permanently redirects `/artists/` to `/artists`, `/favorites//` to
+// `/favorites` and so on, keeping the query. Without it `/artists/` would reach the detail
+// handler with no ID, and `/artists/1/` would be a second URL for the same page
+// Only GET and HEAD are redirected, browsers turn a redirected POST into a GET
+// `/` itself and `/static/` (where the file server handles directories) are left alone
+func TrimTrailingSlash(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+			!strings.HasSuffix(p, "/") || strings.TrimRight(p, "/") == "" || strings.HasPrefix(p, "/static/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// This runs before ServeMux cleans the path, so `//evil.example/` must not become the
+		// protocol-relative `//evil.example`; Clean collapses the slashes and drops the trailing ones
+		target := withBasePath(r, path.Clean(r.URL.EscapedPath()))
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrimTrailingSlash(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h := TrimTrailingSlash(next)
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		prefix   string
+		status   int
+		location string
+	}{
+		{"list", http.MethodGet, "/artists/", "", http.StatusMovedPermanently, "/artists"},
+		{"keeps query", http.MethodGet, "/artists/?source=deezer&q=a", "", http.StatusMovedPermanently, "/artists?source=deezer&q=a"},
+		{"several slashes", http.MethodGet, "/favorites//", "", http.StatusMovedPermanently, "/favorites"},
+		{"detail", http.MethodHead, "/artists/1/", "", http.StatusMovedPermanently, "/artists/1"},
+		{"base path", http.MethodGet, "/artists/", "/app", http.StatusMovedPermanently, "/app/artists"},
+		{"no open redirect", http.MethodGet, "//evil.example/", "", http.StatusMovedPermanently, "/evil.example"},
+		{"no open redirect deeper", http.MethodGet, "///evil.example//x/", "", http.StatusMovedPermanently, "/evil.example/x"},
+		{"root", http.MethodGet, "/", "", http.StatusNoContent, ""},
+		{"only slashes", http.MethodGet, "//", "", http.StatusNoContent, ""},
+		{"static", http.MethodGet, "/static/css/", "", http.StatusNoContent, ""},
+		{"no slash", http.MethodGet, "/artists", "", http.StatusNoContent, ""},
+		{"post", http.MethodPost, "/artists/", "", http.StatusNoContent, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.prefix != "" {
+				r.Header.Set("X-Forwarded-Prefix", tt.prefix)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Location"); got != tt.location {
+				t.Fatalf("Location = %q, want %q", got, tt.location)
+			}
+		})
+	}
+}
@@ -6,13 +6,24 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 
+	"palasgroupietracker/internal/geo"
 	"palasgroupietracker/internal/handlers"
 	"palasgroupietracker/internal/store"
 )
 
+// shutdownTimeout bounds how long in-flight requests get after SIGINT/SIGTERM
+const shutdownTimeout = 10 * time.Second
+
+// sessionPurgeInterval is how often expired sessions are deleted, getCurrentUser only drops
+// the ones that are presented again
+const sessionPurgeInterval = time.Hour
+
 // Run bootstraps the app and blocks serving HTTP. It logs fatal on unrecoverable errors
 // Keeping this in internal/server allows cmd/server/main.go to stay minimal.
 func Run() {
@@ -44,6 +55,15 @@ func run(ctx context.Context) error {
 		handlers.SetStore(dbStore)
 	}
 
+	// Resolved concert locations survive restarts when `GEOCODE_CACHE_FILE` is set
+	geocoder := geo.NewGeocoderWithCache(os.Getenv("GEOCODE_CACHE_FILE"))
+	defer func() {
+		if err := geocoder.Close(); err != nil {
+			log.Println("could not save geocoder cache:", err)
+		}
+	}()
+	handlers.SetGeocoder(geocoder)
+
 	registerRoutes(mux)
 
 	port := os.Getenv("PORT")
@@ -54,19 +74,112 @@ func run(ctx context.Context) error {
 	addr := ":" + port
 	log.Println("listening on", addr)
 
-	return http.ListenAndServe(addr, mux)
+	// Request IDs wrap every route so error pages and JSON errors can quote them
+	// Trailing slashes are dropped before routing, `/artists/` is the list, not an empty detail page
+	srv := &http.Server{Addr: addr, Handler: handlers.WithRequestID(handlers.TrimTrailingSlash(mux))}
+
+	// Stop cleanly on deploys so the deferred cleanups (geocoder cache, DB) still run
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	purgeDone := make(chan struct{})
+	if dbStore != nil {
+		go func() {
+			defer close(purgeDone)
+			purgeExpiredSessions(ctx, dbStore, sessionPurgeInterval)
+		}()
+	} else {
+		close(purgeDone)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		stop()
+		<-purgeDone
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	// The purge stops with ctx, wait for it so the store isn't closed under it
+	<-purgeDone
+	return err
+}
+
+// purgeExpiredSessions deletes expired sessions now and then every interval, until ctx is done
+func purgeExpiredSessions(ctx context.Context, s *store.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.DeleteExpiredSessions(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Println("could not purge expired sessions:", err)
+		case n > 0:
+			log.Println("purged", n, "expired sessions")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// jsonRoutes are the JSON API endpoints, each one is described in internal/handlers/openapi.json
+// and the tests check the two agree. They can be opened to other origins with `CORS_ORIGINS`,
+// except the favorites sync which relies on the session cookie
+var jsonRoutes = []struct {
+	pattern string
+	handler http.HandlerFunc
+}{
+	{"/artists/suggest", handlers.CORS(handlers.ArtistsSuggestHandler)},
+	{"/quicksearch", handlers.CORS(handlers.QuickSearchHandler)},
+	{"/lyrics", handlers.CORS(handlers.LyricsHandler)},
+	{"/api/artists", handlers.CORS(handlers.ArtistsAPIHandler)},
+	{"/api/openapi.json", handlers.CORS(handlers.OpenAPIHandler)},
+	{"/api/favorites", handlers.FavoritesSyncHandler},
 }
 
 func registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/artists", handlers.ArtistsHandler)
 	mux.HandleFunc("/artists/ajax", handlers.ArtistsAjaxHandler)
-	mux.HandleFunc("/artists/suggest", handlers.ArtistsSuggestHandler)
 	mux.HandleFunc("/artists/", handlers.ArtistDetailHandler)
 	mux.HandleFunc("/favorites", handlers.FavoritesHandler)
-	mux.HandleFunc("/favorites/toggle", handlers.ToggleFavoriteHandler)
-	mux.HandleFunc("/login", handlers.LoginHandler)
-	mux.HandleFunc("/register", handlers.RegisterHandler)
-	mux.HandleFunc("/logout", handlers.LogoutHandler)
+	for _, route := range jsonRoutes {
+		mux.HandleFunc(route.pattern, route.handler)
+	}
+
+	// Form posts get a small body cap so a huge POST can't eat memory
+	maxForm := handlers.MaxFormBytes()
+	mux.HandleFunc("/favorites/toggle", handlers.LimitFormBody(maxForm, handlers.ToggleFavoriteHandler))
+	mux.HandleFunc("/favorites/bulk", handlers.LimitFormBody(maxForm, handlers.BulkFavoritesHandler))
+	mux.HandleFunc("/report", handlers.LimitFormBody(maxForm, handlers.ReportHandler))
+	// Maintainer pages, only served when `ADMIN_TOKEN` is set
+	mux.HandleFunc("/admin/reports", handlers.AdminReportsHandler)
+	mux.HandleFunc("/admin/reports/resolve", handlers.LimitFormBody(maxForm, handlers.AdminResolveReportHandler))
+	mux.HandleFunc("/login", handlers.LimitFormBody(maxForm, handlers.LoginHandler))
+	mux.HandleFunc("/register", handlers.LimitFormBody(maxForm, handlers.RegisterHandler))
+	mux.HandleFunc("/logout", handlers.LimitFormBody(maxForm, handlers.LogoutHandler))
+	mux.HandleFunc("/forgot-password", handlers.LimitFormBody(maxForm, handlers.ForgotPasswordHandler))
+	mux.HandleFunc("/reset-password", handlers.LimitFormBody(maxForm, handlers.ResetPasswordHandler))
+	mux.HandleFunc("/account/password", handlers.LimitFormBody(maxForm, handlers.AccountPasswordHandler))
+	mux.HandleFunc("/account/delete", handlers.LimitFormBody(maxForm, handlers.AccountDeleteHandler))
+	mux.HandleFunc("/account/sessions", handlers.LimitFormBody(maxForm, handlers.AccountSessionsHandler))
+
+	// Browsers fetch these at the root, not under `/static/`
+	mux.HandleFunc("/favicon.ico", handlers.FaviconHandler)
+	mux.HandleFunc("/site.webmanifest", handlers.ManifestHandler)
 
 	// Serve static assets from `web/static` under the `/static/` URL prefix
 	fileServer := http.FileServer(http.Dir("web/static"))
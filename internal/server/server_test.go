@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"palasgroupietracker/internal/handlers"
+	"palasgroupietracker/internal/store"
+)
+
+// documentedPaths returns the paths of the OpenAPI document, as served by /api/openapi.json
+func documentedPaths(t *testing.T) []string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	handlers.OpenAPIHandler(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	var spec struct {
+		Paths map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+
+	paths := make([]string, 0, len(spec.Paths))
+	for p := range spec.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestOpenAPIPathsAreRouted(t *testing.T) {
+	mux := http.NewServeMux()
+	registerRoutes(mux)
+
+	for _, p := range documentedPaths(t) {
+		// Path parameters are IDs, any value reaches the same handler
+		concrete := strings.ReplaceAll(p, "{id}", "1")
+		_, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, concrete, nil))
+		// "/" is the catch-all that answers 404 for anything but the home page
+		if pattern == "" || pattern == "/" {
+			t.Errorf("documented path %s is not routed", p)
+		}
+	}
+}
+
+func TestJSONRoutesAreDocumented(t *testing.T) {
+	documented := make(map[string]bool)
+	for _, p := range documentedPaths(t) {
+		documented[p] = true
+	}
+
+	for _, route := range jsonRoutes {
+		if !documented[route.pattern] {
+			t.Errorf("JSON route %s is missing from openapi.json", route.pattern)
+		}
+	}
+}
+
+func TestPurgeExpiredSessionsStopsWithContext(t *testing.T) {
+	// A store without a database fails every purge, the loop has to keep going until ctx ends
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		purgeExpiredSessions(ctx, &store.Store{}, time.Millisecond)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("purge loop still running after the context was canceled")
+	}
+	if n := strings.Count(logs.String(), "could not purge expired sessions"); n < 2 {
+		t.Fatalf("logged %d failed purges, want the loop to retry on every tick", n)
+	}
+}
+
+func TestPurgeExpiredSessionsCanceledUpFront(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		purgeExpiredSessions(ctx, &store.Store{}, time.Hour)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("purge loop waited for a tick after the context was canceled")
+	}
+}
+
+func TestArtistRoutesPatterns(t *testing.T) {
+	mux := http.NewServeMux()
+	registerRoutes(mux)
+
+	// Only the exact paths reach the ajax and suggest handlers, everything else under
+	// `/artists/` goes to ArtistDetailHandler, which refuses the reserved names
+	tests := map[string]string{
+		"/artists/ajax":      "/artists/ajax",
+		"/artists/suggest":   "/artists/suggest",
+		"/artists/ajax/":     "/artists/",
+		"/artists/suggest/x": "/artists/",
+		"/artists/12":        "/artists/",
+	}
+	for target, want := range tests {
+		_, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, target, nil))
+		if pattern != want {
+			t.Errorf("%s is routed to %q, want %q", target, pattern, want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package store
+
+// FavoriteKey identifies a favorite regardless of who saved it or when
+type FavoriteKey struct {
+	Source   string
+	ArtistID string
+}
+
+// Key returns the (source, artist_id) pair favorites are compared by
+func (f Favorite) Key() FavoriteKey {
+	return FavoriteKey{Source: f.Source, ArtistID: f.ArtistID}
+}
+
+// IntersectFavorites returns the favorites of a whose key is also in b
+// Results keep a's order and entries, repeated keys are kept once
+func IntersectFavorites(a, b []Favorite) []Favorite {
+	in := favoriteKeySet(b)
+	return filterFavorites(a, func(k FavoriteKey) bool { return in[k] })
+}
+
+// DiffFavorites returns the favorites of a whose key is not in b
+func DiffFavorites(a, b []Favorite) []Favorite {
+	in := favoriteKeySet(b)
+	return filterFavorites(a, func(k FavoriteKey) bool { return !in[k] })
+}
+
+// UnionFavorites returns a followed by the favorites of b missing from a
+// When both sides have a key, a's entry wins
+func UnionFavorites(a, b []Favorite) []Favorite {
+	out := filterFavorites(a, func(FavoriteKey) bool { return true })
+	return append(out, DiffFavorites(b, a)...)
+}
+
+func favoriteKeySet(favs []Favorite) map[FavoriteKey]bool {
+	set := make(map[FavoriteKey]bool, len(favs))
+	for _, f := range favs {
+		set[f.Key()] = true
+	}
+	return set
+}
+
+// filterFavorites keeps the first favorite of each key that passes keep
+func filterFavorites(favs []Favorite, keep func(FavoriteKey) bool) []Favorite {
+	out := make([]Favorite, 0, len(favs))
+	seen := make(map[FavoriteKey]bool, len(favs))
+	for _, f := range favs {
+		k := f.Key()
+		if seen[k] || !keep(k) {
+			continue
+		}
+		seen[k] = true
+		out = append(out, f)
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// favs builds favorites from "source:id" pairs, UserID records the position so tests can tell copies apart
+func favs(keys ...string) []Favorite {
+	out := make([]Favorite, 0, len(keys))
+	for i, k := range keys {
+		src, id, _ := strings.Cut(k, ":")
+		out = append(out, Favorite{UserID: int64(i + 1), Source: src, ArtistID: id})
+	}
+	return out
+}
+
+// favRefs lists favorites as "source:id#UserID" so order, keys and which copy was kept are all checked
+func favRefs(fs []Favorite) []string {
+	out := make([]string, 0, len(fs))
+	for _, f := range fs {
+		out = append(out, f.Source+":"+f.ArtistID+"#"+strconv.FormatInt(f.UserID, 10))
+	}
+	return out
+}
+
+func TestFavoriteKey(t *testing.T) {
+	a := Favorite{UserID: 1, Source: "deezer", ArtistID: "27", CreatedAt: time.Now()}
+	b := Favorite{UserID: 2, Source: "deezer", ArtistID: "27"}
+	if a.Key() != b.Key() {
+		t.Fatalf("same artist has keys %v and %v", a.Key(), b.Key())
+	}
+	if want := (FavoriteKey{Source: "deezer", ArtistID: "27"}); a.Key() != want {
+		t.Fatalf("Key = %v, want %v", a.Key(), want)
+	}
+	// The same ID in another source is another artist
+	if c := (Favorite{Source: "spotify", ArtistID: "27"}); c.Key() == a.Key() {
+		t.Fatal("keys ignore the source")
+	}
+}
+
+func TestFavoriteSetOperations(t *testing.T) {
+	tests := []struct {
+		name                   string
+		a, b                   []Favorite
+		intersect, diff, union []string
+	}{
+		{
+			name:      "both empty",
+			intersect: []string{}, diff: []string{}, union: []string{},
+		},
+		{
+			name:      "empty b",
+			a:         favs("groupie:1", "deezer:2"),
+			intersect: []string{},
+			diff:      []string{"groupie:1#1", "deezer:2#2"},
+			union:     []string{"groupie:1#1", "deezer:2#2"},
+		},
+		{
+			name:      "empty a",
+			b:         favs("groupie:1"),
+			intersect: []string{},
+			diff:      []string{},
+			union:     []string{"groupie:1#1"},
+		},
+		{
+			name:      "overlap keeps a's order and entries",
+			a:         favs("groupie:3", "deezer:2", "groupie:1"),
+			b:         favs("groupie:1", "spotify:9", "groupie:3"),
+			intersect: []string{"groupie:3#1", "groupie:1#3"},
+			diff:      []string{"deezer:2#2"},
+			union:     []string{"groupie:3#1", "deezer:2#2", "groupie:1#3", "spotify:9#2"},
+		},
+		{
+			name:      "same id in another source",
+			a:         favs("groupie:1"),
+			b:         favs("deezer:1"),
+			intersect: []string{},
+			diff:      []string{"groupie:1#1"},
+			union:     []string{"groupie:1#1", "deezer:1#1"},
+		},
+		{
+			name:      "repeated keys are kept once",
+			a:         favs("groupie:1", "groupie:1", "deezer:2"),
+			b:         favs("groupie:1", "groupie:1", "apple:5", "apple:5"),
+			intersect: []string{"groupie:1#1"},
+			diff:      []string{"deezer:2#3"},
+			union:     []string{"groupie:1#1", "deezer:2#3", "apple:5#3"},
+		},
+		{
+			name:      "identical sets",
+			a:         favs("groupie:1", "deezer:2"),
+			b:         favs("deezer:2", "groupie:1"),
+			intersect: []string{"groupie:1#1", "deezer:2#2"},
+			diff:      []string{},
+			union:     []string{"groupie:1#1", "deezer:2#2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := favRefs(IntersectFavorites(tt.a, tt.b)); !reflect.DeepEqual(got, tt.intersect) {
+				t.Errorf("IntersectFavorites = %v, want %v", got, tt.intersect)
+			}
+			if got := favRefs(DiffFavorites(tt.a, tt.b)); !reflect.DeepEqual(got, tt.diff) {
+				t.Errorf("DiffFavorites = %v, want %v", got, tt.diff)
+			}
+			if got := favRefs(UnionFavorites(tt.a, tt.b)); !reflect.DeepEqual(got, tt.union) {
+				t.Errorf("UnionFavorites = %v, want %v", got, tt.union)
+			}
+		})
+	}
+}
+
+func TestFavoriteSetOperationsLeaveInputsAlone(t *testing.T) {
+	a := favs("groupie:1", "groupie:1", "deezer:2")
+	b := favs("deezer:2", "apple:3")
+	wantA, wantB := favRefs(a), favRefs(b)
+
+	IntersectFavorites(a, b)
+	DiffFavorites(a, b)
+	UnionFavorites(a, b)
+	if !reflect.DeepEqual(favRefs(a), wantA) || !reflect.DeepEqual(favRefs(b), wantB) {
+		t.Fatalf("inputs changed to %v and %v", favRefs(a), favRefs(b))
+	}
+}
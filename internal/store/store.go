@@ -16,6 +16,8 @@ import (
 
 var ErrNoDatabaseURL = errors.New("database url not set")
 var ErrEmailExists = errors.New("email already exists")
+var ErrReportNotFound = errors.New("report not found")
+var ErrResetTokenInvalid = errors.New("password reset token invalid or expired")
 
 // Store wraps the database connection and basic CRUD helpers
 type Store struct {
@@ -124,12 +126,38 @@ func (s *Store) Migrate(ctx context.Context) error {
 		`CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions(user_id);`,
 		`CREATE TABLE IF NOT EXISTS favorites (
             user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-            source TEXT NOT NULL CHECK (source IN ('groupie','spotify','deezer','apple')),
+            source TEXT NOT NULL CONSTRAINT favorites_source_check ` + favoritesSourceCheck + `,
             artist_id TEXT NOT NULL,
             created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
             PRIMARY KEY (user_id, source, artist_id)
         );`,
 		`CREATE INDEX IF NOT EXISTS favorites_source_idx ON favorites(source);`,
+		// Bumped on every favorites change so sync clients can skip unchanged lists
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS favorites_version BIGINT NOT NULL DEFAULT 0;`,
+		// Preferred artists page layout, empty means the default
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS artists_view TEXT NOT NULL DEFAULT '';`,
+		// User reports about broken artist data, reviewed by maintainers
+		`CREATE TABLE IF NOT EXISTS reports (
+            id BIGSERIAL PRIMARY KEY,
+            source TEXT NOT NULL,
+            artist_id TEXT NOT NULL,
+            issue_type TEXT NOT NULL,
+            note TEXT NOT NULL DEFAULT '',
+            user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+            client_ip TEXT NOT NULL DEFAULT '',
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        );`,
+		`CREATE INDEX IF NOT EXISTS reports_created_at_idx ON reports(created_at DESC);`,
+		// Set when a maintainer marks the report as handled
+		`ALTER TABLE reports ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMPTZ;`,
+		// Single-use password reset links, only the token hash is stored
+		`CREATE TABLE IF NOT EXISTS password_resets (
+            token_hash TEXT PRIMARY KEY,
+            user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            expires_at TIMESTAMPTZ NOT NULL
+        );`,
+		`CREATE INDEX IF NOT EXISTS password_resets_user_id_idx ON password_resets(user_id);`,
 	}
 
 	for _, stmt := range statements {
@@ -137,10 +165,76 @@ func (s *Store) Migrate(ctx context.Context) error {
 			return fmt.Errorf("migrate: %w", err)
 		}
 	}
+	if err := s.migrateFavoritesSourceCheck(ctx); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
 
 	return nil
 }
 
+// favoritesSourceCheck lists the sources a favorite can belong to
+const favoritesSourceCheck = `CHECK (source IN ('groupie','spotify','deezer','apple','musicbrainz'))`
+
+// migrateFavoritesSourceCheck widens the source check of databases created before a source
+// was added. Replacing a constraint locks the whole table, so it's only done when the
+// allowed sources differ from favoritesSourceCheck
+func (s *Store) migrateFavoritesSourceCheck(ctx context.Context) error {
+	var def string
+	err := s.DB.QueryRowContext(ctx, `
+        SELECT pg_get_constraintdef(oid)
+        FROM pg_constraint
+        WHERE conrelid = 'favorites'::regclass AND conname = 'favorites_source_check'
+    `).Scan(&def)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil && sameQuotedValues(def, favoritesSourceCheck) {
+		return nil
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+        ALTER TABLE favorites DROP CONSTRAINT IF EXISTS favorites_source_check
+    `); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+        ALTER TABLE favorites ADD CONSTRAINT favorites_source_check `+favoritesSourceCheck); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+var quotedValueRe = regexp.MustCompile(`'((?:[^']|'')*)'`)
+
+// sameQuotedValues reports whether a and b quote the same set of SQL string literals,
+// so `IN ('a','b')` matches PostgreSQL's own `(source = ANY (ARRAY['a'::text, 'b'::text]))`
+func sameQuotedValues(a, b string) bool {
+	set := func(s string) map[string]bool {
+		out := make(map[string]bool)
+		for _, m := range quotedValueRe.FindAllStringSubmatch(s, -1) {
+			out[m[1]] = true
+		}
+		return out
+	}
+
+	sa, sb := set(a), set(b)
+	if len(sa) != len(sb) {
+		return false
+	}
+	for v := range sa {
+		if !sb[v] {
+			return false
+		}
+	}
+	return true
+}
+
 // User represents an account in the database
 type User struct {
 	ID           int64
@@ -158,6 +252,14 @@ type Session struct {
 	ExpiresAt time.Time
 }
 
+// PasswordReset is a pending password reset link
+type PasswordReset struct {
+	TokenHash string
+	UserID    int64
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
 // Favorite represents a user's saved artist for a given source
 type Favorite struct {
 	UserID    int64
@@ -166,6 +268,36 @@ type Favorite struct {
 	CreatedAt time.Time
 }
 
+// Report is a user-submitted note about wrong or missing artist data
+type Report struct {
+	ID        int64
+	Source    string
+	ArtistID  string
+	IssueType string
+	Note      string
+	// UserID is 0 for anonymous reports
+	UserID    int64
+	ClientIP  string
+	CreatedAt time.Time
+	// ResolvedAt is nil while the report is open
+	ResolvedAt *time.Time
+}
+
+// Report statuses accepted by ReportFilter
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusResolved = "resolved"
+)
+
+// ReportFilter narrows ListReports, empty fields match everything
+type ReportFilter struct {
+	IssueType string
+	// Status is ReportStatusOpen, ReportStatusResolved or "" for both
+	Status string
+	Limit  int
+	Offset int
+}
+
 // CreateUser inserts a new user, returning ErrEmailExists on duplicates
 func (s *Store) CreateUser(ctx context.Context, email, passwordHash string) (*User, error) {
 	if s == nil || s.DB == nil {
@@ -284,6 +416,247 @@ func (s *Store) DeleteSessionByTokenHash(ctx context.Context, tokenHash string)
 	return err
 }
 
+// DeleteUser removes an account, its sessions, favorites and reset links go with it
+// (ON DELETE CASCADE) and its reports are kept without the user
+func (s *Store) DeleteUser(ctx context.Context, userID int64) error {
+	if s == nil || s.DB == nil {
+		return errors.New("store not initialized")
+	}
+
+	res, err := s.DB.ExecContext(ctx, `
+        DELETE FROM users WHERE id = $1
+    `, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteUserSessions logs a user out of every device
+func (s *Store) DeleteUserSessions(ctx context.Context, userID int64) error {
+	if s == nil || s.DB == nil {
+		return errors.New("store not initialized")
+	}
+
+	_, err := s.DB.ExecContext(ctx, `
+        DELETE FROM sessions WHERE user_id = $1
+    `, userID)
+	return err
+}
+
+// ListSessionsByUserID returns a user's sessions that haven't expired, newest first
+func (s *Store) ListSessionsByUserID(ctx context.Context, userID int64) ([]Session, error) {
+	if s == nil || s.DB == nil {
+		return nil, errors.New("store not initialized")
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+        SELECT id, user_id, token_hash, created_at, expires_at
+        FROM sessions
+        WHERE user_id = $1 AND expires_at > NOW()
+        ORDER BY created_at DESC, id DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Session
+	for rows.Next() {
+		var sess Session
+		if err := rows.Scan(&sess.ID, &sess.UserID, &sess.TokenHash, &sess.CreatedAt, &sess.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, sess)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// DeleteExpiredSessions removes every session past its expiry and returns how many went
+func (s *Store) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	if s == nil || s.DB == nil {
+		return 0, errors.New("store not initialized")
+	}
+
+	res, err := s.DB.ExecContext(ctx, `
+        DELETE FROM sessions WHERE expires_at < NOW()
+    `)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteSessionsByUserIDExcept logs a user out of every device but the session with keepTokenHash
+func (s *Store) DeleteSessionsByUserIDExcept(ctx context.Context, userID int64, keepTokenHash string) error {
+	if s == nil || s.DB == nil {
+		return errors.New("store not initialized")
+	}
+
+	_, err := s.DB.ExecContext(ctx, `
+        DELETE FROM sessions WHERE user_id = $1 AND token_hash <> $2
+    `, userID, keepTokenHash)
+	return err
+}
+
+// UpdateUserPassword replaces a user's password hash
+func (s *Store) UpdateUserPassword(ctx context.Context, userID int64, passwordHash string) error {
+	if s == nil || s.DB == nil {
+		return errors.New("store not initialized")
+	}
+
+	res, err := s.DB.ExecContext(ctx, `
+        UPDATE users SET password_hash = $2 WHERE id = $1
+    `, userID, passwordHash)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreatePasswordReset stores a reset token hash for a user
+// Expired tokens of every user are dropped on the way, so the table stays small
+func (s *Store) CreatePasswordReset(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	if s == nil || s.DB == nil {
+		return errors.New("store not initialized")
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `
+        DELETE FROM password_resets WHERE expires_at <= NOW()
+    `); err != nil {
+		return err
+	}
+	_, err := s.DB.ExecContext(ctx, `
+        INSERT INTO password_resets (token_hash, user_id, expires_at)
+        VALUES ($1, $2, $3)
+    `, tokenHash, userID, expiresAt)
+	return err
+}
+
+// GetPasswordReset fetches a reset that is still valid at now, ErrResetTokenInvalid otherwise
+func (s *Store) GetPasswordReset(ctx context.Context, tokenHash string, now time.Time) (*PasswordReset, error) {
+	if s == nil || s.DB == nil {
+		return nil, errors.New("store not initialized")
+	}
+
+	var pr PasswordReset
+	err := s.DB.QueryRowContext(ctx, `
+        SELECT token_hash, user_id, created_at, expires_at
+        FROM password_resets
+        WHERE token_hash = $1
+    `, tokenHash).Scan(&pr.TokenHash, &pr.UserID, &pr.CreatedAt, &pr.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrResetTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !now.Before(pr.ExpiresAt) {
+		return nil, ErrResetTokenInvalid
+	}
+	return &pr, nil
+}
+
+// ConsumePasswordReset uses up a reset token and returns its user
+// Every other pending reset of that user is dropped too, so older links stop working
+// An expired token is deleted and reported as ErrResetTokenInvalid
+func (s *Store) ConsumePasswordReset(ctx context.Context, tokenHash string, now time.Time) (int64, error) {
+	return s.consumePasswordReset(ctx, tokenHash, now, nil)
+}
+
+// ResetPassword uses up a reset token like ConsumePasswordReset and, in the same transaction,
+// sets the user's new password hash and ends all their sessions
+// Either everything happens or nothing does, a token is never spent without the password changing
+func (s *Store) ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (int64, error) {
+	return s.consumePasswordReset(ctx, tokenHash, now, func(tx *sql.Tx, userID int64) error {
+		res, err := tx.ExecContext(ctx, `
+            UPDATE users SET password_hash = $2 WHERE id = $1
+        `, userID, passwordHash)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return sql.ErrNoRows
+		}
+
+		// Whoever knew the old password is logged out, including other devices
+		_, err = tx.ExecContext(ctx, `
+            DELETE FROM sessions WHERE user_id = $1
+        `, userID)
+		return err
+	})
+}
+
+// consumePasswordReset deletes a valid token and the user's other resets, then runs apply
+// (when set) before committing
+func (s *Store) consumePasswordReset(ctx context.Context, tokenHash string, now time.Time, apply func(tx *sql.Tx, userID int64) error) (int64, error) {
+	if s == nil || s.DB == nil {
+		return 0, errors.New("store not initialized")
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var userID int64
+	var expiresAt time.Time
+	err = tx.QueryRowContext(ctx, `
+        DELETE FROM password_resets
+        WHERE token_hash = $1
+        RETURNING user_id, expires_at
+    `, tokenHash).Scan(&userID, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrResetTokenInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !now.Before(expiresAt) {
+		// Keep the delete, an expired token is no use to anyone
+		if err := tx.Commit(); err != nil {
+			return 0, err
+		}
+		return 0, ErrResetTokenInvalid
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        DELETE FROM password_resets WHERE user_id = $1
+    `, userID); err != nil {
+		return 0, err
+	}
+	if apply != nil {
+		if err := apply(tx, userID); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
 // ListFavoriteIDsBySource returns artist IDs for a user and source
 func (s *Store) ListFavoriteIDsBySource(ctx context.Context, userID int64, source string) ([]string, error) {
 	if s == nil || s.DB == nil {
@@ -370,12 +743,19 @@ func (s *Store) IsFavorite(ctx context.Context, userID int64, source, artistID s
 }
 
 // ToggleFavorite inserts or removes a favorite and returns true if added
+// Both paths bump the user's favorites version in the same transaction
 func (s *Store) ToggleFavorite(ctx context.Context, userID int64, source, artistID string) (bool, error) {
 	if s == nil || s.DB == nil {
 		return false, errors.New("store not initialized")
 	}
 
-	res, err := s.DB.ExecContext(ctx, `
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
         DELETE FROM favorites
         WHERE user_id = $1 AND source = $2 AND artist_id = $3
     `, userID, source, artistID)
@@ -383,17 +763,218 @@ func (s *Store) ToggleFavorite(ctx context.Context, userID int64, source, artist
 		return false, err
 	}
 
-	if rows, _ := res.RowsAffected(); rows > 0 {
-		return false, nil
+	added := false
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO favorites (user_id, source, artist_id)
+            VALUES ($1, $2, $3)
+        `, userID, source, artistID)
+		if err != nil {
+			return false, err
+		}
+		added = true
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE users SET favorites_version = favorites_version + 1 WHERE id = $1
+    `, userID); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return added, nil
+}
+
+// AddFavorites saves several artists of one source in a single insert and returns how many were new
+// IDs already saved are skipped, the favorites version is bumped only when something was added
+func (s *Store) AddFavorites(ctx context.Context, userID int64, source string, artistIDs []string) (int, error) {
+	if s == nil || s.DB == nil {
+		return 0, errors.New("store not initialized")
+	}
+	if len(artistIDs) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
 	}
+	defer tx.Rollback()
 
-	_, err = s.DB.ExecContext(ctx, `
+	res, err := tx.ExecContext(ctx, `
         INSERT INTO favorites (user_id, source, artist_id)
-        VALUES ($1, $2, $3)
-    `, userID, source, artistID)
+        SELECT $1, $2, artist_id
+        FROM unnest($3::text[]) AS artist_id
+        ON CONFLICT DO NOTHING
+    `, userID, source, pq.Array(artistIDs))
 	if err != nil {
-		return false, err
+		return 0, err
+	}
+
+	added, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if added > 0 {
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE users SET favorites_version = favorites_version + 1 WHERE id = $1
+        `, userID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(added), nil
+}
+
+// GetFavoritesVersion returns the counter bumped by every favorites change, 0 for untouched accounts
+func (s *Store) GetFavoritesVersion(ctx context.Context, userID int64) (int64, error) {
+	if s == nil || s.DB == nil {
+		return 0, errors.New("store not initialized")
+	}
+
+	var version int64
+	err := s.DB.QueryRowContext(ctx, `
+        SELECT favorites_version
+        FROM users
+        WHERE id = $1
+    `, userID).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// GetArtistsView returns the user's saved artists page layout, "" when never chosen
+func (s *Store) GetArtistsView(ctx context.Context, userID int64) (string, error) {
+	if s == nil || s.DB == nil {
+		return "", errors.New("store not initialized")
 	}
 
-	return true, nil
+	var view string
+	err := s.DB.QueryRowContext(ctx, `
+        SELECT artists_view
+        FROM users
+        WHERE id = $1
+    `, userID).Scan(&view)
+	if err != nil {
+		return "", err
+	}
+	return view, nil
+}
+
+// SetArtistsView saves the user's artists page layout, callers validate the value
+func (s *Store) SetArtistsView(ctx context.Context, userID int64, view string) error {
+	if s == nil || s.DB == nil {
+		return errors.New("store not initialized")
+	}
+
+	_, err := s.DB.ExecContext(ctx, `
+        UPDATE users
+        SET artists_view = $2
+        WHERE id = $1
+    `, userID, view)
+	return err
+}
+
+// CreateReport stores a report and returns its ID, callers validate the fields
+func (s *Store) CreateReport(ctx context.Context, rep Report) (int64, error) {
+	if s == nil || s.DB == nil {
+		return 0, errors.New("store not initialized")
+	}
+
+	var userID sql.NullInt64
+	if rep.UserID > 0 {
+		userID = sql.NullInt64{Int64: rep.UserID, Valid: true}
+	}
+
+	var id int64
+	err := s.DB.QueryRowContext(ctx, `
+        INSERT INTO reports (source, artist_id, issue_type, note, user_id, client_ip)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id
+    `, rep.Source, rep.ArtistID, rep.IssueType, rep.Note, userID, rep.ClientIP).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ListReports returns one page of reports (newest first) and the number matching the filter
+func (s *Store) ListReports(ctx context.Context, f ReportFilter) ([]Report, int, error) {
+	if s == nil || s.DB == nil {
+		return nil, 0, errors.New("store not initialized")
+	}
+
+	where := "WHERE ($1 = '' OR issue_type = $1)"
+	switch f.Status {
+	case ReportStatusOpen:
+		where += " AND resolved_at IS NULL"
+	case ReportStatusResolved:
+		where += " AND resolved_at IS NOT NULL"
+	}
+
+	var total int
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM reports `+where, f.IssueType).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+        SELECT id, source, artist_id, issue_type, note, COALESCE(user_id, 0), client_ip, created_at, resolved_at
+        FROM reports
+        `+where+`
+        ORDER BY created_at DESC, id DESC
+        LIMIT $2 OFFSET $3
+    `, f.IssueType, f.Limit, f.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []Report
+	for rows.Next() {
+		var rep Report
+		var resolved sql.NullTime
+		if err := rows.Scan(&rep.ID, &rep.Source, &rep.ArtistID, &rep.IssueType, &rep.Note, &rep.UserID, &rep.ClientIP, &rep.CreatedAt, &resolved); err != nil {
+			return nil, 0, err
+		}
+		if resolved.Valid {
+			t := resolved.Time
+			rep.ResolvedAt = &t
+		}
+		out = append(out, rep)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+// ResolveReport marks a report as handled, or reopens it when resolved is false
+func (s *Store) ResolveReport(ctx context.Context, id int64, resolved bool) error {
+	if s == nil || s.DB == nil {
+		return errors.New("store not initialized")
+	}
+
+	res, err := s.DB.ExecContext(ctx, `
+        UPDATE reports
+        SET resolved_at = CASE WHEN $2 THEN COALESCE(resolved_at, NOW()) ELSE NULL END
+        WHERE id = $1
+    `, id, resolved)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrReportNotFound
+	}
+	return nil
 }
@@ -0,0 +1,547 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSchema keeps these tests away from the handlers tests sharing the same database
+const testSchema = "store_test"
+
+// openTestStore connects to TEST_DATABASE_URL with a freshly migrated, empty schema
+// The tests are skipped when the variable isn't set, they need a real PostgreSQL
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	dsn := strings.TrimSpace(os.Getenv("TEST_DATABASE_URL"))
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	dsn = normalizePostgresDSN(dsn)
+	ctx := context.Background()
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	for _, stmt := range []string{
+		`DROP SCHEMA IF EXISTS ` + testSchema + ` CASCADE`,
+		`CREATE SCHEMA ` + testSchema,
+	} {
+		if _, err := admin.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := sql.Open("postgres", withSearchPath(dsn, testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	s := &Store{DB: db}
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// withSearchPath adds a search_path connection parameter to either DSN form
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
+
+func newTestUser(t *testing.T, s *Store, email string) *User {
+	t.Helper()
+	u, err := s.CreateUser(context.Background(), email, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func newTestSession(t *testing.T, s *Store, userID int64, tokenHash string, expiresAt time.Time) {
+	t.Helper()
+	if _, err := s.CreateSession(context.Background(), userID, tokenHash, expiresAt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sessionExists(t *testing.T, s *Store, tokenHash string) bool {
+	t.Helper()
+	_, err := s.GetSessionByTokenHash(context.Background(), tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return true
+}
+
+func TestUpdateUserPassword(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	u := newTestUser(t, s, "change@example.com")
+
+	if err := s.UpdateUserPassword(ctx, u.ID, "new-hash"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetUserByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PasswordHash != "new-hash" {
+		t.Fatalf("PasswordHash = %q, want %q", got.PasswordHash, "new-hash")
+	}
+
+	if err := s.UpdateUserPassword(ctx, u.ID+1000, "x"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("unknown user: err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestListSessionsByUserID(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a := newTestUser(t, s, "list@example.com")
+	b := newTestUser(t, s, "other@example.com")
+	later := time.Now().Add(time.Hour)
+
+	newTestSession(t, s, a.ID, "oldest", later)
+	newTestSession(t, s, a.ID, "expired", time.Now().Add(-time.Minute))
+	newTestSession(t, s, a.ID, "middle", later)
+	newTestSession(t, s, b.ID, "someone-else", later)
+	newTestSession(t, s, a.ID, "newest", later)
+
+	sessions, err := s.ListSessionsByUserID(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Newest first, expired and other users' sessions left out
+	var got []string
+	for _, sess := range sessions {
+		if sess.UserID != a.ID {
+			t.Errorf("session %s belongs to user %d", sess.TokenHash, sess.UserID)
+		}
+		got = append(got, sess.TokenHash)
+	}
+	if want := []string{"newest", "middle", "oldest"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("sessions = %v, want %v", got, want)
+	}
+
+	none, err := s.ListSessionsByUserID(ctx, a.ID+1000)
+	if err != nil || len(none) != 0 {
+		t.Fatalf("unknown user: sessions = %v, err = %v", none, err)
+	}
+}
+
+func TestDeleteSessionsByUserIDExcept(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	a := newTestUser(t, s, "a@example.com")
+	b := newTestUser(t, s, "b@example.com")
+	later := time.Now().Add(time.Hour)
+	for _, h := range []string{"a1", "a2", "a3"} {
+		newTestSession(t, s, a.ID, h, later)
+	}
+	newTestSession(t, s, b.ID, "b1", later)
+
+	if err := s.DeleteSessionsByUserIDExcept(ctx, a.ID, "a2"); err != nil {
+		t.Fatal(err)
+	}
+	for h, want := range map[string]bool{"a1": false, "a2": true, "a3": false, "b1": true} {
+		if got := sessionExists(t, s, h); got != want {
+			t.Errorf("session %s exists = %v, want %v", h, got, want)
+		}
+	}
+}
+
+func TestDeleteExpiredSessions(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	u := newTestUser(t, s, "purge@example.com")
+	now := time.Now()
+
+	newTestSession(t, s, u.ID, "expired1", now.Add(-time.Hour))
+	newTestSession(t, s, u.ID, "expired2", now.Add(-30*24*time.Hour))
+	newTestSession(t, s, u.ID, "valid1", now.Add(time.Hour))
+	newTestSession(t, s, u.ID, "valid2", now.Add(30*24*time.Hour))
+
+	n, err := s.DeleteExpiredSessions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("deleted %d sessions, want 2", n)
+	}
+	for h, want := range map[string]bool{"expired1": false, "expired2": false, "valid1": true, "valid2": true} {
+		if got := sessionExists(t, s, h); got != want {
+			t.Errorf("session %s exists = %v, want %v", h, got, want)
+		}
+	}
+
+	// Nothing left to purge
+	if n, err := s.DeleteExpiredSessions(ctx); err != nil || n != 0 {
+		t.Fatalf("second purge deleted %d, err = %v, want 0", n, err)
+	}
+}
+
+func TestDeleteUserCascades(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	gone := newTestUser(t, s, "gone@example.com")
+	kept := newTestUser(t, s, "kept@example.com")
+	later := time.Now().Add(time.Hour)
+
+	newTestSession(t, s, gone.ID, "gone1", later)
+	newTestSession(t, s, gone.ID, "gone2", later)
+	newTestSession(t, s, kept.ID, "kept1", later)
+	for _, u := range []*User{gone, kept} {
+		if _, err := s.AddFavorites(ctx, u.ID, "groupie", []string{"1", "2"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.CreatePasswordReset(ctx, gone.ID, "gone-reset", later); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteUser(ctx, gone.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetUserByID(ctx, gone.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetUserByID err = %v, want sql.ErrNoRows", err)
+	}
+	for h, want := range map[string]bool{"gone1": false, "gone2": false, "kept1": true} {
+		if got := sessionExists(t, s, h); got != want {
+			t.Errorf("session %s exists = %v, want %v", h, got, want)
+		}
+	}
+	if favs, err := s.ListFavorites(ctx, gone.ID); err != nil || len(favs) != 0 {
+		t.Errorf("deleted user's favorites = %v, err = %v, want none", favs, err)
+	}
+	if favs, err := s.ListFavorites(ctx, kept.ID); err != nil || len(favs) != 2 {
+		t.Errorf("other user's favorites = %v, err = %v, want 2", favs, err)
+	}
+	if _, err := s.GetPasswordReset(ctx, "gone-reset", time.Now()); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("reset link err = %v, want ErrResetTokenInvalid", err)
+	}
+
+	// The address can be registered again
+	newTestUser(t, s, "gone@example.com")
+
+	if err := s.DeleteUser(ctx, gone.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("second delete err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestPasswordResetSingleUse(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	u := newTestUser(t, s, "reset@example.com")
+	now := time.Now()
+
+	if err := s.CreatePasswordReset(ctx, u.ID, "first", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreatePasswordReset(ctx, u.ID, "second", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	pr, err := s.GetPasswordReset(ctx, "second", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.UserID != u.ID {
+		t.Fatalf("UserID = %d, want %d", pr.UserID, u.ID)
+	}
+
+	userID, err := s.ConsumePasswordReset(ctx, "second", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != u.ID {
+		t.Fatalf("consumed UserID = %d, want %d", userID, u.ID)
+	}
+
+	// The used token and the older one of the same user both stop working
+	for _, h := range []string{"second", "first"} {
+		if _, err := s.GetPasswordReset(ctx, h, now); !errors.Is(err, ErrResetTokenInvalid) {
+			t.Errorf("GetPasswordReset(%s) err = %v, want ErrResetTokenInvalid", h, err)
+		}
+		if _, err := s.ConsumePasswordReset(ctx, h, now); !errors.Is(err, ErrResetTokenInvalid) {
+			t.Errorf("ConsumePasswordReset(%s) err = %v, want ErrResetTokenInvalid", h, err)
+		}
+	}
+}
+
+func TestPasswordResetExpiry(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	u := newTestUser(t, s, "expired@example.com")
+	expiresAt := time.Now().Add(time.Hour)
+
+	if err := s.CreatePasswordReset(ctx, u.ID, "token", expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetPasswordReset(ctx, "token", expiresAt.Add(-time.Second)); err != nil {
+		t.Fatalf("before expiry: %v", err)
+	}
+	if _, err := s.GetPasswordReset(ctx, "token", expiresAt); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Fatalf("at expiry: err = %v, want ErrResetTokenInvalid", err)
+	}
+
+	if _, err := s.ConsumePasswordReset(ctx, "token", expiresAt.Add(time.Second)); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Fatalf("consume after expiry: err = %v, want ErrResetTokenInvalid", err)
+	}
+	// The expired token is deleted on the way, it can't be used even with an earlier clock
+	if _, err := s.ConsumePasswordReset(ctx, "token", expiresAt.Add(-time.Minute)); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Fatalf("expired token still stored: err = %v, want ErrResetTokenInvalid", err)
+	}
+}
+
+func TestPasswordResetUnknownToken(t *testing.T) {
+	s := openTestStore(t)
+
+	if _, err := s.GetPasswordReset(context.Background(), "missing", time.Now()); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Fatalf("err = %v, want ErrResetTokenInvalid", err)
+	}
+}
+
+func TestSameQuotedValues(t *testing.T) {
+	// What PostgreSQL's pg_get_constraintdef gives back for favoritesSourceCheck
+	stored := `CHECK ((source = ANY (ARRAY['groupie'::text, 'spotify'::text, 'deezer'::text, 'apple'::text, 'musicbrainz'::text])))`
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"stored form", stored, favoritesSourceCheck, true},
+		{"order ignored", `IN ('b','a')`, `IN ('a','b')`, true},
+		{"missing value", `CHECK ((source = ANY (ARRAY['groupie'::text, 'spotify'::text, 'deezer'::text, 'apple'::text])))`, favoritesSourceCheck, false},
+		{"extra value", `IN ('a','b','c')`, `IN ('a','b')`, false},
+		{"escaped quote", `IN ('it''s')`, `IN ('it''s')`, true},
+		{"nothing quoted", `CHECK (true)`, `IN ('a')`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameQuotedValues(tt.a, tt.b); got != tt.want {
+				t.Fatalf("sameQuotedValues = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// favoritesSourceCheckOID identifies the current constraint, a new OID means it was recreated
+func favoritesSourceCheckOID(t *testing.T, s *Store) int64 {
+	t.Helper()
+	var oid int64
+	if err := s.DB.QueryRowContext(context.Background(), `
+        SELECT oid::bigint FROM pg_constraint
+        WHERE conrelid = 'favorites'::regclass AND conname = 'favorites_source_check'
+    `).Scan(&oid); err != nil {
+		t.Fatal(err)
+	}
+	return oid
+}
+
+func TestMigrateFavoritesSourceCheck(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	before := favoritesSourceCheckOID(t, s)
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if after := favoritesSourceCheckOID(t, s); after != before {
+		t.Fatal("an up to date source check was recreated")
+	}
+
+	// A database from before MusicBrainz gets its check widened
+	for _, stmt := range []string{
+		`ALTER TABLE favorites DROP CONSTRAINT favorites_source_check`,
+		`ALTER TABLE favorites ADD CONSTRAINT favorites_source_check CHECK (source IN ('groupie','spotify','deezer','apple'))`,
+	} {
+		if _, err := s.DB.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	u := newTestUser(t, s, "mb@example.com")
+	if _, err := s.ToggleFavorite(ctx, u.ID, "musicbrainz", "5b11f4ce-a62d-471e-81fc-a69a8278c7da"); err != nil {
+		t.Fatalf("musicbrainz favorite rejected after migrate: %v", err)
+	}
+	if _, err := s.ToggleFavorite(ctx, u.ID, "napster", "1"); err == nil {
+		t.Fatal("unknown source accepted")
+	}
+}
+
+func TestAddFavorites(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	u := newTestUser(t, s, "bulk@example.com")
+
+	added, err := s.AddFavorites(ctx, u.ID, "groupie", []string{"1", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 {
+		t.Fatalf("added = %d, want 2", added)
+	}
+	version, err := s.GetFavoritesVersion(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Already saved IDs are skipped, and nothing new leaves the version alone
+	added, err = s.AddFavorites(ctx, u.ID, "groupie", []string{"2", "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 0 {
+		t.Fatalf("re-adding: added = %d, want 0", added)
+	}
+	if v, _ := s.GetFavoritesVersion(ctx, u.ID); v != version {
+		t.Fatalf("version moved from %d to %d without a change", version, v)
+	}
+
+	added, err = s.AddFavorites(ctx, u.ID, "groupie", []string{"2", "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 {
+		t.Fatalf("added = %d, want 1", added)
+	}
+	if v, _ := s.GetFavoritesVersion(ctx, u.ID); v <= version {
+		t.Fatalf("version = %d, want above %d", v, version)
+	}
+
+	if added, err := s.AddFavorites(ctx, u.ID, "groupie", nil); err != nil || added != 0 {
+		t.Fatalf("empty list: added = %d, err = %v", added, err)
+	}
+	if _, err := s.AddFavorites(ctx, u.ID, "napster", []string{"1"}); err == nil {
+		t.Fatal("unknown source accepted")
+	}
+}
+
+func TestToggleFavoriteBumpsVersion(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	u := newTestUser(t, s, "toggle@example.com")
+
+	version, err := s.GetFavoritesVersion(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 0 {
+		t.Fatalf("untouched account version = %d, want 0", version)
+	}
+
+	// Adding and removing both move the version, so a client can't miss either
+	for _, wantAdded := range []bool{true, false, true} {
+		added, err := s.ToggleFavorite(ctx, u.ID, "deezer", "27")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != wantAdded {
+			t.Fatalf("added = %v, want %v", added, wantAdded)
+		}
+		v, err := s.GetFavoritesVersion(ctx, u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v <= version {
+			t.Fatalf("added = %v: version = %d, want above %d", added, v, version)
+		}
+		version = v
+	}
+
+	// Another user's changes leave this version alone
+	other := newTestUser(t, s, "toggle-other@example.com")
+	if _, err := s.ToggleFavorite(ctx, other.ID, "deezer", "27"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.GetFavoritesVersion(ctx, u.ID); v != version {
+		t.Fatalf("version moved from %d to %d on another user's change", version, v)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	u := newTestUser(t, s, "reset-tx@example.com")
+	now := time.Now()
+
+	if err := s.CreatePasswordReset(ctx, u.ID, "token", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateSession(ctx, u.ID, "session", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	userID, err := s.ResetPassword(ctx, "token", "new-hash", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userID != u.ID {
+		t.Fatalf("UserID = %d, want %d", userID, u.ID)
+	}
+	got, err := s.GetUserByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PasswordHash != "new-hash" {
+		t.Fatalf("PasswordHash = %q, want new-hash", got.PasswordHash)
+	}
+	if _, err := s.GetSessionByTokenHash(ctx, "session"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("session after reset: err = %v, want sql.ErrNoRows", err)
+	}
+
+	// The token is spent, a second reset changes nothing
+	if _, err := s.ResetPassword(ctx, "token", "other-hash", now); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Fatalf("reuse: err = %v, want ErrResetTokenInvalid", err)
+	}
+	if got, _ := s.GetUserByID(ctx, u.ID); got.PasswordHash != "new-hash" {
+		t.Fatalf("PasswordHash = %q after a spent token, want new-hash", got.PasswordHash)
+	}
+}
+
+func TestResetPasswordExpired(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	u := newTestUser(t, s, "reset-expired@example.com")
+	expiresAt := time.Now().Add(time.Hour)
+
+	if err := s.CreatePasswordReset(ctx, u.ID, "token", expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	before, err := s.GetUserByID(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ResetPassword(ctx, "token", "new-hash", expiresAt); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Fatalf("err = %v, want ErrResetTokenInvalid", err)
+	}
+	if got, _ := s.GetUserByID(ctx, u.ID); got.PasswordHash != before.PasswordHash {
+		t.Fatal("an expired token changed the password")
+	}
+}
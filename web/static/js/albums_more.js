@@ -0,0 +1,60 @@
+(function () { // IIFE to avoid leaking globals
+    // "Show more" loader for detail page albums, appends cards from /artists/{id}/albums
+    const buttons = document.querySelectorAll("[data-albums-more]");
+    if (!buttons.length) return;
+
+    // toInt parses an int safely and returns 0 for invalid values
+    function toInt(v) {
+        const n = parseInt(v, 10);
+        return Number.isFinite(n) ? n : 0;
+    }
+
+    // loadMore fetches the next page of cards for one source and appends them
+    function loadMore(btn) {
+        const source = btn.getAttribute("data-albums-more") || "";
+        const target = document.querySelector('[data-albums-more-target="' + source + '"]');
+        const baseUrl = btn.getAttribute("data-albums-url") || "";
+        if (!target || !baseUrl) return;
+
+        const offset = toInt(btn.getAttribute("data-albums-offset"));
+        const limit = toInt(btn.getAttribute("data-albums-limit"));
+        const url = baseUrl + "&offset=" + encodeURIComponent(String(offset)) + "&limit=" + encodeURIComponent(String(limit));
+
+        const label = btn.textContent;
+        btn.disabled = true;
+        btn.textContent = "Loading...";
+
+        fetch(url, {
+            headers: {
+                "Accept": "text/html"
+            }
+        })
+            .then(function (res) { // first stage checks HTTP status and paging headers
+                if (!res.ok) {
+                    throw new Error("request failed " + res.status);
+                }
+                const hasMore = res.headers.get("X-Has-More") === "1";
+                const nextOffset = toInt(res.headers.get("X-Next-Offset"));
+                return res.text().then(function (html) {
+                    return { html: html, hasMore: hasMore, nextOffset: nextOffset };
+                });
+            })
+            .then(function (page) { // second stage appends the HTML fragment
+                target.insertAdjacentHTML("beforeend", page.html);
+                btn.setAttribute("data-albums-offset", String(page.nextOffset || offset));
+                btn.disabled = false;
+                btn.textContent = label;
+                if (!page.hasMore) btn.remove();
+            })
+            .catch(function () { // keep the button so the user can retry
+                btn.disabled = false;
+                btn.textContent = label;
+            });
+    }
+
+    buttons.forEach(function (btn) { // per-button setup
+        btn.addEventListener("click", function () {
+            loadMore(btn);
+        });
+    });
+})();
@@ -11,6 +11,17 @@
     }
   }
 
+  let sharedByDay = {};
+  const sharedEl = document.getElementById("artist_shared_concerts_json");
+  if (sharedEl) {
+    try {
+      // YYYY-MM-DD -> other artists playing that day, built server-side from the relations
+      sharedByDay = JSON.parse(sharedEl.textContent || "{}") || {};
+    } catch {
+      sharedByDay = {};
+    }
+  }
+
   renderTimeline(locations);
   bindMapControls();
 
@@ -398,6 +409,7 @@
         p.className = "mt-2 text-xs text-slate-600 dark:text-slate-400";
         p.textContent = "No mappable location details for this day.";
         info.appendChild(p);
+        appendShared(iso);
         return;
       }
 
@@ -429,8 +441,45 @@
         ul.appendChild(li);
       }
       info.appendChild(ul);
+      appendShared(iso);
     }
 
+    // appendShared lists the other artists playing on iso, with links to their pages
+    function appendShared(iso) {
+      const shared = Array.isArray(sharedByDay[iso]) ? sharedByDay[iso] : [];
+      if (shared.length === 0) return;
+
+      const heading = document.createElement("div");
+      heading.className = "mt-3 text-xs font-semibold text-slate-900 dark:text-slate-200";
+      heading.textContent = "Also playing that day";
+      info.appendChild(heading);
+
+      const ul = document.createElement("ul");
+      ul.className = "mt-1 space-y-1";
+      for (const s of shared) {
+        const li = document.createElement("li");
+        li.className = "text-xs text-slate-600 dark:text-slate-400";
+
+        const a = document.createElement("a");
+        a.href = String(s.url || "");
+        a.className = "font-medium text-slate-900 hover:text-emerald-500 transition-colors dark:text-slate-200";
+        a.textContent = String(s.name || "");
+        li.appendChild(a);
+        li.appendChild(document.createTextNode(s.sameLocation ? ` (same venue, ${s.location})` : ` (${s.location})`));
+        ul.appendChild(li);
+      }
+      info.appendChild(ul);
+    }
+
+    // Let map popups open a day in the timeline panel
+    const state = window.__groupieTracker || (window.__groupieTracker = {});
+    state.selectConcertDate = (raw) => {
+      const parsed = parseConcertDate(String(raw || "").replace(/^\*/, ""));
+      if (!parsed) return;
+      selectDay(parsed.iso);
+      timelineEl.scrollIntoView({ behavior: "smooth", block: "nearest" });
+    };
+
     function focusMap(lat, lng) {
       const state = window.__groupieTracker || {};
       const map = state.leafletMap;
@@ -47,6 +47,8 @@
 
     const name = String(loc.name || "").trim() || "Unknown location";
     const dates = Array.isArray(loc.dates) ? loc.dates : [];
+    // Labels are the display forms of dates, in the same order
+    const labels = Array.isArray(loc.labels) ? loc.labels : [];
 
     // Build popup content with DOM nodes to avoid HTML injection
     const popup = document.createElement("div");
@@ -58,16 +60,26 @@
     if (dates.length > 0) {
       const ul = document.createElement("ul");
       ul.className = "mt-1 list-disc pl-5 text-xs";
-      for (const d of dates) {
+      dates.forEach((d, i) => {
         const li = document.createElement("li");
-        li.textContent = String(d);
+        // Dates open the timeline panel, which lists other artists playing that day
+        const btn = document.createElement("button");
+        btn.type = "button";
+        btn.className = "cursor-pointer text-emerald-600 hover:text-emerald-500";
+        btn.textContent = String(labels[i] || d);
+        btn.addEventListener("click", () => {
+          if (typeof state.selectConcertDate === "function") state.selectConcertDate(d);
+        });
+        li.appendChild(btn);
         ul.appendChild(li);
-      }
+      });
       popup.appendChild(ul);
     }
 
+    // Locations with only past concerts are dimmed so upcoming ones stand out
+    const markerOpts = loc.has_upcoming ? {} : { opacity: 0.5, title: name + " (past)" };
     state.locationMarkersByKey[markerKey(lat, lng)] =
-      window.L.marker([lat, lng]).addTo(map).bindPopup(popup);
+      window.L.marker([lat, lng], markerOpts).addTo(map).bindPopup(popup);
     // Use bounds to auto-fit the map view to all markers
     bounds.push([lat, lng]);
   }
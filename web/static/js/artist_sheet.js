@@ -0,0 +1,7 @@
+(() => { // IIFE to avoid leaking globals
+  // The print button opens the browser dialog, where "Save as PDF" exports the sheet
+  const btn = document.querySelector("[data-sheet-print]");
+  if (!btn || typeof window.print !== "function") return;
+
+  btn.addEventListener("click", () => window.print());
+})();
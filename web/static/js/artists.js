@@ -41,7 +41,9 @@
 
     function updateResultCount() {
         if (!resultsEl) return;
-        const cards = list.querySelectorAll("a").length;
+        // Paged lists carry the filtered total, the cards are only the current page
+        const totalEl = list.querySelector("[data-total-results]");
+        const cards = totalEl ? Number(totalEl.getAttribute("data-total-results")) : list.querySelectorAll("article").length;
         resultsEl.textContent = String(cards) + " results";
     }
 
@@ -50,14 +52,14 @@
         try {
             const u = new URL(window.location.href);
             const s = (u.searchParams.get("source") || "").trim().toLowerCase();
-            if (s === "spotify" || s === "deezer" || s === "apple" || s === "groupie") return s;
+            if (s === "spotify" || s === "deezer" || s === "apple" || s === "musicbrainz" || s === "groupie") return s;
         } catch (e) {
             // Ignore invalid URL parsing and fall back to the hidden input
         }
 
         const sourceInput = document.getElementById("source");
         const v = sourceInput ? String(sourceInput.value || "").trim().toLowerCase() : "";
-        if (v === "spotify" || v === "deezer" || v === "apple") return v;
+        if (v === "spotify" || v === "deezer" || v === "apple" || v === "musicbrainz") return v;
         return "groupie";
     }
 
@@ -223,6 +225,37 @@
     normalizeRanges();
     updateResultCount();
 
+    // Grid/list toggle, switches in place and keeps the current filters
+    // The ajax request carries `view`, so the server remembers the choice
+    const viewInput = document.getElementById("view");
+    const viewButtons = document.querySelectorAll("[data-artists-view]");
+    const activeViewClasses = ["bg-slate-200", "text-slate-950", "dark:bg-slate-700", "dark:text-white"];
+    const idleViewClasses = ["text-slate-600", "hover:text-slate-950", "dark:text-slate-300", "dark:hover:text-white"];
+
+    viewButtons.forEach(function (btn) { // callback runs for each toggle button
+        btn.addEventListener("click", function (event) {
+            if (!viewInput) return;
+            event.preventDefault();
+
+            const view = btn.getAttribute("data-artists-view");
+            if (viewInput.value === view) return;
+            viewInput.value = view;
+
+            list.className = list.getAttribute(view === "list" ? "data-list-class" : "data-grid-class") || list.className;
+            viewButtons.forEach(function (other) {
+                const active = other === btn;
+                other.setAttribute("aria-pressed", active ? "true" : "false");
+                other.classList.remove.apply(other.classList, active ? idleViewClasses : activeViewClasses);
+                other.classList.add.apply(other.classList, active ? activeViewClasses : idleViewClasses);
+            });
+
+            if (timeoutId) {
+                window.clearTimeout(timeoutId);
+            }
+            fetchArtists();
+        });
+    });
+
     // Search suggestions (Groupie mode only)
     const searchInput = document.getElementById("q");
     const locationInput = document.getElementById("location");
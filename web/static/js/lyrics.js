@@ -0,0 +1,95 @@
+(function () { // IIFE to avoid leaking globals
+    // Lyrics modal, loads `/lyrics?artist=&title=` when a track's "Lyrics" button is clicked
+    const modalRoot = document.getElementById("lyrics_modal_root");
+    const backdrop = document.getElementById("lyrics_modal_backdrop");
+    const titleEl = document.getElementById("lyrics_modal_title");
+    const textEl = document.getElementById("lyrics_modal_text");
+    const closeBtn = document.getElementById("lyrics_modal_close_btn");
+
+    if (!modalRoot || !backdrop || !titleEl || !textEl || !closeBtn) {
+        return;
+    }
+
+    // Ignore responses for a song the user already closed or switched away from
+    let requestId = 0;
+
+    function getBasePath() {
+        const bp = document.body ? (document.body.getAttribute("data-base-path") || "") : "";
+        return String(bp || "").replace(/\/+$/, "");
+    }
+
+    // showModal opens the modal with a title and a status or lyrics text
+    function showModal(title, text) {
+        titleEl.textContent = title;
+        textEl.textContent = text;
+        modalRoot.classList.remove("hidden");
+        modalRoot.classList.add("flex");
+        modalRoot.setAttribute("aria-hidden", "false");
+        document.body.classList.add("overflow-hidden");
+    }
+
+    function closeModal() {
+        requestId++;
+        modalRoot.classList.add("hidden");
+        modalRoot.classList.remove("flex");
+        modalRoot.setAttribute("aria-hidden", "true");
+        document.body.classList.remove("overflow-hidden");
+    }
+
+    // loadLyrics fetches lyrics as JSON and renders them as plain text
+    function loadLyrics(artist, title) {
+        const id = ++requestId;
+        showModal(title + " — " + artist, "Loading lyrics...");
+
+        const params = new URLSearchParams();
+        params.set("artist", artist);
+        params.set("title", title);
+
+        fetch(getBasePath() + "/lyrics?" + params.toString(), {
+            headers: {
+                "Accept": "application/json"
+            }
+        })
+            .then(function (res) { // errors carry a JSON message too
+                return res.json().then(function (data) {
+                    return { ok: res.ok, data: data || {} };
+                });
+            })
+            .then(function (result) {
+                if (id !== requestId) return;
+                if (!result.ok) {
+                    textEl.textContent = result.data.error || "Lyrics are unavailable for this song.";
+                    return;
+                }
+                textEl.textContent = result.data.lyrics || "";
+            })
+            .catch(function () {
+                if (id !== requestId) return;
+                textEl.textContent = "Lyrics are unavailable right now.";
+            });
+    }
+
+    // onDocumentClick uses event delegation so every track row shares one listener
+    function onDocumentClick(e) {
+        const btn = e.target && e.target.closest ? e.target.closest("[data-lyrics-open]") : null;
+        if (!btn) return;
+
+        e.preventDefault();
+
+        const artist = btn.getAttribute("data-lyrics-artist") || "";
+        const title = btn.getAttribute("data-lyrics-title") || "";
+        if (!artist || !title) return;
+
+        loadLyrics(artist, title);
+    }
+
+    // onKeyDown closes the modal on Escape
+    function onKeyDown(e) {
+        if (e.key === "Escape") closeModal();
+    }
+
+    backdrop.addEventListener("click", closeModal);
+    closeBtn.addEventListener("click", closeModal);
+    document.addEventListener("keydown", onKeyDown);
+    document.addEventListener("click", onDocumentClick);
+})();
@@ -46,18 +46,20 @@
         if (e.key === "Escape") closeModal();
     }
 
-    // Bind click handlers on all Spotify "Open" buttons in the page
-    document.querySelectorAll("[data-spotify-open]").forEach((btn) => { // per-button setup
-        // Each button carries the target URL in a data attribute
-        btn.addEventListener("click", (e) => { // click handler opens the modal
-            e.preventDefault();
-            openModal({
-                spotifyUrl: btn.getAttribute("data-spotify-url") || ""
-            });
+    // onDocumentClick uses event delegation so cards appended by "show more" work too
+    function onDocumentClick(e) {
+        const btn = e.target && e.target.closest ? e.target.closest("[data-spotify-open]") : null;
+        if (!btn) return;
+
+        e.preventDefault();
+
+        openModal({
+            spotifyUrl: btn.getAttribute("data-spotify-url") || ""
         });
-    });
+    }
 
     backdrop.addEventListener("click", closeModal);
     closeBtn.addEventListener("click", closeModal);
     document.addEventListener("keydown", onKeyDown);
+    document.addEventListener("click", onDocumentClick);
 })();